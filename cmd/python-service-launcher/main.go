@@ -31,19 +31,43 @@
 //	python-service-launcher --startup              # same as above (explicit mode)
 //	python-service-launcher --check                # run health check
 //	python-service-launcher --status               # check if service is running
+//	python-service-launcher --status --output json # machine-readable status (also yaml)
+//	python-service-launcher --stop                 # SIGTERM the running service, SIGKILL after timeout
+//	python-service-launcher --reload               # SIGHUP the running service so it reloads itself
+//	python-service-launcher --validate             # resolve config and report lint warnings
+//	python-service-launcher --print-env            # print the resolved child env, secrets redacted
+//	python-service-launcher --explain-config       # print key config values and where each came from
+//	python-service-launcher --detect               # print detected CPU/memory limits as JSON, no config needed
 //	python-service-launcher --static-config PATH   # override static config path
 //	python-service-launcher --custom-config PATH   # override custom config path
+//	python-service-launcher --config-dir DIR       # read both configs from DIR
+//	python-service-launcher --stderr-file PATH     # child stderr to PATH (needs mergeStderr: false)
+//	python-service-launcher --dev                  # run from the current directory: no PID file, debug logs
+//	python-service-launcher --dev --watch          # also restart the process when the executable changes
 package main
 
 import (
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/jaymd96/python-service-launcher/launchlib"
 )
 
+// Exit codes for launcher failures, following sysexits(3) so orchestrators
+// can tell configuration problems apart from service failures.
+const (
+	exitFailure        = 1
+	exitConfigParse    = 65 // EX_DATAERR
+	exitConfigNotFound = 66 // EX_NOINPUT
+	exitConfigInvalid  = 78 // EX_CONFIG
+)
+
 var (
 	// Build-time variables set by -ldflags
 	version   = "dev"
@@ -52,12 +76,30 @@ var (
 
 func main() {
 	// Flags
-	staticConfig := flag.String("static-config", "", "Path to static launcher config (default: service/bin/launcher-static.yml)")
+	staticConfig := flag.String("static-config", "", "Path to static launcher config (default: service/bin/launcher-static.yml, then ./, $XDG_CONFIG_HOME/python-service-launcher, /etc/python-service-launcher)")
 	customConfig := flag.String("custom-config", "", "Path to custom launcher config (default: var/conf/launcher-custom.yml)")
+	configDir := flag.String("config-dir", "", "Directory containing launcher-static.yml and launcher-custom.yml; --static-config/--custom-config take precedence")
 	distRootFlag := flag.String("dist-root", "", "Distribution root directory (default: auto-detect from executable path)")
-	mode := flag.String("mode", "startup", "Launch mode: startup, check, status")
+	mode := flag.String("mode", "startup", "Launch mode: startup, check, status, stop, reload, validate, print-env, explain-config")
 	checkMode := flag.Bool("check", false, "Run health check instead of starting the service")
+	stderrFile := flag.String("stderr-file", "", "Append the process's stderr to this file when the static config sets mergeStderr: false (default: the launcher's stderr)")
+	execMode := flag.Bool("exec", false, "Replace the launcher with the process instead of supervising it (no watchdog, PID file or signal forwarding)")
 	statusMode := flag.Bool("status", false, "Check if the service is running")
+	stopMode := flag.Bool("stop", false, "Stop the running service")
+	reloadMode := flag.Bool("reload", false, "Send SIGHUP to the running service (from its PID file) so it reloads itself")
+	stopTimeout := flag.Duration("stop-timeout", 30*time.Second, "How long --stop waits after SIGTERM before sending SIGKILL")
+	pidFile := flag.String("pid-file", "", "PID file used by --status, --stop and --reload (default: from static config, else var/run/<service>.pid)")
+	output := flag.String("output", launchlib.OutputText, "Output format for --status, --stop and --reload: text, json, yaml")
+	validateMode := flag.Bool("validate", false, "Resolve the configuration and report warnings without launching")
+	printEnvMode := flag.Bool("print-env", false, "Print the fully resolved child environment, sorted, and exit")
+	showSecrets := flag.Bool("show-secrets", false, "Do not redact secret values in --print-env output")
+	explainMode := flag.Bool("explain-config", false, "Print the resolved memory, watchdog and env settings with the source of each (static, custom, default, env-override) and exit")
+	detectMode := flag.Bool("detect", false, "Print the detected CPU count, cgroup memory limit and container status as JSON and exit (needs no config)")
+	var devMode bool
+	flag.BoolVar(&devMode, "dev", false, "Run from a source checkout: use the current directory as the dist root (no chdir), skip the PID file, log text at debug level, and disable the watchdog unless configured")
+	flag.BoolVar(&devMode, "foreground", false, "Alias for --dev")
+	watchMode := flag.Bool("watch", false, "With --dev, restart the process when the executable's mtime changes")
+	watchConfig := flag.Bool("watch-config", false, "With --watch, also restart when the static or custom config changes")
 	showVersion := flag.Bool("version", false, "Print version and exit")
 	serviceName := flag.String("service-name", "", "Service name (auto-detected from config if omitted)")
 	serviceVersion := flag.String("service-version", "", "Service version (auto-detected from manifest if omitted)")
@@ -69,6 +111,19 @@ func main() {
 		os.Exit(0)
 	}
 
+	// --detect reports on the machine, not a distribution, so it runs before
+	// the dist root is resolved.
+	if *detectMode {
+		os.Exit(doDetect())
+	}
+
+	if (*watchMode || *watchConfig) && !devMode {
+		fmt.Fprintln(os.Stderr, "--watch and --watch-config require --dev")
+		os.Exit(2)
+	}
+
+	*staticConfig, *customConfig = resolveConfigPaths(*staticConfig, *customConfig, *configDir)
+
 	// Determine mode from flags
 	launchMode := *mode
 	if *checkMode {
@@ -77,31 +132,47 @@ func main() {
 	if *statusMode {
 		launchMode = "status"
 	}
+	if *stopMode {
+		launchMode = "stop"
+	}
+	if *reloadMode {
+		launchMode = "reload"
+	}
+	if *validateMode {
+		launchMode = "validate"
+	}
+	if *printEnvMode {
+		launchMode = "print-env"
+	}
+	if *explainMode {
+		launchMode = "explain-config"
+	}
 
-	// Determine distribution root.
-	var distRoot string
-	if *distRootFlag != "" {
-		distRoot = *distRootFlag
-	} else {
-		// The launcher binary lives at service/bin/<arch>/python-service-launcher,
-		// so the dist root is three directories up.
-		execPath, err := os.Executable()
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Failed to determine executable path: %v\n", err)
-			os.Exit(1)
-		}
-		distRoot = filepath.Dir(filepath.Dir(filepath.Dir(filepath.Dir(execPath))))
+	// Remember where the launcher was invoked, which is searched for
+	// ./launcher-static.yml when the default static config is missing.
+	searchDir, err := os.Getwd()
+	if err != nil {
+		searchDir = "."
 	}
 
-	// Change to dist root so all relative paths resolve correctly
-	if err := os.Chdir(distRoot); err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to chdir to distribution root %s: %v\n", distRoot, err)
+	setup, err := resolveLaunchSetup(*distRootFlag, devMode, searchDir, os.Executable)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to determine executable path: %v\n", err)
 		os.Exit(1)
 	}
+	distRoot := setup.distRoot
+
+	// Change to dist root so all relative paths resolve correctly
+	if setup.chdir {
+		if err := os.Chdir(distRoot); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to chdir to distribution root %s: %v\n", distRoot, err)
+			os.Exit(1)
+		}
+	}
 
 	switch launchMode {
 	case "startup":
-		exitCode := doStartup(*staticConfig, *customConfig, *serviceName, *serviceVersion, distRoot)
+		exitCode := doStartup(*staticConfig, *customConfig, *serviceName, *serviceVersion, distRoot, searchDir, *stderrFile, *execMode, setup.dev, *watchMode || *watchConfig, *watchConfig)
 		os.Exit(exitCode)
 
 	case "check":
@@ -109,7 +180,42 @@ func main() {
 		os.Exit(exitCode)
 
 	case "status":
-		exitCode := doStatus(*serviceName)
+		pidPath, err := resolvePidFile(*pidFile, *staticConfig, *serviceName, distRoot, searchDir)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to resolve PID file: %v\n", err)
+			os.Exit(exitCodeForError(err))
+		}
+		exitCode := doStatus(pidPath, *output)
+		os.Exit(exitCode)
+
+	case "stop":
+		pidPath, err := resolvePidFile(*pidFile, *staticConfig, *serviceName, distRoot, searchDir)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to resolve PID file: %v\n", err)
+			os.Exit(exitCodeForError(err))
+		}
+		exitCode := doStop(pidPath, *stopTimeout, *output)
+		os.Exit(exitCode)
+
+	case "reload":
+		pidPath, err := resolvePidFile(*pidFile, *staticConfig, *serviceName, distRoot, searchDir)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to resolve PID file: %v\n", err)
+			os.Exit(exitCodeForError(err))
+		}
+		exitCode := doReload(pidPath, *output)
+		os.Exit(exitCode)
+
+	case "validate":
+		exitCode := doValidate(*staticConfig, *customConfig, *serviceName, *serviceVersion, distRoot, searchDir)
+		os.Exit(exitCode)
+
+	case "print-env":
+		exitCode := doPrintEnv(*staticConfig, *customConfig, *serviceName, *serviceVersion, distRoot, searchDir, *showSecrets)
+		os.Exit(exitCode)
+
+	case "explain-config":
+		exitCode := doExplainConfig(*staticConfig, *customConfig, *serviceName, *serviceVersion, distRoot, searchDir)
 		os.Exit(exitCode)
 
 	default:
@@ -118,51 +224,149 @@ func main() {
 	}
 }
 
-func doStartup(staticConfigPath, customConfigPath, serviceName, serviceVersion, distRoot string) int {
-	// Auto-detect service name and version from manifest if not provided
-	if serviceName == "" || serviceVersion == "" {
-		name, ver, err := readManifestMetadata("deployment/manifest.yml")
+func doStartup(staticConfigPath, customConfigPath, serviceName, serviceVersion, distRoot, searchDir, stderrFile string, exec, dev, watch, watchConfig bool) int {
+	serviceName, serviceVersion = resolveServiceMetadata(serviceName, serviceVersion)
+
+	var stderr io.Writer = os.Stderr
+	if stderrFile != "" {
+		file, err := os.OpenFile(stderrFile, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "WARNING: failed to read manifest: %v\n", err)
-			if serviceName == "" {
-				serviceName = "unknown"
-			}
-			if serviceVersion == "" {
-				serviceVersion = "0.0.0"
-			}
-		} else {
-			if serviceName == "" {
-				serviceName = name
-			}
-			if serviceVersion == "" {
-				serviceVersion = ver
-			}
+			fmt.Fprintf(os.Stderr, "Failed to open stderr file: %v\n", err)
+			return 1
 		}
+		defer file.Close()
+		stderr = file
 	}
 
 	params := launchlib.LauncherParams{
 		DistRoot:         distRoot,
 		StaticConfigPath: staticConfigPath,
 		CustomConfigPath: customConfigPath,
+		SearchDir:        searchDir,
 		ServiceName:      serviceName,
 		ServiceVersion:   serviceVersion,
 		Stdout:           os.Stdout,
+		Stderr:           stderr,
+		LauncherVersion:  version,
+		LauncherCommit:   gitCommit,
+		Exec:             exec,
+		Dev:              dev,
+		Watch:            watch,
+		WatchConfig:      watchConfig,
 	}
 
 	launcher := launchlib.NewLauncher(params)
 	result, err := launcher.Launch()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Launch failed: %v\n", err)
-		return 1
+		return exitCodeForError(err)
 	}
 
 	if result.WatchdogTriggered {
 		fmt.Fprintf(os.Stderr, "Process was terminated by RSS watchdog (OOM prevention)\n")
 	}
+	if result.OOMKilled {
+		fmt.Fprintf(os.Stderr, "Process was killed by the kernel OOM killer\n")
+	}
+	if result.CriticalSubprocessFailure != "" {
+		fmt.Fprintf(os.Stderr, "Process was shut down because a %s\n", result.CriticalSubprocessFailure)
+	}
 
 	return result.ExitCode
 }
 
+func doValidate(staticConfigPath, customConfigPath, serviceName, serviceVersion, distRoot, searchDir string) int {
+	serviceName, serviceVersion = resolveServiceMetadata(serviceName, serviceVersion)
+
+	params := launchlib.LauncherParams{
+		DistRoot:         distRoot,
+		StaticConfigPath: staticConfigPath,
+		CustomConfigPath: customConfigPath,
+		SearchDir:        searchDir,
+		ServiceName:      serviceName,
+		ServiceVersion:   serviceVersion,
+		Stdout:           os.Stdout,
+		LauncherVersion:  version,
+		LauncherCommit:   gitCommit,
+	}
+
+	launcher := launchlib.NewLauncher(params)
+	warnings, err := launcher.Validate()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Validation failed: %v\n", err)
+		return exitCodeForError(err)
+	}
+
+	fmt.Printf("Configuration valid (%d warning(s))\n", len(warnings))
+	return 0
+}
+
+func doExplainConfig(staticConfigPath, customConfigPath, serviceName, serviceVersion, distRoot, searchDir string) int {
+	serviceName, serviceVersion = resolveServiceMetadata(serviceName, serviceVersion)
+
+	// Launcher logs go to stderr so stdout is only the report.
+	params := launchlib.LauncherParams{
+		DistRoot:         distRoot,
+		StaticConfigPath: staticConfigPath,
+		CustomConfigPath: customConfigPath,
+		SearchDir:        searchDir,
+		ServiceName:      serviceName,
+		ServiceVersion:   serviceVersion,
+		Stdout:           os.Stderr,
+		LauncherVersion:  version,
+		LauncherCommit:   gitCommit,
+	}
+
+	launcher := launchlib.NewLauncher(params)
+	fields, err := launcher.ExplainConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to resolve configuration: %v\n", err)
+		return exitCodeForError(err)
+	}
+	for _, field := range fields {
+		fmt.Println(field)
+	}
+	return 0
+}
+
+func doDetect() int {
+	data, err := json.MarshalIndent(launchlib.DetectCapacity(), "", "  ")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to encode detection report: %v\n", err)
+		return 1
+	}
+	fmt.Println(string(data))
+	return 0
+}
+
+func doPrintEnv(staticConfigPath, customConfigPath, serviceName, serviceVersion, distRoot, searchDir string, showSecrets bool) int {
+	serviceName, serviceVersion = resolveServiceMetadata(serviceName, serviceVersion)
+
+	// Launcher logs go to stderr so stdout is only the environment, for piping.
+	params := launchlib.LauncherParams{
+		DistRoot:         distRoot,
+		StaticConfigPath: staticConfigPath,
+		CustomConfigPath: customConfigPath,
+		SearchDir:        searchDir,
+		ServiceName:      serviceName,
+		ServiceVersion:   serviceVersion,
+		Stdout:           os.Stderr,
+		LauncherVersion:  version,
+		LauncherCommit:   gitCommit,
+	}
+
+	launcher := launchlib.NewLauncher(params)
+	env, err := launcher.ResolveEnv(showSecrets)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to resolve environment: %v\n", err)
+		return exitCodeForError(err)
+	}
+	for _, entry := range env {
+		fmt.Println(entry)
+	}
+	return 0
+}
+
 func doCheck(serviceName, distRoot string) int {
 	// Read the check config and run the health check PEX
 	checkConfigPath := "service/bin/launcher-check.yml"
@@ -174,98 +378,236 @@ func doCheck(serviceName, distRoot string) int {
 	params := launchlib.LauncherParams{
 		DistRoot:         distRoot,
 		StaticConfigPath: checkConfigPath,
+		Check:            true,
 		ServiceName:      serviceName,
 		ServiceVersion:   "check",
 		Stdout:           os.Stdout,
+		LauncherVersion:  version,
+		LauncherCommit:   gitCommit,
 	}
 
 	launcher := launchlib.NewLauncher(params)
 	result, err := launcher.Launch()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Health check failed: %v\n", err)
-		return 1
+		return exitCodeForError(err)
 	}
 	return result.ExitCode
 }
 
-func doStatus(serviceName string) int {
-	pidPath := fmt.Sprintf("var/run/%s.pid", serviceName)
-	pid, err := launchlib.ReadPidFile(pidPath)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Service not running (no pid file at %s)\n", pidPath)
+func doStatus(pidPath, output string) int {
+	status := launchlib.CheckServiceStatus(pidPath)
+	if status.Reason == launchlib.StatusReasonStalePidFile {
+		launchlib.RemovePidFile(pidPath)
+	}
+	// Text-mode failures go to stderr; structured output always goes to stdout.
+	if err := printStatus(status, output, !status.Running && output == launchlib.OutputText); err != nil {
+		return 1
+	}
+	if !status.Running {
 		return 1
 	}
+	return 0
+}
 
-	if !launchlib.IsProcessAlive(pid) {
-		fmt.Fprintf(os.Stderr, "Service not running (stale pid file, pid=%d)\n", pid)
-		launchlib.RemovePidFile(pidPath)
+func doStop(pidPath string, timeout time.Duration, output string) int {
+	text := output == launchlib.OutputText
+	status := launchlib.CheckServiceStatus(pidPath)
+	// Reject a bad --output before signalling anything.
+	if _, err := launchlib.FormatStatus(status, output); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return 1
+	}
+	switch status.Reason {
+	case launchlib.StatusReasonPidFileDisabled:
+		if text {
+			fmt.Fprintf(os.Stderr, "PID file is disabled; cannot stop service\n")
+		} else {
+			_ = printStatus(status, output, false)
+		}
+		return 1
+	case launchlib.StatusReasonNoPidFile, launchlib.StatusReasonStalePidFile:
+		if status.Reason == launchlib.StatusReasonStalePidFile {
+			launchlib.RemovePidFile(pidPath)
+		}
+		if err := printStatus(status, output, false); err != nil {
+			return 1
+		}
+		return 0
+	}
+
+	if text {
+		fmt.Printf("Stopping service (pid=%d)...\n", status.Pid)
+	}
+	killed, err := launchlib.StopProcess(status.Pid, timeout)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to stop service: %v\n", err)
 		return 1
 	}
+	launchlib.RemovePidFile(pidPath)
 
-	fmt.Printf("Service running: pid=%d\n", pid)
+	status.Running = false
+	status.Reason = launchlib.StatusReasonStopped
+	if killed {
+		status.Reason = launchlib.StatusReasonKilled
+	}
+	if !text {
+		if err := printStatus(status, output, false); err != nil {
+			return 1
+		}
+		return 0
+	}
+	if killed {
+		fmt.Printf("Graceful shutdown timed out after %s, sent SIGKILL\n", timeout)
+	}
+	fmt.Printf("Service stopped\n")
 	return 0
 }
 
-// readManifestMetadata extracts product-name and product-version from the SLS manifest.
-func readManifestMetadata(path string) (string, string, error) {
-	data, err := os.ReadFile(path)
+func doReload(pidPath, output string) int {
+	// Reject a bad --output before signalling anything.
+	if _, err := launchlib.FormatStatus(launchlib.ServiceStatus{}, output); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return 1
+	}
+	status, err := launchlib.ReloadService(pidPath)
 	if err != nil {
-		return "", "", err
+		fmt.Fprintf(os.Stderr, "Failed to reload service: %v\n", err)
+		return 1
+	}
+	if status.Reason == launchlib.StatusReasonStalePidFile {
+		launchlib.RemovePidFile(pidPath)
+	}
+	// Text-mode failures go to stderr; structured output always goes to stdout.
+	if err := printStatus(status, output, !status.Running && output == launchlib.OutputText); err != nil {
+		return 1
 	}
+	if !status.Running {
+		return 1
+	}
+	return 0
+}
 
-	// Simple line-based parsing to avoid a full YAML dependency in main
-	// (the launchlib already uses yaml.v3, but keeping main minimal)
-	var name, ver string
-	for _, line := range splitLines(string(data)) {
-		line = trimSpace(line)
-		if hasPrefix(line, "product-name:") {
-			name = trimQuotes(trimSpace(line[len("product-name:"):]))
-		}
-		if hasPrefix(line, "product-version:") {
-			ver = trimQuotes(trimSpace(line[len("product-version:"):]))
-		}
+// exitCodeForError maps a launch error to an exit code, distinguishing the
+// kinds of configuration failure.
+func exitCodeForError(err error) int {
+	switch {
+	case errors.Is(err, launchlib.ErrStaticConfigNotFound):
+		return exitConfigNotFound
+	case errors.Is(err, launchlib.ErrConfigParse):
+		return exitConfigParse
+	case errors.Is(err, launchlib.ErrConfigValidation):
+		return exitConfigInvalid
+	default:
+		return exitFailure
+	}
+}
+
+// printStatus writes status in the requested format to stdout, or to stderr
+// when toStderr is set.
+func printStatus(status launchlib.ServiceStatus, output string, toStderr bool) error {
+	formatted, err := launchlib.FormatStatus(status, output)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return err
 	}
-	if name == "" {
-		return "", "", fmt.Errorf("product-name not found in manifest")
+	if toStderr {
+		fmt.Fprintln(os.Stderr, formatted)
+	} else {
+		fmt.Println(formatted)
 	}
-	return name, ver, nil
+	return nil
 }
 
-// Minimal string helpers to avoid importing strings in main
-func splitLines(s string) []string {
-	var lines []string
-	start := 0
-	for i := 0; i < len(s); i++ {
-		if s[i] == '\n' {
-			lines = append(lines, s[start:i])
-			start = i + 1
-		}
+// launchSetup is how main prepares the distribution root before running a mode.
+type launchSetup struct {
+	// distRoot is the distribution root passed to the launcher.
+	distRoot string
+
+	// chdir is whether to change into distRoot first.
+	chdir bool
+
+	// dev is whether the launch runs in dev mode (LauncherParams.Dev).
+	dev bool
+}
+
+// resolveLaunchSetup derives the launch setup from the --dist-root and --dev
+// flags. Without --dist-root the dist root is three directories above the
+// launcher binary (service/bin/<arch>/python-service-launcher), or cwd in dev
+// mode. Dev mode never changes directory.
+func resolveLaunchSetup(distRootFlag string, dev bool, cwd string, executable func() (string, error)) (launchSetup, error) {
+	setup := launchSetup{distRoot: distRootFlag, chdir: !dev, dev: dev}
+	if setup.distRoot != "" {
+		return setup, nil
 	}
-	if start < len(s) {
-		lines = append(lines, s[start:])
+	if dev {
+		setup.distRoot = cwd
+		return setup, nil
 	}
-	return lines
+	execPath, err := executable()
+	if err != nil {
+		return launchSetup{}, err
+	}
+	setup.distRoot = filepath.Dir(filepath.Dir(filepath.Dir(filepath.Dir(execPath))))
+	return setup, nil
 }
 
-func trimSpace(s string) string {
-	i := 0
-	for i < len(s) && (s[i] == ' ' || s[i] == '\t' || s[i] == '\r') {
-		i++
+// resolveConfigPaths applies --config-dir to the static and custom config
+// paths. Precedence: an explicit --static-config/--custom-config, then
+// --config-dir, then the launcher defaults (returned as empty paths).
+func resolveConfigPaths(staticConfig, customConfig, configDir string) (string, string) {
+	if configDir == "" {
+		return staticConfig, customConfig
+	}
+	if staticConfig == "" {
+		staticConfig = filepath.Join(configDir, "launcher-static.yml")
 	}
-	j := len(s)
-	for j > i && (s[j-1] == ' ' || s[j-1] == '\t' || s[j-1] == '\r') {
-		j--
+	if customConfig == "" {
+		customConfig = filepath.Join(configDir, "launcher-custom.yml")
 	}
-	return s[i:j]
+	return staticConfig, customConfig
 }
 
-func hasPrefix(s, prefix string) bool {
-	return len(s) >= len(prefix) && s[:len(prefix)] == prefix
+// resolvePidFile determines the PID file for --status, --stop and --reload. An
+// explicit --pid-file wins; otherwise the static config is found and read the
+// same way startup does, and any error reading it is returned.
+func resolvePidFile(flagValue, staticConfigPath, serviceName, distRoot, searchDir string) (string, error) {
+	if flagValue != "" {
+		return flagValue, nil
+	}
+	serviceName, _ = resolveServiceMetadata(serviceName, "unused")
+	launcher := launchlib.NewLauncher(launchlib.LauncherParams{
+		DistRoot:         distRoot,
+		StaticConfigPath: staticConfigPath,
+		SearchDir:        searchDir,
+		ServiceName:      serviceName,
+		Stdout:           os.Stderr,
+	})
+	return launcher.PidFile()
 }
 
-func trimQuotes(s string) string {
-	if len(s) >= 2 && ((s[0] == '"' && s[len(s)-1] == '"') || (s[0] == '\'' && s[len(s)-1] == '\'')) {
-		return s[1 : len(s)-1]
+// resolveServiceMetadata fills in the service name and version from the SLS
+// manifest when they were not provided on the command line.
+func resolveServiceMetadata(serviceName, serviceVersion string) (string, string) {
+	if serviceName != "" && serviceVersion != "" {
+		return serviceName, serviceVersion
+	}
+	metadata, err := launchlib.ReadManifestMetadata(launchlib.DefaultManifestPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "WARNING: failed to read manifest: %v\n", err)
+		if serviceName == "" {
+			serviceName = "unknown"
+		}
+		if serviceVersion == "" {
+			serviceVersion = "0.0.0"
+		}
+		return serviceName, serviceVersion
+	}
+	if serviceName == "" {
+		serviceName = metadata.ProductName
+	}
+	if serviceVersion == "" {
+		serviceVersion = metadata.ProductVersion
 	}
-	return s
+	return serviceName, serviceVersion
 }
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/jaymd96/python-service-launcher/launchlib"
+)
+
+func TestResolveConfigPaths(t *testing.T) {
+	tests := []struct {
+		name       string
+		static     string
+		custom     string
+		configDir  string
+		wantStatic string
+		wantCustom string
+	}{
+		{"defaults", "", "", "", "", ""},
+		{"config dir", "", "", "/etc/svc", "/etc/svc/launcher-static.yml", "/etc/svc/launcher-custom.yml"},
+		{"relative config dir", "", "", "conf", "conf/launcher-static.yml", "conf/launcher-custom.yml"},
+		{"explicit static wins", "s.yml", "", "/etc/svc", "s.yml", "/etc/svc/launcher-custom.yml"},
+		{"explicit custom wins", "", "c.yml", "/etc/svc", "/etc/svc/launcher-static.yml", "c.yml"},
+		{"explicit without config dir", "s.yml", "c.yml", "", "s.yml", "c.yml"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotStatic, gotCustom := resolveConfigPaths(tt.static, tt.custom, tt.configDir)
+			if gotStatic != tt.wantStatic || gotCustom != tt.wantCustom {
+				t.Errorf("expected (%q, %q), got (%q, %q)", tt.wantStatic, tt.wantCustom, gotStatic, gotCustom)
+			}
+		})
+	}
+}
+
+func TestResolveLaunchSetup(t *testing.T) {
+	executable := func() (string, error) { return "/opt/svc/service/bin/linux-amd64/python-service-launcher", nil }
+	tests := []struct {
+		name         string
+		distRootFlag string
+		dev          bool
+		want         launchSetup
+	}{
+		{"installed", "", false, launchSetup{distRoot: "/opt/svc", chdir: true}},
+		{"explicit dist root", "/srv/svc", false, launchSetup{distRoot: "/srv/svc", chdir: true}},
+		{"dev uses cwd", "", true, launchSetup{distRoot: "/home/dev/checkout", dev: true}},
+		{"dev with explicit dist root", "/srv/svc", true, launchSetup{distRoot: "/srv/svc", dev: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolveLaunchSetup(tt.distRootFlag, tt.dev, "/home/dev/checkout", executable)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+
+	failing := func() (string, error) { return "", errors.New("no executable") }
+	if _, err := resolveLaunchSetup("", false, "/home/dev/checkout", failing); err == nil {
+		t.Error("expected an error when the executable path is unknown")
+	}
+	if _, err := resolveLaunchSetup("", true, "/home/dev/checkout", failing); err != nil {
+		t.Errorf("expected dev mode not to need the executable path, got %v", err)
+	}
+}
+
+func TestExitCodeForError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"not found", fmt.Errorf("config error: %w", launchlib.ErrStaticConfigNotFound), exitConfigNotFound},
+		{"parse", fmt.Errorf("config error: %w", launchlib.ErrConfigParse), exitConfigParse},
+		{"validation", fmt.Errorf("config error: %w", &launchlib.ConfigValidationError{Field: "executable"}), exitConfigInvalid},
+		{"other", errors.New("failed to start process"), exitFailure},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := exitCodeForError(tt.err); got != tt.want {
+				t.Errorf("expected exit code %d, got %d", tt.want, got)
+			}
+		})
+	}
+}
@@ -15,9 +15,20 @@
 package launchlib
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
+	"net/url"
 	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
@@ -31,14 +42,90 @@ const (
 type LaunchMode string
 
 const (
-	LaunchModePEX     LaunchMode = "pex"
-	LaunchModeModule  LaunchMode = "module"
-	LaunchModeScript  LaunchMode = "script"
-	LaunchModeUvicorn LaunchMode = "uvicorn"
+	LaunchModePEX      LaunchMode = "pex"
+	LaunchModeModule   LaunchMode = "module"
+	LaunchModeScript   LaunchMode = "script"
+	LaunchModeUvicorn  LaunchMode = "uvicorn"
 	LaunchModeGunicorn LaunchMode = "gunicorn"
-	LaunchModeCommand LaunchMode = "command"
+	LaunchModeCommand  LaunchMode = "command"
 )
 
+// ListMergeMode controls how a custom config list combines with the static one.
+type ListMergeMode string
+
+const (
+	// ListMergeAppend appends the custom list to the static list. It is the default.
+	ListMergeAppend ListMergeMode = "append"
+
+	// ListMergeReplace uses the custom list instead of the static list, even
+	// when it is empty.
+	ListMergeReplace ListMergeMode = "replace"
+)
+
+// mergeList combines a static and custom list according to mode.
+func mergeList(static, custom []string, mode ListMergeMode) []string {
+	if mode == ListMergeReplace {
+		return append([]string{}, custom...)
+	}
+	return append(append([]string{}, static...), custom...)
+}
+
+// mergeEnvAppend combines two envAppend maps, appending the overlay's entries
+// after the base's for each variable.
+func mergeEnvAppend(base, overlay map[string][]string) map[string][]string {
+	if len(base) == 0 && len(overlay) == 0 {
+		return nil
+	}
+	merged := make(map[string][]string, len(base)+len(overlay))
+	for k, v := range base {
+		merged[k] = append([]string{}, v...)
+	}
+	for k, v := range overlay {
+		merged[k] = append(merged[k], v...)
+	}
+	return merged
+}
+
+// validatePathEntries checks pythonPathEntries and envAppend. An entry may not
+// be empty or contain the path list separator, which would split it.
+func validatePathEntries(pythonPathEntries []string, envAppend map[string][]string) error {
+	for i, entry := range pythonPathEntries {
+		if err := validatePathEntry(entry); err != nil {
+			return invalidField(fmt.Sprintf("pythonPathEntries[%d]", i), entry, "%v", err)
+		}
+	}
+	for _, key := range sortedKeys(envAppend) {
+		if key == "" || strings.Contains(key, "=") {
+			return invalidField("envAppend", key, "expected an environment variable name")
+		}
+		for i, entry := range envAppend[key] {
+			if err := validatePathEntry(entry); err != nil {
+				return invalidField(fmt.Sprintf("envAppend.%s[%d]", key, i), entry, "%v", err)
+			}
+		}
+	}
+	return nil
+}
+
+func validatePathEntry(entry string) error {
+	if entry == "" {
+		return errors.New("must not be empty")
+	}
+	if strings.ContainsRune(entry, os.PathListSeparator) {
+		return fmt.Errorf("must not contain %q", os.PathListSeparator)
+	}
+	return nil
+}
+
+// validateListMergeMode checks a custom config list merge mode.
+func validateListMergeMode(field string, mode ListMergeMode) error {
+	switch mode {
+	case "", ListMergeAppend, ListMergeReplace:
+		return nil
+	}
+	return invalidField(field, mode, "expected %q or %q", ListMergeAppend, ListMergeReplace)
+}
+
 // MemoryMode controls how the launcher manages memory limits for the Python process.
 type MemoryMode string
 
@@ -60,6 +147,9 @@ type PathsConfig struct {
 	PidFile      string `yaml:"pidFile,omitempty"`      // Default: var/run/%s.pid (%s = service name)
 	TmpDir       string `yaml:"tmpDir,omitempty"`       // Default: var/data/tmp
 	Manifest     string `yaml:"manifest,omitempty"`     // Default: deployment/manifest.yml
+
+	// PidFileEnabled controls whether a PID file is written at all. Default: true.
+	PidFileEnabled *bool `yaml:"pidFileEnabled,omitempty"`
 }
 
 // StaticLauncherConfig represents the immutable configuration generated at build time.
@@ -70,6 +160,12 @@ type StaticLauncherConfig struct {
 	// ConfigVersion must be 1.
 	ConfigVersion int `yaml:"configVersion" validate:"nonzero"`
 
+	// Include lists YAML files, relative to the distribution root, merged
+	// beneath this file when it is read: later includes win over earlier
+	// ones and this file wins over all of them. Includes may nest; cycles
+	// are an error.
+	Include []string `yaml:"include,omitempty"`
+
 	// LaunchMode controls how the process is started. Default: "pex".
 	LaunchMode LaunchMode `yaml:"launchMode,omitempty"`
 
@@ -81,6 +177,22 @@ type StaticLauncherConfig struct {
 	// Supports environment variable references like "$PYTHON_3_11_HOME/bin/python3".
 	PythonPath string `yaml:"pythonPath,omitempty"`
 
+	// PythonVersions maps version names (e.g. "3.11") to interpreter paths.
+	// When the custom config selects one with pythonVersion, its path replaces
+	// PythonPath. Paths support the same environment variable references.
+	PythonVersions map[string]string `yaml:"pythonVersions,omitempty"`
+
+	// RequirePythonVersion optionally constrains the interpreter version, e.g.
+	// ">=3.11,<3.13". It is checked before launch by running "pythonPath --version"
+	// and only applies when PythonPath is set and the launch mode is not "command".
+	RequirePythonVersion string `yaml:"requirePythonVersion,omitempty"`
+
+	// ResolveInterpreterFromShebang makes a PEX launch without PythonPath run
+	// the interpreter named on the PEX's "#!" line explicitly, so the PEX need
+	// not be executable and a missing interpreter is reported by name. A
+	// "/usr/bin/env python3" shebang is looked up on PATH.
+	ResolveInterpreterFromShebang bool `yaml:"resolveInterpreterFromShebang,omitempty"`
+
 	// EntryPoint optionally overrides the PEX's baked-in entry point.
 	// Format: "module.path:callable" (e.g., "my_service.server:main").
 	// If empty, the PEX's default entry point is used.
@@ -89,10 +201,35 @@ type StaticLauncherConfig struct {
 	// Args are arguments passed to the Python entry point after the PEX is invoked.
 	Args []string `yaml:"args,omitempty"`
 
+	// ArgsFile names a file (relative to the distribution root, or absolute)
+	// holding one argument per line. Blank lines and lines starting with '#'
+	// are ignored. Its arguments are appended after the static and custom Args.
+	ArgsFile string `yaml:"argsFile,omitempty"`
+
 	// Env specifies environment variables set before launching the process.
 	// These cannot reference each other or use shell expansion.
 	Env map[string]string `yaml:"env,omitempty"`
 
+	// EnvFromFile maps environment variable names to files (relative to the
+	// distribution root, or absolute) whose trimmed contents become the value.
+	// Use this for secrets rather than putting them in Env. A name set in both
+	// Env and EnvFromFile takes the file's value.
+	EnvFromFile map[string]string `yaml:"envFromFile,omitempty"`
+
+	// PythonPathEntries are prepended to PYTHONPATH (relative to the
+	// distribution root, or absolute), keeping any inherited or Env value
+	// after them.
+	PythonPathEntries []string `yaml:"pythonPathEntries,omitempty"`
+
+	// EnvAppend appends entries to path-list variables such as PATH or
+	// LD_LIBRARY_PATH, joined with the OS path list separator, after any
+	// inherited or Env value. Entries are used as given, not resolved.
+	EnvAppend map[string][]string `yaml:"envAppend,omitempty"`
+
+	// EnvInherit controls which of the launcher's own environment variables
+	// are passed through to the process.
+	EnvInherit EnvInheritConfig `yaml:"envInherit,omitempty"`
+
 	// PythonOpts are flags passed to the Python interpreter itself (before the PEX path).
 	// Examples: ["-O", "-u", "-W", "error"]
 	// Note: most of these should be set via env vars (PYTHONOPTIMIZE, PYTHONUNBUFFERED)
@@ -106,7 +243,14 @@ type StaticLauncherConfig struct {
 	Resources ResourceConfig `yaml:"resources,omitempty"`
 
 	// Dirs lists directories to create (relative to distribution root) before launch.
-	Dirs []string `yaml:"dirs,omitempty"`
+	// Entries are either a plain path or an object with mode and ownership.
+	// Unset means DefaultDirs(); an explicit empty list creates nothing.
+	Dirs []DirConfig `yaml:"dirs,omitempty"`
+
+	// WorkingDir overrides the primary process's working directory, relative to
+	// the distribution root. Other relative paths still resolve against the
+	// distribution root. Default: the distribution root.
+	WorkingDir string `yaml:"workingDir,omitempty"`
 
 	// Watchdog configures the RSS monitoring watchdog.
 	// Only active when Memory.Mode is "cgroup-aware" or "fixed".
@@ -126,16 +270,116 @@ type StaticLauncherConfig struct {
 
 	// CPU controls CPU detection and thread pool sizing.
 	CPU CPUConfig `yaml:"cpu,omitempty"`
+
+	// Telemetry controls OpenTelemetry resource attributes in the process env.
+	Telemetry TelemetryConfig `yaml:"telemetry,omitempty"`
+
+	// Metrics controls exporting launcher metrics such as exit status.
+	Metrics MetricsConfig `yaml:"metrics,omitempty"`
+
+	// SocketActivation passes sockets inherited via LISTEN_FDS (systemd socket
+	// activation) to the process, with LISTEN_PID rewritten to the child's pid.
+	SocketActivation bool `yaml:"socketActivation,omitempty"`
+
+	// Diagnostics installs a signal handler that dumps launcher state and
+	// forwards the signal so the process can dump its own heap or threads.
+	Diagnostics DiagnosticsConfig `yaml:"diagnostics,omitempty"`
+
+	// ContainerIndicators are environment variables (NAME or NAME=value) that
+	// mark a container. Default: CONTAINER, KUBERNETES_SERVICE_HOST, container.
+	// The /.dockerenv and /run/.containerenv files and pid 1's cgroups are
+	// always checked as well.
+	ContainerIndicators []string `yaml:"containerIndicators,omitempty"`
+
+	// WriteManifest writes the resolved argv, redacted env, config hash,
+	// launcher version and limits to var/log/launch-manifest.json on each launch.
+	WriteManifest bool `yaml:"writeManifest,omitempty"`
+
+	// ExitCodeMap remaps the process's exit code to the launcher's exit code.
+	// The key -1 matches a process killed by a signal, including one
+	// terminated by the watchdog. Unmapped codes pass through unchanged.
+	ExitCodeMap map[int]int `yaml:"exitCodeMap,omitempty"`
+
+	// TemplateEnabled expands text/template actions such as
+	// {{.ServiceName}} in args, env values and the entry point after merging.
+	// Off by default so literal "{{" in existing configs is left alone.
+	TemplateEnabled bool `yaml:"templateEnabled,omitempty"`
+
+	// ExecMode replaces the launcher with the process via exec once the
+	// environment, directories and resource limits are set up, instead of
+	// forking and supervising it. The watchdog, PID file and signal forwarding
+	// are unavailable in this mode.
+	ExecMode bool `yaml:"execMode,omitempty"`
+
+	// ReapChildren reaps orphaned descendants reparented to the launcher.
+	// Default: true when the launcher is PID 1. When enabled and not PID 1,
+	// the launcher becomes a child subreaper (Linux) so orphans reach it.
+	ReapChildren *bool `yaml:"reapChildren,omitempty"`
+
+	// MergeStderr sends the process's stderr to the launcher's stdout, like
+	// go-java-launcher. Set to false to route stderr to a separate writer
+	// (--stderr-file on the command line). Default: true.
+	MergeStderr *bool `yaml:"mergeStderr,omitempty"`
+
+	// OutputPrefix tags each line of the primary's and subprocesses' output
+	// with the process it came from. Default: disabled.
+	OutputPrefix OutputPrefixConfig `yaml:"outputPrefix,omitempty"`
+
+	// OutputBuffer buffers output bound for the launcher's stdout so a slow
+	// reader cannot stall the process. Default: disabled.
+	OutputBuffer OutputBufferConfig `yaml:"outputBuffer,omitempty"`
+
+	// Startup is a probe that must pass after the process is started, or the
+	// launch fails. Default: disabled.
+	Startup StartupProbeConfig `yaml:"startup,omitempty"`
+
+	// CgroupRoot is where the cgroup hierarchy is mounted, for memory and
+	// CPU limit detection, memory pressure and the watchdog's cgroup kills.
+	// Set it when the container's cgroup is mounted elsewhere, e.g. in a
+	// sandbox. Default: "/sys/fs/cgroup".
+	CgroupRoot string `yaml:"cgroupRoot,omitempty"`
+
+	// BytecodeCaching lets Python write .pyc files, which long-running
+	// services with large import graphs may want. The cache is kept out of
+	// the distribution in BytecodeCacheDir via PYTHONPYCACHEPREFIX. Default:
+	// false (PYTHONDONTWRITEBYTECODE=1).
+	BytecodeCaching *bool `yaml:"bytecodeCaching,omitempty"`
+
+	// BytecodeCacheDir is the PYTHONPYCACHEPREFIX used with BytecodeCaching,
+	// relative to the distribution root. Default: "var/data/pycache".
+	BytecodeCacheDir string `yaml:"bytecodeCacheDir,omitempty"`
+
+	// ShutdownSequence is the escalation used when the watchdog terminates
+	// the process or the launch is cancelled: each signal is sent in turn,
+	// waiting up to its waitSeconds for the process to exit. Default: SIGTERM,
+	// then SIGKILL after watchdog.gracePeriodSeconds. It should end in SIGKILL.
+	ShutdownSequence []SignalStep `yaml:"shutdownSequence,omitempty"`
+
+	// ShutdownNotifyFile, relative to the distribution root, is written just
+	// before the launcher starts shutting the process down, giving the
+	// deadline for the final signal of the shutdown sequence. Its path is
+	// exported as LAUNCHER_SHUTDOWN_NOTIFY_FILE, so a process that cannot
+	// handle SIGTERM promptly (e.g. blocked in C) can poll for it and flush.
+	// When set, a SIGTERM to the launcher runs the shutdown sequence instead
+	// of only being forwarded.
+	ShutdownNotifyFile string `yaml:"shutdownNotifyFile,omitempty"`
+
+	// SignalRemap translates a signal received by the launcher into the one
+	// forwarded to the process, e.g. {SIGINT: SIGTERM} for an app that only
+	// shuts down cleanly on SIGTERM. Only SIGTERM, SIGINT and SIGHUP are
+	// forwarded; unmapped signals are sent unchanged.
+	SignalRemap map[string]string `yaml:"signalRemap,omitempty"`
 }
 
 // MemoryConfig controls memory limit detection and enforcement.
 type MemoryConfig struct {
 	// Mode determines the memory management strategy.
-	// Default: "cgroup-aware" when CONTAINER env var is present, "unmanaged" otherwise.
+	// Default: "cgroup-aware" in a container, "unmanaged" otherwise.
 	Mode MemoryMode `yaml:"mode,omitempty"`
 
 	// MaxRSSPercent is the target RSS as a percentage of the detected or fixed memory limit.
-	// Default: 75. Only used when Mode is "cgroup-aware".
+	// Fractions up to 1.0 are read as percentages (0.75 means 75); values
+	// above 100 are rejected. Default: 75. Only used when Mode is "cgroup-aware".
 	MaxRSSPercent float64 `yaml:"maxRssPercent,omitempty"`
 
 	// FixedLimitBytes is an explicit memory ceiling in bytes.
@@ -144,18 +388,45 @@ type MemoryConfig struct {
 
 	// HeapFragmentationBuffer is subtracted from the target to account for
 	// Python's memory allocator fragmentation and overhead from native extensions.
-	// Default: 0.10 (10%). The effective limit becomes:
+	// Default: 0.10 (10%); an explicit 0 disables the buffer. The effective
+	// limit becomes:
 	//   effectiveLimit = detectedLimit * MaxRSSPercent/100 * (1 - HeapFragmentationBuffer)
-	HeapFragmentationBuffer float64 `yaml:"heapFragmentationBuffer,omitempty"`
+	HeapFragmentationBuffer *float64 `yaml:"heapFragmentationBuffer,omitempty"`
+
+	// MinHeadroomBytes guarantees an absolute reserve below the detected or
+	// fixed limit: the effective limit is capped at limit - MinHeadroomBytes
+	// when that is lower than the percentage-based target. Percentages alone
+	// reserve 100MB at 1GB but over 3GB at 32GB. The 64MiB minimum effective
+	// limit still applies afterwards. Default: 0 (no absolute reserve).
+	MinHeadroomBytes uint64 `yaml:"minHeadroomBytes,omitempty"`
 
 	// MallocTrimThreshold sets MALLOC_TRIM_THRESHOLD_ to encourage glibc to
 	// return memory to the OS. Default: 131072 (128KB). Set to -1 to disable.
-	MallocTrimThreshold int64 `yaml:"mallocTrimThreshold,omitempty"`
+	MallocTrimThreshold *int64 `yaml:"mallocTrimThreshold,omitempty"`
 
 	// MallocArenaMax sets MALLOC_ARENA_MAX to limit the number of glibc arenas.
 	// Each arena can hold fragmented free memory that inflates RSS.
 	// Default: 2. Set to 0 to use glibc default (8 * num_cpus).
-	MallocArenaMax int `yaml:"mallocArenaMax,omitempty"`
+	MallocArenaMax *int `yaml:"mallocArenaMax,omitempty"`
+
+	// CgroupReadAttempts is how many times the cgroup memory limit is read
+	// before giving up, with a short backoff between attempts, to ride out a
+	// cgroup hierarchy that is briefly unreadable during container startup.
+	// Default: 3.
+	CgroupReadAttempts int `yaml:"cgroupReadAttempts,omitempty"`
+
+	// ExtraLimitEnvVars names additional environment variables set from the
+	// effective memory limit, for libraries that read their own limit
+	// variable. Each entry is NAME (the full limit in bytes) or NAME:PERCENT
+	// (that percentage of it, e.g. "RAY_memory:50"). They are set alongside
+	// MEMORY_LIMIT_BYTES and SLS_MEMORY_LIMIT_BYTES, which are always set.
+	ExtraLimitEnvVars []string `yaml:"extraLimitEnvVars,omitempty"`
+
+	// DescriptorFile, if set, receives a JSON object with the detected CPU
+	// count, memory limits and container facts before the process starts, and
+	// LAUNCHER_LIMITS_FILE is set to its path. Resolved relative to the
+	// distribution root.
+	DescriptorFile string `yaml:"descriptorFile,omitempty"`
 }
 
 // WatchdogConfig controls the RSS monitoring goroutine that prevents OOM kills.
@@ -168,17 +439,161 @@ type WatchdogConfig struct {
 	// Default: 5.
 	PollIntervalSeconds int `yaml:"pollIntervalSeconds,omitempty"`
 
+	// PollIntervalMillis is a finer-grained poll interval that takes
+	// precedence over PollIntervalSeconds when set. Minimum: 50.
+	PollIntervalMillis int `yaml:"pollIntervalMillis,omitempty"`
+
 	// SoftLimitPercent triggers a warning log when RSS exceeds this percentage
-	// of the effective memory limit. Default: 85.
+	// of the effective memory limit. Accepts 0-100 or a fraction up to 1.0.
+	// Default: 85.
 	SoftLimitPercent float64 `yaml:"softLimitPercent,omitempty"`
 
 	// HardLimitPercent triggers SIGTERM when RSS exceeds this percentage
-	// of the effective memory limit. Default: 95.
+	// of the effective memory limit. Accepts 0-100 or a fraction up to 1.0.
+	// Default: 95.
 	HardLimitPercent float64 `yaml:"hardLimitPercent,omitempty"`
 
 	// GracePeriodSeconds is how long to wait after SIGTERM before sending SIGKILL.
 	// Default: 30.
 	GracePeriodSeconds int `yaml:"gracePeriodSeconds,omitempty"`
+
+	// AdaptiveGrace shortens the watchdog's grace period the further RSS is
+	// over the hard limit when it fires: the full GracePeriodSeconds just
+	// over the limit, half at twice the limit, and so on, down to a floor of
+	// 5 seconds. A process far over the limit is likely to be OOM-killed
+	// before a long graceful shutdown completes. Only applies to the default
+	// SIGTERM -> SIGKILL escalation, not a custom shutdownSequence.
+	// Default: false.
+	AdaptiveGrace *bool `yaml:"adaptiveGrace,omitempty"`
+
+	// ObserveOnly runs the watchdog without enforcement: crossing the hard
+	// limit logs that the process would have been terminated, but no signal
+	// is sent. For trying out new thresholds before enforcing them.
+	// Default: false.
+	ObserveOnly *bool `yaml:"observeOnly,omitempty"`
+
+	// SmoothingAlpha compares an exponentially weighted moving average of RSS
+	// against the limits instead of each raw sample, so a momentary spike
+	// does not trip the hard limit while sustained growth still does. Each
+	// sample moves the average by alpha: lower values smooth more. Must be
+	// in (0, 1]; 1 is the same as no smoothing. Default: unset (raw samples).
+	SmoothingAlpha *float64 `yaml:"smoothingAlpha,omitempty"`
+
+	// RefreshLimits re-reads the cgroup memory limit every few polls while the
+	// process runs and recomputes the thresholds when it has changed, e.g.
+	// after an in-place pod resize. Only applies in cgroup-aware mode.
+	// Default: false.
+	RefreshLimits *bool `yaml:"refreshLimits,omitempty"`
+
+	// MaxConsecutiveReadFailures is how many RSS reads in a row may fail before
+	// the watchdog concludes the process has exited and stops. Default: 3.
+	MaxConsecutiveReadFailures int `yaml:"maxConsecutiveReadFailures,omitempty"`
+
+	// PeakRSSFile, if set, receives the peak RSS of the process in bytes when it
+	// exits. Resolved relative to the distribution root. When the watchdog is not
+	// running, a lightweight sampler tracks the peak instead.
+	PeakRSSFile string `yaml:"peakRssFile,omitempty"`
+
+	// EventLogFile, if set, has a JSON line appended for every watchdog state
+	// transition and new peak RSS, with the time, state, rss, hard limit and
+	// pid. Resolved relative to the distribution root.
+	EventLogFile string `yaml:"eventLogFile,omitempty"`
+
+	// StartupGraceSeconds is how long after start the watchdog only observes:
+	// RSS above the hard limit is logged but does not trigger termination, so
+	// transient startup allocations don't kill the process. Default: 0.
+	StartupGraceSeconds *int `yaml:"startupGraceSeconds,omitempty"`
+
+	// PressureWarnPercent enables cgroup v2 memory pressure (PSI) monitoring:
+	// a warning is logged when "some avg10" exceeds this percentage.
+	// Default: 0 (disabled).
+	PressureWarnPercent *float64 `yaml:"pressureWarnPercent,omitempty"`
+
+	// KillCgroupOnEscalation makes the post-grace escalation write to the
+	// process's cgroup v2 cgroup.kill, terminating every process in the cgroup
+	// (including leaked workers) instead of only SIGKILLing the primary.
+	// Falls back to SIGKILL when cgroup.kill is unavailable. Default: false.
+	KillCgroupOnEscalation *bool `yaml:"killCgroupOnEscalation,omitempty"`
+
+	// AnonymousOnly compares only anonymous memory (from
+	// /proc/[pid]/smaps_rollup) against the limits, excluding file-backed
+	// pages such as mmap'd read-only datasets that the kernel can reclaim.
+	// Falls back to total RSS from statm when smaps_rollup is unavailable.
+	// Default: false.
+	AnonymousOnly *bool `yaml:"anonymousOnly,omitempty"`
+
+	// LeakWarnBytesPerMinute enables leak detection: a "possible memory leak"
+	// warning is logged when RSS grows monotonically faster than this rate for
+	// several consecutive sampling windows. Advisory only; it never triggers
+	// termination. Default: 0 (disabled).
+	LeakWarnBytesPerMinute *uint64 `yaml:"leakWarnBytesPerMinute,omitempty"`
+}
+
+// DirConfig describes a directory to create before launch.
+type DirConfig struct {
+	// Path is relative to the distribution root, or absolute.
+	Path string `yaml:"path"`
+
+	// Mode is an octal permission string such as "0700". Default: "0755".
+	Mode string `yaml:"mode,omitempty"`
+
+	// Owner and Group are user/group names or numeric ids applied with chown.
+	// Default: the launcher's own uid/gid.
+	Owner string `yaml:"owner,omitempty"`
+	Group string `yaml:"group,omitempty"`
+}
+
+// DefaultDirs returns the directories created when the static config does
+// not set dirs, matching the go-java-launcher layout.
+func DefaultDirs() []DirConfig {
+	return []DirConfig{{Path: "var/data/tmp"}, {Path: "var/log"}, {Path: "var/run"}}
+}
+
+// UnmarshalYAML accepts either a plain path string or a mapping.
+func (d *DirConfig) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*d = DirConfig{Path: node.Value}
+		return nil
+	}
+	type plain DirConfig
+	return node.Decode((*plain)(d))
+}
+
+// FileMode parses Mode, returning 0755 when it is unset.
+func (d DirConfig) FileMode() (os.FileMode, error) {
+	if d.Mode == "" {
+		return 0755, nil
+	}
+	mode, err := strconv.ParseUint(d.Mode, 8, 32)
+	if err != nil || mode > 0777 {
+		return 0, fmt.Errorf("invalid mode %q for directory %s: expected octal permissions like \"0750\"", d.Mode, d.Path)
+	}
+	return os.FileMode(mode), nil
+}
+
+// EnvInheritPolicy controls which launcher environment variables the process inherits.
+type EnvInheritPolicy string
+
+const (
+	// EnvInheritAll passes the launcher's entire environment through.
+	EnvInheritAll EnvInheritPolicy = "all"
+
+	// EnvInheritNone starts from an empty environment.
+	EnvInheritNone EnvInheritPolicy = "none"
+
+	// EnvInheritAllowlist passes through only variables matching AllowlistPatterns.
+	EnvInheritAllowlist EnvInheritPolicy = "allowlist"
+)
+
+// EnvInheritConfig restricts which host environment variables reach the process.
+// Config-specified env, memory variables and service metadata are always set.
+type EnvInheritConfig struct {
+	// Policy is "all", "none" or "allowlist". Default: "all".
+	Policy EnvInheritPolicy `yaml:"policy,omitempty"`
+
+	// AllowlistPatterns are glob patterns (e.g. "LC_*") matched against variable
+	// names when Policy is "allowlist".
+	AllowlistPatterns []string `yaml:"allowlistPatterns,omitempty"`
 }
 
 // ResourceConfig specifies OS-level resource limits set via setrlimit before exec.
@@ -186,11 +601,46 @@ type ResourceConfig struct {
 	// MaxOpenFiles sets RLIMIT_NOFILE. Default: 65536.
 	MaxOpenFiles uint64 `yaml:"maxOpenFiles,omitempty"`
 
+	// MaxOpenFilesSoft and MaxOpenFilesHard set the RLIMIT_NOFILE soft and
+	// hard limits separately, e.g. a low soft limit so descriptor leaks hit
+	// EMFILE early, with a high hard limit the service may raise it to. Each
+	// falls back to MaxOpenFiles; when neither is set, that limit is left
+	// unchanged. The soft limit must not exceed the hard limit.
+	MaxOpenFilesSoft uint64 `yaml:"maxOpenFilesSoft,omitempty"`
+	MaxOpenFilesHard uint64 `yaml:"maxOpenFilesHard,omitempty"`
+
 	// MaxProcesses sets RLIMIT_NPROC. Default: 4096.
 	MaxProcesses uint64 `yaml:"maxProcesses,omitempty"`
 
 	// CoreDumpEnabled controls whether core dumps are permitted. Default: false.
 	CoreDumpEnabled bool `yaml:"coreDumpEnabled,omitempty"`
+
+	// Nice sets the scheduling priority (-20..19) of the process and its
+	// subprocesses once started; the launcher keeps its own priority.
+	// Positive values lower priority. Default: 0 (leave unchanged).
+	Nice int `yaml:"nice,omitempty"`
+
+	// OOMScoreAdj is written to /proc/<pid>/oom_score_adj of the process just
+	// after it starts (-1000..1000). Positive values make the kernel OOM-kill
+	// it before the launcher, so the launcher survives to report; the RSS
+	// watchdog still aims to terminate it first. Lowering the score needs
+	// CAP_SYS_RESOURCE. Default: 0 (leave the inherited score unchanged).
+	OOMScoreAdj int `yaml:"oomScoreAdj,omitempty"`
+
+	// RunAsUser and RunAsGroup drop privileges for the process (and any
+	// subprocesses) after root-only setup such as rlimits and chown. Each is a
+	// name or numeric id. RunAsGroup defaults to the user's primary group.
+	RunAsUser  string `yaml:"runAsUser,omitempty"`
+	RunAsGroup string `yaml:"runAsGroup,omitempty"`
+
+	// Umask is the octal file mode creation mask (e.g. "0027") for the process
+	// and subprocesses. Default: "" (inherit the launcher's umask).
+	Umask string `yaml:"umask,omitempty"`
+
+	// PinToCpuset sets the CPU affinity of the process and subprocesses to the
+	// cgroup cpuset (cpuset.cpus.effective, or the cgroup v1 cpuset), e.g. to
+	// keep a memory-sensitive process on one NUMA node. Linux only. Default: false.
+	PinToCpuset bool `yaml:"pinToCpuset,omitempty"`
 }
 
 // SubProcessConfig defines a sidecar process launched alongside the primary.
@@ -206,6 +656,12 @@ type SubProcessConfig struct {
 
 	// Env specifies additional environment variables for this subprocess.
 	Env map[string]string `yaml:"env,omitempty"`
+
+	// Critical shuts the primary process down (using the shutdown sequence)
+	// when this subprocess exits or fails to start, and the launch then
+	// exits with ExitCodeCriticalSubprocess. Default: false, where an exited
+	// subprocess is left as is.
+	Critical bool `yaml:"critical,omitempty"`
 }
 
 // CustomLauncherConfig represents the mutable configuration that operators can
@@ -221,13 +677,37 @@ type CustomLauncherConfig struct {
 	// (and override) the static config's env.
 	Env map[string]string `yaml:"env,omitempty"`
 
-	// PythonOpts are appended to the static config's PythonOpts.
+	// EnvFromFile is merged with (and overrides) the static config's envFromFile.
+	EnvFromFile map[string]string `yaml:"envFromFile,omitempty"`
+
+	// PythonPathEntries are prepended to PYTHONPATH ahead of the static
+	// config's entries.
+	PythonPathEntries []string `yaml:"pythonPathEntries,omitempty"`
+
+	// EnvAppend entries are appended after the static config's entries for
+	// the same variable.
+	EnvAppend map[string][]string `yaml:"envAppend,omitempty"`
+
+	// PythonOpts are appended to the static config's PythonOpts, or replace
+	// them when PythonOptsMode is "replace".
 	PythonOpts []string `yaml:"pythonOpts,omitempty"`
 
-	// Args are appended to the static config's Args.
+	// PythonOptsMode is "append" (the default) or "replace".
+	PythonOptsMode ListMergeMode `yaml:"pythonOptsMode,omitempty"`
+
+	// PythonVersion selects an interpreter from the static config's
+	// pythonVersions, overriding its pythonPath.
+	PythonVersion string `yaml:"pythonVersion,omitempty"`
+
+	// Args are appended to the static config's Args, or replace them when
+	// ArgsMode is "replace" (e.g. to run "migrate" instead of "server").
 	// Note: later args typically override earlier args for most Python CLI frameworks.
 	Args []string `yaml:"args,omitempty"`
 
+	// ArgsMode is "append" (the default) or "replace". Arguments from the
+	// static argsFile are appended either way.
+	ArgsMode ListMergeMode `yaml:"argsMode,omitempty"`
+
 	// Memory overrides for the memory configuration.
 	Memory *MemoryConfig `yaml:"memory,omitempty"`
 
@@ -241,38 +721,82 @@ type CustomLauncherConfig struct {
 
 // MergedConfig is the resolved configuration after combining static and custom configs.
 type MergedConfig struct {
-	LaunchMode   LaunchMode
-	Executable   string
-	PythonPath   string
-	EntryPoint   string
-	Args         []string
-	Env          map[string]string
-	PythonOpts   []string
-	Memory       MemoryConfig
-	Watchdog     WatchdogConfig
-	Resources    ResourceConfig
-	Dirs         []string
-	SubProcesses []SubProcessConfig
-	Paths        PathsConfig
-	Logging      LoggingConfig
-	Readiness    ReadinessConfig
-	CPU          CPUConfig
+	LaunchMode           LaunchMode
+	Executable           string
+	PythonPath           string
+	PythonVersion        string // selected from PythonVersions, "" if none
+	EntryPoint           string
+	RequirePythonVersion string
+	Args                 []string
+	ArgsFile             string
+	Env                  map[string]string
+	EnvFromFile          map[string]string
+	PythonPathEntries    []string
+	EnvAppend            map[string][]string
+	EnvInherit           EnvInheritConfig
+	PythonOpts           []string
+	Memory               MemoryConfig
+	Watchdog             WatchdogConfig
+	Resources            ResourceConfig
+	Dirs                 []DirConfig
+	WorkingDir           string
+	SubProcesses         []SubProcessConfig
+	Paths                PathsConfig
+	Logging              LoggingConfig
+	Readiness            ReadinessConfig
+	CPU                  CPUConfig
+	Telemetry            TelemetryConfig
+	SocketActivation     bool
+	Metrics              MetricsConfig
+	Diagnostics          DiagnosticsConfig
+	WriteManifest        bool
+	ExitCodeMap          map[int]int
+	TemplateEnabled      bool
+	ExecMode             bool
+	ReapChildren         *bool
+	MergeStderr          *bool
+	OutputPrefix         OutputPrefixConfig
+	OutputBuffer         OutputBufferConfig
+	Startup              StartupProbeConfig
+	CgroupRoot           string
+	BytecodeCaching      *bool
+	BytecodeCacheDir     string
+	ShutdownSequence     []SignalStep
+	ShutdownNotifyFile   string
+	SignalRemap          map[string]string
+
+	ResolveInterpreterFromShebang bool
 
 	// Computed fields
 	EffectiveMemoryLimitBytes uint64
 	EffectiveCPUCount         int
 	IsContainer               bool
-	CgroupVersion             int // 1 or 2, 0 if not in container
+	ContainerRuntime          string // informational, see detectRuntime
+	CgroupVersion             int    // 1 or 2, 0 if not in container
+	LauncherVersion           string // from LauncherParams
+	LauncherCommit            string // from LauncherParams
+
+	// MemoryWarnings lists memory settings that conflict with the chosen mode,
+	// and any LAUNCHER_MEMORY_MODE override.
+	MemoryWarnings []string
+
+	// Provenance records where the high-value fields (memory, watchdog,
+	// pythonPath) and each env key ("env.NAME") came from. See ExplainConfig.
+	Provenance map[string]ConfigSource
 }
 
 // DefaultMemoryConfig returns sensible defaults for memory management.
 func DefaultMemoryConfig() MemoryConfig {
+	fragBuffer := 0.10
+	trimThreshold := int64(131072)
+	arenaMax := 2
 	return MemoryConfig{
 		Mode:                    MemoryModeCgroupAware,
 		MaxRSSPercent:           75,
-		HeapFragmentationBuffer: 0.10,
-		MallocTrimThreshold:     131072,
-		MallocArenaMax:          2,
+		HeapFragmentationBuffer: &fragBuffer,
+		MallocTrimThreshold:     &trimThreshold,
+		MallocArenaMax:          &arenaMax,
+		CgroupReadAttempts:      3,
 	}
 }
 
@@ -280,12 +804,26 @@ func DefaultMemoryConfig() MemoryConfig {
 func DefaultWatchdogConfig() WatchdogConfig {
 	enabled := true
 	return WatchdogConfig{
-		Enabled:             &enabled,
-		PollIntervalSeconds: 5,
-		SoftLimitPercent:    85,
-		HardLimitPercent:    95,
-		GracePeriodSeconds:  30,
+		Enabled:                    &enabled,
+		PollIntervalSeconds:        5,
+		SoftLimitPercent:           85,
+		HardLimitPercent:           95,
+		GracePeriodSeconds:         30,
+		MaxConsecutiveReadFailures: 3,
+	}
+}
+
+// OpenFilesLimits returns the RLIMIT_NOFILE soft and hard limits to set, 0
+// meaning leave that limit unchanged.
+func (c ResourceConfig) OpenFilesLimits() (soft, hard uint64) {
+	soft, hard = c.MaxOpenFiles, c.MaxOpenFiles
+	if c.MaxOpenFilesSoft > 0 {
+		soft = c.MaxOpenFilesSoft
 	}
+	if c.MaxOpenFilesHard > 0 {
+		hard = c.MaxOpenFilesHard
+	}
+	return soft, hard
 }
 
 // DefaultResourceConfig returns sensible defaults for resource limits.
@@ -296,15 +834,88 @@ func DefaultResourceConfig() ResourceConfig {
 	}
 }
 
+var (
+	// ErrStaticConfigNotFound is returned when the static (or check) config
+	// file does not exist.
+	ErrStaticConfigNotFound = errors.New("static config not found")
+
+	// ErrConfigParse is returned when a config file is not valid YAML for
+	// its schema.
+	ErrConfigParse = errors.New("config parse error")
+
+	// ErrConfigValidation matches every *ConfigValidationError with errors.Is.
+	ErrConfigValidation = errors.New("invalid config")
+
+	// ErrConfigTooLarge is returned when a config file exceeds the maximum
+	// config size.
+	ErrConfigTooLarge = errors.New("config too large")
+)
+
+// ConfigValidationError reports a config field whose value failed validation.
+type ConfigValidationError struct {
+	// Field is the YAML path of the field, e.g. "resources.nice".
+	Field string
+
+	// Value is the offending value.
+	Value string
+
+	// Reason explains what is wrong with Value.
+	Reason string
+}
+
+func (e *ConfigValidationError) Error() string {
+	return fmt.Sprintf("%s %q: %s", e.Field, e.Value, e.Reason)
+}
+
+// Is makes errors.Is(err, ErrConfigValidation) true for validation errors.
+func (e *ConfigValidationError) Is(target error) bool {
+	return target == ErrConfigValidation
+}
+
+func invalidField(field string, value any, format string, args ...any) error {
+	return &ConfigValidationError{
+		Field:  field,
+		Value:  fmt.Sprint(value),
+		Reason: fmt.Sprintf(format, args...),
+	}
+}
+
 // GetConfigsFromFiles reads and parses both configuration files.
 // The custom config file is optional and will be silently ignored if absent.
+//
+// Relative include paths in the static config are resolved against the
+// working directory.
 func GetConfigsFromFiles(
 	staticConfigFile string,
 	customConfigFile string,
 	stdout io.Writer,
 ) (StaticLauncherConfig, CustomLauncherConfig, error) {
+	return getConfigsFromFiles(staticConfigFile, customConfigFile, nil, stdout, identityPath)
+}
+
+// GetConfigsFromFilesWithFetcher is GetConfigsFromFiles with a remote custom
+// config: if fetcher is non-nil, the custom config it returns is layered on
+// top of the local custom config file, taking precedence over it.
+func GetConfigsFromFilesWithFetcher(
+	staticConfigFile string,
+	customConfigFile string,
+	fetcher ConfigFetcher,
+	stdout io.Writer,
+) (StaticLauncherConfig, CustomLauncherConfig, error) {
+	return getConfigsFromFiles(staticConfigFile, customConfigFile, fetcher, stdout, identityPath)
+}
+
+// getConfigsFromFiles is GetConfigsFromFiles with static config includes
+// resolved by resolve.
+func getConfigsFromFiles(
+	staticConfigFile string,
+	customConfigFile string,
+	fetcher ConfigFetcher,
+	stdout io.Writer,
+	resolve func(string) string,
+) (StaticLauncherConfig, CustomLauncherConfig, error) {
 
-	staticConfig, err := readStaticConfig(staticConfigFile)
+	staticConfig, err := readStaticConfig(staticConfigFile, resolve)
 	if err != nil {
 		return StaticLauncherConfig{}, CustomLauncherConfig{}, fmt.Errorf(
 			"failed to read static config from %s: %w", staticConfigFile, err)
@@ -316,14 +927,109 @@ func GetConfigsFromFiles(
 			"failed to read custom config from %s: %w", customConfigFile, err)
 	}
 
+	if fetcher != nil {
+		remoteConfig, err := fetchCustomConfig(context.Background(), fetcher)
+		if err != nil {
+			return StaticLauncherConfig{}, CustomLauncherConfig{}, fmt.Errorf(
+				"failed to fetch remote custom config: %w", err)
+		}
+		// Checked before the overlay, where a local configVersion could
+		// otherwise mask the remote one.
+		if err := validateCustomHeader(remoteConfig); err != nil {
+			return StaticLauncherConfig{}, CustomLauncherConfig{}, fmt.Errorf(
+				"invalid remote custom config: %w", err)
+		}
+		customConfig = overlayCustomConfig(customConfig, remoteConfig)
+	}
+
+	normalizePercentages(&staticConfig.Memory, &staticConfig.Watchdog)
+	normalizePercentages(customConfig.Memory, customConfig.Watchdog)
+
 	if err := validateStaticConfig(staticConfig); err != nil {
 		return StaticLauncherConfig{}, CustomLauncherConfig{}, fmt.Errorf(
 			"invalid static config: %w", err)
 	}
 
+	if err := validateCustomConfig(staticConfig, customConfig); err != nil {
+		return StaticLauncherConfig{}, CustomLauncherConfig{}, fmt.Errorf(
+			"invalid custom config: %w", err)
+	}
+
 	return staticConfig, customConfig, nil
 }
 
+// Static config search locations used when the SLS default is missing.
+const (
+	staticConfigFileName  = "launcher-static.yml"
+	staticConfigSearchDir = "python-service-launcher"
+)
+
+// systemConfigDir is the system-wide config directory searched last.
+var systemConfigDir = "/etc"
+
+// StaticConfigSearchPaths returns the fallback locations for the static config,
+// in precedence order: workDir, $XDG_CONFIG_HOME (or ~/.config), then
+// /etc/python-service-launcher.
+func StaticConfigSearchPaths(workDir string, getenv func(string) string) []string {
+	paths := []string{filepath.Join(workDir, staticConfigFileName)}
+	configHome := getenv("XDG_CONFIG_HOME")
+	if configHome == "" {
+		if home := getenv("HOME"); home != "" {
+			configHome = filepath.Join(home, ".config")
+		}
+	}
+	if configHome != "" {
+		paths = append(paths, filepath.Join(configHome, staticConfigSearchDir, staticConfigFileName))
+	}
+	return append(paths, filepath.Join(systemConfigDir, staticConfigSearchDir, staticConfigFileName))
+}
+
+// FindStaticConfig returns defaultPath if it exists, otherwise the first of
+// candidates that exists. It returns defaultPath and false when none do, so
+// the caller reports the missing default.
+func FindStaticConfig(defaultPath string, candidates []string) (string, bool) {
+	for _, candidate := range append([]string{defaultPath}, candidates...) {
+		if info, err := os.Stat(candidate); err == nil && !info.IsDir() {
+			return candidate, true
+		}
+	}
+	return defaultPath, false
+}
+
+// GetCheckConfigFromFile reads and validates a health-check config. Checks
+// never apply the service's custom config, so only the check config's own env
+// and settings are used. Unless the check config sets them explicitly, memory
+// is unmanaged and the watchdog and PID file are disabled, so a check is never
+// killed by the watchdog or clobbers the running service's PID file.
+//
+// Relative include paths are resolved against the working directory.
+func GetCheckConfigFromFile(path string) (StaticLauncherConfig, error) {
+	return getCheckConfigFromFile(path, identityPath)
+}
+
+// getCheckConfigFromFile is GetCheckConfigFromFile with includes resolved by
+// resolve.
+func getCheckConfigFromFile(path string, resolve func(string) string) (StaticLauncherConfig, error) {
+	config, err := readStaticConfig(path, resolve)
+	if err != nil {
+		return StaticLauncherConfig{}, fmt.Errorf("failed to read check config from %s: %w", path, err)
+	}
+	if err := validateStaticConfig(config); err != nil {
+		return StaticLauncherConfig{}, fmt.Errorf("invalid check config: %w", err)
+	}
+	disabled := false
+	if config.Memory.Mode == "" {
+		config.Memory.Mode = MemoryModeUnmanaged
+	}
+	if config.Watchdog.Enabled == nil {
+		config.Watchdog.Enabled = &disabled
+	}
+	if config.Paths.PidFileEnabled == nil {
+		config.Paths.PidFileEnabled = &disabled
+	}
+	return config, nil
+}
+
 // MergeConfigs combines the static and custom configurations into a single resolved config.
 func MergeConfigs(
 	static StaticLauncherConfig,
@@ -335,21 +1041,54 @@ func MergeConfigs(
 	}
 
 	merged := MergedConfig{
-		LaunchMode:   launchMode,
-		Executable:   static.Executable,
-		PythonPath:   static.PythonPath,
-		EntryPoint:   static.EntryPoint,
-		Args:         append(append([]string{}, static.Args...), custom.Args...),
-		PythonOpts:   append(append([]string{}, static.PythonOpts...), custom.PythonOpts...),
-		Memory:       mergeMemoryConfig(static.Memory, custom.Memory),
-		Watchdog:     mergeWatchdogConfig(static.Watchdog, custom.Watchdog),
-		Resources:    static.Resources,
-		Dirs:         static.Dirs,
-		SubProcesses: static.SubProcesses,
-		Paths:        static.Paths,
-		Logging:      static.Logging,
-		Readiness:    static.Readiness,
-		CPU:          static.CPU,
+		LaunchMode:           launchMode,
+		Executable:           static.Executable,
+		PythonPath:           static.PythonPath,
+		EntryPoint:           static.EntryPoint,
+		RequirePythonVersion: static.RequirePythonVersion,
+		Args:                 mergeList(static.Args, custom.Args, custom.ArgsMode),
+		ArgsFile:             static.ArgsFile,
+		PythonOpts:           mergeList(static.PythonOpts, custom.PythonOpts, custom.PythonOptsMode),
+		Memory:               mergeMemoryConfig(static.Memory, custom.Memory),
+		MemoryWarnings:       memoryConfigConflicts(rawMemoryConfig(static.Memory, custom.Memory)),
+		Watchdog:             mergeWatchdogConfig(static.Watchdog, custom.Watchdog),
+		Resources:            static.Resources,
+		Dirs:                 static.Dirs,
+		WorkingDir:           static.WorkingDir,
+		SubProcesses:         static.SubProcesses,
+		Paths:                static.Paths,
+		Logging:              static.Logging,
+		Readiness:            static.Readiness,
+		CPU:                  static.CPU,
+		Telemetry:            static.Telemetry,
+		SocketActivation:     static.SocketActivation,
+		Metrics:              static.Metrics,
+		Diagnostics:          static.Diagnostics,
+		WriteManifest:        static.WriteManifest,
+		ExitCodeMap:          static.ExitCodeMap,
+		TemplateEnabled:      static.TemplateEnabled,
+		ExecMode:             static.ExecMode,
+		ReapChildren:         static.ReapChildren,
+		MergeStderr:          static.MergeStderr,
+		OutputPrefix:         static.OutputPrefix,
+		OutputBuffer:         static.OutputBuffer,
+		Startup:              static.Startup,
+		CgroupRoot:           static.CgroupRoot,
+		BytecodeCaching:      static.BytecodeCaching,
+		BytecodeCacheDir:     static.BytecodeCacheDir,
+		ShutdownSequence:     static.ShutdownSequence,
+		ShutdownNotifyFile:   static.ShutdownNotifyFile,
+		SignalRemap:          static.SignalRemap,
+		EnvInherit:           static.EnvInherit,
+		Provenance:           configProvenance(static, custom),
+	}
+	merged.ResolveInterpreterFromShebang = static.ResolveInterpreterFromShebang
+
+	// A selected interpreter version overrides pythonPath. An unknown version
+	// is rejected when the configs are read, so it is ignored here.
+	if pythonPath, ok := static.PythonVersions[custom.PythonVersion]; ok && custom.PythonVersion != "" {
+		merged.PythonPath = pythonPath
+		merged.PythonVersion = custom.PythonVersion
 	}
 
 	// Merge environment: static as base, custom overrides
@@ -360,30 +1099,166 @@ func MergeConfigs(
 	for k, v := range custom.Env {
 		merged.Env[k] = v
 	}
+	if len(static.EnvFromFile) > 0 || len(custom.EnvFromFile) > 0 {
+		merged.EnvFromFile = make(map[string]string)
+		for k, v := range static.EnvFromFile {
+			merged.EnvFromFile[k] = v
+		}
+		for k, v := range custom.EnvFromFile {
+			merged.EnvFromFile[k] = v
+		}
+	}
+	// Custom entries come first so the more specific config wins imports.
+	if len(static.PythonPathEntries) > 0 || len(custom.PythonPathEntries) > 0 {
+		merged.PythonPathEntries = mergeList(custom.PythonPathEntries, static.PythonPathEntries, ListMergeAppend)
+	}
+	merged.EnvAppend = mergeEnvAppend(static.EnvAppend, custom.EnvAppend)
 
 	// Detect container environment
-	_, merged.IsContainer = os.LookupEnv("CONTAINER")
+	indicators := static.ContainerIndicators
+	if len(indicators) == 0 {
+		indicators = DefaultContainerIndicators
+	}
+	merged.IsContainer = DetectContainer(indicators, os.LookupEnv, containerFilesystem())
+	merged.ContainerRuntime = detectRuntime(os.LookupEnv, containerFilesystem())
 	if custom.DangerousDisableContainerSupport {
 		merged.IsContainer = false
 	}
 
+	if mode, ok := os.LookupEnv(memoryModeEnv); ok && mode != "" {
+		merged.Memory.Mode, merged.MemoryWarnings = overrideMemoryMode(merged.Memory.Mode, MemoryMode(mode),
+			custom.DangerousDisableContainerSupport, merged.MemoryWarnings)
+		if merged.Memory.Mode == MemoryMode(mode) && !custom.DangerousDisableContainerSupport {
+			merged.Provenance["memory.mode"] = ConfigSourceEnvOverride
+		}
+	}
+
 	return merged
 }
 
-func readStaticConfig(path string) (StaticLauncherConfig, error) {
-	data, err := os.ReadFile(path)
+// memoryModeEnv forces the merged memory mode, for temporarily changing it
+// (e.g. disabling the watchdog) without editing config files.
+const memoryModeEnv = "LAUNCHER_MEMORY_MODE"
+
+// overrideMemoryMode applies a LAUNCHER_MEMORY_MODE value to the configured
+// mode, appending a warning that explains what happened. The override is
+// ignored when it is not a known mode or when container support is disabled.
+func overrideMemoryMode(configured, override MemoryMode, containerSupportDisabled bool, warnings []string) (MemoryMode, []string) {
+	switch override {
+	case MemoryModeCgroupAware, MemoryModeFixed, MemoryModeUnmanaged:
+	default:
+		return configured, append(warnings, fmt.Sprintf("%s=%s is not a memory mode (expected %s, %s or %s); ignoring it",
+			memoryModeEnv, override, MemoryModeCgroupAware, MemoryModeFixed, MemoryModeUnmanaged))
+	}
+	if containerSupportDisabled {
+		return configured, append(warnings, fmt.Sprintf("%s=%s is ignored because dangerousDisableContainerSupport is set",
+			memoryModeEnv, override))
+	}
+	return override, append(warnings, fmt.Sprintf("%s=%s is overriding memory.mode (%s from config); unset it to restore the configured mode",
+		memoryModeEnv, override, configured))
+}
+
+// DefaultMaxConfigBytes is the largest config file the launcher reads, unless
+// LAUNCHER_MAX_CONFIG_BYTES says otherwise.
+const DefaultMaxConfigBytes = 4 << 20
+
+// maxConfigBytesEnv overrides DefaultMaxConfigBytes. It is an environment
+// variable because the limit applies before any config has been read.
+const maxConfigBytesEnv = "LAUNCHER_MAX_CONFIG_BYTES"
+
+// maxConfigBytes returns the config size limit from LAUNCHER_MAX_CONFIG_BYTES,
+// or DefaultMaxConfigBytes when it is unset.
+func maxConfigBytes() (int64, error) {
+	value, ok := os.LookupEnv(maxConfigBytesEnv)
+	if !ok || value == "" {
+		return DefaultMaxConfigBytes, nil
+	}
+	limit, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || limit <= 0 {
+		return 0, fmt.Errorf("invalid %s %q: expected a positive number of bytes", maxConfigBytesEnv, value)
+	}
+	return limit, nil
+}
+
+// readConfigFile reads the config file at path. Unlike os.ReadFile it refuses
+// anything but a regular file (after following symlinks), so a path pointing
+// at a device or FIFO cannot block or exhaust memory, and it reads at most
+// maxConfigBytes.
+func readConfigFile(path string) ([]byte, error) {
+	limit, err := maxConfigBytes()
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.Mode().IsRegular() {
+		return nil, fmt.Errorf("%s is not a regular file (mode %s)", path, info.Mode().Type())
+	}
+	if info.Size() > limit {
+		return nil, fmt.Errorf("%w: %s is %d bytes, more than %d (set %s to raise the limit)",
+			ErrConfigTooLarge, path, info.Size(), limit, maxConfigBytesEnv)
+	}
+
+	f, err := os.Open(path)
 	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	// The file may have grown since the stat.
+	data, err := io.ReadAll(io.LimitReader(f, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > limit {
+		return nil, fmt.Errorf("%w: %s is more than %d bytes (set %s to raise the limit)",
+			ErrConfigTooLarge, path, limit, maxConfigBytesEnv)
+	}
+	return data, nil
+}
+
+// readStaticConfig reads the static config at path, merging any included
+// files resolved with resolve.
+func readStaticConfig(path string, resolve func(string) string) (StaticLauncherConfig, error) {
+	data, err := readConfigFile(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return StaticLauncherConfig{}, fmt.Errorf("%w: %w", ErrStaticConfigNotFound, err)
+		}
 		return StaticLauncherConfig{}, err
 	}
 	var config StaticLauncherConfig
 	if err := yaml.Unmarshal(data, &config); err != nil {
+		return StaticLauncherConfig{}, fmt.Errorf("%w: %w", ErrConfigParse, err)
+	}
+	if len(config.Include) == 0 {
+		return config, nil
+	}
+
+	doc, err := readConfigWithIncludes(path, resolve)
+	if err != nil {
 		return StaticLauncherConfig{}, err
 	}
-	return config, nil
+	merged, err := yaml.Marshal(doc)
+	if err != nil {
+		return StaticLauncherConfig{}, err
+	}
+	var resolved StaticLauncherConfig
+	if err := yaml.Unmarshal(merged, &resolved); err != nil {
+		return StaticLauncherConfig{}, fmt.Errorf("%w: %w", ErrConfigParse, err)
+	}
+	return resolved, nil
+}
+
+// identityPath leaves a path as given, so relative paths resolve against the
+// working directory.
+func identityPath(path string) string {
+	return path
 }
 
 func readCustomConfig(path string, stdout io.Writer) (CustomLauncherConfig, error) {
-	data, err := os.ReadFile(path)
+	data, err := readConfigFile(path)
 	if err != nil {
 		if os.IsNotExist(err) {
 			fmt.Fprintf(stdout, "Custom config file %s not found, using defaults\n", path)
@@ -393,7 +1268,7 @@ func readCustomConfig(path string, stdout io.Writer) (CustomLauncherConfig, erro
 	}
 	var config CustomLauncherConfig
 	if err := yaml.Unmarshal(data, &config); err != nil {
-		return CustomLauncherConfig{}, err
+		return CustomLauncherConfig{}, fmt.Errorf("%w: %w", ErrConfigParse, err)
 	}
 	return config, nil
 }
@@ -401,64 +1276,412 @@ func readCustomConfig(path string, stdout io.Writer) (CustomLauncherConfig, erro
 func validateStaticConfig(config StaticLauncherConfig) error {
 	// Empty configType defaults to "python"
 	if config.ConfigType != "" && config.ConfigType != ConfigTypePython {
-		return fmt.Errorf("expected configType %q, got %q", ConfigTypePython, config.ConfigType)
+		return invalidField("configType", config.ConfigType, "expected %q", ConfigTypePython)
 	}
 	if config.ConfigVersion != 1 {
-		return fmt.Errorf("expected configVersion 1, got %d", config.ConfigVersion)
+		return invalidField("configVersion", config.ConfigVersion, "expected 1")
 	}
 	if config.Executable == "" {
-		return fmt.Errorf("executable must not be empty")
+		return invalidField("executable", "", "must not be empty")
+	}
+	if config.Resources.Nice < minNice || config.Resources.Nice > maxNice {
+		return invalidField("resources.nice", config.Resources.Nice, "must be between %d and %d", minNice, maxNice)
+	}
+	if config.Resources.OOMScoreAdj < minOOMScoreAdj || config.Resources.OOMScoreAdj > maxOOMScoreAdj {
+		return invalidField("resources.oomScoreAdj", config.Resources.OOMScoreAdj, "must be between %d and %d", minOOMScoreAdj, maxOOMScoreAdj)
+	}
+	if soft, hard := config.Resources.OpenFilesLimits(); soft > 0 && hard > 0 && soft > hard {
+		return invalidField("resources.maxOpenFilesSoft", soft, "must not exceed the hard limit %d", hard)
+	}
+	if config.Resources.Umask != "" {
+		if _, err := ParseUmask(config.Resources.Umask); err != nil {
+			return invalidField("resources.umask", config.Resources.Umask, "expected octal like \"0027\"")
+		}
+	}
+	if config.OutputBuffer.SizeBytes < 0 {
+		return invalidField("outputBuffer.sizeBytes", config.OutputBuffer.SizeBytes, "must not be negative")
+	}
+	if config.CgroupRoot != "" && !path.IsAbs(config.CgroupRoot) {
+		return invalidField("cgroupRoot", config.CgroupRoot, "must be an absolute path")
+	}
+	for i, dir := range config.Dirs {
+		if dir.Path == "" {
+			return invalidField(fmt.Sprintf("dirs[%d].path", i), "", "must not be empty")
+		}
+		if _, err := ExpandDirRange(dir.Path); err != nil {
+			return invalidField(fmt.Sprintf("dirs[%d].path", i), dir.Path, "%v", err)
+		}
+		if _, err := dir.FileMode(); err != nil {
+			return invalidField(fmt.Sprintf("dirs[%d].mode", i), dir.Mode, "expected octal permissions like \"0750\"")
+		}
+	}
+	switch config.EnvInherit.Policy {
+	case "", EnvInheritAll, EnvInheritNone, EnvInheritAllowlist:
+	default:
+		return invalidField("envInherit.policy", config.EnvInherit.Policy, "expected %q, %q or %q",
+			EnvInheritAll, EnvInheritNone, EnvInheritAllowlist)
+	}
+	for i, pattern := range config.EnvInherit.AllowlistPatterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return invalidField(fmt.Sprintf("envInherit.allowlistPatterns[%d]", i), pattern, "%v", err)
+		}
+	}
+	if config.Diagnostics.Enabled {
+		sig, err := diagnosticSignal(config.Diagnostics)
+		if err != nil {
+			return invalidField("diagnostics.signal", config.Diagnostics.Signal, "%v", err)
+		}
+		switch sig {
+		case syscall.SIGKILL, syscall.SIGTERM, syscall.SIGINT, syscall.SIGHUP:
+			return invalidField("diagnostics.signal", config.Diagnostics.Signal, "reserved for process shutdown")
+		}
+	}
+	if config.RequirePythonVersion != "" {
+		if _, err := parseVersionConstraint(config.RequirePythonVersion); err != nil {
+			return invalidField("requirePythonVersion", config.RequirePythonVersion, "%v", err)
+		}
+	}
+	if config.ResolveInterpreterFromShebang && config.LaunchMode != "" && config.LaunchMode != LaunchModePEX {
+		return invalidField("resolveInterpreterFromShebang", true, "only applies to launchMode %q", LaunchModePEX)
+	}
+	if err := validateExtraLimitEnvVars(config.Memory.ExtraLimitEnvVars); err != nil {
+		return err
+	}
+	if err := validatePathEntries(config.PythonPathEntries, config.EnvAppend); err != nil {
+		return err
+	}
+	if err := validatePercentages(&config.Memory, &config.Watchdog); err != nil {
+		return err
+	}
+	if err := validatePollInterval(&config.Watchdog); err != nil {
+		return err
+	}
+	if err := validateSmoothingAlpha(&config.Watchdog); err != nil {
+		return err
+	}
+	if err := validateStartupProbe(config.Startup); err != nil {
+		return err
+	}
+	if config.Readiness.BindInterface != "" && config.Readiness.BindAddress != "" {
+		return invalidField("readiness.bindInterface", config.Readiness.BindInterface, "set only one of bindAddress and bindInterface")
+	}
+	if warmupURL := config.Readiness.Warmup.URL; warmupURL != "" {
+		if u, err := url.Parse(warmupURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return invalidField("readiness.warmup.url", warmupURL, "expected an http or https URL")
+		}
+	}
+	if err := validateShutdownSequence(config.ShutdownSequence); err != nil {
+		return err
+	}
+	if _, err := parseSignalRemap(config.SignalRemap); err != nil {
+		return err
+	}
+	for version, pythonPath := range config.PythonVersions {
+		if pythonPath == "" {
+			return invalidField("pythonVersions."+version, "", "must not be empty")
+		}
+	}
+	return nil
+}
+
+// validateExtraLimitEnvVars checks memory.extraLimitEnvVars entries.
+func validateExtraLimitEnvVars(entries []string) error {
+	for i, entry := range entries {
+		if _, _, err := parseLimitEnvVar(entry); err != nil {
+			return invalidField(fmt.Sprintf("memory.extraLimitEnvVars[%d]", i), entry, "%v", err)
+		}
+	}
+	return nil
+}
+
+// normalizePercent converts a fractional percentage (0 < value <= 1) such as
+// 0.75 into its whole-number form (75). Other values are returned unchanged so
+// that zero keeps meaning "use the default" and out-of-range values are left
+// for validatePercent to reject.
+func normalizePercent(value float64) float64 {
+	if value > 0 && value <= 1 {
+		return value * 100
+	}
+	return value
+}
+
+// normalizePercentages rewrites the percentage fields of memory and watchdog
+// in place. Either argument may be nil.
+func normalizePercentages(memory *MemoryConfig, watchdog *WatchdogConfig) {
+	if memory != nil {
+		memory.MaxRSSPercent = normalizePercent(memory.MaxRSSPercent)
+	}
+	if watchdog != nil {
+		watchdog.SoftLimitPercent = normalizePercent(watchdog.SoftLimitPercent)
+		watchdog.HardLimitPercent = normalizePercent(watchdog.HardLimitPercent)
+	}
+}
+
+// validatePercent rejects percentages outside 0-100. Zero is allowed because
+// it means the field is unset.
+func validatePercent(field string, value float64) error {
+	if value < 0 || value > 100 {
+		return invalidField(field, value, "must be between 0 and 100 (or a fraction up to 1.0)")
 	}
 	return nil
 }
 
+// validatePercentages checks the percentage fields of memory and watchdog.
+// Either argument may be nil.
+func validatePercentages(memory *MemoryConfig, watchdog *WatchdogConfig) error {
+	if memory != nil {
+		if err := validatePercent("memory.maxRssPercent", memory.MaxRSSPercent); err != nil {
+			return err
+		}
+	}
+	if watchdog != nil {
+		if err := validatePercent("watchdog.softLimitPercent", watchdog.SoftLimitPercent); err != nil {
+			return err
+		}
+		if err := validatePercent("watchdog.hardLimitPercent", watchdog.HardLimitPercent); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validatePollInterval rejects a watchdog.pollIntervalMillis so short that
+// polling would burn CPU. The watchdog may be nil.
+func validatePollInterval(watchdog *WatchdogConfig) error {
+	if watchdog == nil || watchdog.PollIntervalMillis == 0 {
+		return nil
+	}
+	if watchdog.PollIntervalMillis < minPollIntervalMillis {
+		return invalidField("watchdog.pollIntervalMillis", watchdog.PollIntervalMillis,
+			"must be at least %d", minPollIntervalMillis)
+	}
+	return nil
+}
+
+// validateSmoothingAlpha rejects a watchdog.smoothingAlpha outside (0, 1].
+func validateSmoothingAlpha(watchdog *WatchdogConfig) error {
+	if watchdog == nil || watchdog.SmoothingAlpha == nil {
+		return nil
+	}
+	if alpha := *watchdog.SmoothingAlpha; alpha <= 0 || alpha > 1 {
+		return invalidField("watchdog.smoothingAlpha", alpha, "must be greater than 0 and at most 1")
+	}
+	return nil
+}
+
+// validateCustomHeader checks the custom config's configType and
+// configVersion, which are optional but must match the static config's when
+// present.
+func validateCustomHeader(custom CustomLauncherConfig) error {
+	if custom.ConfigType != "" && custom.ConfigType != ConfigTypePython {
+		return invalidField("configType", custom.ConfigType, "expected %q", ConfigTypePython)
+	}
+	if custom.ConfigVersion != 0 && custom.ConfigVersion != 1 {
+		return invalidField("configVersion", custom.ConfigVersion, "expected 1")
+	}
+	return nil
+}
+
+// validateCustomConfig checks the custom config against the static config:
+// configType and configVersion must match if present, memory and watchdog
+// overrides must be well formed and pythonVersion, if set, must be one of the
+// static config's pythonVersions.
+func validateCustomConfig(static StaticLauncherConfig, custom CustomLauncherConfig) error {
+	if err := validateCustomHeader(custom); err != nil {
+		return err
+	}
+	if custom.Memory != nil {
+		if err := validateExtraLimitEnvVars(custom.Memory.ExtraLimitEnvVars); err != nil {
+			return err
+		}
+	}
+	if err := validatePercentages(custom.Memory, custom.Watchdog); err != nil {
+		return err
+	}
+	if err := validatePollInterval(custom.Watchdog); err != nil {
+		return err
+	}
+	if err := validateSmoothingAlpha(custom.Watchdog); err != nil {
+		return err
+	}
+	if err := validateListMergeMode("argsMode", custom.ArgsMode); err != nil {
+		return err
+	}
+	if err := validatePathEntries(custom.PythonPathEntries, custom.EnvAppend); err != nil {
+		return err
+	}
+	if err := validateListMergeMode("pythonOptsMode", custom.PythonOptsMode); err != nil {
+		return err
+	}
+	if custom.PythonVersion == "" {
+		return nil
+	}
+	if _, ok := static.PythonVersions[custom.PythonVersion]; ok {
+		return nil
+	}
+	if len(static.PythonVersions) == 0 {
+		return invalidField("pythonVersion", custom.PythonVersion, "static config defines no pythonVersions")
+	}
+	versions := make([]string, 0, len(static.PythonVersions))
+	for version := range static.PythonVersions {
+		versions = append(versions, version)
+	}
+	sort.Strings(versions)
+	return invalidField("pythonVersion", custom.PythonVersion, "not in pythonVersions (available: %s)",
+		strings.Join(versions, ", "))
+}
+
 func mergeMemoryConfig(static MemoryConfig, custom *MemoryConfig) MemoryConfig {
-	result := static
+	return applyMemoryDefaults(rawMemoryConfig(static, custom))
+}
+
+// rawMemoryConfig applies the custom overrides without filling in defaults, so
+// callers can tell which fields were set explicitly.
+func rawMemoryConfig(static MemoryConfig, custom *MemoryConfig) MemoryConfig {
 	if custom == nil {
-		return applyMemoryDefaults(result)
+		return static
+	}
+	return overrideMemoryConfig(static, *custom)
+}
+
+// memoryConfigConflicts returns a warning for each explicitly set memory field
+// that the effective mode ignores or reinterprets. These are not errors so that
+// a shared config can switch modes without removing fields.
+func memoryConfigConflicts(config MemoryConfig) []string {
+	mode := config.Mode
+	if mode == "" {
+		mode = DefaultMemoryConfig().Mode
+	}
+
+	var warnings []string
+	if config.FixedLimitBytes > 0 && mode != MemoryModeFixed {
+		warnings = append(warnings, fmt.Sprintf(
+			"memory.fixedLimitBytes=%d is ignored because memory.mode is %q; set mode: fixed to use it",
+			config.FixedLimitBytes, mode))
+	}
+	if config.MaxRSSPercent > 0 && mode == MemoryModeFixed {
+		warnings = append(warnings, fmt.Sprintf(
+			"memory.maxRssPercent=%g is applied to memory.fixedLimitBytes in fixed mode; the fixed limit is not used as-is",
+			config.MaxRSSPercent))
+	}
+	if config.MaxRSSPercent > 0 && mode == MemoryModeUnmanaged {
+		warnings = append(warnings, fmt.Sprintf(
+			"memory.maxRssPercent=%g is ignored because memory.mode is %q",
+			config.MaxRSSPercent, mode))
+	}
+	if config.MinHeadroomBytes > 0 && mode == MemoryModeUnmanaged {
+		warnings = append(warnings, fmt.Sprintf(
+			"memory.minHeadroomBytes=%d is ignored because memory.mode is %q",
+			config.MinHeadroomBytes, mode))
+	}
+	return warnings
+}
+
+// overrideMemoryConfig returns base with every set field of override applied.
+// Pointer fields count as set when non-nil, so an explicit zero overrides.
+func overrideMemoryConfig(base MemoryConfig, override MemoryConfig) MemoryConfig {
+	result := base
+	if override.Mode != "" {
+		result.Mode = override.Mode
+	}
+	if override.MaxRSSPercent > 0 {
+		result.MaxRSSPercent = override.MaxRSSPercent
+	}
+	if override.FixedLimitBytes > 0 {
+		result.FixedLimitBytes = override.FixedLimitBytes
+	}
+	if override.HeapFragmentationBuffer != nil {
+		result.HeapFragmentationBuffer = override.HeapFragmentationBuffer
 	}
-	if custom.Mode != "" {
-		result.Mode = custom.Mode
+	if override.MinHeadroomBytes > 0 {
+		result.MinHeadroomBytes = override.MinHeadroomBytes
 	}
-	if custom.MaxRSSPercent > 0 {
-		result.MaxRSSPercent = custom.MaxRSSPercent
+	if override.MallocTrimThreshold != nil {
+		result.MallocTrimThreshold = override.MallocTrimThreshold
 	}
-	if custom.FixedLimitBytes > 0 {
-		result.FixedLimitBytes = custom.FixedLimitBytes
+	if override.MallocArenaMax != nil {
+		result.MallocArenaMax = override.MallocArenaMax
 	}
-	if custom.HeapFragmentationBuffer > 0 {
-		result.HeapFragmentationBuffer = custom.HeapFragmentationBuffer
+	if override.CgroupReadAttempts > 0 {
+		result.CgroupReadAttempts = override.CgroupReadAttempts
 	}
-	if custom.MallocTrimThreshold != 0 {
-		result.MallocTrimThreshold = custom.MallocTrimThreshold
+	if len(override.ExtraLimitEnvVars) > 0 {
+		result.ExtraLimitEnvVars = override.ExtraLimitEnvVars
 	}
-	if custom.MallocArenaMax != 0 {
-		result.MallocArenaMax = custom.MallocArenaMax
+	if override.DescriptorFile != "" {
+		result.DescriptorFile = override.DescriptorFile
 	}
-	return applyMemoryDefaults(result)
+	return result
 }
 
 func mergeWatchdogConfig(static WatchdogConfig, custom *WatchdogConfig) WatchdogConfig {
-	result := static
 	if custom == nil {
-		return applyWatchdogDefaults(result)
+		return applyWatchdogDefaults(static)
+	}
+	return applyWatchdogDefaults(overrideWatchdogConfig(static, *custom))
+}
+
+// overrideWatchdogConfig returns base with every set field of override applied.
+// Pointer fields count as set when non-nil, so an explicit zero overrides.
+func overrideWatchdogConfig(base WatchdogConfig, override WatchdogConfig) WatchdogConfig {
+	result := base
+	if override.Enabled != nil {
+		result.Enabled = override.Enabled
+	}
+	if override.PollIntervalSeconds > 0 {
+		result.PollIntervalSeconds = override.PollIntervalSeconds
+		// A later layer's seconds replace an earlier layer's millis.
+		result.PollIntervalMillis = 0
+	}
+	if override.PollIntervalMillis > 0 {
+		result.PollIntervalMillis = override.PollIntervalMillis
 	}
-	if custom.Enabled != nil {
-		result.Enabled = custom.Enabled
+	if override.SoftLimitPercent > 0 {
+		result.SoftLimitPercent = override.SoftLimitPercent
 	}
-	if custom.PollIntervalSeconds > 0 {
-		result.PollIntervalSeconds = custom.PollIntervalSeconds
+	if override.HardLimitPercent > 0 {
+		result.HardLimitPercent = override.HardLimitPercent
 	}
-	if custom.SoftLimitPercent > 0 {
-		result.SoftLimitPercent = custom.SoftLimitPercent
+	if override.GracePeriodSeconds > 0 {
+		result.GracePeriodSeconds = override.GracePeriodSeconds
 	}
-	if custom.HardLimitPercent > 0 {
-		result.HardLimitPercent = custom.HardLimitPercent
+	if override.AdaptiveGrace != nil {
+		result.AdaptiveGrace = override.AdaptiveGrace
 	}
-	if custom.GracePeriodSeconds > 0 {
-		result.GracePeriodSeconds = custom.GracePeriodSeconds
+	if override.ObserveOnly != nil {
+		result.ObserveOnly = override.ObserveOnly
 	}
-	return applyWatchdogDefaults(result)
+	if override.SmoothingAlpha != nil {
+		result.SmoothingAlpha = override.SmoothingAlpha
+	}
+	if override.RefreshLimits != nil {
+		result.RefreshLimits = override.RefreshLimits
+	}
+	if override.MaxConsecutiveReadFailures > 0 {
+		result.MaxConsecutiveReadFailures = override.MaxConsecutiveReadFailures
+	}
+	if override.PeakRSSFile != "" {
+		result.PeakRSSFile = override.PeakRSSFile
+	}
+	if override.EventLogFile != "" {
+		result.EventLogFile = override.EventLogFile
+	}
+	if override.StartupGraceSeconds != nil {
+		result.StartupGraceSeconds = override.StartupGraceSeconds
+	}
+	if override.PressureWarnPercent != nil {
+		result.PressureWarnPercent = override.PressureWarnPercent
+	}
+	if override.KillCgroupOnEscalation != nil {
+		result.KillCgroupOnEscalation = override.KillCgroupOnEscalation
+	}
+	if override.AnonymousOnly != nil {
+		result.AnonymousOnly = override.AnonymousOnly
+	}
+	if override.LeakWarnBytesPerMinute != nil {
+		result.LeakWarnBytesPerMinute = override.LeakWarnBytesPerMinute
+	}
+	return result
 }
 
 func applyMemoryDefaults(config MemoryConfig) MemoryConfig {
@@ -469,18 +1692,33 @@ func applyMemoryDefaults(config MemoryConfig) MemoryConfig {
 	if config.MaxRSSPercent == 0 {
 		config.MaxRSSPercent = defaults.MaxRSSPercent
 	}
-	if config.HeapFragmentationBuffer == 0 {
+	if config.HeapFragmentationBuffer == nil {
 		config.HeapFragmentationBuffer = defaults.HeapFragmentationBuffer
 	}
-	if config.MallocTrimThreshold == 0 {
+	if config.MallocTrimThreshold == nil {
 		config.MallocTrimThreshold = defaults.MallocTrimThreshold
 	}
-	if config.MallocArenaMax == 0 {
+	if config.MallocArenaMax == nil {
 		config.MallocArenaMax = defaults.MallocArenaMax
 	}
+	if config.CgroupReadAttempts <= 0 {
+		config.CgroupReadAttempts = defaults.CgroupReadAttempts
+	}
 	return config
 }
 
+// minPollIntervalMillis is the smallest accepted watchdog.pollIntervalMillis.
+const minPollIntervalMillis = 50
+
+// PollInterval returns how often the watchdog polls: PollIntervalMillis if
+// set, otherwise PollIntervalSeconds.
+func (c WatchdogConfig) PollInterval() time.Duration {
+	if c.PollIntervalMillis > 0 {
+		return time.Duration(c.PollIntervalMillis) * time.Millisecond
+	}
+	return time.Duration(c.PollIntervalSeconds) * time.Second
+}
+
 func applyWatchdogDefaults(config WatchdogConfig) WatchdogConfig {
 	defaults := DefaultWatchdogConfig()
 	if config.Enabled == nil {
@@ -498,5 +1736,16 @@ func applyWatchdogDefaults(config WatchdogConfig) WatchdogConfig {
 	if config.GracePeriodSeconds == 0 {
 		config.GracePeriodSeconds = defaults.GracePeriodSeconds
 	}
+	if config.MaxConsecutiveReadFailures == 0 {
+		config.MaxConsecutiveReadFailures = defaults.MaxConsecutiveReadFailures
+	}
 	return config
 }
+
+// valueOr returns *p, or fallback when p is nil.
+func valueOr[T any](p *T, fallback T) T {
+	if p == nil {
+		return fallback
+	}
+	return *p
+}
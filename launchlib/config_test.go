@@ -16,9 +16,16 @@ package launchlib
 
 import (
 	"bytes"
+	"errors"
 	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
+	"syscall"
 	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
 )
 
 func TestReadStaticConfig(t *testing.T) {
@@ -58,7 +65,7 @@ dirs:
 		t.Fatal(err)
 	}
 
-	config, err := readStaticConfig(path)
+	config, err := readStaticConfig(path, identityPath)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -84,8 +91,8 @@ dirs:
 	if config.Memory.MaxRSSPercent != 80 {
 		t.Errorf("expected maxRssPercent 80, got %f", config.Memory.MaxRSSPercent)
 	}
-	if config.Memory.HeapFragmentationBuffer != 0.15 {
-		t.Errorf("expected heapFragmentationBuffer 0.15, got %f", config.Memory.HeapFragmentationBuffer)
+	if valueOr(config.Memory.HeapFragmentationBuffer, 0) != 0.15 {
+		t.Errorf("expected heapFragmentationBuffer 0.15, got %v", config.Memory.HeapFragmentationBuffer)
 	}
 	if config.Watchdog.PollIntervalSeconds != 10 {
 		t.Errorf("expected watchdog poll 10, got %d", config.Watchdog.PollIntervalSeconds)
@@ -305,11 +312,11 @@ func TestDefaultsApplied(t *testing.T) {
 	if merged.Memory.MaxRSSPercent != 75 {
 		t.Errorf("expected default maxRssPercent 75, got %f", merged.Memory.MaxRSSPercent)
 	}
-	if merged.Memory.HeapFragmentationBuffer != 0.10 {
-		t.Errorf("expected default heapFragmentationBuffer 0.10, got %f", merged.Memory.HeapFragmentationBuffer)
+	if valueOr(merged.Memory.HeapFragmentationBuffer, 0) != 0.10 {
+		t.Errorf("expected default heapFragmentationBuffer 0.10, got %v", merged.Memory.HeapFragmentationBuffer)
 	}
-	if merged.Memory.MallocArenaMax != 2 {
-		t.Errorf("expected default mallocArenaMax 2, got %d", merged.Memory.MallocArenaMax)
+	if valueOr(merged.Memory.MallocArenaMax, 0) != 2 {
+		t.Errorf("expected default mallocArenaMax 2, got %v", merged.Memory.MallocArenaMax)
 	}
 
 	// Watchdog defaults
@@ -326,3 +333,828 @@ func TestDefaultsApplied(t *testing.T) {
 		t.Errorf("expected default hard limit 95, got %f", merged.Watchdog.HardLimitPercent)
 	}
 }
+
+func TestMergeConfigsExplicitZeroOverrides(t *testing.T) {
+	staticPath, customPath := writeTestConfigs(t, testStaticYAML+`
+memory:
+  heapFragmentationBuffer: 0.15
+  mallocArenaMax: 4
+watchdog:
+  startupGraceSeconds: 30
+  anonymousOnly: true
+`, `
+memory:
+  heapFragmentationBuffer: 0
+  mallocArenaMax: 0
+watchdog:
+  startupGraceSeconds: 0
+  anonymousOnly: false
+`)
+	static, custom, err := GetConfigsFromFiles(staticPath, customPath, &bytes.Buffer{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	merged := MergeConfigs(static, custom)
+
+	if got := valueOr(merged.Memory.HeapFragmentationBuffer, -1); got != 0 {
+		t.Errorf("expected explicit heapFragmentationBuffer 0 to survive the merge, got %v", got)
+	}
+	if got := valueOr(merged.Memory.MallocArenaMax, -1); got != 0 {
+		t.Errorf("expected explicit mallocArenaMax 0 to survive the merge, got %d", got)
+	}
+	if got := valueOr(merged.Watchdog.StartupGraceSeconds, -1); got != 0 {
+		t.Errorf("expected explicit startupGraceSeconds 0 to survive the merge, got %d", got)
+	}
+	if valueOr(merged.Watchdog.AnonymousOnly, true) {
+		t.Error("expected custom anonymousOnly false to override static true")
+	}
+	// Absent fields still fall back to the defaults.
+	if got := valueOr(merged.Memory.MallocTrimThreshold, 0); got != 131072 {
+		t.Errorf("expected default mallocTrimThreshold 131072, got %d", got)
+	}
+
+	env := BuildMemoryEnv(merged, MemoryLimits{EffectiveLimitBytes: 1 << 30})
+	if value, ok := env["MALLOC_ARENA_MAX"]; ok {
+		t.Errorf("expected MALLOC_ARENA_MAX unset for the glibc default, got %q", value)
+	}
+}
+
+func TestMergeConfigsListModes(t *testing.T) {
+	static := StaticLauncherConfig{
+		Args:       []string{"server", "var/conf/app.yml"},
+		PythonOpts: []string{"-O"},
+	}
+	tests := []struct {
+		name           string
+		custom         CustomLauncherConfig
+		wantArgs       []string
+		wantPythonOpts []string
+	}{
+		{
+			name:           "default append",
+			custom:         CustomLauncherConfig{Args: []string{"--debug"}, PythonOpts: []string{"-u"}},
+			wantArgs:       []string{"server", "var/conf/app.yml", "--debug"},
+			wantPythonOpts: []string{"-O", "-u"},
+		},
+		{
+			name:           "explicit append",
+			custom:         CustomLauncherConfig{Args: []string{"--debug"}, ArgsMode: ListMergeAppend},
+			wantArgs:       []string{"server", "var/conf/app.yml", "--debug"},
+			wantPythonOpts: []string{"-O"},
+		},
+		{
+			name:           "replace args",
+			custom:         CustomLauncherConfig{Args: []string{"migrate"}, ArgsMode: ListMergeReplace, PythonOpts: []string{"-u"}},
+			wantArgs:       []string{"migrate"},
+			wantPythonOpts: []string{"-O", "-u"},
+		},
+		{
+			name:           "replace python opts with nothing",
+			custom:         CustomLauncherConfig{PythonOptsMode: ListMergeReplace},
+			wantArgs:       []string{"server", "var/conf/app.yml"},
+			wantPythonOpts: []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			merged := MergeConfigs(static, tt.custom)
+			if !reflect.DeepEqual(merged.Args, tt.wantArgs) {
+				t.Errorf("expected args %v, got %v", tt.wantArgs, merged.Args)
+			}
+			if !reflect.DeepEqual(merged.PythonOpts, tt.wantPythonOpts) {
+				t.Errorf("expected python opts %v, got %v", tt.wantPythonOpts, merged.PythonOpts)
+			}
+		})
+	}
+}
+
+func TestValidateCustomConfigListModes(t *testing.T) {
+	static := StaticLauncherConfig{ConfigVersion: 1, Executable: "service/bin/app.pex"}
+	if err := validateCustomConfig(static, CustomLauncherConfig{ArgsMode: "prepend"}); err == nil {
+		t.Error("expected an error for argsMode prepend")
+	}
+	if err := validateCustomConfig(static, CustomLauncherConfig{PythonOptsMode: "overwrite"}); err == nil {
+		t.Error("expected an error for pythonOptsMode overwrite")
+	}
+	if err := validateCustomConfig(static, CustomLauncherConfig{ArgsMode: ListMergeReplace, PythonOptsMode: ListMergeAppend}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestMergeConfigsPathEntries(t *testing.T) {
+	static := StaticLauncherConfig{
+		PythonPathEntries: []string{"service/lib"},
+		EnvAppend:         map[string][]string{"PATH": {"/opt/static/bin"}},
+	}
+	custom := CustomLauncherConfig{
+		PythonPathEntries: []string{"var/plugins"},
+		EnvAppend:         map[string][]string{"PATH": {"/opt/custom/bin"}, "LD_LIBRARY_PATH": {"/opt/lib"}},
+	}
+	merged := MergeConfigs(static, custom)
+	assertArgs(t, []string{"var/plugins", "service/lib"}, merged.PythonPathEntries)
+	assertArgs(t, []string{"/opt/static/bin", "/opt/custom/bin"}, merged.EnvAppend["PATH"])
+	assertArgs(t, []string{"/opt/lib"}, merged.EnvAppend["LD_LIBRARY_PATH"])
+	if got := static.EnvAppend["PATH"]; len(got) != 1 {
+		t.Errorf("expected the static envAppend to be left unchanged, got %v", got)
+	}
+}
+
+func TestValidatePathEntries(t *testing.T) {
+	static := StaticLauncherConfig{ConfigVersion: 1, Executable: "service/bin/app.pex"}
+	tests := []struct {
+		name    string
+		entries []string
+		append  map[string][]string
+		wantErr string
+	}{
+		{"valid", []string{"service/lib", "/opt/lib"}, map[string][]string{"PATH": {"/opt/bin"}}, ""},
+		{"empty entry", []string{""}, nil, "pythonPathEntries[0]"},
+		{"separator in entry", []string{"a:b"}, nil, "pythonPathEntries[0]"},
+		{"empty append entry", nil, map[string][]string{"PATH": {"/opt/bin", ""}}, "envAppend.PATH[1]"},
+		{"invalid name", nil, map[string][]string{"A=B": {"/opt/bin"}}, "envAppend"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := static
+			config.PythonPathEntries = tt.entries
+			config.EnvAppend = tt.append
+			err := validateStaticConfig(config)
+			customErr := validateCustomConfig(static, CustomLauncherConfig{PythonPathEntries: tt.entries, EnvAppend: tt.append})
+			if tt.wantErr == "" {
+				if err != nil || customErr != nil {
+					t.Errorf("unexpected errors: %v, %v", err, customErr)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected a static error for %s, got %v", tt.wantErr, err)
+			}
+			if customErr == nil || !strings.Contains(customErr.Error(), tt.wantErr) {
+				t.Errorf("expected a custom error for %s, got %v", tt.wantErr, customErr)
+			}
+		})
+	}
+}
+
+func TestValidateStaticConfigOOMScoreAdjRange(t *testing.T) {
+	config := StaticLauncherConfig{
+		ConfigVersion: 1,
+		Executable:    "service/bin/app.pex",
+		Resources:     ResourceConfig{OOMScoreAdj: 1001},
+	}
+	if err := validateStaticConfig(config); err == nil {
+		t.Error("expected an error for out-of-range oomScoreAdj")
+	}
+	config.Resources.OOMScoreAdj = -1000
+	if err := validateStaticConfig(config); err != nil {
+		t.Errorf("unexpected error for oomScoreAdj -1000: %v", err)
+	}
+}
+
+func TestValidateStaticConfigNiceRange(t *testing.T) {
+	config := StaticLauncherConfig{
+		ConfigVersion: 1,
+		Executable:    "service/bin/app.pex",
+		Resources:     ResourceConfig{Nice: 25},
+	}
+	if err := validateStaticConfig(config); err == nil {
+		t.Error("expected an error for out-of-range nice")
+	}
+	config.Resources.Nice = 10
+	if err := validateStaticConfig(config); err != nil {
+		t.Errorf("unexpected error for nice 10: %v", err)
+	}
+}
+
+func TestValidateStaticConfigOpenFiles(t *testing.T) {
+	tests := []struct {
+		name      string
+		resources ResourceConfig
+		wantErr   bool
+	}{
+		{"soft below hard", ResourceConfig{MaxOpenFilesSoft: 1024, MaxOpenFilesHard: 65536}, false},
+		{"soft equals hard", ResourceConfig{MaxOpenFilesSoft: 4096, MaxOpenFilesHard: 4096}, false},
+		{"soft above hard", ResourceConfig{MaxOpenFilesSoft: 65536, MaxOpenFilesHard: 1024}, true},
+		{"soft above maxOpenFiles", ResourceConfig{MaxOpenFiles: 1024, MaxOpenFilesSoft: 4096}, true},
+		{"hard only", ResourceConfig{MaxOpenFilesHard: 1024}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := StaticLauncherConfig{ConfigVersion: 1, Executable: "service/bin/app.pex", Resources: tt.resources}
+			if err := validateStaticConfig(config); (err != nil) != tt.wantErr {
+				t.Errorf("expected error %t, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestValidateStaticConfigBindInterface(t *testing.T) {
+	config := StaticLauncherConfig{
+		ConfigVersion: 1,
+		Executable:    "service/bin/app.pex",
+		Readiness:     ReadinessConfig{BindInterface: "eth0", BindAddress: "0.0.0.0"},
+	}
+	if err := validateStaticConfig(config); err == nil {
+		t.Error("expected an error when both bindAddress and bindInterface are set")
+	}
+	config.Readiness.BindAddress = ""
+	if err := validateStaticConfig(config); err != nil {
+		t.Errorf("unexpected error for bindInterface alone: %v", err)
+	}
+}
+
+func TestValidateStaticConfigResolveInterpreterFromShebang(t *testing.T) {
+	config := StaticLauncherConfig{
+		ConfigVersion:                 1,
+		LaunchMode:                    LaunchModeModule,
+		Executable:                    "app",
+		ResolveInterpreterFromShebang: true,
+	}
+	if err := validateStaticConfig(config); err == nil {
+		t.Error("expected resolveInterpreterFromShebang to be rejected outside pex mode")
+	}
+	config.LaunchMode = LaunchModePEX
+	if err := validateStaticConfig(config); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestValidateSmoothingAlpha(t *testing.T) {
+	for _, tt := range []struct {
+		alpha   float64
+		wantErr bool
+	}{{0.2, false}, {1, false}, {0, true}, {-0.5, true}, {1.5, true}} {
+		alpha := tt.alpha
+		err := validateSmoothingAlpha(&WatchdogConfig{SmoothingAlpha: &alpha})
+		if (err != nil) != tt.wantErr {
+			t.Errorf("alpha %v: expected error %t, got %v", tt.alpha, tt.wantErr, err)
+		}
+	}
+}
+
+func TestValidateStaticConfigCgroupRoot(t *testing.T) {
+	config := StaticLauncherConfig{
+		ConfigVersion: 1,
+		Executable:    "service/bin/app.pex",
+		CgroupRoot:    "sandbox/cgroup",
+	}
+	if err := validateStaticConfig(config); err == nil {
+		t.Error("expected an error for a relative cgroupRoot")
+	}
+	config.CgroupRoot = "/sandbox/cgroup"
+	if err := validateStaticConfig(config); err != nil {
+		t.Errorf("unexpected error for an absolute cgroupRoot: %v", err)
+	}
+}
+
+func TestValidateStaticConfigOutputBuffer(t *testing.T) {
+	config := StaticLauncherConfig{
+		ConfigVersion: 1,
+		Executable:    "service/bin/app.pex",
+		OutputBuffer:  OutputBufferConfig{Enabled: true, SizeBytes: -1},
+	}
+	if err := validateStaticConfig(config); err == nil {
+		t.Error("expected an error for a negative outputBuffer.sizeBytes")
+	}
+	config.OutputBuffer.SizeBytes = 0
+	if err := validateStaticConfig(config); err != nil {
+		t.Errorf("unexpected error for the default outputBuffer.sizeBytes: %v", err)
+	}
+}
+
+func TestValidateStaticConfigSignalRemap(t *testing.T) {
+	base := StaticLauncherConfig{ConfigVersion: 1, Executable: "service/bin/app.pex"}
+	tests := []struct {
+		remap   map[string]string
+		wantErr bool
+	}{
+		{nil, false},
+		{map[string]string{"SIGINT": "SIGTERM"}, false},
+		{map[string]string{"HUP": "usr1", "SIGTERM": "SIGQUIT"}, false},
+		{map[string]string{"SIGINT": "SIGBOGUS"}, true},
+		{map[string]string{"SIGBOGUS": "SIGTERM"}, true},
+		{map[string]string{"SIGUSR2": "SIGTERM"}, true},
+	}
+	for _, tt := range tests {
+		config := base
+		config.SignalRemap = tt.remap
+		err := validateStaticConfig(config)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("signalRemap %v: expected error %v, got %v", tt.remap, tt.wantErr, err)
+		}
+	}
+}
+
+func TestValidateStaticConfigEnvInherit(t *testing.T) {
+	base := StaticLauncherConfig{ConfigVersion: 1, Executable: "service/bin/app.pex"}
+
+	config := base
+	config.EnvInherit = EnvInheritConfig{Policy: "some"}
+	if err := validateStaticConfig(config); err == nil {
+		t.Error("expected an error for an unknown policy")
+	}
+
+	config = base
+	config.EnvInherit = EnvInheritConfig{Policy: EnvInheritAllowlist, AllowlistPatterns: []string{"LC_["}}
+	if err := validateStaticConfig(config); err == nil {
+		t.Error("expected an error for a malformed glob")
+	}
+
+	config = base
+	config.EnvInherit = EnvInheritConfig{Policy: EnvInheritAllowlist, AllowlistPatterns: []string{"LC_*", "PATH"}}
+	if err := validateStaticConfig(config); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestMemoryConfigConflicts(t *testing.T) {
+	tests := []struct {
+		name    string
+		static  MemoryConfig
+		custom  *MemoryConfig
+		ignored string // field named in the single expected warning; empty for none
+	}{
+		{"fixed limit with cgroup-aware", MemoryConfig{Mode: MemoryModeCgroupAware, FixedLimitBytes: 1 << 30}, nil, "fixedLimitBytes"},
+		{"fixed limit with default mode", MemoryConfig{FixedLimitBytes: 1 << 30}, nil, "fixedLimitBytes"},
+		{"fixed limit with unmanaged", MemoryConfig{Mode: MemoryModeUnmanaged, FixedLimitBytes: 1 << 30}, nil, "fixedLimitBytes"},
+		{"maxRssPercent with fixed", MemoryConfig{Mode: MemoryModeFixed, FixedLimitBytes: 1 << 30, MaxRSSPercent: 80}, nil, "maxRssPercent"},
+		{"maxRssPercent with unmanaged", MemoryConfig{Mode: MemoryModeUnmanaged, MaxRSSPercent: 80}, nil, "maxRssPercent"},
+		{"minHeadroomBytes with unmanaged", MemoryConfig{Mode: MemoryModeUnmanaged, MinHeadroomBytes: 1 << 30}, nil, "minHeadroomBytes"},
+		{"custom switches to fixed", MemoryConfig{MaxRSSPercent: 80}, &MemoryConfig{Mode: MemoryModeFixed, FixedLimitBytes: 1 << 30}, "maxRssPercent"},
+		{"fixed without percent", MemoryConfig{Mode: MemoryModeFixed, FixedLimitBytes: 1 << 30}, nil, ""},
+		{"cgroup-aware with percent", MemoryConfig{Mode: MemoryModeCgroupAware, MaxRSSPercent: 80}, nil, ""},
+		{"custom headroom with fixed", MemoryConfig{Mode: MemoryModeFixed, FixedLimitBytes: 1 << 30}, &MemoryConfig{MinHeadroomBytes: 1 << 28}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			merged := MergeConfigs(
+				StaticLauncherConfig{ConfigVersion: 1, Executable: "app.pex", Memory: tt.static},
+				CustomLauncherConfig{Memory: tt.custom},
+			)
+			warnings := merged.MemoryWarnings
+			if tt.ignored == "" {
+				if len(warnings) != 0 {
+					t.Errorf("expected no warnings, got %v", warnings)
+				}
+				return
+			}
+			if len(warnings) != 1 {
+				t.Fatalf("expected one warning, got %v", warnings)
+			}
+			if !strings.Contains(warnings[0], "memory."+tt.ignored) {
+				t.Errorf("expected warning to name memory.%s, got %q", tt.ignored, warnings[0])
+			}
+		})
+	}
+}
+
+func TestDirsAcceptStringsAndObjects(t *testing.T) {
+	var config StaticLauncherConfig
+	err := yaml.Unmarshal([]byte(`
+dirs:
+  - var/log
+  - path: var/data/secrets
+    mode: "0700"
+    owner: app
+    group: app
+`), &config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []DirConfig{
+		{Path: "var/log"},
+		{Path: "var/data/secrets", Mode: "0700", Owner: "app", Group: "app"},
+	}
+	if len(config.Dirs) != len(want) {
+		t.Fatalf("expected %d dirs, got %+v", len(want), config.Dirs)
+	}
+	for i := range want {
+		if config.Dirs[i] != want[i] {
+			t.Errorf("dir %d: expected %+v, got %+v", i, want[i], config.Dirs[i])
+		}
+	}
+}
+
+func TestValidateStaticConfigDirMode(t *testing.T) {
+	config := StaticLauncherConfig{
+		ConfigVersion: 1,
+		Executable:    "service/bin/app.pex",
+		Dirs:          []DirConfig{{Path: "var/tmp", Mode: "0999"}},
+	}
+	if err := validateStaticConfig(config); err == nil {
+		t.Error("expected an error for a non-octal mode")
+	}
+	config.Dirs[0].Mode = "0750"
+	if err := validateStaticConfig(config); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestGetConfigsFromFilesErrors(t *testing.T) {
+	t.Run("static not found", func(t *testing.T) {
+		_, _, err := GetConfigsFromFiles(filepath.Join(t.TempDir(), "missing.yml"), "", &bytes.Buffer{})
+		if !errors.Is(err, ErrStaticConfigNotFound) {
+			t.Errorf("expected ErrStaticConfigNotFound, got %v", err)
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("expected the underlying not-exist error to be preserved, got %v", err)
+		}
+	})
+
+	t.Run("static parse", func(t *testing.T) {
+		staticPath, customPath := writeTestConfigs(t, "configVersion: [1\n", "")
+		_, _, err := GetConfigsFromFiles(staticPath, customPath, &bytes.Buffer{})
+		if !errors.Is(err, ErrConfigParse) {
+			t.Errorf("expected ErrConfigParse, got %v", err)
+		}
+	})
+
+	t.Run("custom parse", func(t *testing.T) {
+		staticPath, customPath := writeTestConfigs(t, testStaticYAML, "env: [not, a, map]\n")
+		_, _, err := GetConfigsFromFiles(staticPath, customPath, &bytes.Buffer{})
+		if !errors.Is(err, ErrConfigParse) {
+			t.Errorf("expected ErrConfigParse, got %v", err)
+		}
+	})
+
+	t.Run("validation", func(t *testing.T) {
+		staticPath, customPath := writeTestConfigs(t, testStaticYAML+"resources:\n  nice: 42\n", "")
+		_, _, err := GetConfigsFromFiles(staticPath, customPath, &bytes.Buffer{})
+		if !errors.Is(err, ErrConfigValidation) {
+			t.Errorf("expected ErrConfigValidation, got %v", err)
+		}
+		var validationErr *ConfigValidationError
+		if !errors.As(err, &validationErr) {
+			t.Fatalf("expected a *ConfigValidationError, got %T", err)
+		}
+		if validationErr.Field != "resources.nice" || validationErr.Value != "42" {
+			t.Errorf("expected field resources.nice with value 42, got %+v", validationErr)
+		}
+		if errors.Is(err, ErrConfigParse) || errors.Is(err, ErrStaticConfigNotFound) {
+			t.Errorf("validation error must not match other config errors: %v", err)
+		}
+	})
+
+	t.Run("too large", func(t *testing.T) {
+		staticPath, customPath := writeTestConfigs(t, testStaticYAML, "env:\n  PADDING: "+strings.Repeat("x", 4096)+"\n")
+		t.Setenv("LAUNCHER_MAX_CONFIG_BYTES", "1024")
+		_, _, err := GetConfigsFromFiles(staticPath, customPath, &bytes.Buffer{})
+		if !errors.Is(err, ErrConfigTooLarge) {
+			t.Errorf("expected ErrConfigTooLarge, got %v", err)
+		}
+
+		t.Setenv("LAUNCHER_MAX_CONFIG_BYTES", "8192")
+		if _, _, err := GetConfigsFromFiles(staticPath, customPath, &bytes.Buffer{}); err != nil {
+			t.Errorf("unexpected error with a raised limit: %v", err)
+		}
+	})
+
+	t.Run("not a regular file", func(t *testing.T) {
+		staticPath, customPath := writeTestConfigs(t, testStaticYAML, "")
+		// Reading a FIFO with no writer would block forever.
+		if err := syscall.Mkfifo(customPath, 0644); err != nil {
+			t.Fatal(err)
+		}
+		_, _, err := GetConfigsFromFiles(staticPath, customPath, &bytes.Buffer{})
+		if err == nil || !strings.Contains(err.Error(), "not a regular file") {
+			t.Errorf("expected a not a regular file error for a FIFO, got %v", err)
+		}
+
+		_, _, err = GetConfigsFromFiles(filepath.Dir(staticPath), customPath, &bytes.Buffer{})
+		if err == nil || !strings.Contains(err.Error(), "not a regular file") {
+			t.Errorf("expected a not a regular file error for a directory, got %v", err)
+		}
+	})
+}
+
+func TestValidateStaticConfigFieldDetails(t *testing.T) {
+	base := StaticLauncherConfig{ConfigVersion: 1, Executable: "service/bin/app.pex"}
+	tests := []struct {
+		name   string
+		modify func(*StaticLauncherConfig)
+		field  string
+		value  string
+	}{
+		{"configType", func(c *StaticLauncherConfig) { c.ConfigType = "java" }, "configType", "java"},
+		{"configVersion", func(c *StaticLauncherConfig) { c.ConfigVersion = 2 }, "configVersion", "2"},
+		{"executable", func(c *StaticLauncherConfig) { c.Executable = "" }, "executable", ""},
+		{"umask", func(c *StaticLauncherConfig) { c.Resources.Umask = "999" }, "resources.umask", "999"},
+		{"dir mode", func(c *StaticLauncherConfig) {
+			c.Dirs = []DirConfig{{Path: "var/log"}, {Path: "var/run", Mode: "rwx"}}
+		}, "dirs[1].mode", "rwx"},
+		{"envInherit glob", func(c *StaticLauncherConfig) {
+			c.EnvInherit = EnvInheritConfig{Policy: EnvInheritAllowlist, AllowlistPatterns: []string{"LC_["}}
+		}, "envInherit.allowlistPatterns[0]", "LC_["},
+		{"extra limit env var", func(c *StaticLauncherConfig) {
+			c.Memory.ExtraLimitEnvVars = []string{"OK", "RAY_memory:200"}
+		}, "memory.extraLimitEnvVars[1]", "RAY_memory:200"},
+		{"startup probe targets", func(c *StaticLauncherConfig) {
+			c.Startup = StartupProbeConfig{TCP: "127.0.0.1:8080", HTTP: "http://127.0.0.1:8080/"}
+		}, "startup", ""},
+		{"startup probe tcp", func(c *StaticLauncherConfig) {
+			c.Startup = StartupProbeConfig{TCP: "8080"}
+		}, "startup.tcp", "8080"},
+		{"warmup url", func(c *StaticLauncherConfig) {
+			c.Readiness.Warmup.URL = "localhost:8080/health"
+		}, "readiness.warmup.url", "localhost:8080/health"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := base
+			tt.modify(&config)
+			var validationErr *ConfigValidationError
+			if err := validateStaticConfig(config); !errors.As(err, &validationErr) {
+				t.Fatalf("expected a *ConfigValidationError, got %v", err)
+			}
+			if validationErr.Field != tt.field || validationErr.Value != tt.value {
+				t.Errorf("expected field %s value %q, got %+v", tt.field, tt.value, validationErr)
+			}
+		})
+	}
+}
+
+func TestPercentageFields(t *testing.T) {
+	staticYAML := testStaticYAML + `memory:
+  maxRssPercent: 0.6
+watchdog:
+  softLimitPercent: 0
+`
+	staticPath, customPath := writeTestConfigs(t, staticYAML, `
+watchdog:
+  hardLimitPercent: 0.75
+`)
+	static, custom, err := GetConfigsFromFiles(staticPath, customPath, &bytes.Buffer{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	merged := MergeConfigs(static, custom)
+	if merged.Memory.MaxRSSPercent != 60 {
+		t.Errorf("expected maxRssPercent 0.6 to become 60, got %v", merged.Memory.MaxRSSPercent)
+	}
+	if merged.Watchdog.SoftLimitPercent != 85 {
+		t.Errorf("expected softLimitPercent 0 to fall back to 85, got %v", merged.Watchdog.SoftLimitPercent)
+	}
+	if merged.Watchdog.HardLimitPercent != 75 {
+		t.Errorf("expected hardLimitPercent 0.75 to become 75, got %v", merged.Watchdog.HardLimitPercent)
+	}
+}
+
+func TestPercentageFieldsOutOfRange(t *testing.T) {
+	tests := []struct {
+		name       string
+		staticYAML string
+		customYAML string
+		field      string
+		value      string
+	}{
+		{"static maxRssPercent", testStaticYAML + "memory:\n  maxRssPercent: 750\n", "",
+			"memory.maxRssPercent", "750"},
+		{"static negative softLimitPercent", testStaticYAML + "watchdog:\n  softLimitPercent: -5\n", "",
+			"watchdog.softLimitPercent", "-5"},
+		{"custom hardLimitPercent", testStaticYAML, "watchdog:\n  hardLimitPercent: 150\n",
+			"watchdog.hardLimitPercent", "150"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			staticPath, customPath := writeTestConfigs(t, tt.staticYAML, tt.customYAML)
+			_, _, err := GetConfigsFromFiles(staticPath, customPath, &bytes.Buffer{})
+			var validationErr *ConfigValidationError
+			if !errors.As(err, &validationErr) {
+				t.Fatalf("expected a *ConfigValidationError, got %v", err)
+			}
+			if validationErr.Field != tt.field || validationErr.Value != tt.value {
+				t.Errorf("expected field %s value %q, got %+v", tt.field, tt.value, validationErr)
+			}
+		})
+	}
+}
+
+func TestCustomConfigHeader(t *testing.T) {
+	tests := []struct {
+		name       string
+		customYAML string
+		fetcher    ConfigFetcher
+		field      string
+		value      string
+	}{
+		{"future version", "configVersion: 2\n", nil, "configVersion", "2"},
+		{"wrong type", "configType: java\nconfigVersion: 1\n", nil, "configType", "java"},
+		{"remote future version", "configVersion: 1\n",
+			&fakeConfigFetcher{data: []byte("configVersion: 2\n")}, "configVersion", "2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			staticPath, customPath := writeTestConfigs(t, testStaticYAML, tt.customYAML)
+			_, _, err := GetConfigsFromFilesWithFetcher(staticPath, customPath, tt.fetcher, &bytes.Buffer{})
+			var validationErr *ConfigValidationError
+			if !errors.As(err, &validationErr) {
+				t.Fatalf("expected a *ConfigValidationError, got %v", err)
+			}
+			if validationErr.Field != tt.field || validationErr.Value != tt.value {
+				t.Errorf("expected field %s value %q, got %+v", tt.field, tt.value, validationErr)
+			}
+		})
+	}
+
+	staticPath, customPath := writeTestConfigs(t, testStaticYAML, "configType: python\nconfigVersion: 1\n")
+	if _, _, err := GetConfigsFromFiles(staticPath, customPath, &bytes.Buffer{}); err != nil {
+		t.Errorf("expected a matching header to be accepted, got %v", err)
+	}
+}
+
+func TestWatchdogPollInterval(t *testing.T) {
+	tests := []struct {
+		name   string
+		static WatchdogConfig
+		custom *WatchdogConfig
+		want   time.Duration
+	}{
+		{"default", WatchdogConfig{}, nil, 5 * time.Second},
+		{"seconds only", WatchdogConfig{PollIntervalSeconds: 2}, nil, 2 * time.Second},
+		{"millis take precedence", WatchdogConfig{PollIntervalSeconds: 2, PollIntervalMillis: 250}, nil, 250 * time.Millisecond},
+		{"custom millis", WatchdogConfig{PollIntervalSeconds: 2}, &WatchdogConfig{PollIntervalMillis: 100}, 100 * time.Millisecond},
+		{"custom seconds replace static millis", WatchdogConfig{PollIntervalMillis: 250}, &WatchdogConfig{PollIntervalSeconds: 3}, 3 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			static := StaticLauncherConfig{Watchdog: tt.static}
+			merged := MergeConfigs(static, CustomLauncherConfig{Watchdog: tt.custom})
+			if got := merged.Watchdog.PollInterval(); got != tt.want {
+				t.Errorf("expected poll interval %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestWatchdogPollIntervalFloor(t *testing.T) {
+	staticPath, customPath := writeTestConfigs(t, testStaticYAML, "watchdog:\n  pollIntervalMillis: 10\n")
+	_, _, err := GetConfigsFromFiles(staticPath, customPath, &bytes.Buffer{})
+	var validationErr *ConfigValidationError
+	if !errors.As(err, &validationErr) || validationErr.Field != "watchdog.pollIntervalMillis" {
+		t.Fatalf("expected watchdog.pollIntervalMillis to be rejected, got %v", err)
+	}
+
+	staticPath, customPath = writeTestConfigs(t, testStaticYAML+"watchdog:\n  pollIntervalMillis: 50\n", "")
+	if _, _, err := GetConfigsFromFiles(staticPath, customPath, &bytes.Buffer{}); err != nil {
+		t.Errorf("expected the minimum interval to be accepted, got %v", err)
+	}
+}
+
+func TestMergeConfigsMemoryModeEnv(t *testing.T) {
+	static := StaticLauncherConfig{Memory: MemoryConfig{Mode: MemoryModeFixed, FixedLimitBytes: 1 << 30}}
+	tests := []struct {
+		name        string
+		env         string
+		custom      CustomLauncherConfig
+		wantMode    MemoryMode
+		wantWarning string
+	}{
+		{"env wins over config", "unmanaged", CustomLauncherConfig{}, MemoryModeUnmanaged,
+			"LAUNCHER_MEMORY_MODE=unmanaged is overriding memory.mode (fixed from config)"},
+		{"env wins over custom config", "cgroup-aware",
+			CustomLauncherConfig{Memory: &MemoryConfig{Mode: MemoryModeUnmanaged}}, MemoryModeCgroupAware,
+			"is overriding memory.mode (unmanaged from config)"},
+		{"unknown mode ignored", "off", CustomLauncherConfig{}, MemoryModeFixed, "is not a memory mode"},
+		{"below dangerousDisableContainerSupport", "unmanaged",
+			CustomLauncherConfig{DangerousDisableContainerSupport: true}, MemoryModeFixed,
+			"ignored because dangerousDisableContainerSupport is set"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("LAUNCHER_MEMORY_MODE", tt.env)
+			merged := MergeConfigs(static, tt.custom)
+			if merged.Memory.Mode != tt.wantMode {
+				t.Errorf("expected memory mode %s, got %s", tt.wantMode, merged.Memory.Mode)
+			}
+			found := false
+			for _, warning := range merged.MemoryWarnings {
+				found = found || strings.Contains(warning, tt.wantWarning)
+			}
+			if !found {
+				t.Errorf("expected a warning containing %q, got %v", tt.wantWarning, merged.MemoryWarnings)
+			}
+		})
+	}
+}
+
+func TestFindStaticConfigPrecedence(t *testing.T) {
+	root := t.TempDir()
+	oldSystemDir := systemConfigDir
+	systemConfigDir = filepath.Join(root, "etc")
+	defer func() { systemConfigDir = oldSystemDir }()
+
+	defaultPath := filepath.Join(root, "dist", defaultStaticConfigPath)
+	workPath := filepath.Join(root, "work", "launcher-static.yml")
+	xdgPath := filepath.Join(root, "xdg", "python-service-launcher", "launcher-static.yml")
+	etcPath := filepath.Join(root, "etc", "python-service-launcher", "launcher-static.yml")
+	env := map[string]string{"XDG_CONFIG_HOME": filepath.Join(root, "xdg")}
+	candidates := StaticConfigSearchPaths(filepath.Join(root, "work"), func(k string) string { return env[k] })
+
+	if want := []string{workPath, xdgPath, etcPath}; !reflect.DeepEqual(candidates, want) {
+		t.Fatalf("expected search paths %v, got %v", want, candidates)
+	}
+
+	if path, found := FindStaticConfig(defaultPath, candidates); found || path != defaultPath {
+		t.Errorf("expected (%s, false) when nothing exists, got (%s, %v)", defaultPath, path, found)
+	}
+
+	// Create the candidates from lowest to highest precedence; each one must
+	// win over those created before it.
+	for _, path := range []string{etcPath, xdgPath, workPath, defaultPath} {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("configType: python\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if got, found := FindStaticConfig(defaultPath, candidates); !found || got != path {
+			t.Errorf("expected %s to be found, got (%s, %v)", path, got, found)
+		}
+	}
+}
+
+func TestStaticConfigSearchPathsHomeFallback(t *testing.T) {
+	env := map[string]string{"HOME": "/home/app"}
+	paths := StaticConfigSearchPaths("/work", func(k string) string { return env[k] })
+	want := "/home/app/.config/python-service-launcher/launcher-static.yml"
+	if len(paths) != 3 || paths[1] != want {
+		t.Errorf("expected %s as the second search path, got %v", want, paths)
+	}
+
+	paths = StaticConfigSearchPaths("/work", func(string) string { return "" })
+	if len(paths) != 2 {
+		t.Errorf("expected only the work dir and system paths without HOME, got %v", paths)
+	}
+}
+
+const testPythonVersionsYAML = `
+configType: python
+configVersion: 1
+executable: service/bin/app.pex
+pythonPath: /usr/bin/python3
+pythonVersions:
+  "3.11": $PYTHON_3_11_HOME/bin/python3
+  "3.12": /opt/python3.12/bin/python3
+`
+
+func TestPythonVersionSelection(t *testing.T) {
+	staticPath, customPath := writeTestConfigs(t, testPythonVersionsYAML, `pythonVersion: "3.12"`)
+	static, custom, err := GetConfigsFromFiles(staticPath, customPath, &bytes.Buffer{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	merged := MergeConfigs(static, custom)
+	if merged.PythonPath != "/opt/python3.12/bin/python3" {
+		t.Errorf("expected the 3.12 interpreter, got %s", merged.PythonPath)
+	}
+	if merged.PythonVersion != "3.12" {
+		t.Errorf("expected pythonVersion 3.12, got %q", merged.PythonVersion)
+	}
+
+	// Without a selection the static pythonPath is used.
+	merged = MergeConfigs(static, CustomLauncherConfig{})
+	if merged.PythonPath != "/usr/bin/python3" || merged.PythonVersion != "" {
+		t.Errorf("expected the static pythonPath, got %s (version %q)", merged.PythonPath, merged.PythonVersion)
+	}
+}
+
+func TestPythonVersionMissing(t *testing.T) {
+	tests := []struct {
+		name       string
+		staticYAML string
+		wantReason string
+	}{
+		{"unknown version", testPythonVersionsYAML, "available: 3.11, 3.12"},
+		{"no versions defined", testStaticYAML, "static config defines no pythonVersions"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			staticPath, customPath := writeTestConfigs(t, tt.staticYAML, `pythonVersion: "3.9"`)
+			_, _, err := GetConfigsFromFiles(staticPath, customPath, &bytes.Buffer{})
+			var validationErr *ConfigValidationError
+			if !errors.As(err, &validationErr) {
+				t.Fatalf("expected a *ConfigValidationError, got %v", err)
+			}
+			if validationErr.Field != "pythonVersion" || validationErr.Value != "3.9" {
+				t.Errorf("expected pythonVersion \"3.9\", got %s %q", validationErr.Field, validationErr.Value)
+			}
+			if !strings.Contains(validationErr.Reason, tt.wantReason) {
+				t.Errorf("expected reason to contain %q, got %q", tt.wantReason, validationErr.Reason)
+			}
+		})
+	}
+}
+
+func TestPythonVersionEnvExpansion(t *testing.T) {
+	t.Setenv("PYTHON_3_11_HOME", "/opt/python3.11")
+	staticPath, customPath := writeTestConfigs(t, testPythonVersionsYAML, `pythonVersion: "3.11"`)
+	static, custom, err := GetConfigsFromFiles(staticPath, customPath, &bytes.Buffer{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	args := BuildCommandArgs(MergeConfigs(static, custom))
+	if args[0] != "/opt/python3.11/bin/python3" {
+		t.Errorf("expected the expanded 3.11 interpreter, got %v", args)
+	}
+}
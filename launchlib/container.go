@@ -0,0 +1,133 @@
+package launchlib
+
+import (
+	"bufio"
+	"bytes"
+	"io/fs"
+	"os"
+	"strings"
+)
+
+// DefaultContainerIndicators are the environment variables whose presence
+// marks a container when StaticLauncherConfig.ContainerIndicators is unset.
+var DefaultContainerIndicators = []string{"CONTAINER", "KUBERNETES_SERVICE_HOST", "container"}
+
+// containerMarkerFiles are created at the filesystem root by container
+// runtimes (Docker and Podman respectively).
+var containerMarkerFiles = []string{".dockerenv", "run/.containerenv"}
+
+// containerCgroupMarkers appear in the cgroup paths of a containerized init
+// process under cgroup v1.
+var containerCgroupMarkers = []string{"docker", "kubepods", "containerd", "libpod", "lxc"}
+
+// containerFilesystem returns the FS to use for container detection.
+var containerFilesystem = func() fs.FS {
+	return os.DirFS("/")
+}
+
+// DetectContainer reports whether the launcher is running in a container.
+// Each indicator is an environment variable name, matched when set, or
+// NAME=value, matched when the variable has exactly that value; an entry may
+// hold several comma-separated indicators. Independently of the indicators,
+// runtime marker files and the cgroup membership of pid 1 are checked.
+func DetectContainer(indicators []string, lookupEnv func(string) (string, bool), filesystem fs.FS) bool {
+	for _, entry := range indicators {
+		for _, indicator := range strings.Split(entry, ",") {
+			if matchesContainerIndicator(strings.TrimSpace(indicator), lookupEnv) {
+				return true
+			}
+		}
+	}
+
+	for _, name := range containerMarkerFiles {
+		if _, err := fs.Stat(filesystem, name); err == nil {
+			return true
+		}
+	}
+
+	for _, cgroupPath := range pid1CgroupPaths(filesystem) {
+		for _, marker := range containerCgroupMarkers {
+			if strings.Contains(cgroupPath, marker) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// pid1CgroupPaths returns the cgroup paths listed in /proc/1/cgroup, or nil
+// if it cannot be read.
+func pid1CgroupPaths(filesystem fs.FS) []string {
+	data, err := fs.ReadFile(filesystem, "proc/1/cgroup")
+	if err != nil {
+		return nil
+	}
+	var paths []string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		// Format: hierarchy-ID:controller-list:cgroup-path
+		parts := strings.SplitN(scanner.Text(), ":", 3)
+		if len(parts) == 3 {
+			paths = append(paths, parts[2])
+		}
+	}
+	return paths
+}
+
+// Container runtime labels returned by detectRuntime.
+const (
+	RuntimeKubernetes = "kubernetes"
+	RuntimePodman     = "podman"
+	RuntimeDocker     = "docker"
+	RuntimeContainerd = "containerd"
+	RuntimeLXC        = "lxc"
+	RuntimeNone       = "none"
+)
+
+// runtimeCgroupMarkers maps cgroup path fragments of pid 1 to the runtime
+// they identify, most specific first: Kubernetes pods also carry the
+// docker or containerd fragment of the runtime beneath them.
+var runtimeCgroupMarkers = []struct{ marker, runtime string }{
+	{"kubepods", RuntimeKubernetes},
+	{"libpod", RuntimePodman},
+	{"docker", RuntimeDocker},
+	{"containerd", RuntimeContainerd},
+	{"lxc", RuntimeLXC},
+}
+
+// detectRuntime names the container runtime the launcher is running under,
+// or RuntimeNone on bare metal or a VM. It is informational only: memory and
+// CPU handling depend on DetectContainer, not on this label.
+func detectRuntime(lookupEnv func(string) (string, bool), filesystem fs.FS) string {
+	if _, ok := lookupEnv("KUBERNETES_SERVICE_HOST"); ok {
+		return RuntimeKubernetes
+	}
+	cgroupPaths := pid1CgroupPaths(filesystem)
+	for _, candidate := range runtimeCgroupMarkers {
+		for _, cgroupPath := range cgroupPaths {
+			if strings.Contains(cgroupPath, candidate.marker) {
+				return candidate.runtime
+			}
+		}
+	}
+	if _, err := fs.Stat(filesystem, "run/.containerenv"); err == nil {
+		return RuntimePodman
+	}
+	if _, err := fs.Stat(filesystem, ".dockerenv"); err == nil {
+		return RuntimeDocker
+	}
+	return RuntimeNone
+}
+
+// matchesContainerIndicator checks a single NAME or NAME=value indicator.
+func matchesContainerIndicator(indicator string, lookupEnv func(string) (string, bool)) bool {
+	if indicator == "" {
+		return false
+	}
+	name, want, hasValue := strings.Cut(indicator, "=")
+	value, ok := lookupEnv(name)
+	if !ok {
+		return false
+	}
+	return !hasValue || value == want
+}
@@ -0,0 +1,111 @@
+package launchlib
+
+import (
+	"io/fs"
+	"testing"
+	"testing/fstest"
+)
+
+func fakeEnv(vars map[string]string) func(string) (string, bool) {
+	return func(name string) (string, bool) {
+		value, ok := vars[name]
+		return value, ok
+	}
+}
+
+func TestDetectContainer(t *testing.T) {
+	empty := fstest.MapFS{}
+	tests := []struct {
+		name       string
+		indicators []string
+		env        map[string]string
+		filesystem fstest.MapFS
+		want       bool
+	}{
+		{"nothing", DefaultContainerIndicators, nil, empty, false},
+		{"CONTAINER", DefaultContainerIndicators, map[string]string{"CONTAINER": ""}, empty, true},
+		{"KUBERNETES_SERVICE_HOST", DefaultContainerIndicators, map[string]string{"KUBERNETES_SERVICE_HOST": "10.0.0.1"}, empty, true},
+		{"container env", DefaultContainerIndicators, map[string]string{"container": "podman"}, empty, true},
+		{"name=value match", []string{"container=docker"}, map[string]string{"container": "docker"}, empty, true},
+		{"name=value mismatch", []string{"container=docker"}, map[string]string{"container": "oci"}, empty, false},
+		{"comma-separated", []string{"FOO, BAR"}, map[string]string{"BAR": "1"}, empty, true},
+		{"custom list replaces defaults", []string{"IN_POD"}, map[string]string{"CONTAINER": "1"}, empty, false},
+		{"dockerenv", nil, nil, fstest.MapFS{".dockerenv": {}}, true},
+		{"containerenv", nil, nil, fstest.MapFS{"run/.containerenv": {}}, true},
+		{"kubepods cgroup", nil, nil, fstest.MapFS{
+			"proc/1/cgroup": {Data: []byte("4:memory:/kubepods/burstable/pod1234/abcd\n0::/\n")},
+		}, true},
+		{"docker cgroup", nil, nil, fstest.MapFS{
+			"proc/1/cgroup": {Data: []byte("1:cpu:/docker/0123456789ab\n")},
+		}, true},
+		{"host cgroup", nil, nil, fstest.MapFS{
+			"proc/1/cgroup": {Data: []byte("1:name=systemd:/init.scope\n0::/init.scope\n")},
+		}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := DetectContainer(tt.indicators, fakeEnv(tt.env), tt.filesystem)
+			if got != tt.want {
+				t.Errorf("expected %t, got %t", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestDetectRuntime(t *testing.T) {
+	empty := fstest.MapFS{}
+	tests := []struct {
+		name       string
+		env        map[string]string
+		filesystem fstest.MapFS
+		want       string
+	}{
+		{"bare metal", nil, fstest.MapFS{
+			"proc/1/cgroup": {Data: []byte("1:name=systemd:/init.scope\n0::/init.scope\n")},
+		}, RuntimeNone},
+		{"nothing readable", nil, empty, RuntimeNone},
+		{"kubernetes env", map[string]string{"KUBERNETES_SERVICE_HOST": "10.0.0.1"}, fstest.MapFS{".dockerenv": {}}, RuntimeKubernetes},
+		{"kubepods cgroup over docker", nil, fstest.MapFS{
+			"proc/1/cgroup": {Data: []byte("4:memory:/kubepods/burstable/pod1234/docker-abcd.scope\n")},
+		}, RuntimeKubernetes},
+		{"dockerenv", nil, fstest.MapFS{".dockerenv": {}}, RuntimeDocker},
+		{"docker cgroup", nil, fstest.MapFS{
+			"proc/1/cgroup": {Data: []byte("1:cpu:/docker/0123456789ab\n")},
+		}, RuntimeDocker},
+		{"containerenv", nil, fstest.MapFS{"run/.containerenv": {}}, RuntimePodman},
+		{"libpod cgroup", nil, fstest.MapFS{
+			"proc/1/cgroup": {Data: []byte("0::/machine.slice/libpod-0123456789ab.scope\n")},
+		}, RuntimePodman},
+		{"containerd cgroup", nil, fstest.MapFS{
+			"proc/1/cgroup": {Data: []byte("0::/system.slice/containerd-0123456789ab.scope\n")},
+		}, RuntimeContainerd},
+		{"lxc cgroup", nil, fstest.MapFS{
+			"proc/1/cgroup": {Data: []byte("0::/lxc/web01\n")},
+		}, RuntimeLXC},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := detectRuntime(fakeEnv(tt.env), tt.filesystem); got != tt.want {
+				t.Errorf("expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestMergeConfigsContainerDetection(t *testing.T) {
+	original := containerFilesystem
+	containerFilesystem = func() fs.FS { return fstest.MapFS{} }
+	defer func() { containerFilesystem = original }()
+
+	t.Setenv("IN_POD", "yes")
+
+	static := StaticLauncherConfig{ContainerIndicators: []string{"IN_POD"}}
+	if merged := MergeConfigs(static, CustomLauncherConfig{}); !merged.IsContainer {
+		t.Error("expected configured indicator to mark a container")
+	}
+
+	custom := CustomLauncherConfig{DangerousDisableContainerSupport: true}
+	if merged := MergeConfigs(static, custom); merged.IsContainer {
+		t.Error("expected dangerousDisableContainerSupport to force IsContainer false")
+	}
+}
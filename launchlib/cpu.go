@@ -12,13 +12,13 @@ import (
 
 const (
 	// cgroupV2CPUMaxPath is the cgroup v2 CPU quota file.
-	cgroupV2CPUMaxPath = "/sys/fs/cgroup/cpu.max"
+	cgroupV2CPUMaxPath = "cpu.max"
 
 	// cgroupV1CPUQuotaPath is the cgroup v1 CPU quota file.
-	cgroupV1CPUQuotaPath = "/sys/fs/cgroup/cpu/cpu.cfs_quota_us"
+	cgroupV1CPUQuotaPath = "cpu/cpu.cfs_quota_us"
 
 	// cgroupV1CPUPeriodPath is the cgroup v1 CPU period file.
-	cgroupV1CPUPeriodPath = "/sys/fs/cgroup/cpu/cpu.cfs_period_us"
+	cgroupV1CPUPeriodPath = "cpu/cpu.cfs_period_us"
 )
 
 // CPUConfig controls CPU detection and thread pool sizing.
@@ -37,8 +37,11 @@ func DefaultCPUConfig() CPUConfig {
 }
 
 // DetectCPUCount returns the effective number of CPUs available to the process.
-// It reads cgroup CPU quotas when available, otherwise falls back to runtime.NumCPU().
-func DetectCPUCount(config CPUConfig, filesystem fs.FS) int {
+// It reads cgroup CPU quotas under cgroupRoot (DefaultCgroupRoot if empty)
+// and caps the result at the size of the cgroup cpuset. Without a quota the
+// cpuset size is used on its own, so a process pinned to a few CPUs is not
+// sized for the whole host; with neither it falls back to runtime.NumCPU().
+func DetectCPUCount(config CPUConfig, cgroupRoot string, filesystem fs.FS) int {
 	if config.Override > 0 {
 		return config.Override
 	}
@@ -46,26 +49,45 @@ func DetectCPUCount(config CPUConfig, filesystem fs.FS) int {
 		return runtime.NumCPU()
 	}
 
+	count := detectQuotaCPUCount(filesystem, cgroupRoot)
+	cpus, err := readCgroupCpuset(filesystem, cgroupRoot)
+	if err != nil {
+		cpus = nil
+	}
+	switch {
+	case count == 0 && len(cpus) > 0:
+		count = len(cpus)
+	case count == 0:
+		count = runtime.NumCPU()
+	case len(cpus) > 0 && len(cpus) < count:
+		count = len(cpus)
+	}
+	return count
+}
+
+// detectQuotaCPUCount returns the CPU count from the cgroup CPU quota, or 0
+// when there is no quota.
+func detectQuotaCPUCount(filesystem fs.FS, cgroupRoot string) int {
 	// Try cgroup v2 cpu.max
-	count, err := readCgroupV2CPU(filesystem)
+	count, err := readCgroupV2CPU(filesystem, cgroupRoot)
 	if err == nil && count > 0 {
 		return count
 	}
 
 	// Try cgroup v1 cpu.cfs_quota_us / cpu.cfs_period_us
-	count, err = readCgroupV1CPU(filesystem)
+	count, err = readCgroupV1CPU(filesystem, cgroupRoot)
 	if err == nil && count > 0 {
 		return count
 	}
 
-	return runtime.NumCPU()
+	return 0
 }
 
 // readCgroupV2CPU reads the CPU count from cgroup v2 cpu.max.
 // Format: "$MAX $PERIOD" (e.g., "200000 100000" = 2 CPUs).
-// "max 100000" means unlimited.
-func readCgroupV2CPU(filesystem fs.FS) (int, error) {
-	data, err := fs.ReadFile(filesystem, relPath(cgroupV2CPUMaxPath))
+// "max 100000" means unlimited and returns 0.
+func readCgroupV2CPU(filesystem fs.FS, cgroupRoot string) (int, error) {
+	data, err := fs.ReadFile(filesystem, cgroupPath(cgroupRoot, cgroupV2CPUMaxPath))
 	if err != nil {
 		return 0, err
 	}
@@ -75,7 +97,7 @@ func readCgroupV2CPU(filesystem fs.FS) (int, error) {
 		return 0, fmt.Errorf("unexpected cpu.max format: %q", content)
 	}
 	if fields[0] == "max" {
-		return runtime.NumCPU(), nil // unlimited
+		return 0, nil // unlimited
 	}
 	quota, err := strconv.ParseFloat(fields[0], 64)
 	if err != nil {
@@ -86,7 +108,7 @@ func readCgroupV2CPU(filesystem fs.FS) (int, error) {
 		return 0, fmt.Errorf("failed to parse cpu.max period: %w", err)
 	}
 	if period == 0 {
-		return runtime.NumCPU(), nil
+		return 0, nil
 	}
 	count := int(math.Ceil(quota / period))
 	if count < 1 {
@@ -95,9 +117,10 @@ func readCgroupV2CPU(filesystem fs.FS) (int, error) {
 	return count, nil
 }
 
-// readCgroupV1CPU reads CPU count from cgroup v1 quota/period files.
-func readCgroupV1CPU(filesystem fs.FS) (int, error) {
-	quotaData, err := fs.ReadFile(filesystem, relPath(cgroupV1CPUQuotaPath))
+// readCgroupV1CPU reads CPU count from cgroup v1 quota/period files, or 0
+// when the quota is unlimited.
+func readCgroupV1CPU(filesystem fs.FS, cgroupRoot string) (int, error) {
+	quotaData, err := fs.ReadFile(filesystem, cgroupPath(cgroupRoot, cgroupV1CPUQuotaPath))
 	if err != nil {
 		return 0, err
 	}
@@ -107,10 +130,10 @@ func readCgroupV1CPU(filesystem fs.FS) (int, error) {
 	}
 	// -1 means unlimited
 	if quota < 0 {
-		return runtime.NumCPU(), nil
+		return 0, nil
 	}
 
-	periodData, err := fs.ReadFile(filesystem, relPath(cgroupV1CPUPeriodPath))
+	periodData, err := fs.ReadFile(filesystem, cgroupPath(cgroupRoot, cgroupV1CPUPeriodPath))
 	if err != nil {
 		return 0, err
 	}
@@ -119,7 +142,7 @@ func readCgroupV1CPU(filesystem fs.FS) (int, error) {
 		return 0, err
 	}
 	if period == 0 {
-		return runtime.NumCPU(), nil
+		return 0, nil
 	}
 
 	count := int(math.Ceil(quota / period))
@@ -135,12 +158,86 @@ func BuildCPUEnv(cpuCount int) map[string]string {
 	return map[string]string{
 		"OMP_NUM_THREADS":      s,
 		"MKL_NUM_THREADS":      s,
-		"OPENBLAS_NUM_THREADS":  s,
+		"OPENBLAS_NUM_THREADS": s,
 		"NUMEXPR_MAX_THREADS":  s,
-		"SERVICE_CPU_COUNT":     s,
+		"SERVICE_CPU_COUNT":    s,
 	}
 }
 
+// overlayCPUEnv sets the BuildCPUEnv variables for cpuCount in env, the
+// process environment built from config. They replace the runtime.NumCPU()
+// thread defaults of BuildMemoryEnv; only a value from config env, or one
+// inherited from the launcher's environment, is kept.
+func overlayCPUEnv(env []string, config MergedConfig, cpuCount int) []string {
+	cpuEnv := BuildCPUEnv(cpuCount)
+	for name := range cpuEnv {
+		if _, ok := config.Env[name]; ok {
+			delete(cpuEnv, name)
+		} else if value, ok := os.LookupEnv(name); ok && inheritsEnvVar(config.EnvInherit, name) {
+			cpuEnv[name] = value
+		}
+	}
+	result := make([]string, 0, len(env)+len(cpuEnv))
+	for _, entry := range env {
+		name, _, _ := strings.Cut(entry, "=")
+		if _, ok := cpuEnv[name]; !ok {
+			result = append(result, entry)
+		}
+	}
+	for name, value := range cpuEnv {
+		result = append(result, name+"="+value)
+	}
+	return result
+}
+
+// threadEnvVars lists the thread-pool variables that native libraries consult
+// when sizing their worker pools.
+var threadEnvVars = []string{
+	"OMP_NUM_THREADS",
+	"MKL_NUM_THREADS",
+	"OPENBLAS_NUM_THREADS",
+	"NUMEXPR_MAX_THREADS",
+}
+
+// threadOversubscriptionFactor is the ratio of requested threads to effective
+// CPUs at which CheckThreadOversubscription starts warning.
+const threadOversubscriptionFactor = 2
+
+// CheckThreadOversubscription compares the thread-pool variables in the final
+// process environment against the effective CPU count. It returns one warning
+// per variable that requests at least threadOversubscriptionFactor times as many
+// threads as there are CPUs, which typically causes heavy contention.
+func CheckThreadOversubscription(env []string, cpuCount int) []string {
+	if cpuCount < 1 {
+		return nil
+	}
+	values := make(map[string]string)
+	for _, e := range env {
+		parts := strings.SplitN(e, "=", 2)
+		if len(parts) == 2 {
+			values[parts[0]] = parts[1]
+		}
+	}
+
+	var warnings []string
+	for _, key := range threadEnvVars {
+		value, ok := values[key]
+		if !ok {
+			continue
+		}
+		threads, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil || threads < 1 {
+			continue
+		}
+		if threads >= cpuCount*threadOversubscriptionFactor {
+			warnings = append(warnings, fmt.Sprintf(
+				"%s=%d requests more threads than the %d effective CPU(s) available; expect contention",
+				key, threads, cpuCount))
+		}
+	}
+	return warnings
+}
+
 // cpuFilesystem returns the FS to use for CPU detection.
 func cpuFilesystem() fs.FS {
 	return os.DirFS("/")
@@ -2,12 +2,13 @@ package launchlib
 
 import (
 	"runtime"
+	"strings"
 	"testing"
 )
 
 func TestDetectCPUCountOverride(t *testing.T) {
 	config := CPUConfig{Override: 4}
-	count := DetectCPUCount(config, testFS(map[string]string{}))
+	count := DetectCPUCount(config, "", testFS(map[string]string{}))
 	if count != 4 {
 		t.Errorf("expected 4 from override, got %d", count)
 	}
@@ -16,7 +17,7 @@ func TestDetectCPUCountOverride(t *testing.T) {
 func TestDetectCPUCountAutoDetectFallback(t *testing.T) {
 	config := CPUConfig{AutoDetect: true}
 	// No cgroup files => falls back to runtime.NumCPU()
-	count := DetectCPUCount(config, testFS(map[string]string{}))
+	count := DetectCPUCount(config, "", testFS(map[string]string{}))
 	if count != runtime.NumCPU() {
 		t.Errorf("expected runtime.NumCPU() = %d, got %d", runtime.NumCPU(), count)
 	}
@@ -24,7 +25,7 @@ func TestDetectCPUCountAutoDetectFallback(t *testing.T) {
 
 func TestDetectCPUCountDisabled(t *testing.T) {
 	config := CPUConfig{AutoDetect: false}
-	count := DetectCPUCount(config, testFS(map[string]string{}))
+	count := DetectCPUCount(config, "", testFS(map[string]string{}))
 	if count != runtime.NumCPU() {
 		t.Errorf("expected runtime.NumCPU() = %d, got %d", runtime.NumCPU(), count)
 	}
@@ -36,7 +37,7 @@ func TestDetectCPUCountCgroupV2(t *testing.T) {
 		"sys/fs/cgroup/cpu.max": "200000 100000\n",
 	})
 	config := CPUConfig{AutoDetect: true}
-	count := DetectCPUCount(config, fs)
+	count := DetectCPUCount(config, "", fs)
 	if count != 2 {
 		t.Errorf("expected 2 CPUs from cgroup v2, got %d", count)
 	}
@@ -47,7 +48,7 @@ func TestDetectCPUCountCgroupV2Unlimited(t *testing.T) {
 		"sys/fs/cgroup/cpu.max": "max 100000\n",
 	})
 	config := CPUConfig{AutoDetect: true}
-	count := DetectCPUCount(config, fs)
+	count := DetectCPUCount(config, "", fs)
 	if count != runtime.NumCPU() {
 		t.Errorf("expected runtime.NumCPU() for unlimited, got %d", count)
 	}
@@ -59,7 +60,7 @@ func TestDetectCPUCountCgroupV1(t *testing.T) {
 		"sys/fs/cgroup/cpu/cpu.cfs_period_us": "100000\n",
 	})
 	config := CPUConfig{AutoDetect: true}
-	count := DetectCPUCount(config, fs)
+	count := DetectCPUCount(config, "", fs)
 	if count != 3 {
 		t.Errorf("expected 3 CPUs from cgroup v1, got %d", count)
 	}
@@ -71,7 +72,7 @@ func TestDetectCPUCountCgroupV1Unlimited(t *testing.T) {
 		"sys/fs/cgroup/cpu/cpu.cfs_period_us": "100000\n",
 	})
 	config := CPUConfig{AutoDetect: true}
-	count := DetectCPUCount(config, fs)
+	count := DetectCPUCount(config, "", fs)
 	if count != runtime.NumCPU() {
 		t.Errorf("expected runtime.NumCPU() for unlimited, got %d", count)
 	}
@@ -86,3 +87,75 @@ func TestBuildCPUEnv(t *testing.T) {
 		t.Errorf("expected SERVICE_CPU_COUNT=4, got %s", env["SERVICE_CPU_COUNT"])
 	}
 }
+
+func TestOverlayCPUEnv(t *testing.T) {
+	t.Setenv("OPENBLAS_NUM_THREADS", "3")
+	config := MergedConfig{Env: map[string]string{"MKL_NUM_THREADS": "8"}}
+	// Memory env thread defaults sized for the host, with config env on top.
+	env := []string{"OMP_NUM_THREADS=64", "MKL_NUM_THREADS=8", "OPENBLAS_NUM_THREADS=64", "NUMEXPR_MAX_THREADS=64", "FOO=bar"}
+
+	got := envToMap(overlayCPUEnv(env, config, 2))
+	want := map[string]string{
+		"OMP_NUM_THREADS":      "2",
+		"MKL_NUM_THREADS":      "8",
+		"OPENBLAS_NUM_THREADS": "3",
+		"NUMEXPR_MAX_THREADS":  "2",
+		"SERVICE_CPU_COUNT":    "2",
+		"FOO":                  "bar",
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("%s = %q, want %q", k, got[k], v)
+		}
+	}
+	if len(got) != len(want) {
+		t.Errorf("expected one entry per variable, got %v", got)
+	}
+}
+
+func TestCheckThreadOversubscription(t *testing.T) {
+	env := []string{
+		"OMP_NUM_THREADS=8",
+		"MKL_NUM_THREADS=1",
+		"PATH=/usr/bin",
+	}
+	warnings := CheckThreadOversubscription(env, 1)
+	if len(warnings) != 1 {
+		t.Fatalf("expected 1 warning, got %d: %v", len(warnings), warnings)
+	}
+	if !strings.Contains(warnings[0], "OMP_NUM_THREADS=8") {
+		t.Errorf("expected warning to name OMP_NUM_THREADS, got %q", warnings[0])
+	}
+}
+
+func TestCheckThreadOversubscriptionWithinBudget(t *testing.T) {
+	env := []string{
+		"OMP_NUM_THREADS=4",
+		"OPENBLAS_NUM_THREADS=7",
+	}
+	if warnings := CheckThreadOversubscription(env, 4); len(warnings) != 0 {
+		t.Errorf("expected no warnings, got %v", warnings)
+	}
+}
+
+func TestCheckThreadOversubscriptionIgnoresInvalid(t *testing.T) {
+	env := []string{"OMP_NUM_THREADS=auto", "MKL_NUM_THREADS="}
+	if warnings := CheckThreadOversubscription(env, 1); len(warnings) != 0 {
+		t.Errorf("expected no warnings for unparseable values, got %v", warnings)
+	}
+}
+
+func TestDetectCPUCountCgroupRoot(t *testing.T) {
+	fs := testFS(map[string]string{
+		"sys/fs/cgroup/cpu.max":                "200000 100000\n",
+		"sandbox/cgroup/cpu.max":               "300000 100000\n",
+		"sandbox/cgroup/cpuset.cpus.effective": "0\n",
+	})
+	config := CPUConfig{AutoDetect: true}
+	if count := DetectCPUCount(config, "/sandbox/cgroup", fs); count != 1 {
+		t.Errorf("expected 1 CPU from the /sandbox/cgroup cpuset, got %d", count)
+	}
+	if count := DetectCPUCount(config, "", fs); count != 2 {
+		t.Errorf("expected 2 CPUs from the default root, got %d", count)
+	}
+}
@@ -0,0 +1,79 @@
+package launchlib
+
+import (
+	"fmt"
+	"io/fs"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// cgroupV2CpusetPath lists the CPUs the cgroup may run on (cgroup v2).
+const cgroupV2CpusetPath = "cpuset.cpus.effective"
+
+// cgroupV1CpusetPaths list the CPUs the cgroup may run on (cgroup v1), the
+// effective set first.
+var cgroupV1CpusetPaths = []string{
+	"cpuset/cpuset.effective_cpus",
+	"cpuset/cpuset.cpus",
+}
+
+// readCgroupCpuset returns the CPUs allowed by the cgroup cpuset under
+// cgroupRoot (DefaultCgroupRoot if empty), trying cgroup v2 before v1. The
+// error is the cgroup v2 one when neither is readable.
+func readCgroupCpuset(filesystem fs.FS, cgroupRoot string) ([]int, error) {
+	data, err := fs.ReadFile(filesystem, cgroupPath(cgroupRoot, cgroupV2CpusetPath))
+	if err == nil {
+		return parseCPUList(string(data))
+	}
+	for _, path := range cgroupV1CpusetPaths {
+		if v1Data, v1Err := fs.ReadFile(filesystem, cgroupPath(cgroupRoot, path)); v1Err == nil {
+			return parseCPUList(string(v1Data))
+		}
+	}
+	return nil, err
+}
+
+// parseCPUList parses a kernel CPU list such as "0-1,4" into CPU numbers, in
+// the order listed.
+func parseCPUList(list string) ([]int, error) {
+	list = strings.TrimSpace(list)
+	if list == "" {
+		return nil, nil
+	}
+	var cpus []int
+	for _, part := range strings.Split(list, ",") {
+		first, last, isRange := strings.Cut(part, "-")
+		start, err := strconv.Atoi(first)
+		if err != nil || start < 0 {
+			return nil, fmt.Errorf("invalid CPU list %q", list)
+		}
+		end := start
+		if isRange {
+			end, err = strconv.Atoi(last)
+			if err != nil || end < start {
+				return nil, fmt.Errorf("invalid CPU list %q", list)
+			}
+		}
+		for cpu := start; cpu <= end; cpu++ {
+			cpus = append(cpus, cpu)
+		}
+	}
+	return cpus, nil
+}
+
+// startPinned runs start, which forks or execs, from an OS thread restricted
+// to cpus so the new process inherits that affinity. Affinity is per thread,
+// hence the thread lock; the thread keeps the mask afterwards, which is
+// harmless because the cgroup already confines the launcher to the same CPUs.
+// With no cpus, start runs unchanged.
+func (l *Launcher) startPinned(cpus []int, start func() error) error {
+	if len(cpus) > 0 {
+		runtime.LockOSThread()
+		defer runtime.UnlockOSThread()
+		if err := setCPUAffinity(cpus); err != nil {
+			l.logger.Warnf("Failed to pin to cpuset: %v (continuing unpinned)", err)
+		}
+	}
+	return start()
+}
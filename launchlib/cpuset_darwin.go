@@ -0,0 +1,8 @@
+package launchlib
+
+import "errors"
+
+// setCPUAffinity is Linux-only.
+func setCPUAffinity([]int) error {
+	return errors.New("CPU affinity is not supported on darwin")
+}
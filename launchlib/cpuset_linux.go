@@ -0,0 +1,27 @@
+package launchlib
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+// setCPUAffinity restricts the calling process to cpus with
+// sched_setaffinity. Children started afterwards inherit the mask.
+func setCPUAffinity(cpus []int) error {
+	maxCPU := 0
+	for _, cpu := range cpus {
+		if cpu > maxCPU {
+			maxCPU = cpu
+		}
+	}
+	mask := make([]uint64, maxCPU/64+1)
+	for _, cpu := range cpus {
+		mask[cpu/64] |= 1 << (uint(cpu) % 64)
+	}
+	_, _, errno := syscall.RawSyscall(syscall.SYS_SCHED_SETAFFINITY, 0,
+		uintptr(len(mask)*8), uintptr(unsafe.Pointer(&mask[0])))
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package launchlib
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"reflect"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestParseCPUList(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    []int
+		wantErr bool
+	}{
+		{"0-1,4", []int{0, 1, 4}, false},
+		{"3\n", []int{3}, false},
+		{"0-3", []int{0, 1, 2, 3}, false},
+		{"", nil, false},
+		{"2-1", nil, true},
+		{"a-b", nil, true},
+		{"0,,1", nil, true},
+	}
+	for _, tt := range tests {
+		got, err := parseCPUList(tt.input)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseCPUList(%q): expected error %v, got %v", tt.input, tt.wantErr, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseCPUList(%q): expected %v, got %v", tt.input, tt.want, got)
+		}
+	}
+}
+
+func TestDetectCPUCountCpuset(t *testing.T) {
+	tests := []struct {
+		name   string
+		cpuMax string
+		cpuset string
+		want   int
+	}{
+		{"cpuset smaller than quota", "800000 100000", "0-1,4", 3},
+		{"quota smaller than cpuset", "200000 100000", "0-7", 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := testFS(map[string]string{
+				"sys/fs/cgroup/cpu.max":               tt.cpuMax + "\n",
+				"sys/fs/cgroup/cpuset.cpus.effective": tt.cpuset + "\n",
+			})
+			if got := DetectCPUCount(CPUConfig{AutoDetect: true}, "", fs); got != tt.want {
+				t.Errorf("expected %d CPUs, got %d", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestDetectCPUCountCpusetWithoutQuota(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+	}{
+		{"v2 unlimited cpu.max", map[string]string{
+			"sys/fs/cgroup/cpu.max":               "max 100000\n",
+			"sys/fs/cgroup/cpuset.cpus.effective": "2-3\n",
+		}},
+		{"v2 no cpu.max", map[string]string{
+			"sys/fs/cgroup/cpuset.cpus.effective": "0,5\n",
+		}},
+		{"v1 unlimited quota", map[string]string{
+			"sys/fs/cgroup/cpu/cpu.cfs_quota_us":         "-1\n",
+			"sys/fs/cgroup/cpu/cpu.cfs_period_us":        "100000\n",
+			"sys/fs/cgroup/cpuset/cpuset.effective_cpus": "6-7\n",
+			"sys/fs/cgroup/cpuset/cpuset.cpus":           "0-7\n",
+		}},
+		{"v1 cpuset.cpus only", map[string]string{
+			"sys/fs/cgroup/cpuset/cpuset.cpus": "0-1\n",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DetectCPUCount(CPUConfig{AutoDetect: true}, "", testFS(tt.files)); got != 2 {
+				t.Errorf("expected the 2 CPU cpuset, got %d", got)
+			}
+		})
+	}
+}
+
+func TestStartPinnedSetsChildAffinity(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("CPU affinity is Linux-only")
+	}
+	var out bytes.Buffer
+	l := &Launcher{logger: NewLogger(&out, LoggingConfig{})}
+	cmd := exec.Command("sleep", "30")
+	if err := l.startPinned([]int{0}, cmd.Start); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+	}()
+	if out.Len() > 0 {
+		t.Fatalf("unexpected warning: %s", out.String())
+	}
+
+	status, err := os.ReadFile("/proc/" + strconv.Itoa(cmd.Process.Pid) + "/status")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range strings.Split(string(status), "\n") {
+		if value, ok := strings.CutPrefix(line, "Cpus_allowed_list:"); ok {
+			if got := strings.TrimSpace(value); got != "0" {
+				t.Errorf("expected the child pinned to CPU 0, got %q", got)
+			}
+			return
+		}
+	}
+	t.Error("expected Cpus_allowed_list in /proc status")
+}
@@ -0,0 +1,61 @@
+package launchlib
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// limitsDescriptorEnv points the child at the limits descriptor file, e.g. for
+// a sitecustomize.py that tunes the interpreter from it.
+const limitsDescriptorEnv = "LAUNCHER_LIMITS_FILE"
+
+// LimitsDescriptor collects the launcher-derived resource facts in one JSON
+// object for the Python side.
+type LimitsDescriptor struct {
+	CPUCount            int    `json:"cpuCount"`
+	EffectiveLimitBytes uint64 `json:"memoryEffectiveBytes"`
+	CgroupLimitBytes    uint64 `json:"memoryCgroupBytes"`
+	SoftWarnBytes       uint64 `json:"memorySoftWarnBytes"`
+	HardKillBytes       uint64 `json:"memoryHardKillBytes"`
+	IsContainer         bool   `json:"isContainer"`
+	CgroupVersion       int    `json:"cgroupVersion"`
+}
+
+// NewLimitsDescriptor builds the descriptor for config and limits.
+func NewLimitsDescriptor(config MergedConfig, limits MemoryLimits) LimitsDescriptor {
+	return LimitsDescriptor{
+		CPUCount:            config.EffectiveCPUCount,
+		EffectiveLimitBytes: limits.EffectiveLimitBytes,
+		CgroupLimitBytes:    limits.CgroupLimitBytes,
+		SoftWarnBytes:       limits.SoftWarnBytes,
+		HardKillBytes:       limits.HardKillBytes,
+		IsContainer:         config.IsContainer,
+		CgroupVersion:       limits.CgroupVersion,
+	}
+}
+
+// WriteLimitsDescriptor writes descriptor as JSON to path, replacing any
+// previous file.
+func WriteLimitsDescriptor(path string, descriptor LimitsDescriptor) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(descriptor, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// appendLimitsDescriptorEnv points limitsDescriptorEnv at path unless env
+// already sets it.
+func appendLimitsDescriptorEnv(env []string, path string) []string {
+	for _, e := range env {
+		if strings.SplitN(e, "=", 2)[0] == limitsDescriptorEnv {
+			return env
+		}
+	}
+	return append(env, limitsDescriptorEnv+"="+path)
+}
@@ -0,0 +1,49 @@
+package launchlib
+
+import (
+	"io/fs"
+	"os"
+)
+
+// CapacityReport is what the launcher detects about the machine it runs on,
+// independent of any config. It is printed by --detect.
+type CapacityReport struct {
+	// CPUCount is the effective CPU count from cgroup quotas and the cpuset,
+	// or runtime.NumCPU() when there are none.
+	CPUCount int `json:"cpuCount"`
+
+	// MemoryCgroupBytes is the cgroup memory limit, or 0 when the cgroup sets
+	// no limit or cannot be read.
+	MemoryCgroupBytes uint64 `json:"memoryCgroupBytes"`
+
+	// CgroupVersion is 1 or 2, or 0 when no cgroup memory controller is found.
+	CgroupVersion int `json:"cgroupVersion"`
+
+	// IsContainer reports whether a default container indicator matched.
+	IsContainer bool `json:"isContainer"`
+}
+
+// DetectCapacity runs CPU, cgroup memory and container detection against the
+// real filesystem and environment, using the defaults a launch without any
+// config would use.
+func DetectCapacity() CapacityReport {
+	return detectCapacity(os.DirFS("/"), os.LookupEnv)
+}
+
+func detectCapacity(filesystem fs.FS, lookupEnv func(string) (string, bool)) CapacityReport {
+	report := CapacityReport{
+		CPUCount:    DetectCPUCount(DefaultCPUConfig(), "", filesystem),
+		IsContainer: DetectContainer(DefaultContainerIndicators, lookupEnv, filesystem),
+	}
+
+	limiter := NewMemoryLimiterWithFS(filesystem)
+	version, err := limiter.detectCgroupVersion()
+	if err != nil {
+		return report
+	}
+	report.CgroupVersion = version
+	if limit, source, err := limiter.readCgroupMemoryLimit(version); err == nil && source != LimitSourceSystemMeminfo {
+		report.MemoryCgroupBytes = limit
+	}
+	return report
+}
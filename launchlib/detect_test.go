@@ -0,0 +1,59 @@
+package launchlib
+
+import (
+	"runtime"
+	"testing"
+)
+
+func TestDetectCapacity(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		env   map[string]string
+		want  CapacityReport
+	}{
+		{
+			name: "cgroup v2 container",
+			files: map[string]string{
+				"sys/fs/cgroup/cgroup.controllers": "cpu memory io",
+				"sys/fs/cgroup/memory.max":         "1073741824\n",
+				"sys/fs/cgroup/cpu.max":            "200000 100000\n",
+			},
+			env:  map[string]string{"KUBERNETES_SERVICE_HOST": "10.0.0.1"},
+			want: CapacityReport{CPUCount: 2, MemoryCgroupBytes: 1073741824, CgroupVersion: 2, IsContainer: true},
+		},
+		{
+			name: "cgroup v2 without a memory limit",
+			files: map[string]string{
+				"proc/meminfo":                     "MemTotal:       16384000 kB\n",
+				"sys/fs/cgroup/cgroup.controllers": "cpu memory io",
+				"sys/fs/cgroup/memory.max":         "max\n",
+				"sys/fs/cgroup/cpu.max":            "max 100000\n",
+			},
+			want: CapacityReport{CPUCount: runtime.NumCPU(), CgroupVersion: 2},
+		},
+		{
+			name: "cgroup v1",
+			files: map[string]string{
+				".dockerenv": "",
+				"sys/fs/cgroup/memory/memory.limit_in_bytes": "536870912\n",
+				"sys/fs/cgroup/cpu/cpu.cfs_quota_us":         "100000\n",
+				"sys/fs/cgroup/cpu/cpu.cfs_period_us":        "100000\n",
+			},
+			want: CapacityReport{CPUCount: 1, MemoryCgroupBytes: 536870912, CgroupVersion: 1, IsContainer: true},
+		},
+		{
+			name:  "no cgroup",
+			files: map[string]string{},
+			want:  CapacityReport{CPUCount: runtime.NumCPU()},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := detectCapacity(testFS(tt.files), fakeEnv(tt.env))
+			if got != tt.want {
+				t.Errorf("expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
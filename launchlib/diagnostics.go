@@ -0,0 +1,176 @@
+package launchlib
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Diagnostic defaults.
+const (
+	defaultDiagnosticSignal = "SIGUSR2"
+	defaultDiagnosticDir    = "var/log"
+)
+
+// Environment variables telling the child how diagnostics are requested, so it
+// can register its own dump handler (e.g. faulthandler.register).
+const (
+	diagnosticSignalEnv = "DIAGNOSTIC_SIGNAL"
+	diagnosticDirEnv    = "DIAGNOSTIC_DIR"
+)
+
+// signalsByName maps the signals accepted in config to their values.
+var signalsByName = map[string]syscall.Signal{
+	"SIGHUP":  syscall.SIGHUP,
+	"SIGINT":  syscall.SIGINT,
+	"SIGQUIT": syscall.SIGQUIT,
+	"SIGKILL": syscall.SIGKILL,
+	"SIGUSR1": syscall.SIGUSR1,
+	"SIGUSR2": syscall.SIGUSR2,
+	"SIGTERM": syscall.SIGTERM,
+}
+
+// ParseSignal returns the signal for a name such as "SIGUSR2" or "USR2".
+func ParseSignal(name string) (syscall.Signal, error) {
+	upper := strings.ToUpper(strings.TrimSpace(name))
+	if !strings.HasPrefix(upper, "SIG") {
+		upper = "SIG" + upper
+	}
+	sig, ok := signalsByName[upper]
+	if !ok {
+		return 0, fmt.Errorf("unsupported signal %q", name)
+	}
+	return sig, nil
+}
+
+// signalName returns the SIG-prefixed name of sig, e.g. "SIGQUIT".
+func signalName(sig syscall.Signal) string {
+	for name, s := range signalsByName {
+		if s == sig {
+			return name
+		}
+	}
+	return sig.String()
+}
+
+// DiagnosticsConfig controls the on-demand diagnostic dump trigger.
+type DiagnosticsConfig struct {
+	// Enabled installs a handler for Signal. On receipt the launcher writes a
+	// snapshot of its own state to Dir, then forwards the signal to the child.
+	Enabled bool `yaml:"enabled,omitempty"`
+
+	// Signal triggers a dump. Default: "SIGUSR2".
+	Signal string `yaml:"signal,omitempty"`
+
+	// Dir is where snapshots are written, relative to the dist root.
+	// Default: "var/log".
+	Dir string `yaml:"dir,omitempty"`
+}
+
+// DiagnosticSnapshot is the launcher-side state written when a diagnostic
+// dump is requested.
+type DiagnosticSnapshot struct {
+	Time          time.Time `json:"time"`
+	Pid           int       `json:"pid"`
+	RSSBytes      uint64    `json:"rssBytes"`
+	PeakRSSBytes  uint64    `json:"peakRssBytes"`
+	WatchdogState string    `json:"watchdogState,omitempty"`
+	Goroutines    int       `json:"goroutines"`
+	Limits        DebugInfo `json:"limits"`
+}
+
+// signalName returns the configured signal name, applying the default.
+func (c DiagnosticsConfig) signalName() string {
+	if c.Signal == "" {
+		return defaultDiagnosticSignal
+	}
+	return c.Signal
+}
+
+// diagnosticSignal returns the configured signal.
+func diagnosticSignal(config DiagnosticsConfig) (syscall.Signal, error) {
+	return ParseSignal(config.signalName())
+}
+
+// appendDiagnosticEnv advertises the diagnostic signal and snapshot directory
+// to the process, unless the config env already sets them.
+func appendDiagnosticEnv(env []string, config DiagnosticsConfig, dir string) []string {
+	set := make(map[string]bool, len(env))
+	for _, e := range env {
+		set[strings.SplitN(e, "=", 2)[0]] = true
+	}
+	if !set[diagnosticSignalEnv] {
+		env = append(env, diagnosticSignalEnv+"="+config.signalName())
+	}
+	if !set[diagnosticDirEnv] {
+		env = append(env, diagnosticDirEnv+"="+dir)
+	}
+	return env
+}
+
+// WriteDiagnosticSnapshot writes snapshot as JSON to a timestamped file in dir
+// and returns its path.
+func WriteDiagnosticSnapshot(dir string, snapshot DiagnosticSnapshot) (string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	data, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	name := fmt.Sprintf("launcher-diagnostic-%s.json", snapshot.Time.UTC().Format("20060102T150405.000Z"))
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// HandleDiagnosticSignal writes a snapshot from takeSnapshot and forwards sig
+// to pid each time sig is received. Call signal.Stop on the returned channel
+// and close it to stop handling.
+func HandleDiagnosticSignal(sig syscall.Signal, pid int, dir string, takeSnapshot func() DiagnosticSnapshot, logger *Logger) chan os.Signal {
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, sig)
+
+	go func() {
+		for range sigs {
+			if path, err := WriteDiagnosticSnapshot(dir, takeSnapshot()); err != nil {
+				logger.Warnf("Failed to write diagnostic snapshot: %v", err)
+			} else {
+				logger.Printf("Diagnostic snapshot written to %s", path)
+			}
+			if err := syscall.Kill(pid, sig); err != nil {
+				logger.Printf("Failed to forward %s to pid %d: %v", sig, pid, err)
+			}
+		}
+	}()
+
+	return sigs
+}
+
+// launcherSnapshot captures the current state for a diagnostic dump. watchdog
+// may be nil when it is not running.
+func launcherSnapshot(pid int, limits MemoryLimits, watchdog *RSSWatchdog, peakRSS func() uint64) DiagnosticSnapshot {
+	snapshot := DiagnosticSnapshot{
+		Time:         time.Now(),
+		Pid:          pid,
+		PeakRSSBytes: peakRSS(),
+		Goroutines:   runtime.NumGoroutine(),
+		Limits:       NewDebugInfo(limits),
+	}
+	if rss, err := readProcessRSS(pid); err == nil {
+		snapshot.RSSBytes = rss
+	}
+	if watchdog != nil {
+		snapshot.WatchdogState = watchdog.State().String()
+		snapshot.Limits.MemoryPressure = watchdog.MemoryPressure()
+	}
+	return snapshot
+}
@@ -0,0 +1,45 @@
+package launchlib
+
+import (
+	"syscall"
+	"testing"
+)
+
+func TestParseSignal(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    syscall.Signal
+		wantErr bool
+	}{
+		{"SIGUSR2", syscall.SIGUSR2, false},
+		{"usr1", syscall.SIGUSR1, false},
+		{" SIGQUIT ", syscall.SIGQUIT, false},
+		{"SIGBOGUS", 0, true},
+		{"", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseSignal(tt.name)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseSignal(%q): expected error=%t, got %v", tt.name, tt.wantErr, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseSignal(%q): expected %v, got %v", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestValidateDiagnosticsSignal(t *testing.T) {
+	base := StaticLauncherConfig{ConfigType: "python", ConfigVersion: 1, Executable: "app.pex"}
+
+	base.Diagnostics = DiagnosticsConfig{Enabled: true}
+	if err := validateStaticConfig(base); err != nil {
+		t.Errorf("expected default signal to validate, got %v", err)
+	}
+	for _, sig := range []string{"SIGTERM", "SIGNOPE"} {
+		base.Diagnostics = DiagnosticsConfig{Enabled: true, Signal: sig}
+		if err := validateStaticConfig(base); err == nil {
+			t.Errorf("expected diagnostics.signal %s to be rejected", sig)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package launchlib
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Kinds of WatchdogEvent.
+const (
+	// WatchdogEventState records a state transition.
+	WatchdogEventState = "state"
+
+	// WatchdogEventPeak records a new peak RSS.
+	WatchdogEventPeak = "peak"
+)
+
+// WatchdogEvent is one line of the watchdog event log.
+type WatchdogEvent struct {
+	Time time.Time `json:"time"`
+
+	// Event is WatchdogEventState or WatchdogEventPeak.
+	Event string `json:"event"`
+
+	// State is the watchdog state after the event.
+	State string `json:"state"`
+
+	// RSS is the reading that caused the event, in bytes.
+	RSS uint64 `json:"rss"`
+
+	// Limit is the hard limit in force, in bytes.
+	Limit uint64 `json:"limit"`
+
+	PID int `json:"pid"`
+}
+
+// watchdogEventLog appends events to a writer as JSON lines. It is safe for
+// concurrent use, and each event is written with a single Write.
+type watchdogEventLog struct {
+	mu     sync.Mutex
+	out    io.Writer
+	failed bool
+}
+
+// write appends event. It reports an error only for the first failed write,
+// so a broken event log is logged once rather than on every poll.
+func (e *watchdogEventLog) write(event WatchdogEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if _, err := e.out.Write(append(data, '\n')); err != nil && !e.failed {
+		e.failed = true
+		return err
+	}
+	return nil
+}
+
+// OpenWatchdogEventLog opens path for appending watchdog events, creating it
+// and its directory if needed.
+func OpenWatchdogEventLog(path string) (*os.File, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	return os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+}
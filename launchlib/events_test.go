@@ -0,0 +1,105 @@
+package launchlib
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestWatchdogEventLog(t *testing.T) {
+	child := exec.Command("sleep", "30")
+	if err := child.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = child.Process.Kill()
+		_ = child.Wait()
+	}()
+
+	readings := []uint64{500, 900, 880, 500, 990}
+	w, _ := newTestWatchdog(WatchdogConfig{GracePeriodSeconds: 30}, func(int) (uint64, error) {
+		rss := readings[0]
+		readings = readings[1:]
+		return rss, nil
+	})
+	w.pid = child.Process.Pid
+	var events bytes.Buffer
+	w.SetEventLog(&events)
+
+	for i := 0; i < 5; i++ {
+		w.check()
+	}
+
+	want := []string{
+		"peak healthy 500",
+		"peak healthy 900",
+		"state soft_warning 900",
+		"state healthy 500",
+		"peak healthy 990",
+		"state hard_limit 990",
+		"state terminating 990",
+	}
+	lines := strings.Split(strings.TrimSpace(events.String()), "\n")
+	if len(lines) != len(want) {
+		t.Fatalf("expected %d events, got %d:\n%s", len(want), len(lines), events.String())
+	}
+	for i, line := range lines {
+		var event WatchdogEvent
+		if err := json.Unmarshal([]byte(line), &event); err != nil {
+			t.Fatalf("event %d is not JSON: %v\n%s", i, err, line)
+		}
+		if got := fmt.Sprintf("%s %s %d", event.Event, event.State, event.RSS); got != want[i] {
+			t.Errorf("event %d: expected %q, got %q", i, want[i], got)
+		}
+		if event.Limit != 950 || event.PID != child.Process.Pid || event.Time.IsZero() {
+			t.Errorf("event %d: expected limit 950, pid %d and a time, got %+v", i, child.Process.Pid, event)
+		}
+	}
+}
+
+func TestWatchdogEventLogConcurrentWrites(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "var/log/watchdog-events.jsonl")
+	file, err := OpenWatchdogEventLog(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	eventLog := &watchdogEventLog{out: file}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				if err := eventLog.write(WatchdogEvent{Event: WatchdogEventPeak, State: "healthy", RSS: uint64(i*10 + j)}); err != nil {
+					t.Error(err)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+	if err := file.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 200 {
+		t.Fatalf("expected 200 events, got %d", len(lines))
+	}
+	for _, line := range lines {
+		var event WatchdogEvent
+		if err := json.Unmarshal([]byte(line), &event); err != nil {
+			t.Fatalf("interleaved or partial event %q: %v", line, err)
+		}
+	}
+}
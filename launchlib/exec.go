@@ -0,0 +1,83 @@
+package launchlib
+
+import (
+	"os"
+	"syscall"
+)
+
+// execSpec is what the launcher applies to itself before it is replaced by
+// the process in exec mode.
+type execSpec struct {
+	argv       []string
+	env        []string
+	dir        string
+	umask      int // negative leaves the umask unchanged
+	credential *syscall.Credential
+}
+
+// replaceProcess switches the launcher to the process's working directory,
+// umask and credentials, then execs it in place of the launcher. It only
+// returns on failure.
+func replaceProcess(spec execSpec) error {
+	if spec.dir != "" {
+		if err := os.Chdir(spec.dir); err != nil {
+			return err
+		}
+	}
+	if spec.umask >= 0 {
+		syscall.Umask(spec.umask)
+	}
+	if spec.credential != nil {
+		// Groups first: dropping the uid removes the right to change them.
+		if !spec.credential.NoSetGroups {
+			groups := make([]int, len(spec.credential.Groups))
+			for i, g := range spec.credential.Groups {
+				groups[i] = int(g)
+			}
+			if err := syscall.Setgroups(groups); err != nil {
+				return err
+			}
+		}
+		if err := syscall.Setgid(int(spec.credential.Gid)); err != nil {
+			return err
+		}
+		if err := syscall.Setuid(int(spec.credential.Uid)); err != nil {
+			return err
+		}
+	}
+	return syscall.Exec(spec.argv[0], spec.argv, spec.env)
+}
+
+// execModeIgnored lists the configured features that need the launcher to
+// stay running as the parent and so do nothing in exec mode.
+func execModeIgnored(config MergedConfig) []string {
+	var ignored []string
+	if len(config.SubProcesses) > 0 {
+		ignored = append(ignored, "subProcesses")
+	}
+	if config.Readiness.Enabled {
+		ignored = append(ignored, "readiness")
+	}
+	if config.Diagnostics.Enabled {
+		ignored = append(ignored, "diagnostics")
+	}
+	if config.Metrics.PushGatewayURL != "" {
+		ignored = append(ignored, "metrics.pushGatewayUrl")
+	}
+	if config.Watchdog.PeakRSSFile != "" {
+		ignored = append(ignored, "watchdog.peakRssFile")
+	}
+	if config.Watchdog.EventLogFile != "" {
+		ignored = append(ignored, "watchdog.eventLogFile")
+	}
+	if config.OutputBuffer.Enabled {
+		ignored = append(ignored, "outputBuffer")
+	}
+	if len(config.SignalRemap) > 0 {
+		ignored = append(ignored, "signalRemap")
+	}
+	if len(config.ExitCodeMap) > 0 {
+		ignored = append(ignored, "exitCodeMap")
+	}
+	return ignored
+}
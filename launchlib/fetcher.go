@@ -0,0 +1,167 @@
+package launchlib
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigFetcher supplies custom launcher configuration from a source other than
+// the local custom config file, such as a central config service. The returned
+// bytes are parsed as a CustomLauncherConfig. Returning no bytes means there are
+// no remote overrides.
+type ConfigFetcher interface {
+	Fetch(ctx context.Context) ([]byte, error)
+}
+
+// NoopConfigFetcher is a ConfigFetcher that never supplies any overrides.
+type NoopConfigFetcher struct{}
+
+// Fetch implements ConfigFetcher.
+func (NoopConfigFetcher) Fetch(context.Context) ([]byte, error) {
+	return nil, nil
+}
+
+// CachingConfigFetcher wraps another ConfigFetcher and persists every successful
+// fetch to CachePath. When the wrapped fetcher fails, the last cached copy is
+// served instead so that a config service outage does not block launches.
+type CachingConfigFetcher struct {
+	Fetcher   ConfigFetcher
+	CachePath string
+}
+
+// Fetch implements ConfigFetcher.
+func (c CachingConfigFetcher) Fetch(ctx context.Context) ([]byte, error) {
+	data, fetchErr := c.Fetcher.Fetch(ctx)
+	if fetchErr == nil {
+		if err := writeFileAtomic(c.CachePath, data, 0600); err != nil {
+			return nil, fmt.Errorf("failed to update config cache %s: %w", c.CachePath, err)
+		}
+		return data, nil
+	}
+
+	cached, err := os.ReadFile(c.CachePath)
+	if err != nil {
+		return nil, fmt.Errorf("fetch failed (%v) and no cached config available: %w", fetchErr, err)
+	}
+	return cached, nil
+}
+
+// fetchCustomConfig retrieves and parses a custom config from the fetcher.
+func fetchCustomConfig(ctx context.Context, fetcher ConfigFetcher) (CustomLauncherConfig, error) {
+	data, err := fetcher.Fetch(ctx)
+	if err != nil {
+		return CustomLauncherConfig{}, err
+	}
+	if len(bytes.TrimSpace(data)) == 0 {
+		return CustomLauncherConfig{}, nil
+	}
+	var config CustomLauncherConfig
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return CustomLauncherConfig{}, fmt.Errorf("%w: %w", ErrConfigParse, err)
+	}
+	return config, nil
+}
+
+// overlayCustomConfig layers overlay on top of base. Env keys in the overlay win,
+// list fields are appended after the base, and memory/watchdog fields follow the
+// same non-zero override rules as the static/custom merge.
+func overlayCustomConfig(base, overlay CustomLauncherConfig) CustomLauncherConfig {
+	result := base
+	if overlay.ConfigType != "" {
+		result.ConfigType = overlay.ConfigType
+	}
+	if overlay.ConfigVersion != 0 {
+		result.ConfigVersion = overlay.ConfigVersion
+	}
+
+	if len(overlay.Env) > 0 {
+		result.Env = make(map[string]string, len(base.Env)+len(overlay.Env))
+		for k, v := range base.Env {
+			result.Env[k] = v
+		}
+		for k, v := range overlay.Env {
+			result.Env[k] = v
+		}
+	}
+
+	if len(overlay.EnvFromFile) > 0 {
+		result.EnvFromFile = make(map[string]string, len(base.EnvFromFile)+len(overlay.EnvFromFile))
+		for k, v := range base.EnvFromFile {
+			result.EnvFromFile[k] = v
+		}
+		for k, v := range overlay.EnvFromFile {
+			result.EnvFromFile[k] = v
+		}
+	}
+
+	if len(overlay.PythonPathEntries) > 0 {
+		result.PythonPathEntries = append(append([]string{}, overlay.PythonPathEntries...), base.PythonPathEntries...)
+	}
+	if len(overlay.EnvAppend) > 0 {
+		result.EnvAppend = mergeEnvAppend(base.EnvAppend, overlay.EnvAppend)
+	}
+
+	if overlay.PythonVersion != "" {
+		result.PythonVersion = overlay.PythonVersion
+	}
+
+	// A replacing overlay replaces the static list as well as the local one.
+	result.PythonOpts = mergeList(base.PythonOpts, overlay.PythonOpts, overlay.PythonOptsMode)
+	if overlay.PythonOptsMode == ListMergeReplace {
+		result.PythonOptsMode = ListMergeReplace
+	}
+	result.Args = mergeList(base.Args, overlay.Args, overlay.ArgsMode)
+	if overlay.ArgsMode == ListMergeReplace {
+		result.ArgsMode = ListMergeReplace
+	}
+
+	if overlay.Memory != nil {
+		memory := *overlay.Memory
+		if base.Memory != nil {
+			memory = overrideMemoryConfig(*base.Memory, *overlay.Memory)
+		}
+		result.Memory = &memory
+	}
+	if overlay.Watchdog != nil {
+		watchdog := *overlay.Watchdog
+		if base.Watchdog != nil {
+			watchdog = overrideWatchdogConfig(*base.Watchdog, *overlay.Watchdog)
+		}
+		result.Watchdog = &watchdog
+	}
+
+	result.DangerousDisableContainerSupport = base.DangerousDisableContainerSupport ||
+		overlay.DangerousDisableContainerSupport
+	return result
+}
+
+// writeFileAtomic writes data to a temporary file next to path and renames it
+// into place, so readers never observe a partially written file.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,153 @@
+package launchlib
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type fakeConfigFetcher struct {
+	data []byte
+	err  error
+}
+
+func (f *fakeConfigFetcher) Fetch(context.Context) ([]byte, error) {
+	return f.data, f.err
+}
+
+func writeTestConfigs(t *testing.T, staticYAML, customYAML string) (string, string) {
+	t.Helper()
+	dir := t.TempDir()
+	staticPath := filepath.Join(dir, "launcher-static.yml")
+	customPath := filepath.Join(dir, "launcher-custom.yml")
+	if err := os.WriteFile(staticPath, []byte(staticYAML), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if customYAML != "" {
+		if err := os.WriteFile(customPath, []byte(customYAML), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return staticPath, customPath
+}
+
+const testStaticYAML = `
+configType: python
+configVersion: 1
+executable: service/bin/app.pex
+`
+
+func TestGetConfigsFromFilesWithRemoteOverrides(t *testing.T) {
+	staticPath, customPath := writeTestConfigs(t, testStaticYAML, `
+env:
+  LOCAL: local
+  SHARED: local
+args: ["--local"]
+memory:
+  maxRssPercent: 60
+`)
+	fetcher := &fakeConfigFetcher{data: []byte(`
+env:
+  SHARED: remote
+args: ["--remote"]
+memory:
+  heapFragmentationBuffer: 0.2
+`)}
+
+	var buf bytes.Buffer
+	_, custom, err := GetConfigsFromFilesWithFetcher(staticPath, customPath, fetcher, &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if custom.Env["LOCAL"] != "local" {
+		t.Errorf("expected local env preserved, got %v", custom.Env)
+	}
+	if custom.Env["SHARED"] != "remote" {
+		t.Errorf("expected remote env to take precedence, got %v", custom.Env)
+	}
+	assertArgs(t, []string{"--local", "--remote"}, custom.Args)
+	if custom.Memory.MaxRSSPercent != 60 || valueOr(custom.Memory.HeapFragmentationBuffer, 0) != 0.2 {
+		t.Errorf("expected memory fields from both layers, got %+v", *custom.Memory)
+	}
+}
+
+func TestOverlayCustomConfigListModes(t *testing.T) {
+	base := CustomLauncherConfig{Args: []string{"--local"}, ArgsMode: ListMergeReplace, PythonOpts: []string{"-u"}}
+
+	result := overlayCustomConfig(base, CustomLauncherConfig{Args: []string{"--remote"}})
+	assertArgs(t, []string{"--local", "--remote"}, result.Args)
+	if result.ArgsMode != ListMergeReplace {
+		t.Errorf("expected an appending overlay to keep the local argsMode, got %q", result.ArgsMode)
+	}
+
+	result = overlayCustomConfig(base, CustomLauncherConfig{PythonOpts: []string{"-X", "dev"}, PythonOptsMode: ListMergeReplace})
+	assertArgs(t, []string{"-X", "dev"}, result.PythonOpts)
+	if result.PythonOptsMode != ListMergeReplace {
+		t.Errorf("expected a replacing overlay to replace the static pythonOpts too, got %q", result.PythonOptsMode)
+	}
+}
+
+func TestOverlayCustomConfigPathEntries(t *testing.T) {
+	base := CustomLauncherConfig{
+		PythonPathEntries: []string{"var/local"},
+		EnvAppend:         map[string][]string{"PATH": {"/opt/local/bin"}},
+	}
+	result := overlayCustomConfig(base, CustomLauncherConfig{
+		PythonPathEntries: []string{"var/remote"},
+		EnvAppend:         map[string][]string{"PATH": {"/opt/remote/bin"}},
+	})
+	assertArgs(t, []string{"var/remote", "var/local"}, result.PythonPathEntries)
+	assertArgs(t, []string{"/opt/local/bin", "/opt/remote/bin"}, result.EnvAppend["PATH"])
+}
+
+func TestGetConfigsFromFilesFetchFailureFallsBackToCache(t *testing.T) {
+	staticPath, customPath := writeTestConfigs(t, testStaticYAML, "")
+	cachePath := filepath.Join(t.TempDir(), "cache", "remote.yml")
+
+	inner := &fakeConfigFetcher{data: []byte("env:\n  FROM: remote\n")}
+	fetcher := CachingConfigFetcher{Fetcher: inner, CachePath: cachePath}
+
+	var buf bytes.Buffer
+	if _, _, err := GetConfigsFromFilesWithFetcher(staticPath, customPath, fetcher, &buf); err != nil {
+		t.Fatal(err)
+	}
+
+	inner.data, inner.err = nil, errors.New("config service unavailable")
+	_, custom, err := GetConfigsFromFilesWithFetcher(staticPath, customPath, fetcher, &buf)
+	if err != nil {
+		t.Fatalf("expected cached config to be served, got error: %v", err)
+	}
+	if custom.Env["FROM"] != "remote" {
+		t.Errorf("expected cached remote env, got %v", custom.Env)
+	}
+}
+
+func TestGetConfigsFromFilesFetchFailureWithoutCache(t *testing.T) {
+	staticPath, customPath := writeTestConfigs(t, testStaticYAML, "")
+	fetcher := CachingConfigFetcher{
+		Fetcher:   &fakeConfigFetcher{err: errors.New("unavailable")},
+		CachePath: filepath.Join(t.TempDir(), "missing.yml"),
+	}
+
+	var buf bytes.Buffer
+	if _, _, err := GetConfigsFromFilesWithFetcher(staticPath, customPath, fetcher, &buf); err == nil {
+		t.Fatal("expected an error when the fetch fails and no cache exists")
+	}
+}
+
+func TestGetConfigsFromFilesNoopFetcher(t *testing.T) {
+	staticPath, customPath := writeTestConfigs(t, testStaticYAML, "env:\n  LOCAL: local\n")
+
+	var buf bytes.Buffer
+	_, custom, err := GetConfigsFromFilesWithFetcher(staticPath, customPath, NoopConfigFetcher{}, &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(custom.Env) != 1 || custom.Env["LOCAL"] != "local" {
+		t.Errorf("expected local config unchanged, got %v", custom.Env)
+	}
+}
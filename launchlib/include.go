@@ -0,0 +1,103 @@
+package launchlib
+
+import (
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// includeKey is the top-level static config key listing files to include.
+const includeKey = "include"
+
+// maxIncludeDepth bounds how deeply includes may nest.
+const maxIncludeDepth = 8
+
+// readConfigWithIncludes reads the YAML mapping at path and merges the files
+// named by its top-level include list beneath it. Includes are resolved with
+// resolve, applied in order (later ones winning), and may include further
+// files; the including file wins over everything it includes. Mappings are
+// merged key by key; any other value replaces the included one.
+func readConfigWithIncludes(path string, resolve func(string) string) (map[string]interface{}, error) {
+	return readIncludeTree(path, resolve, []string{path})
+}
+
+func readIncludeTree(path string, resolve func(string) string, chain []string) (map[string]interface{}, error) {
+	data, err := readConfigFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var doc map[string]interface{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("%w: %s: %w", ErrConfigParse, path, err)
+	}
+	if doc == nil {
+		doc = map[string]interface{}{}
+	}
+
+	rawIncludes, ok := doc[includeKey]
+	if !ok {
+		return doc, nil
+	}
+	delete(doc, includeKey)
+	includes, err := includePaths(rawIncludes)
+	if err != nil {
+		return nil, invalidField(includeKey, rawIncludes, "%v in %s", err, path)
+	}
+	if len(includes) > 0 && len(chain) > maxIncludeDepth {
+		return nil, invalidField(includeKey, path, "includes nested deeper than %d files", maxIncludeDepth)
+	}
+
+	base := map[string]interface{}{}
+	for _, include := range includes {
+		includePath := resolve(include)
+		for _, seen := range chain {
+			if seen == includePath {
+				return nil, invalidField(includeKey, include, "include cycle: %s -> %s",
+					strings.Join(chain, " -> "), includePath)
+			}
+		}
+		included, err := readIncludeTree(includePath, resolve, append(chain[:len(chain):len(chain)], includePath))
+		if err != nil {
+			return nil, fmt.Errorf("include %s: %w", include, err)
+		}
+		base = mergeYAMLMaps(base, included)
+	}
+	return mergeYAMLMaps(base, doc), nil
+}
+
+// includePaths converts the decoded include value to a list of paths.
+func includePaths(value interface{}) ([]string, error) {
+	items, ok := value.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("must be a list of paths")
+	}
+	paths := make([]string, 0, len(items))
+	for _, item := range items {
+		path, ok := item.(string)
+		if !ok || path == "" {
+			return nil, fmt.Errorf("entries must be non-empty paths")
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
+
+// mergeYAMLMaps returns base with override applied: nested mappings merge
+// recursively, and any other override value replaces the base value.
+func mergeYAMLMaps(base, override map[string]interface{}) map[string]interface{} {
+	result := make(map[string]interface{}, len(base)+len(override))
+	for k, v := range base {
+		result[k] = v
+	}
+	for k, v := range override {
+		baseMap, baseIsMap := result[k].(map[string]interface{})
+		overrideMap, overrideIsMap := v.(map[string]interface{})
+		if baseIsMap && overrideIsMap {
+			result[k] = mergeYAMLMaps(baseMap, overrideMap)
+			continue
+		}
+		result[k] = v
+	}
+	return result
+}
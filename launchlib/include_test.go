@@ -0,0 +1,151 @@
+package launchlib
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeIncludeFiles writes files (dist-relative path -> content) under a new
+// dist root and returns a resolver for it.
+func writeIncludeFiles(t *testing.T, files map[string]string) func(string) string {
+	t.Helper()
+	distRoot := t.TempDir()
+	resolve := func(path string) string {
+		if filepath.IsAbs(path) {
+			return path
+		}
+		return filepath.Join(distRoot, path)
+	}
+	for name, content := range files {
+		path := resolve(name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return resolve
+}
+
+func TestStaticConfigInclude(t *testing.T) {
+	resolve := writeIncludeFiles(t, map[string]string{
+		"service/bin/launcher-static.yml": `
+include: [service/conf/common.yml]
+configType: python
+configVersion: 1
+executable: service/bin/app.pex
+`,
+		"service/conf/common.yml": `
+memory:
+  maxRssPercent: 60
+resources:
+  maxOpenFiles: 4096
+`,
+	})
+
+	config, err := readStaticConfig(resolve("service/bin/launcher-static.yml"), resolve)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Executable != "service/bin/app.pex" {
+		t.Errorf("expected executable from the including file, got %q", config.Executable)
+	}
+	if config.Memory.MaxRSSPercent != 60 {
+		t.Errorf("expected maxRssPercent 60 from the include, got %v", config.Memory.MaxRSSPercent)
+	}
+	if config.Resources.MaxOpenFiles != 4096 {
+		t.Errorf("expected maxOpenFiles 4096 from the include, got %d", config.Resources.MaxOpenFiles)
+	}
+}
+
+func TestStaticConfigIncludePrecedence(t *testing.T) {
+	resolve := writeIncludeFiles(t, map[string]string{
+		"launcher-static.yml": `
+include: [first.yml, second.yml]
+configType: python
+configVersion: 1
+executable: service/bin/app.pex
+memory:
+  mode: fixed
+env:
+  FROM: main
+`,
+		"first.yml": `
+memory:
+  mode: unmanaged
+  maxRssPercent: 50
+  fixedLimitBytes: 1073741824
+env:
+  FROM: first
+  FIRST: "1"
+`,
+		"second.yml": `
+memory:
+  maxRssPercent: 70
+env:
+  FROM: second
+`,
+	})
+
+	config, err := readStaticConfig(resolve("launcher-static.yml"), resolve)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Memory.Mode != MemoryModeFixed {
+		t.Errorf("expected the including file's memory.mode to win, got %q", config.Memory.Mode)
+	}
+	if config.Memory.MaxRSSPercent != 70 {
+		t.Errorf("expected the later include's maxRssPercent 70, got %v", config.Memory.MaxRSSPercent)
+	}
+	if config.Memory.FixedLimitBytes != 1073741824 {
+		t.Errorf("expected nested fields merged from the first include, got fixedLimitBytes %d", config.Memory.FixedLimitBytes)
+	}
+	if config.Env["FROM"] != "main" || config.Env["FIRST"] != "1" {
+		t.Errorf("expected env merged with the including file winning, got %v", config.Env)
+	}
+}
+
+func TestStaticConfigIncludeCycle(t *testing.T) {
+	resolve := writeIncludeFiles(t, map[string]string{
+		"launcher-static.yml": "include: [a.yml]\nconfigVersion: 1\nexecutable: app\n",
+		"a.yml":               "include: [b.yml]\n",
+		"b.yml":               "include: [launcher-static.yml]\n",
+	})
+
+	_, err := readStaticConfig(resolve("launcher-static.yml"), resolve)
+	if !errors.Is(err, ErrConfigValidation) || !strings.Contains(err.Error(), "include cycle") {
+		t.Fatalf("expected an include cycle validation error, got %v", err)
+	}
+}
+
+func TestStaticConfigIncludeDepthLimit(t *testing.T) {
+	files := map[string]string{
+		"launcher-static.yml": "include: [level1.yml]\nconfigVersion: 1\nexecutable: app\n",
+	}
+	for i := 1; i <= maxIncludeDepth+1; i++ {
+		files[fmt.Sprintf("level%d.yml", i)] = fmt.Sprintf("include: [level%d.yml]\n", i+1)
+	}
+	files[fmt.Sprintf("level%d.yml", maxIncludeDepth+2)] = "executable: app\n"
+	resolve := writeIncludeFiles(t, files)
+
+	_, err := readStaticConfig(resolve("launcher-static.yml"), resolve)
+	if !errors.Is(err, ErrConfigValidation) || !strings.Contains(err.Error(), "nested deeper") {
+		t.Fatalf("expected a depth limit validation error, got %v", err)
+	}
+}
+
+func TestStaticConfigIncludeMissing(t *testing.T) {
+	resolve := writeIncludeFiles(t, map[string]string{
+		"launcher-static.yml": "include: [missing.yml]\nconfigVersion: 1\nexecutable: app\n",
+	})
+
+	_, err := readStaticConfig(resolve("launcher-static.yml"), resolve)
+	if err == nil || !strings.Contains(err.Error(), "include missing.yml") {
+		t.Fatalf("expected an error naming the missing include, got %v", err)
+	}
+}
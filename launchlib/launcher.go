@@ -16,12 +16,18 @@ package launchlib
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"os/exec"
+	"os/signal"
 	"path/filepath"
+	"runtime"
+	"sort"
 	"strings"
+	"sync/atomic"
+	"syscall"
 	"time"
 )
 
@@ -33,13 +39,21 @@ const (
 
 // LauncherParams holds the parameters for a launch operation.
 type LauncherParams struct {
-	// ConfigDir is the root of the SLS distribution.
-	// All relative paths in configs are resolved against this.
+	// DistRoot is the root of the SLS distribution. All relative paths in
+	// configs, including dirs and the PID file, are resolved against it
+	// rather than the launcher's working directory, so an embedder may call
+	// Launch or LaunchWithContext from any directory.
 	DistRoot string
 
-	// StaticConfigPath overrides the default static config location.
+	// StaticConfigPath overrides the default static config location. When it
+	// is empty and service/bin/launcher-static.yml is missing, the launcher
+	// searches StaticConfigSearchPaths instead.
 	StaticConfigPath string
 
+	// SearchDir is the directory searched for ./launcher-static.yml when the
+	// default static config is missing. Default: the current directory.
+	SearchDir string
+
 	// CustomConfigPath overrides the default custom config location.
 	CustomConfigPath string
 
@@ -49,13 +63,47 @@ type LauncherParams struct {
 	// ServiceVersion is exposed as an env var to the process.
 	ServiceVersion string
 
+	// ConfigFetcher optionally supplies a remote custom config that is layered
+	// on top of the local custom config file. Nil disables remote config.
+	ConfigFetcher ConfigFetcher
+
 	// Stdout is where launcher output is written.
 	Stdout io.Writer
+
+	// Stderr receives the process's stderr when the static config sets
+	// mergeStderr: false. When nil, stderr stays merged into Stdout.
+	Stderr io.Writer
+
+	// Check runs the health check instead of the service: StaticConfigPath
+	// defaults to service/bin/launcher-check.yml and is read with
+	// GetCheckConfigFromFile, and the custom config is not applied.
+	Check bool
+
+	// LauncherVersion and LauncherCommit identify the launcher build in the
+	// launch manifest.
+	LauncherVersion string
+	LauncherCommit  string
+
+	// Exec replaces the launcher with the process instead of forking it, as
+	// if the static config set execMode.
+	Exec bool
+
+	// Dev adjusts the merged config for running from a source checkout: no
+	// PID file, text logging at debug level, and no watchdog unless a config
+	// sets watchdog.enabled. See applyDevMode.
+	Dev bool
+
+	// Watch restarts the process whenever the executable changes, and the
+	// static and custom configs too with WatchConfig. It requires Dev and
+	// cannot be combined with exec mode.
+	Watch       bool
+	WatchConfig bool
 }
 
 // LaunchResult describes the outcome of a launch operation.
 type LaunchResult struct {
-	// ExitCode is the exit code of the child process. -1 if the process was signaled.
+	// ExitCode is the exit code of the child process, -1 if the process was
+	// signaled, after applying any exitCodeMap entry.
 	ExitCode int
 
 	// WatchdogTriggered is true if the watchdog sent SIGTERM due to memory pressure.
@@ -63,13 +111,45 @@ type LaunchResult struct {
 
 	// Duration is how long the process ran.
 	Duration time.Duration
+
+	// Cancelled is true if the launch context was cancelled and the launcher
+	// shut the process down.
+	Cancelled bool
+
+	// PeakRSSBytes is the highest RSS observed for the process, or 0 if unknown.
+	PeakRSSBytes uint64
+
+	// OOMKilled is true if the process was SIGKILLed and the cgroup's
+	// oom_kill counter went up while it ran: the kernel OOM killer, not the
+	// watchdog, ended it.
+	OOMKilled bool
+
+	// CriticalSubprocessFailure describes the critical subprocess whose exit
+	// shut the process down, or is empty. ExitCode is then
+	// ExitCodeCriticalSubprocess.
+	CriticalSubprocessFailure string
 }
 
+// ExitCodeCriticalSubprocess is the launch's exit code when a critical
+// subprocess exits and the launcher shuts the primary process down
+// (EX_SOFTWARE). exitCodeMap can remap it.
+const ExitCodeCriticalSubprocess = 70
+
 // Launcher orchestrates the full lifecycle of launching a Python process.
 type Launcher struct {
 	params  LauncherParams
 	logger  *Logger
 	limiter *MemoryLimiter
+
+	// searchStaticConfig is set when StaticConfigPath was defaulted, enabling
+	// the fallback search.
+	searchStaticConfig bool
+
+	// exec replaces the launcher with the process in exec mode.
+	exec func(execSpec) error
+
+	// restartPending is set when watch mode stops the process to restart it.
+	restartPending atomic.Bool
 }
 
 // NewLauncher creates a new Launcher with the given parameters.
@@ -77,8 +157,20 @@ func NewLauncher(params LauncherParams) *Launcher {
 	if params.Stdout == nil {
 		params.Stdout = os.Stdout
 	}
+	searchStaticConfig := false
 	if params.StaticConfigPath == "" {
 		params.StaticConfigPath = defaultStaticConfigPath
+		if params.Check {
+			params.StaticConfigPath = defaultCheckConfigPath
+		} else {
+			searchStaticConfig = true
+		}
+	}
+	if params.SearchDir == "" {
+		params.SearchDir = "."
+		if wd, err := os.Getwd(); err == nil {
+			params.SearchDir = wd
+		}
 	}
 	if params.CustomConfigPath == "" {
 		params.CustomConfigPath = defaultCustomConfigPath
@@ -87,168 +179,496 @@ func NewLauncher(params LauncherParams) *Launcher {
 	// For now, use text mode.
 	logger := NewLogger(params.Stdout, DefaultLoggingConfig())
 	return &Launcher{
-		params:  params,
-		logger:  logger,
-		limiter: NewMemoryLimiter(),
+		params:             params,
+		logger:             logger,
+		limiter:            NewMemoryLimiter(),
+		searchStaticConfig: searchStaticConfig,
+		exec:               replaceProcess,
 	}
 }
 
-// Launch executes the full launch sequence and blocks until the process exits.
-func (l *Launcher) Launch() (LaunchResult, error) {
-	startTime := time.Now()
+// launchPlan holds everything resolved before the process is forked.
+type launchPlan struct {
+	config  MergedConfig
+	limits  MemoryLimits
+	cmdArgs []string
+	env     []string
 
-	l.logger.Printf("python-service-launcher starting (service=%s, version=%s)",
-		l.params.ServiceName, l.params.ServiceVersion)
+	// secretEnv names the variables whose values came from EnvFromFile.
+	secretEnv []string
 
-	// --- 1. Read and merge configs ---
+	// configHash identifies the static and custom configs that were read.
+	configHash string
 
-	staticPath := l.resolvePath(l.params.StaticConfigPath)
-	customPath := l.resolvePath(l.params.CustomConfigPath)
+	// watchPaths are the files whose changes restart the process in watch
+	// mode.
+	watchPaths []string
+}
 
-	staticConfig, customConfig, err := GetConfigsFromFiles(staticPath, customPath, l.params.Stdout)
-	if err != nil {
-		return LaunchResult{ExitCode: 1}, fmt.Errorf("config error: %w", err)
+// Launch executes the full launch sequence and blocks until the process exits.
+// SIGINT and SIGHUP received by the launcher are forwarded to the process; a
+// SIGTERM shuts it down with the shutdown sequence, escalating as configured.
+// In watch mode the process is relaunched each time a watched file changes.
+func (l *Launcher) Launch() (LaunchResult, error) {
+	if l.params.Watch {
+		return l.launchWatching()
 	}
+	return l.launch(context.Background(), true)
+}
 
-	merged := MergeConfigs(staticConfig, customConfig)
-
-	// Re-initialize logger with config-specified settings
-	l.logger = NewLogger(l.params.Stdout, merged.Logging)
-
-	l.logConfig(merged)
+// LaunchWithContext executes the full launch sequence and blocks until the
+// process exits. Cancelling ctx shuts the process down the same way a SIGTERM
+// does: the process receives SIGTERM, is sent SIGKILL if it is still alive after
+// the watchdog grace period, and the readiness probe drains before returning.
+// No OS signal forwarding is installed; the caller owns signal handling.
+func (l *Launcher) LaunchWithContext(ctx context.Context) (LaunchResult, error) {
+	return l.launch(ctx, false)
+}
 
-	// --- CPU detection ---
-	cpuCount := DetectCPUCount(merged.CPU, cpuFilesystem())
-	merged.EffectiveCPUCount = cpuCount
-	l.logger.Printf("CPU: detected %d effective CPUs", cpuCount)
+func (l *Launcher) launch(ctx context.Context, forwardSignals bool) (LaunchResult, error) {
+	startTime := time.Now()
 
-	// --- 2. Compute memory limits ---
+	l.logger.Printf("python-service-launcher starting (service=%s, version=%s, launcher=%s, commit=%s)",
+		l.params.ServiceName, l.params.ServiceVersion, l.params.LauncherVersion, l.params.LauncherCommit)
 
-	limits, err := l.limiter.ComputeLimits(merged)
+	plan, err := l.plan()
 	if err != nil {
-		// Memory limit detection failure is non-fatal in non-container environments.
-		// In containers, it's a hard error because we need the watchdog.
-		if merged.IsContainer {
-			return LaunchResult{ExitCode: 1}, fmt.Errorf("memory limit detection failed in container: %w", err)
-		}
-		l.logger.Printf("WARNING: failed to detect memory limits: %v (continuing with unmanaged memory)", err)
-		merged.Memory.Mode = MemoryModeUnmanaged
-		limits = MemoryLimits{}
+		return LaunchResult{ExitCode: 1}, err
 	}
-	merged.EffectiveMemoryLimitBytes = limits.EffectiveLimitBytes
-
-	if limits.EffectiveLimitBytes > 0 {
-		l.logger.Printf("Memory limits: cgroup=%s effective=%s mode=%s",
-			formatBytes(limits.CgroupLimitBytes),
-			formatBytes(limits.EffectiveLimitBytes),
-			merged.Memory.Mode,
-		)
+	merged := plan.config
+	limits := plan.limits
+	cmdArgs := plan.cmdArgs
+	env := plan.env
+
+	if len(plan.watchPaths) > 0 {
+		var stop context.CancelFunc
+		ctx, stop = context.WithCancel(ctx)
+		defer stop()
+		go l.watchForRestart(ctx, stop, plan.watchPaths)
 	}
 
 	// --- 3. Create required directories ---
 
-	dirs := merged.Dirs
-	if len(dirs) == 0 {
-		// Default directories matching go-java-launcher conventions
-		dirs = []string{"var/data/tmp", "var/log", "var/run"}
+	// Unset means the defaults; an explicit empty list creates nothing.
+	dirConfigs := merged.Dirs
+	if dirConfigs == nil {
+		dirConfigs = DefaultDirs()
+	}
+	// Brace ranges are expanded here so each directory gets its permissions.
+	var dirs []string
+	var expandedConfigs []DirConfig
+	for _, dir := range dirConfigs {
+		paths, err := ExpandDirRange(dir.Path)
+		if err != nil {
+			return LaunchResult{ExitCode: 1}, fmt.Errorf("directory creation failed: %w", err)
+		}
+		for _, p := range paths {
+			dirs = append(dirs, l.resolvePath(p))
+			expandedConfigs = append(expandedConfigs, dir)
+		}
 	}
 	if err := CreateDirectories(dirs); err != nil {
 		return LaunchResult{ExitCode: 1}, fmt.Errorf("directory creation failed: %w", err)
 	}
+	for i, dir := range expandedConfigs {
+		var chownErr *DirectoryChownError
+		if err := ApplyDirectoryPermissions(dirs[i], dir); errors.As(err, &chownErr) {
+			l.logger.Warnf("%v (continuing with current ownership)", err)
+		} else if err != nil {
+			return LaunchResult{ExitCode: 1}, fmt.Errorf("directory setup failed: %w", err)
+		}
+	}
+
+	if merged.WriteManifest {
+		manifestPath := l.resolvePath(launchManifestPath)
+		if err := WriteLaunchManifest(manifestPath, l.newLaunchManifest(plan)); err != nil {
+			l.logger.Warnf("Failed to write launch manifest %s: %v", manifestPath, err)
+		}
+	}
+
+	if merged.Memory.DescriptorFile != "" {
+		descriptorPath := l.resolvePath(merged.Memory.DescriptorFile)
+		if err := WriteLimitsDescriptor(descriptorPath, NewLimitsDescriptor(merged, limits)); err != nil {
+			return LaunchResult{ExitCode: 1}, fmt.Errorf("limits descriptor: %w", err)
+		}
+	}
+
+	notifyPath := ""
+	if merged.ShutdownNotifyFile != "" {
+		// A notice left by an earlier run would read as an immediate shutdown.
+		notifyPath = l.resolvePath(merged.ShutdownNotifyFile)
+		if err := os.Remove(notifyPath); err != nil && !os.IsNotExist(err) {
+			return LaunchResult{ExitCode: 1}, fmt.Errorf("shutdown notice: %w", err)
+		}
+		defer os.Remove(notifyPath)
+	}
+
+	workingDir := l.params.DistRoot
+	if merged.WorkingDir != "" {
+		workingDir = l.resolvePath(merged.WorkingDir)
+		info, err := os.Stat(workingDir)
+		if err != nil {
+			return LaunchResult{ExitCode: 1}, fmt.Errorf("working directory: %w", err)
+		}
+		if !info.IsDir() {
+			return LaunchResult{ExitCode: 1}, fmt.Errorf("working directory %s is not a directory", workingDir)
+		}
+	}
 
 	// --- 4. Set resource limits ---
 
 	if err := SetResourceLimits(merged.Resources); err != nil {
 		l.logger.Printf("WARNING: failed to set resource limits: %v", err)
 	}
+	var pinnedCPUs []int
+	if merged.Resources.PinToCpuset {
+		cpus, err := readCgroupCpuset(cpuFilesystem(), merged.CgroupRoot)
+		if err != nil || len(cpus) == 0 {
+			l.logger.Warnf("Cannot pin to cpuset %s: %v (continuing unpinned)", "/"+cgroupPath(merged.CgroupRoot, cgroupV2CpusetPath), err)
+		} else {
+			pinnedCPUs = cpus
+			l.logger.Printf("CPU affinity: pinning to cpuset %v", cpus)
+		}
+	}
 
-	// --- 5. Build command and environment ---
-
-	cmdArgs := BuildCommandArgs(merged)
-	env := BuildProcessEnv(merged, limits, l.params.ServiceName, l.params.ServiceVersion)
+	umask := -1
+	if merged.Resources.Umask != "" {
+		// Validated with the static config, so it always parses.
+		umask, _ = ParseUmask(merged.Resources.Umask)
+		l.logger.Printf("File creation mask: umask=%04o", umask)
+	}
 
-	// Overlay CPU env vars
-	cpuEnv := BuildCPUEnv(cpuCount)
-	for _, e := range env {
-		parts := strings.SplitN(e, "=", 2)
-		if len(parts) == 2 {
-			// Only set CPU vars if not already overridden
-			delete(cpuEnv, parts[0])
-		}
+	credential, err := BuildCredential(merged.Resources)
+	if err != nil {
+		return LaunchResult{ExitCode: 1}, err
 	}
-	for k, v := range cpuEnv {
-		env = append(env, k+"="+v)
+	if credential != nil {
+		l.logger.Printf("Running as uid=%d gid=%d", credential.Uid, credential.Gid)
 	}
 
-	// Resolve the executable path
-	executablePath := l.resolvePath(cmdArgs[0])
-	cmdArgs[0] = executablePath
+	// Socket activation only applies to the primary process; subprocesses
+	// keep using env.
+	primaryArgs, primaryEnv := cmdArgs, env
+	var listenFiles []*os.File
+	if merged.SocketActivation {
+		listenFiles, err = InheritedListenFDs()
+		if err != nil {
+			return LaunchResult{ExitCode: 1}, fmt.Errorf("socket activation: %w", err)
+		}
+		if len(listenFiles) > 0 {
+			primaryArgs = socketActivationArgs(cmdArgs)
+			primaryEnv = socketActivationEnv(env, len(listenFiles))
+			l.logger.Printf("Socket activation: passing %d inherited socket(s)", len(listenFiles))
+		} else {
+			l.logger.Println("Socket activation: no LISTEN_FDS for this process")
+		}
+	}
 
 	l.logger.Printf("Launching: %s", strings.Join(cmdArgs, " "))
 
+	if merged.ExecMode || l.params.Exec {
+		l.logger.Warnf("Exec mode: the launcher is replaced by the process; the RSS watchdog, PID file and signal forwarding are disabled")
+		if ignored := execModeIgnored(merged); len(ignored) > 0 {
+			l.logger.Warnf("Exec mode: ignoring %s", strings.Join(ignored, ", "))
+		}
+		err := l.startPinned(pinnedCPUs, func() error {
+			// The nice value belongs to a thread, so it is set on the one
+			// that execs.
+			runtime.LockOSThread()
+			defer runtime.UnlockOSThread()
+			l.applyNice(0, merged.Resources.Nice)
+			return l.exec(execSpec{
+				argv:       primaryArgs,
+				env:        primaryEnv,
+				dir:        workingDir,
+				umask:      umask,
+				credential: credential,
+			})
+		})
+		if err != nil {
+			return LaunchResult{ExitCode: 1}, fmt.Errorf("failed to exec process: %w", err)
+		}
+		return LaunchResult{}, nil
+	}
+
+	if merged.OutputBuffer.Enabled {
+		stdout, logger := l.params.Stdout, l.logger
+		buffered := newBufferedOutput(stdout, merged.OutputBuffer.bufferBytes())
+		l.params.Stdout = buffered
+		l.logger = NewLogger(buffered, merged.Logging)
+		l.limiter.SetLogger(l.logger)
+		stopReports := buffered.reportDrops(l.logger, dropReportInterval)
+		defer func() {
+			stopReports()
+			drained := buffered.Close(outputDrainTimeout)
+			l.params.Stdout, l.logger = stdout, logger
+			l.limiter.SetLogger(logger)
+			if !drained {
+				logger.Warnf("Output buffer: stdout did not accept buffered output within %s; discarding it", outputDrainTimeout)
+			}
+			if dropped := buffered.Dropped(); dropped > 0 {
+				logger.Warnf("Output buffer dropped %d log lines in total", dropped)
+			}
+		}()
+	}
+
 	// --- 6. Fork the process ---
 
-	cmd := exec.Command(cmdArgs[0], cmdArgs[1:]...)
-	cmd.Stdout = l.params.Stdout
-	cmd.Stderr = l.params.Stdout // merge stderr into stdout, same as go-java-launcher
-	cmd.Env = env
-	cmd.Dir = l.params.DistRoot
+	cmd := exec.Command(primaryArgs[0], primaryArgs[1:]...)
+	primaryTag := merged.OutputPrefix.PrimaryTag
+	if primaryTag == "" {
+		primaryTag = defaultPrimaryOutputTag
+	}
+	var flushPrimaryOutput func()
+	cmd.Stdout, cmd.Stderr, flushPrimaryOutput = l.childOutput(merged, primaryTag)
+	cmd.Env = primaryEnv
+	cmd.ExtraFiles = listenFiles
+	cmd.Dir = workingDir
+	if credential != nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{Credential: credential}
+	}
+
+	// The readiness endpoint is bound before the fork, so a port conflict
+	// aborts the launch instead of leaving the process without a probe. It
+	// reports not ready until the process has started.
+	readinessCtx, readinessCancel := context.WithCancel(context.Background())
+	defer readinessCancel()
 
-	if err := cmd.Start(); err != nil {
+	probe := NewReadinessProbe(merged.Readiness, l.logger)
+	debugInfo := NewDebugInfo(limits)
+	debugInfo.ContainerRuntime = merged.ContainerRuntime
+	probe.SetDebugInfo(debugInfo)
+	if err := probe.Start(readinessCtx); err != nil {
+		return LaunchResult{ExitCode: 1}, err
+	}
+
+	var reaper *ChildReaper
+	if reapChildrenEnabled(merged.ReapChildren) {
+		reaper = StartChildReaper(l.logger)
+		defer reaper.Stop()
+		l.logger.Println("Reaping orphaned child processes")
+	}
+
+	// Snapshot the OOM kill counter so a kernel OOM kill can be told apart
+	// from any other SIGKILL. Unavailable outside cgroup v2.
+	oomKillsBefore, oomErr := l.limiter.OOMKillCount()
+
+	if err := l.startPinned(pinnedCPUs, func() error { return reaper.Start(cmd, umask) }); err != nil {
 		return LaunchResult{ExitCode: 1}, fmt.Errorf("failed to start process: %w", err)
 	}
 
 	pid := cmd.Process.Pid
 	l.logger.Printf("Process started: pid=%d", pid)
-
-	// Write PID file
-	pidPath := fmt.Sprintf("var/run/%s.pid", l.params.ServiceName)
-	if err := WritePidFile(pid, pidPath); err != nil {
-		l.logger.Printf("WARNING: failed to write pid file: %v", err)
+	probe.SetChildPid(pid)
+	l.applyNice(pid, merged.Resources.Nice)
+	if merged.Resources.OOMScoreAdj != 0 {
+		if err := ApplyOOMScoreAdj(pid, merged.Resources.OOMScoreAdj); err != nil {
+			l.logger.Warnf("%v (continuing with the inherited OOM score)", err)
+		} else {
+			l.logger.Printf("OOM score: oom_score_adj=%d", merged.Resources.OOMScoreAdj)
+		}
 	}
-	defer RemovePidFile(pidPath)
 
-	// --- 7. Start readiness probe ---
-
-	readinessCtx, readinessCancel := context.WithCancel(context.Background())
-	defer readinessCancel()
+	// exited is closed once the process has been waited for, so the startup
+	// probe can stop early without consuming waitDone.
+	waitDone := make(chan error, 1)
+	exited := make(chan struct{})
+	go func() {
+		err := cmd.Wait()
+		close(exited)
+		waitDone <- err
+	}()
 
-	probe := NewReadinessProbe(merged.Readiness, l.logger)
-	probe.Start(readinessCtx)
-	probe.SetReady()
+	// Write PID file
+	if pidPath := PidFilePath(merged.Paths, l.params.ServiceName); pidPath != "" {
+		pidPath = l.resolvePath(pidPath)
+		if err := WritePidFile(pid, pidPath); err != nil {
+			l.logger.Printf("WARNING: failed to write pid file: %v", err)
+		}
+		defer RemovePidFile(pidPath)
+	} else {
+		l.logger.Println("PID file disabled")
+	}
 
-	// --- 8. Start the RSS watchdog ---
+	// --- 7. Start the RSS watchdog ---
 
 	watchdogCtx, watchdogCancel := context.WithCancel(context.Background())
 	defer watchdogCancel()
 
+	// watchdogFired is closed once the watchdog has sent the first shutdown
+	// signal; the wait below then runs the rest of the sequence.
 	watchdogTriggered := make(chan bool, 1)
+	watchdogFired := make(chan struct{})
+	peakRSS := func() uint64 { return 0 }
+	var watchdog *RSSWatchdog
 
 	if merged.Memory.Mode != MemoryModeUnmanaged && merged.Watchdog.Enabled != nil && *merged.Watchdog.Enabled {
-		watchdog := NewRSSWatchdog(pid, limits, merged.Watchdog, l.logger)
+		watchdog = NewRSSWatchdog(pid, limits, merged.Watchdog, l.logger)
+		watchdog.SetCgroupRoot(merged.CgroupRoot)
+		watchdog.OnMemoryPressure(probe.ReportMemoryPressure)
+		watchdog.SetShutdownSequence(merged.ShutdownSequence)
+		if merged.Watchdog.EventLogFile != "" {
+			eventPath := l.resolvePath(merged.Watchdog.EventLogFile)
+			if eventLog, err := OpenWatchdogEventLog(eventPath); err != nil {
+				l.logger.Warnf("Failed to open watchdog event log %s: %v", eventPath, err)
+			} else {
+				defer eventLog.Close()
+				watchdog.SetEventLog(eventLog)
+			}
+		}
+		if valueOr(merged.Watchdog.RefreshLimits, false) && merged.Memory.Mode == MemoryModeCgroupAware {
+			// One attempt per refresh: a failure keeps the current limits
+			// rather than holding up the poll with retries.
+			refreshConfig := merged
+			refreshConfig.Memory.CgroupReadAttempts = 1
+			watchdog.SetLimitRefresher(func() (MemoryLimits, error) { return l.limiter.ComputeLimits(refreshConfig) })
+		}
+		peakRSS = watchdog.PeakRSS
 		go func() {
 			triggered := watchdog.Run(watchdogCtx)
 			watchdogTriggered <- triggered
+			if triggered {
+				close(watchdogFired)
+			}
 		}()
 	} else {
 		watchdogTriggered <- false
+		if merged.Watchdog.PeakRSSFile != "" {
+			sampler := NewRSSSampler(pid, merged.Watchdog.PollInterval())
+			peakRSS = sampler.PeakRSS
+			go sampler.Run(watchdogCtx)
+		}
 	}
 
-	// --- 9. Forward signals ---
+	// --- 8. Forward signals ---
+
+	// Signals are handled before the startup probe so a SIGTERM while it runs
+	// shuts the process down instead of killing the launcher. terminated is
+	// closed when the launcher receives SIGTERM, which runs the shutdown
+	// sequence instead of being forwarded once; without signal forwarding it
+	// stays open.
+	terminated := make(chan struct{})
+	var remap map[syscall.Signal]syscall.Signal
+	if forwardSignals {
+		// Validated with the static config, so the remap always parses.
+		remap, _ = parseSignalRemap(merged.SignalRemap)
+		for _, name := range sortedKeys(merged.SignalRemap) {
+			in, _ := ParseSignal(name)
+			l.logger.Printf("Signals: forwarding %s as %s", signalName(in), signalName(remap[in]))
+		}
+		terminate := make(chan os.Signal, 1)
+		signal.Notify(terminate, syscall.SIGTERM)
+		defer func() {
+			signal.Stop(terminate)
+			close(terminate)
+		}()
+		go func() {
+			if _, ok := <-terminate; ok {
+				close(terminated)
+			}
+		}()
+		sigChan := forwardSignalsTo(pid, remap, syscall.SIGINT, syscall.SIGHUP)
+		defer func() {
+			signal.Stop(sigChan)
+			close(sigChan)
+		}()
+	}
 
-	sigChan := ForwardSignals(pid)
-	defer func() {
-		close(sigChan)
-	}()
+	if merged.Diagnostics.Enabled {
+		// Validated with the static config, so the signal always parses.
+		sig, _ := diagnosticSignal(merged.Diagnostics)
+		dir := l.diagnosticDir(merged.Diagnostics)
+		diagChan := HandleDiagnosticSignal(sig, pid, dir, func() DiagnosticSnapshot {
+			return launcherSnapshot(pid, limits, watchdog, peakRSS)
+		}, l.logger)
+		defer func() {
+			signal.Stop(diagChan)
+			close(diagChan)
+		}()
+		l.logger.Printf("Diagnostics: send %s to dump state to %s", merged.Diagnostics.signalName(), dir)
+	}
+
+	// --- 9. Run the startup probe, then mark the readiness probe ready ---
+
+	// shuttingDown reports whether a SIGTERM, the watchdog or cancellation has
+	// begun stopping the process; the wait below finishes the shutdown.
+	shuttingDown := func() bool {
+		select {
+		case <-terminated:
+		case <-watchdogFired:
+		case <-ctx.Done():
+		default:
+			return false
+		}
+		return true
+	}
+
+	if merged.Startup.Enabled() {
+		// The probe stops as soon as a shutdown begins rather than running
+		// out its timeout.
+		probeCtx, stopProbe := context.WithCancel(ctx)
+		go func() {
+			select {
+			case <-terminated:
+			case <-watchdogFired:
+			case <-probeCtx.Done():
+			}
+			stopProbe()
+		}()
+		err := RunStartupProbe(probeCtx, merged.Startup, l.params.DistRoot, reaper, exited, l.logger)
+		stopProbe()
+		if err != nil {
+			steps := resolveShutdownSequence(merged.ShutdownSequence, time.Duration(merged.Watchdog.GracePeriodSeconds)*time.Second)
+			l.logger.Errorf("%v; sending %s to pid %d", err, signalName(steps[0].signal), pid)
+			l.notifyShutdown(notifyPath, steps)
+			_ = terminateAndWait(cmd.Process, waitDone, steps, l.logger)
+			flushPrimaryOutput()
+			return LaunchResult{ExitCode: 1, Duration: time.Since(startTime)}, err
+		}
+	}
+
+	// Once a shutdown has begun the process is never marked ready and the
+	// subprocesses are not started.
+	subProcesses := merged.SubProcesses
+	if shuttingDown() {
+		subProcesses = nil
+	} else if warmup := merged.Readiness.Warmup; warmup.URL != "" {
+		go func() {
+			l.logger.Printf("Warming up %s before marking ready", warmup.URL)
+			if err := RunWarmup(readinessCtx, warmup, l.logger); err != nil {
+				if readinessCtx.Err() != nil {
+					return
+				}
+				l.logger.Warnf("Warmup skipped: %v", err)
+			}
+			probe.SetReady()
+		}()
+	} else {
+		probe.SetReady()
+	}
 
 	// --- 10. Launch subprocesses ---
 
+	// Each subprocess is waited for as soon as it starts, so a critical one
+	// exiting can end the launch; criticalExit carries the first such exit.
 	var subCmds []*exec.Cmd
-	for _, sub := range merged.SubProcesses {
+	var subFlushes []func()
+	var subWaits []chan error
+	criticalExit := make(chan string, 1)
+	reportCritical := func(message string) {
+		select {
+		case criticalExit <- message:
+		default:
+		}
+	}
+	for _, sub := range subProcesses {
 		subCmd := exec.Command(l.resolvePath(sub.Executable), sub.Args...)
-		subCmd.Stdout = l.params.Stdout
-		subCmd.Stderr = l.params.Stdout
+		var flushSubOutput func()
+		subCmd.Stdout, subCmd.Stderr, flushSubOutput = l.childOutput(merged, sub.Name)
 		subCmd.Dir = l.params.DistRoot
+		if credential != nil {
+			subCmd.SysProcAttr = &syscall.SysProcAttr{Credential: credential}
+		}
 
 		// Build subprocess env: inherit from parent, overlay subprocess-specific
 		subEnv := make([]string, len(env))
@@ -258,17 +678,59 @@ func (l *Launcher) Launch() (LaunchResult, error) {
 		}
 		subCmd.Env = subEnv
 
-		if err := subCmd.Start(); err != nil {
+		if err := l.startPinned(pinnedCPUs, func() error { return reaper.Start(subCmd, umask) }); err != nil {
 			l.logger.Printf("WARNING: failed to start subprocess %s: %v", sub.Name, err)
+			if sub.Critical {
+				reportCritical(fmt.Sprintf("critical subprocess %s failed to start: %v", sub.Name, err))
+			}
 			continue
 		}
 		l.logger.Printf("Subprocess started: name=%s pid=%d", sub.Name, subCmd.Process.Pid)
+		l.applyNice(subCmd.Process.Pid, merged.Resources.Nice)
+		subWait := make(chan error, 1)
+		go func(name string, critical bool) {
+			err := subCmd.Wait()
+			subWait <- err
+			if critical {
+				reportCritical(fmt.Sprintf("critical subprocess %s exited (%s)", name, describeExit(err)))
+			}
+		}(sub.Name, sub.Critical)
 		subCmds = append(subCmds, subCmd)
+		subFlushes = append(subFlushes, flushSubOutput)
+		subWaits = append(subWaits, subWait)
 	}
 
 	// --- 11. Wait for primary process exit ---
 
-	waitErr := cmd.Wait()
+	var waitErr error
+	cancelled := false
+	criticalFailure := ""
+	select {
+	case waitErr = <-waitDone:
+	case <-watchdogFired:
+		waitErr = watchdog.CompleteShutdown(waitDone)
+	case <-ctx.Done():
+		cancelled = true
+		steps := resolveShutdownSequence(merged.ShutdownSequence, time.Duration(merged.Watchdog.GracePeriodSeconds)*time.Second)
+		l.logger.Printf("Launch cancelled (%v), sending %s to pid %d", ctx.Err(), signalName(steps[0].signal), pid)
+		l.notifyShutdown(notifyPath, steps)
+		waitErr = terminateAndWait(cmd.Process, waitDone, steps, l.logger)
+	case <-terminated:
+		steps := resolveShutdownSequence(merged.ShutdownSequence, time.Duration(merged.Watchdog.GracePeriodSeconds)*time.Second)
+		// A remapped SIGTERM replaces a leading SIGTERM step.
+		if out, ok := remap[syscall.SIGTERM]; ok && steps[0].signal == syscall.SIGTERM {
+			steps[0].signal = out
+		}
+		l.logger.Printf("Received SIGTERM, sending %s to pid %d", signalName(steps[0].signal), pid)
+		l.notifyShutdown(notifyPath, steps)
+		waitErr = terminateAndWait(cmd.Process, waitDone, steps, l.logger)
+	case criticalFailure = <-criticalExit:
+		steps := resolveShutdownSequence(merged.ShutdownSequence, time.Duration(merged.Watchdog.GracePeriodSeconds)*time.Second)
+		l.logger.Errorf("%s, sending %s to pid %d", criticalFailure, signalName(steps[0].signal), pid)
+		l.notifyShutdown(notifyPath, steps)
+		waitErr = terminateAndWait(cmd.Process, waitDone, steps, l.logger)
+	}
+	flushPrimaryOutput()
 	watchdogCancel() // stop the watchdog
 	readinessCancel()
 
@@ -278,16 +740,28 @@ func (l *Launcher) Launch() (LaunchResult, error) {
 	duration := time.Since(startTime)
 
 	// Cleanup subprocesses
-	for _, subCmd := range subCmds {
-		if subCmd.Process != nil {
-			_ = subCmd.Process.Kill()
-			_ = subCmd.Wait()
-		}
+	for i, subCmd := range subCmds {
+		_ = subCmd.Process.Kill()
+		<-subWaits[i]
+		subFlushes[i]()
 	}
 
 	// Determine exit code
 	result := LaunchResult{
-		Duration: duration,
+		Duration:                  duration,
+		Cancelled:                 cancelled,
+		CriticalSubprocessFailure: criticalFailure,
+	}
+
+	result.PeakRSSBytes = peakRSS()
+	if kernelPeak := processMaxRSS(cmd.ProcessState); kernelPeak > result.PeakRSSBytes {
+		result.PeakRSSBytes = kernelPeak
+	}
+	if merged.Watchdog.PeakRSSFile != "" {
+		peakPath := l.resolvePath(merged.Watchdog.PeakRSSFile)
+		if err := WritePeakRSSFile(peakPath, result.PeakRSSBytes); err != nil {
+			l.logger.Printf("WARNING: failed to write peak RSS file %s: %v", peakPath, err)
+		}
 	}
 
 	// Check if watchdog triggered
@@ -307,13 +781,436 @@ func (l *Launcher) Launch() (LaunchResult, error) {
 	} else {
 		result.ExitCode = 0
 	}
+	if criticalFailure != "" {
+		result.ExitCode = ExitCodeCriticalSubprocess
+	}
+
+	if oomErr == nil && killedBySIGKILL(cmd.ProcessState) {
+		if after, err := l.limiter.OOMKillCount(); err == nil && after > oomKillsBefore {
+			result.OOMKilled = true
+			l.logger.Warnf("Process was killed by the kernel OOM killer (cgroup oom_kill %d -> %d), "+
+				"not by the watchdog; consider lowering memory.maxRssPercent or watchdog.hardLimitPercent",
+				oomKillsBefore, after)
+		}
+	}
 
-	l.logger.Printf("Process exited: code=%d duration=%s watchdog_triggered=%t",
-		result.ExitCode, duration.Round(time.Millisecond), result.WatchdogTriggered)
+	l.logger.Printf("Process exited: code=%d duration=%s watchdog_triggered=%t oom_killed=%t peak_rss=%s",
+		result.ExitCode, duration.Round(time.Millisecond), result.WatchdogTriggered, result.OOMKilled,
+		formatBytes(result.PeakRSSBytes))
+
+	if merged.Metrics.PushGatewayURL != "" {
+		err := PushJobMetrics(context.Background(), merged.Metrics, l.params.ServiceName, JobMetrics{
+			ExitCode:     result.ExitCode,
+			Duration:     duration,
+			PeakRSSBytes: result.PeakRSSBytes,
+		})
+		if err != nil {
+			l.logger.Warnf("Failed to push metrics: %v", err)
+		}
+	}
+
+	if mapped, ok := merged.ExitCodeMap[result.ExitCode]; ok {
+		l.logger.Printf("Exit code %d mapped to %d by exitCodeMap", result.ExitCode, mapped)
+		result.ExitCode = mapped
+	}
 
 	return result, nil
 }
 
+// describeExit describes a cmd.Wait result for logs, e.g. "exit status 1".
+func describeExit(err error) string {
+	if err == nil {
+		return "exit status 0"
+	}
+	return err.Error()
+}
+
+// killedBySIGKILL reports whether the process was terminated by SIGKILL.
+func killedBySIGKILL(state *os.ProcessState) bool {
+	if state == nil {
+		return false
+	}
+	status, ok := state.Sys().(syscall.WaitStatus)
+	return ok && status.Signaled() && status.Signal() == syscall.SIGKILL
+}
+
+// Validate resolves the configuration, limits, and process environment exactly
+// as Launch would, without creating directories or starting any process.
+// It returns the startup lint warnings; an error means the launch would fail.
+func (l *Launcher) Validate() ([]string, error) {
+	plan, err := l.plan()
+	if err != nil {
+		return nil, err
+	}
+	warnings := append([]string{}, plan.config.MemoryWarnings...)
+	warnings = append(warnings, ShutdownSequenceWarnings(plan.config.ShutdownSequence)...)
+	return append(warnings, CheckThreadOversubscription(plan.env, plan.config.EffectiveCPUCount)...), nil
+}
+
+// ResolveEnv resolves the configuration exactly as Launch would and returns the
+// environment the primary process would receive, sorted by name. Unless
+// showSecrets is set, values from EnvFromFile and variables with secret-looking
+// names are redacted.
+func (l *Launcher) ResolveEnv(showSecrets bool) ([]string, error) {
+	plan, err := l.plan()
+	if err != nil {
+		return nil, err
+	}
+	env := append([]string{}, plan.env...)
+	if !showSecrets {
+		env = RedactEnv(env, plan.secretEnv)
+	}
+	sort.Strings(env)
+	return env, nil
+}
+
+// ExplainConfig resolves the configuration exactly as Launch would and reports
+// where each high-value field and env key came from.
+func (l *Launcher) ExplainConfig() ([]FieldProvenance, error) {
+	plan, err := l.plan()
+	if err != nil {
+		return nil, err
+	}
+	return ExplainConfig(plan.config), nil
+}
+
+// PidFile resolves the static config exactly as Launch would and returns the
+// absolute path of the PID file it writes, or "" when the PID file is
+// disabled. An unreadable or invalid static config is an error.
+func (l *Launcher) PidFile() (string, error) {
+	staticConfig, err := readStaticConfig(l.staticConfigPath(), l.resolvePath)
+	if err != nil {
+		return "", fmt.Errorf("config error: %w", err)
+	}
+	normalizePercentages(&staticConfig.Memory, &staticConfig.Watchdog)
+	if err := validateStaticConfig(staticConfig); err != nil {
+		return "", fmt.Errorf("config error: invalid static config: %w", err)
+	}
+	pidPath := PidFilePath(staticConfig.Paths, l.params.ServiceName)
+	if pidPath == "" {
+		return "", nil
+	}
+	return l.resolvePath(pidPath), nil
+}
+
+// staticConfigPath returns the static config to read: StaticConfigPath under
+// the distribution root, or when it was defaulted and is missing, the first
+// static config found on the search path.
+func (l *Launcher) staticConfigPath() string {
+	staticPath := l.resolvePath(l.params.StaticConfigPath)
+	if !l.searchStaticConfig {
+		return staticPath
+	}
+	found, ok := FindStaticConfig(staticPath, StaticConfigSearchPaths(l.params.SearchDir, os.Getenv))
+	if ok && found != staticPath {
+		l.logger.Printf("Static config not found at %s; using %s", staticPath, found)
+	}
+	return found
+}
+
+// plan reads and merges the configs, detects CPU and memory limits, and builds
+// the command line and environment for the primary process.
+func (l *Launcher) plan() (launchPlan, error) {
+	// --- 1. Read and merge configs ---
+
+	staticPath := l.staticConfigPath()
+	customPath := l.resolvePath(l.params.CustomConfigPath)
+
+	var staticConfig StaticLauncherConfig
+	var customConfig CustomLauncherConfig
+	var err error
+	if l.params.Check {
+		staticConfig, err = getCheckConfigFromFile(staticPath, l.resolvePath)
+	} else {
+		staticConfig, customConfig, err = getConfigsFromFiles(staticPath, customPath, l.params.ConfigFetcher, l.params.Stdout, l.resolvePath)
+	}
+	if err != nil {
+		return launchPlan{}, fmt.Errorf("config error: %w", err)
+	}
+
+	merged := MergeConfigs(staticConfig, customConfig)
+	if merged.TemplateEnabled {
+		hostname, _ := os.Hostname()
+		merged, err = ExpandTemplates(merged, TemplateContext{
+			ServiceName:    l.params.ServiceName,
+			ServiceVersion: l.params.ServiceVersion,
+			Hostname:       hostname,
+		})
+		if err != nil {
+			return launchPlan{}, fmt.Errorf("config error: %w", err)
+		}
+	}
+	hash, err := configHash(staticConfig, customConfig)
+	if err != nil {
+		return launchPlan{}, fmt.Errorf("config error: %w", err)
+	}
+
+	if l.params.Dev {
+		merged = applyDevMode(merged)
+	}
+
+	// Re-initialize logger with config-specified settings
+	l.logger = NewLogger(l.params.Stdout, merged.Logging)
+	l.limiter.SetLogger(l.logger)
+	l.limiter.SetCgroupRoot(merged.CgroupRoot)
+	if l.params.Dev {
+		l.logger.Printf("Dev mode: PID file disabled, debug logging, watchdog enabled=%t", valueOr(merged.Watchdog.Enabled, false))
+	}
+	var watchPaths []string
+	if l.params.Watch {
+		if merged.ExecMode || l.params.Exec {
+			return launchPlan{}, errors.New("watch mode cannot be combined with exec mode")
+		}
+		watchPaths = []string{l.resolvePath(merged.Executable)}
+		if _, err := os.Stat(watchPaths[0]); err != nil {
+			l.logger.Warnf("Watch: executable %s is not a file (%v); only its creation will restart the process", watchPaths[0], err)
+		}
+		if l.params.WatchConfig {
+			watchPaths = append(watchPaths, staticPath, customPath)
+		}
+		l.logger.Printf("Watch: restarting the process when any of %s changes", strings.Join(watchPaths, ", "))
+	}
+
+	l.logConfig(merged)
+	for _, warning := range merged.MemoryWarnings {
+		l.logger.Warnf("%s", warning)
+	}
+	for _, warning := range ShutdownSequenceWarnings(merged.ShutdownSequence) {
+		l.logger.Warnf("%s", warning)
+	}
+
+	if merged.ResolveInterpreterFromShebang && merged.PythonPath == "" && merged.LaunchMode == LaunchModePEX {
+		interpreter, args, err := ReadShebangInterpreter(l.resolvePath(merged.Executable))
+		if err != nil {
+			return launchPlan{}, fmt.Errorf("pex interpreter check failed: %w", err)
+		}
+		merged.PythonPath = interpreter
+		merged.PythonOpts = append(args, merged.PythonOpts...)
+		l.logger.Printf("Python interpreter %s resolved from the PEX shebang", interpreter)
+	}
+
+	if merged.RequirePythonVersion != "" && merged.PythonPath != "" && merged.LaunchMode != LaunchModeCommand {
+		pythonPath := ResolveEnvVarPath(merged.PythonPath, merged.Env)
+		if err := verifyPythonVersion(pythonPath, merged.RequirePythonVersion); err != nil {
+			return launchPlan{}, fmt.Errorf("python interpreter check failed: %w", err)
+		}
+		l.logger.Printf("Python interpreter %s satisfies %s", pythonPath, merged.RequirePythonVersion)
+	}
+
+	merged.LauncherVersion = l.params.LauncherVersion
+	merged.LauncherCommit = l.params.LauncherCommit
+
+	// --- CPU detection ---
+	cpuCount := DetectCPUCount(merged.CPU, merged.CgroupRoot, cpuFilesystem())
+	merged.EffectiveCPUCount = cpuCount
+	l.logger.Printf("CPU: detected %d effective CPUs", cpuCount)
+
+	// --- 2. Compute memory limits ---
+
+	limits, err := l.limiter.ComputeLimits(merged)
+	if err != nil {
+		// Memory limit detection failure is non-fatal in non-container environments.
+		// In containers, it's a hard error because we need the watchdog.
+		if merged.IsContainer {
+			return launchPlan{}, fmt.Errorf("memory limit detection failed in container: %w", err)
+		}
+		l.logger.Printf("WARNING: failed to detect memory limits: %v (continuing with unmanaged memory)", err)
+		merged.Memory.Mode = MemoryModeUnmanaged
+		limits = MemoryLimits{}
+	}
+	merged.EffectiveMemoryLimitBytes = limits.EffectiveLimitBytes
+
+	if limits.EffectiveLimitBytes > 0 {
+		l.logger.Printf("Memory limits: cgroup=%s effective=%s mode=%s source=%s",
+			formatBytes(limits.CgroupLimitBytes),
+			formatBytes(limits.EffectiveLimitBytes),
+			merged.Memory.Mode,
+			limits.LimitSource,
+		)
+	}
+	if limits.ClampedToFloor {
+		l.logger.Printf("WARNING: computed memory limit is below the %s floor; using %s (cgroup=%s). The container is likely under-provisioned",
+			formatBytes(minimumEffectiveLimitBytes), formatBytes(limits.EffectiveLimitBytes), formatBytes(limits.CgroupLimitBytes))
+	}
+
+	// --- 5. Build command and environment ---
+
+	if merged.ArgsFile != "" {
+		fileArgs, err := ReadArgsFile(l.resolvePath(merged.ArgsFile))
+		if err != nil {
+			return launchPlan{}, err
+		}
+		merged.Args = append(merged.Args, fileArgs...)
+	}
+	argsConfig := merged
+	if merged.WorkingDir != "" && isPathExecutable(merged.LaunchMode) {
+		// The process runs from WorkingDir, but the executable is relative
+		// to the distribution root.
+		argsConfig.Executable = l.resolvePath(merged.Executable)
+	}
+	cmdArgs := BuildCommandArgs(argsConfig)
+	// File-sourced values layer at the same precedence as config env. They are
+	// kept out of merged so they never appear in logged configuration.
+	envConfig := merged
+	if len(merged.EnvFromFile) > 0 {
+		fileEnv, err := ReadEnvFromFiles(merged.EnvFromFile, l.resolvePath)
+		if err != nil {
+			return launchPlan{}, err
+		}
+		envConfig.Env = make(map[string]string, len(merged.Env)+len(fileEnv))
+		for k, v := range merged.Env {
+			envConfig.Env[k] = v
+		}
+		for k, v := range fileEnv {
+			envConfig.Env[k] = v
+		}
+	}
+	if valueOr(merged.BytecodeCaching, false) {
+		// The child may run from another working directory.
+		cacheDir := merged.BytecodeCacheDir
+		if cacheDir == "" {
+			cacheDir = defaultBytecodeCacheDir
+		}
+		envConfig.BytecodeCacheDir = l.resolvePath(cacheDir)
+	}
+	if len(merged.PythonPathEntries) > 0 {
+		envConfig.PythonPathEntries = make([]string, len(merged.PythonPathEntries))
+		for i, entry := range merged.PythonPathEntries {
+			envConfig.PythonPathEntries[i] = l.resolvePath(entry)
+		}
+	}
+	if merged.WorkingDir != "" {
+		// Keep TMPDIR on the directory created under the distribution root,
+		// and modules importable from it.
+		tmpDir := merged.Paths.TmpDir
+		if tmpDir == "" {
+			tmpDir = defaultTmpDir
+		}
+		envConfig.Paths.TmpDir = l.resolvePath(tmpDir)
+		if !isPathExecutable(merged.LaunchMode) && merged.LaunchMode != LaunchModeCommand {
+			envConfig.PythonPathEntries = append(envConfig.PythonPathEntries, filepath.Clean(l.params.DistRoot))
+		}
+	}
+	env := BuildProcessEnv(envConfig, limits, l.params.ServiceName, l.params.ServiceVersion)
+	for _, override := range MemoryEnvOverrides(envConfig, limits) {
+		l.logger.Printf("Memory env: %s", override)
+	}
+
+	env = overlayCPUEnv(env, envConfig, cpuCount)
+
+	if merged.Diagnostics.Enabled {
+		env = appendDiagnosticEnv(env, merged.Diagnostics, l.diagnosticDir(merged.Diagnostics))
+	}
+	if merged.Memory.DescriptorFile != "" {
+		env = appendLimitsDescriptorEnv(env, l.resolvePath(merged.Memory.DescriptorFile))
+	}
+	if merged.ShutdownNotifyFile != "" {
+		env = appendShutdownNotifyEnv(env, l.resolvePath(merged.ShutdownNotifyFile))
+	}
+
+	for _, warning := range CheckThreadOversubscription(env, cpuCount) {
+		l.logger.Warnf("%s", warning)
+	}
+
+	// Resolve the executable path
+	executablePath := l.resolvePath(cmdArgs[0])
+	cmdArgs[0] = executablePath
+
+	return launchPlan{
+		config:  merged,
+		limits:  limits,
+		cmdArgs: cmdArgs,
+		env:     env,
+
+		secretEnv:  sortedKeys(merged.EnvFromFile),
+		configHash: hash,
+		watchPaths: watchPaths,
+	}, nil
+}
+
+// isPathExecutable reports whether mode passes the executable to Python as a
+// file path rather than a module name.
+func isPathExecutable(mode LaunchMode) bool {
+	return mode == LaunchModePEX || mode == LaunchModeScript || mode == ""
+}
+
+// applyDevMode returns config adjusted for local development: the PID file is
+// disabled, logging is text at debug level, and the watchdog is disabled
+// unless the static or custom config set watchdog.enabled explicitly.
+func applyDevMode(config MergedConfig) MergedConfig {
+	disabled := false
+	config.Paths.PidFileEnabled = &disabled
+	config.Logging.Format = LogFormatText
+	config.Logging.Level = "debug"
+	if provenanceSource(config, "watchdog.enabled") == ConfigSourceDefault {
+		config.Watchdog.Enabled = &disabled
+	}
+	return config
+}
+
+// applyNice sets the nice value of the started process pid, or of the calling
+// thread when pid is 0. A failure only warns.
+func (l *Launcher) applyNice(pid, nice int) {
+	if nice == 0 {
+		return
+	}
+	if err := ApplyNice(pid, nice); err != nil {
+		l.logger.Warnf("%v (continuing at current priority)", err)
+		return
+	}
+	l.logger.Printf("Scheduling priority: nice=%d pid=%d", nice, pid)
+}
+
+// notifyShutdown writes the shutdown notice to path, when one is configured,
+// for a shutdown about to run steps. A failed write only warns.
+func (l *Launcher) notifyShutdown(path string, steps []shutdownStep) {
+	if path == "" {
+		return
+	}
+	now := time.Now()
+	deadline := shutdownDeadline(steps, now)
+	if err := WriteShutdownNotice(path, now, deadline); err != nil {
+		l.logger.Warnf("Failed to write shutdown notice %s: %v", path, err)
+		return
+	}
+	l.logger.Printf("Shutdown notice written to %s (deadline %s)", path, deadline.Format(time.RFC3339))
+}
+
+// childStderr returns where the process's stderr goes: merged into Stdout,
+// same as go-java-launcher, unless mergeStderr is false and a separate writer
+// was provided.
+func (l *Launcher) childStderr(config MergedConfig) io.Writer {
+	if config.MergeStderr != nil && !*config.MergeStderr && l.params.Stderr != nil {
+		return l.params.Stderr
+	}
+	return l.params.Stdout
+}
+
+// childOutput returns a process's stdout and stderr writers. With
+// outputPrefix enabled each line is tagged with "[tag] "; the returned flush
+// writes out an unterminated last line and must be called after the process
+// has been waited for.
+func (l *Launcher) childOutput(config MergedConfig, tag string) (stdout, stderr io.Writer, flush func()) {
+	stdout, stderr = l.params.Stdout, l.childStderr(config)
+	if !config.OutputPrefix.Enabled {
+		return stdout, stderr, func() {}
+	}
+	prefixedOut := newPrefixWriter(stdout, tag)
+	prefixedErr := newPrefixWriter(stderr, tag)
+	return prefixedOut, prefixedErr, func() {
+		_ = prefixedOut.Flush()
+		_ = prefixedErr.Flush()
+	}
+}
+
+// diagnosticDir returns the absolute directory for diagnostic snapshots.
+func (l *Launcher) diagnosticDir(config DiagnosticsConfig) string {
+	if config.Dir == "" {
+		return l.resolvePath(defaultDiagnosticDir)
+	}
+	return l.resolvePath(config.Dir)
+}
+
 // resolvePath resolves a path relative to the distribution root.
 func (l *Launcher) resolvePath(path string) string {
 	if filepath.IsAbs(path) {
@@ -326,15 +1223,24 @@ func (l *Launcher) resolvePath(path string) string {
 func (l *Launcher) logConfig(config MergedConfig) {
 	l.logger.Printf("Config: executable=%s entryPoint=%s pythonPath=%s",
 		config.Executable, config.EntryPoint, config.PythonPath)
+	if config.PythonVersion != "" {
+		l.logger.Printf("Config: pythonVersion=%s selected from pythonVersions", config.PythonVersion)
+	}
 	l.logger.Printf("Config: memory.mode=%s memory.maxRssPercent=%.0f%% memory.fragBuffer=%.0f%%",
-		config.Memory.Mode, config.Memory.MaxRSSPercent, config.Memory.HeapFragmentationBuffer*100)
+		config.Memory.Mode, config.Memory.MaxRSSPercent, valueOr(config.Memory.HeapFragmentationBuffer, 0)*100)
 	if len(config.Args) > 0 {
 		l.logger.Printf("Config: args=%v", config.Args)
 	}
+	if config.WorkingDir != "" {
+		l.logger.Printf("Config: workingDir=%s", config.WorkingDir)
+	}
+	for _, name := range sortedKeys(config.EnvFromFile) {
+		l.logger.Printf("Config: envFromFile %s=<redacted from %s>", name, config.EnvFromFile[name])
+	}
 	if config.Watchdog.Enabled != nil {
-		l.logger.Printf("Config: watchdog.enabled=%t watchdog.poll=%ds watchdog.soft=%.0f%% watchdog.hard=%.0f%%",
+		l.logger.Printf("Config: watchdog.enabled=%t watchdog.poll=%s watchdog.soft=%.0f%% watchdog.hard=%.0f%%",
 			*config.Watchdog.Enabled,
-			config.Watchdog.PollIntervalSeconds,
+			config.Watchdog.PollInterval(),
 			config.Watchdog.SoftLimitPercent,
 			config.Watchdog.HardLimitPercent,
 		)
@@ -342,4 +1248,7 @@ func (l *Launcher) logConfig(config MergedConfig) {
 	if config.IsContainer {
 		l.logger.Println("Config: running in container mode")
 	}
+	if config.ContainerRuntime != "" {
+		l.logger.Printf("Config: container runtime=%s", config.ContainerRuntime)
+	}
 }
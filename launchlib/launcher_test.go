@@ -0,0 +1,2008 @@
+package launchlib
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// syncBuffer is a bytes.Buffer safe for concurrent writes from the launcher
+// logger and the child process output copier.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// newTestLauncher writes staticYAML into a fresh distribution root and returns
+// a Launcher for it along with its captured output.
+func newTestLauncher(t *testing.T, staticYAML string) (*Launcher, *syncBuffer) {
+	t.Helper()
+	distRoot := t.TempDir()
+	staticPath := filepath.Join(distRoot, defaultStaticConfigPath)
+	if err := os.MkdirAll(filepath.Dir(staticPath), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(staticPath, []byte(staticYAML), 0644); err != nil {
+		t.Fatal(err)
+	}
+	out := &syncBuffer{}
+	launcher := NewLauncher(LauncherParams{
+		DistRoot:       distRoot,
+		ServiceName:    "test-service",
+		ServiceVersion: "1.0.0",
+		Stdout:         out,
+	})
+	return launcher, out
+}
+
+func TestLaunchWithContextCancel(t *testing.T) {
+	launcher, out := newTestLauncher(t, `
+configType: python
+configVersion: 1
+launchMode: command
+executable: /bin/sh
+args: ["-c", "exec sleep 30"]
+memory:
+  mode: unmanaged
+watchdog:
+  gracePeriodSeconds: 5
+`)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(200*time.Millisecond, cancel)
+
+	start := time.Now()
+	result, err := launcher.LaunchWithContext(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v\n%s", err, out)
+	}
+	if !result.Cancelled {
+		t.Error("expected result to report cancellation")
+	}
+	if result.ExitCode != -1 {
+		t.Errorf("expected signaled exit code -1, got %d", result.ExitCode)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("child was not terminated promptly (took %s)", elapsed)
+	}
+}
+
+func TestLaunchWithContextCancelEscalatesToSIGKILL(t *testing.T) {
+	launcher, out := newTestLauncher(t, `
+configType: python
+configVersion: 1
+launchMode: command
+executable: /bin/sh
+args: ["-c", "trap '' TERM; exec sleep 30"]
+memory:
+  mode: unmanaged
+watchdog:
+  gracePeriodSeconds: 1
+`)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(200*time.Millisecond, cancel)
+
+	result, err := launcher.LaunchWithContext(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v\n%s", err, out)
+	}
+	if !result.Cancelled || result.ExitCode != -1 {
+		t.Errorf("expected cancelled signaled exit, got %+v", result)
+	}
+	if !bytes.Contains([]byte(out.String()), []byte("sending SIGKILL")) {
+		t.Errorf("expected SIGKILL escalation in output, got:\n%s", out)
+	}
+}
+
+func TestLaunchWritesPeakRSSFile(t *testing.T) {
+	launcher, out := newTestLauncher(t, `
+configType: python
+configVersion: 1
+launchMode: command
+executable: /bin/sh
+args: ["-c", "exec sleep 0.3"]
+memory:
+  mode: unmanaged
+watchdog:
+  peakRssFile: var/log/peak-rss
+`)
+
+	result, err := launcher.Launch()
+	if err != nil {
+		t.Fatalf("unexpected error: %v\n%s", err, out)
+	}
+
+	data, err := os.ReadFile(filepath.Join(launcher.params.DistRoot, "var/log/peak-rss"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	peak, err := strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+	if err != nil {
+		t.Fatalf("peak RSS file is not a byte count: %q", data)
+	}
+	// Any real process needs at least a few pages and far less than a terabyte.
+	if peak < 4096 || peak > 1<<40 {
+		t.Errorf("implausible peak RSS %d", peak)
+	}
+	if peak != result.PeakRSSBytes {
+		t.Errorf("file peak %d does not match result peak %d", peak, result.PeakRSSBytes)
+	}
+}
+
+func TestLaunchCustomPidFile(t *testing.T) {
+	launcher, out := newTestLauncher(t, `
+configType: python
+configVersion: 1
+launchMode: command
+executable: /bin/sh
+args: ["-c", "sleep 0.5; exec cat run/custom-test-service.pid"]
+memory:
+  mode: unmanaged
+paths:
+  pidFile: run/custom-%s.pid
+`)
+
+	result, err := launcher.Launch()
+	if err != nil {
+		t.Fatalf("unexpected error: %v\n%s", err, out)
+	}
+	if result.ExitCode != 0 {
+		t.Fatalf("expected pid file at custom path while running, got exit %d\n%s", result.ExitCode, out)
+	}
+	if _, err := os.Stat(filepath.Join(launcher.params.DistRoot, "run/custom-test-service.pid")); !os.IsNotExist(err) {
+		t.Errorf("expected pid file removed after exit, stat err = %v", err)
+	}
+}
+
+func TestLaunchPidFileDisabled(t *testing.T) {
+	launcher, out := newTestLauncher(t, `
+configType: python
+configVersion: 1
+launchMode: command
+executable: /bin/sh
+args: ["-c", "sleep 0.5; test ! -e var/run/test-service.pid"]
+memory:
+  mode: unmanaged
+paths:
+  pidFileEnabled: false
+`)
+
+	result, err := launcher.Launch()
+	if err != nil {
+		t.Fatalf("unexpected error: %v\n%s", err, out)
+	}
+	if result.ExitCode != 0 {
+		t.Errorf("expected no pid file while running, got exit %d\n%s", result.ExitCode, out)
+	}
+}
+
+func TestLaunchDirs(t *testing.T) {
+	tests := []struct {
+		name       string
+		dirs       string
+		wantExist  []string
+		wantAbsent []string
+	}{
+		{"unset uses defaults", "", []string{"var/data/tmp", "var/log", "var/run"}, nil},
+		{"empty creates nothing", "dirs: []\n", nil, []string{"var/data/tmp", "var/log"}},
+		{"custom replaces defaults", "dirs: [var/cache]\n", []string{"var/cache"}, []string{"var/data/tmp", "var/log"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			launcher, out := newTestLauncher(t, `
+configType: python
+configVersion: 1
+launchMode: command
+executable: /bin/true
+memory:
+  mode: unmanaged
+`+tt.dirs)
+			if _, err := launcher.Launch(); err != nil {
+				t.Fatalf("unexpected error: %v\n%s", err, out)
+			}
+			for _, dir := range tt.wantExist {
+				if info, err := os.Stat(filepath.Join(launcher.params.DistRoot, dir)); err != nil || !info.IsDir() {
+					t.Errorf("expected %s to be created, got %v", dir, err)
+				}
+			}
+			for _, dir := range tt.wantAbsent {
+				if _, err := os.Stat(filepath.Join(launcher.params.DistRoot, dir)); !os.IsNotExist(err) {
+					t.Errorf("expected %s not to be created, got %v", dir, err)
+				}
+			}
+		})
+	}
+}
+
+func TestLaunchDirsInvalidModeCreatesNothing(t *testing.T) {
+	launcher, out := newTestLauncher(t, `
+configType: python
+configVersion: 1
+launchMode: command
+executable: /bin/true
+memory:
+  mode: unmanaged
+dirs:
+  - var/cache
+  - path: var/secrets
+    mode: rwx
+`)
+	if _, err := launcher.Launch(); err == nil {
+		t.Fatalf("expected an error for an invalid dir mode\n%s", out)
+	}
+	for _, dir := range []string{"var/cache", "var/secrets"} {
+		if _, err := os.Stat(filepath.Join(launcher.params.DistRoot, dir)); !os.IsNotExist(err) {
+			t.Errorf("expected %s not to be created, got %v", dir, err)
+		}
+	}
+}
+
+func TestLaunchWorkingDirOverride(t *testing.T) {
+	launcher, out := newTestLauncher(t, `
+configType: python
+configVersion: 1
+launchMode: command
+executable: /bin/sh
+args: ["-c", "pwd -P > ../cwd"]
+memory:
+  mode: unmanaged
+dirs: [var/log, var/run, app/conf]
+workingDir: app/conf
+`)
+
+	result, err := launcher.Launch()
+	if err != nil {
+		t.Fatalf("unexpected error: %v\n%s", err, out)
+	}
+	if result.ExitCode != 0 {
+		t.Fatalf("expected exit 0, got %d\n%s", result.ExitCode, out)
+	}
+
+	data, err := os.ReadFile(filepath.Join(launcher.params.DistRoot, "app/cwd"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := filepath.EvalSymlinks(filepath.Join(launcher.params.DistRoot, "app/conf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.TrimSpace(string(data)); got != want {
+		t.Errorf("expected child cwd %s, got %s", want, got)
+	}
+}
+
+func TestLaunchWorkingDirScriptMode(t *testing.T) {
+	launcher, out := newTestLauncher(t, `
+configType: python
+configVersion: 1
+launchMode: script
+pythonPath: /bin/sh
+executable: service/bin/run.sh
+memory:
+  mode: unmanaged
+dirs: [var/data/tmp, app/conf]
+workingDir: app/conf
+`)
+	distRoot := launcher.params.DistRoot
+	script := "echo \"$TMPDIR\" > \"$TMPDIR/tmpdir\"\n"
+	if err := os.WriteFile(filepath.Join(distRoot, "service/bin/run.sh"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := launcher.Launch()
+	if err != nil {
+		t.Fatalf("unexpected error: %v\n%s", err, out)
+	}
+	if result.ExitCode != 0 {
+		t.Fatalf("expected the script to be found from the working dir, got exit %d\n%s", result.ExitCode, out)
+	}
+	data, err := os.ReadFile(filepath.Join(distRoot, "var/data/tmp/tmpdir"))
+	if err != nil {
+		t.Fatalf("expected TMPDIR under the distribution root: %v\n%s", err, out)
+	}
+	if got, want := strings.TrimSpace(string(data)), filepath.Join(distRoot, "var/data/tmp"); got != want {
+		t.Errorf("expected TMPDIR=%s, got %s", want, got)
+	}
+}
+
+func TestLaunchWorkingDirMissing(t *testing.T) {
+	launcher, _ := newTestLauncher(t, `
+configType: python
+configVersion: 1
+launchMode: command
+executable: /bin/true
+memory:
+  mode: unmanaged
+workingDir: does/not/exist
+`)
+
+	if _, err := launcher.Launch(); err == nil {
+		t.Error("expected an error for a missing working directory")
+	}
+}
+
+func TestLaunchEnvFromFile(t *testing.T) {
+	launcher, out := newTestLauncher(t, `
+configType: python
+configVersion: 1
+launchMode: command
+executable: /bin/sh
+args: ["-c", "test \"$DB_PASSWORD\" = \"$(cat var/secrets/db-password)\""]
+memory:
+  mode: unmanaged
+envFromFile:
+  DB_PASSWORD: var/secrets/db-password
+`)
+	secret := filepath.Join(launcher.params.DistRoot, "var/secrets/db-password")
+	if err := os.MkdirAll(filepath.Dir(secret), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(secret, []byte("hunter2\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := launcher.Launch()
+	if err != nil {
+		t.Fatalf("unexpected error: %v\n%s", err, out)
+	}
+	if result.ExitCode != 0 {
+		t.Errorf("expected secret in child env, got exit %d\n%s", result.ExitCode, out)
+	}
+	if strings.Contains(out.String(), "hunter2") {
+		t.Errorf("secret value leaked into launcher output:\n%s", out)
+	}
+}
+
+func TestLaunchLogsMemoryEnvOverrides(t *testing.T) {
+	launcher, out := newTestLauncher(t, `
+configType: python
+configVersion: 1
+launchMode: command
+executable: /bin/true
+memory:
+  mode: fixed
+  fixedLimitBytes: 1073741824
+env:
+  PYTHONMALLOC: pymalloc
+`)
+
+	env, err := launcher.ResolveEnv(false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v\n%s", err, out)
+	}
+	if got := envToMap(env)["PYTHONMALLOC"]; got != "pymalloc" {
+		t.Errorf("expected config env to win with PYTHONMALLOC=pymalloc, got %q", got)
+	}
+	if !strings.Contains(out.String(), "Memory env: PYTHONMALLOC=pymalloc from config env overrides the launcher's PYTHONMALLOC=malloc") {
+		t.Errorf("expected the PYTHONMALLOC override to be logged, got:\n%s", out)
+	}
+}
+
+func TestLaunchResolvesIncludesFromDistRoot(t *testing.T) {
+	launcher, out := newTestLauncher(t, `
+include: [service/conf/common.yml]
+configType: python
+configVersion: 1
+launchMode: command
+executable: /bin/true
+`)
+	common := filepath.Join(launcher.params.DistRoot, "service/conf/common.yml")
+	if err := os.MkdirAll(filepath.Dir(common), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(common, []byte("env:\n  SHARED_SETTING: from-include\nmemory:\n  mode: unmanaged\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	env, err := launcher.ResolveEnv(false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v\n%s", err, out)
+	}
+	if got := envToMap(env)["SHARED_SETTING"]; got != "from-include" {
+		t.Errorf("expected SHARED_SETTING from the dist-relative include, got %q", got)
+	}
+}
+
+func TestResolveEnv(t *testing.T) {
+	t.Setenv("PSL_TEST_API_TOKEN", "inherited-token")
+	launcher, out := newTestLauncher(t, `
+configType: python
+configVersion: 1
+launchMode: command
+executable: /bin/true
+memory:
+  mode: fixed
+  fixedLimitBytes: 1073741824
+env:
+  APP_MODE: production
+envFromFile:
+  DB_PASSWORD: var/secrets/db
+`)
+	secret := filepath.Join(launcher.params.DistRoot, "var/secrets/db")
+	if err := os.MkdirAll(filepath.Dir(secret), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(secret, []byte("hunter2\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	env, err := launcher.ResolveEnv(false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v\n%s", err, out)
+	}
+	if !sort.StringsAreSorted(env) {
+		t.Error("expected environment sorted by name")
+	}
+	vars := envToMap(env)
+	if vars["SERVICE_NAME"] != "test-service" {
+		t.Errorf("expected SERVICE_NAME=test-service, got %q", vars["SERVICE_NAME"])
+	}
+	if _, ok := vars["SLS_MEMORY_LIMIT_BYTES"]; !ok {
+		t.Error("expected SLS_MEMORY_LIMIT_BYTES in resolved env")
+	}
+	if vars["APP_MODE"] != "production" {
+		t.Errorf("expected APP_MODE=production, got %q", vars["APP_MODE"])
+	}
+	if vars["DB_PASSWORD"] != redactedValue || vars["PSL_TEST_API_TOKEN"] != redactedValue {
+		t.Errorf("expected secrets redacted, got DB_PASSWORD=%q PSL_TEST_API_TOKEN=%q",
+			vars["DB_PASSWORD"], vars["PSL_TEST_API_TOKEN"])
+	}
+
+	env, err = launcher.ResolveEnv(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := envToMap(env)["DB_PASSWORD"]; got != "hunter2" {
+		t.Errorf("expected secret shown with showSecrets, got %q", got)
+	}
+}
+
+func TestResolveEnvBytecodeCacheDir(t *testing.T) {
+	t.Setenv("PYTHONDONTWRITEBYTECODE", "1")
+	launcher, out := newTestLauncher(t, `
+configType: python
+configVersion: 1
+launchMode: command
+executable: /bin/true
+memory:
+  mode: unmanaged
+bytecodeCaching: true
+`)
+	env, err := launcher.ResolveEnv(false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v\n%s", err, out)
+	}
+	vars := envToMap(env)
+	want := filepath.Join(launcher.params.DistRoot, "var/data/pycache")
+	if vars["PYTHONPYCACHEPREFIX"] != want {
+		t.Errorf("expected PYTHONPYCACHEPREFIX=%s, got %q", want, vars["PYTHONPYCACHEPREFIX"])
+	}
+	if _, ok := vars["PYTHONDONTWRITEBYTECODE"]; ok {
+		t.Error("expected bytecodeCaching to drop the inherited PYTHONDONTWRITEBYTECODE")
+	}
+}
+
+func TestResolveEnvPythonPathEntries(t *testing.T) {
+	t.Setenv("PYTHONPATH", "/inherited/site")
+	launcher, out := newTestLauncher(t, `
+configType: python
+configVersion: 1
+launchMode: command
+executable: /bin/true
+memory:
+  mode: unmanaged
+pythonPathEntries: [service/lib, /opt/shared]
+`)
+	env, err := launcher.ResolveEnv(false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v\n%s", err, out)
+	}
+	want := filepath.Join(launcher.params.DistRoot, "service/lib") + ":/opt/shared:/inherited/site"
+	if got := envToMap(env)["PYTHONPATH"]; got != want {
+		t.Errorf("expected PYTHONPATH=%s, got %q", want, got)
+	}
+}
+
+func TestLaunchResolveInterpreterFromShebang(t *testing.T) {
+	launcher, out := newTestLauncher(t, `
+configType: python
+configVersion: 1
+executable: service.pex
+args: [hello]
+memory:
+  mode: unmanaged
+resolveInterpreterFromShebang: true
+`)
+	// Not executable, so only an explicit interpreter can run it.
+	pex := filepath.Join(launcher.params.DistRoot, "service.pex")
+	if err := os.WriteFile(pex, []byte("#!/bin/sh\ntest \"$1\" = hello && exit 7\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := launcher.Launch()
+	if err != nil {
+		t.Fatalf("unexpected error: %v\n%s", err, out)
+	}
+	if result.ExitCode != 7 {
+		t.Errorf("expected the shebang interpreter to run the PEX and exit 7, got %d\n%s", result.ExitCode, out)
+	}
+
+	if err := os.WriteFile(pex, []byte("#!/opt/missing/python3.11\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := launcher.Launch(); err == nil || !strings.Contains(err.Error(), "/opt/missing/python3.11") {
+		t.Errorf("expected an error naming the missing interpreter, got %v", err)
+	}
+}
+
+func TestLaunchOOMScoreAdj(t *testing.T) {
+	original := writeOOMScoreAdj
+	defer func() { writeOOMScoreAdj = original }()
+	writes := make(map[int]int)
+	writeOOMScoreAdj = func(pid, adj int) error {
+		writes[pid] = adj
+		return nil
+	}
+
+	launcher, out := newTestLauncher(t, `
+configType: python
+configVersion: 1
+launchMode: command
+executable: /bin/sh
+args: ["-c", "echo $$ > child.pid"]
+memory:
+  mode: unmanaged
+resources:
+  oomScoreAdj: 500
+`)
+	result, err := launcher.Launch()
+	if err != nil {
+		t.Fatalf("unexpected error: %v\n%s", err, out)
+	}
+	if result.ExitCode != 0 {
+		t.Fatalf("expected exit 0, got %d\n%s", result.ExitCode, out)
+	}
+
+	data, err := os.ReadFile(filepath.Join(launcher.params.DistRoot, "child.pid"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(writes) != 1 || writes[pid] != 500 {
+		t.Errorf("expected oom_score_adj 500 written for child pid %d, got %v", pid, writes)
+	}
+}
+
+func TestLaunchNiceAppliedToChild(t *testing.T) {
+	original := setPriority
+	defer func() { setPriority = original }()
+	calls := make(map[int]int)
+	setPriority = func(pid, nice int) error {
+		calls[pid] = nice
+		return nil
+	}
+
+	launcher, out := newTestLauncher(t, `
+configType: python
+configVersion: 1
+launchMode: command
+executable: /bin/sh
+args: ["-c", "echo $$ > child.pid"]
+memory:
+  mode: unmanaged
+resources:
+  nice: 10
+`)
+	result, err := launcher.Launch()
+	if err != nil {
+		t.Fatalf("unexpected error: %v\n%s", err, out)
+	}
+	if result.ExitCode != 0 {
+		t.Fatalf("expected exit 0, got %d\n%s", result.ExitCode, out)
+	}
+
+	data, err := os.ReadFile(filepath.Join(launcher.params.DistRoot, "child.pid"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(calls) != 1 || calls[pid] != 10 {
+		t.Errorf("expected nice 10 set only on child pid %d, got %v", pid, calls)
+	}
+}
+
+func TestLaunchShutdownNotifyFile(t *testing.T) {
+	launcher, out := newTestLauncher(t, `
+configType: python
+configVersion: 1
+launchMode: command
+executable: /bin/sh
+args:
+  - -c
+  - |
+    trap '' TERM
+    touch started
+    while [ ! -e "$LAUNCHER_SHUTDOWN_NOTIFY_FILE" ]; do sleep 0.05; done
+    cp "$LAUNCHER_SHUTDOWN_NOTIFY_FILE" notice
+memory:
+  mode: unmanaged
+watchdog:
+  gracePeriodSeconds: 30
+shutdownNotifyFile: var/run/shutdown
+`)
+	started := filepath.Join(launcher.params.DistRoot, "started")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		for {
+			if _, err := os.Stat(started); err == nil {
+				cancel()
+				return
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+	}()
+
+	before := time.Now()
+	result, err := launcher.LaunchWithContext(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v\n%s", err, out)
+	}
+	if result.ExitCode != 0 {
+		t.Fatalf("expected the process to exit 0 after reading the notice, got %d\n%s", result.ExitCode, out)
+	}
+
+	data, err := os.ReadFile(filepath.Join(launcher.params.DistRoot, "notice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	notice := envToMap(strings.Split(strings.TrimSpace(string(data)), "\n"))
+	if notice["SHUTDOWN_GRACE_SECONDS"] != "30" {
+		t.Errorf("expected SHUTDOWN_GRACE_SECONDS=30, got %q", notice["SHUTDOWN_GRACE_SECONDS"])
+	}
+	deadline, err := strconv.ParseInt(notice["SHUTDOWN_DEADLINE_UNIX"], 10, 64)
+	if err != nil {
+		t.Fatalf("unparseable SHUTDOWN_DEADLINE_UNIX in %q", data)
+	}
+	if min, max := before.Add(30*time.Second).Unix(), time.Now().Add(30*time.Second).Unix(); deadline < min || deadline > max {
+		t.Errorf("expected a deadline between %d and %d, got %d", min, max, deadline)
+	}
+	if _, err := os.Stat(filepath.Join(launcher.params.DistRoot, "var/run/shutdown")); !os.IsNotExist(err) {
+		t.Errorf("expected the notice removed after exit, stat err = %v", err)
+	}
+}
+
+func TestApplyDevMode(t *testing.T) {
+	enabled := true
+	tests := []struct {
+		name        string
+		static      StaticLauncherConfig
+		custom      CustomLauncherConfig
+		wantEnabled bool
+	}{
+		{"watchdog from defaults", StaticLauncherConfig{Logging: LoggingConfig{Format: LogFormatJSON}}, CustomLauncherConfig{}, false},
+		{"watchdog enabled by static", StaticLauncherConfig{Watchdog: WatchdogConfig{Enabled: &enabled}}, CustomLauncherConfig{}, true},
+		{"watchdog enabled by custom", StaticLauncherConfig{}, CustomLauncherConfig{Watchdog: &WatchdogConfig{Enabled: &enabled}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := applyDevMode(MergeConfigs(tt.static, tt.custom))
+			if PidFilePath(config.Paths, "svc") != "" {
+				t.Error("expected the PID file disabled")
+			}
+			if config.Logging.Format != LogFormatText || config.Logging.Level != "debug" {
+				t.Errorf("expected text logging at debug level, got %+v", config.Logging)
+			}
+			if got := valueOr(config.Watchdog.Enabled, false); got != tt.wantEnabled {
+				t.Errorf("expected watchdog enabled=%t, got %t", tt.wantEnabled, got)
+			}
+		})
+	}
+}
+
+func TestLaunchDevSkipsPidFile(t *testing.T) {
+	launcher, out := newTestLauncher(t, `
+configType: python
+configVersion: 1
+launchMode: command
+executable: /bin/sh
+args: ["-c", "sleep 0.5; test ! -e var/run/test-service.pid"]
+memory:
+  mode: unmanaged
+`)
+	launcher.params.Dev = true
+
+	result, err := launcher.Launch()
+	if err != nil {
+		t.Fatalf("unexpected error: %v\n%s", err, out)
+	}
+	if result.ExitCode != 0 {
+		t.Errorf("expected no pid file in dev mode, got exit %d\n%s", result.ExitCode, out)
+	}
+	if !strings.Contains(out.String(), "Dev mode:") {
+		t.Errorf("expected dev mode to be logged, got:\n%s", out)
+	}
+}
+
+func TestLaunchSocketActivation(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("uses /proc to inspect the child's descriptors")
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	file, err := listener.(*net.TCPListener).File()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Give the launcher its own descriptor, as systemd would.
+	fd, err := syscall.Dup(int(file.Fd()))
+	file.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	originalStart := listenFDsStart
+	listenFDsStart = fd
+	defer func() { listenFDsStart = originalStart }()
+	t.Setenv("LISTEN_FDS", "1")
+	t.Setenv("LISTEN_PID", strconv.Itoa(os.Getpid()))
+
+	launcher, out := newTestLauncher(t, `
+configType: python
+configVersion: 1
+launchMode: command
+executable: /bin/sh
+args: ["-c", "test \"$LISTEN_PID\" = \"$$\" && test \"$LISTEN_FDS\" = 1 && readlink /proc/self/fd/3 | grep -q '^socket:'"]
+memory:
+  mode: unmanaged
+socketActivation: true
+`)
+
+	result, err := launcher.Launch()
+	if err != nil {
+		t.Fatalf("unexpected error: %v\n%s", err, out)
+	}
+	if result.ExitCode != 0 {
+		t.Errorf("expected LISTEN_PID rewritten to the child pid and the socket on fd 3, got exit %d\n%s",
+			result.ExitCode, out)
+	}
+}
+
+func TestInheritedListenFDsOtherProcess(t *testing.T) {
+	t.Setenv("LISTEN_FDS", "2")
+	t.Setenv("LISTEN_PID", strconv.Itoa(os.Getpid()+1))
+	files, err := InheritedListenFDs()
+	if err != nil || files != nil {
+		t.Errorf("expected sockets for another pid to be ignored, got %v (err %v)", files, err)
+	}
+}
+
+func TestLaunchDiagnosticSignalWritesSnapshot(t *testing.T) {
+	launcher, out := newTestLauncher(t, `
+configType: python
+configVersion: 1
+launchMode: command
+executable: /bin/sh
+args: ["-c", "trap 'echo $DIAGNOSTIC_SIGNAL > var/log/child-dump; exit 0' USR2; touch var/log/child-ready; sleep 5 & wait"]
+memory:
+  mode: unmanaged
+diagnostics:
+  enabled: true
+`)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	// Wait until the child has installed its trap, or the forwarded signal
+	// would kill it.
+	readyPath := filepath.Join(launcher.params.DistRoot, "var/log/child-ready")
+	go func() {
+		for {
+			if _, err := os.Stat(readyPath); err == nil && strings.Contains(out.String(), "Diagnostics: send SIGUSR2") {
+				break
+			}
+			if ctx.Err() != nil {
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		_ = syscall.Kill(os.Getpid(), syscall.SIGUSR2)
+	}()
+
+	result, err := launcher.LaunchWithContext(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v\n%s", err, out)
+	}
+	if result.Cancelled || result.ExitCode != 0 {
+		t.Fatalf("expected child to exit 0 from its USR2 trap, got %+v\n%s", result, out)
+	}
+
+	logDir := filepath.Join(launcher.params.DistRoot, "var/log")
+	snapshots, err := filepath.Glob(filepath.Join(logDir, "launcher-diagnostic-*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snapshots) != 1 {
+		t.Fatalf("expected 1 snapshot, got %v\n%s", snapshots, out)
+	}
+	data, err := os.ReadFile(snapshots[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	var snapshot DiagnosticSnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		t.Fatalf("snapshot is not valid JSON: %v\n%s", err, data)
+	}
+	if snapshot.Pid == 0 || snapshot.Goroutines == 0 {
+		t.Errorf("expected pid and goroutine count in snapshot, got %+v", snapshot)
+	}
+
+	marker, err := os.ReadFile(filepath.Join(logDir, "child-dump"))
+	if err != nil {
+		t.Fatalf("signal was not forwarded to the child: %v\n%s", err, out)
+	}
+	if got := strings.TrimSpace(string(marker)); got != "SIGUSR2" {
+		t.Errorf("expected DIAGNOSTIC_SIGNAL=SIGUSR2 in child env, got %q", got)
+	}
+}
+
+func TestLaunchWritesManifest(t *testing.T) {
+	launcher, out := newTestLauncher(t, `
+configType: python
+configVersion: 1
+launchMode: command
+executable: /bin/sh
+args: ["-c", "exit 0"]
+memory:
+  mode: unmanaged
+envFromFile:
+  DB_PASSWORD: var/secrets/db-password
+writeManifest: true
+`)
+	launcher.params.LauncherVersion = "1.2.3"
+	secret := filepath.Join(launcher.params.DistRoot, "var/secrets/db-password")
+	if err := os.MkdirAll(filepath.Dir(secret), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(secret, []byte("hunter2\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := launcher.Launch(); err != nil {
+		t.Fatalf("unexpected error: %v\n%s", err, out)
+	}
+
+	data, err := os.ReadFile(filepath.Join(launcher.params.DistRoot, launchManifestPath))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "hunter2") {
+		t.Errorf("secret value leaked into manifest:\n%s", data)
+	}
+	var manifest LaunchManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		t.Fatalf("manifest is not valid JSON: %v\n%s", err, data)
+	}
+	if len(manifest.ConfigHash) != 64 {
+		t.Errorf("expected a SHA-256 config hash, got %q", manifest.ConfigHash)
+	}
+	if manifest.LauncherVersion != "1.2.3" {
+		t.Errorf("expected launcher version 1.2.3, got %q", manifest.LauncherVersion)
+	}
+	found := false
+	for _, entry := range manifest.Env {
+		if entry == "DB_PASSWORD="+redactedValue {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("expected redacted DB_PASSWORD in manifest env, got %v", manifest.Env)
+	}
+	if len(manifest.Argv) == 0 || manifest.Argv[0] != "/bin/sh" {
+		t.Errorf("expected argv to start with /bin/sh, got %v", manifest.Argv)
+	}
+}
+
+func TestResolveEnvLauncherVersion(t *testing.T) {
+	launcher, out := newTestLauncher(t, `
+configType: python
+configVersion: 1
+launchMode: command
+executable: /bin/true
+memory:
+  mode: unmanaged
+`)
+	launcher.params.LauncherVersion = "1.4.0"
+	launcher.params.LauncherCommit = "abc1234"
+
+	env, err := launcher.ResolveEnv(false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v\n%s", err, out)
+	}
+	vars := envToMap(env)
+	if vars["LAUNCHER_VERSION"] != "1.4.0" {
+		t.Errorf("expected LAUNCHER_VERSION=1.4.0, got %q", vars["LAUNCHER_VERSION"])
+	}
+	if vars["LAUNCHER_COMMIT"] != "abc1234" {
+		t.Errorf("expected LAUNCHER_COMMIT=abc1234, got %q", vars["LAUNCHER_COMMIT"])
+	}
+
+	launcher.params.LauncherVersion = ""
+	launcher.params.LauncherCommit = ""
+	env, err = launcher.ResolveEnv(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := envToMap(env)["LAUNCHER_VERSION"]; ok {
+		t.Error("expected no LAUNCHER_VERSION when the version is unknown")
+	}
+}
+
+func newTestCheckLauncher(t *testing.T, checkYAML string) (*Launcher, *syncBuffer) {
+	t.Helper()
+	distRoot := t.TempDir()
+	for path, content := range map[string]string{
+		defaultCheckConfigPath:  checkYAML,
+		defaultCustomConfigPath: "configType: python\nconfigVersion: 1\nenv:\n  PROD_ONLY: \"1\"\n",
+	} {
+		path = filepath.Join(distRoot, path)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	out := &syncBuffer{}
+	launcher := NewLauncher(LauncherParams{
+		DistRoot:       distRoot,
+		Check:          true,
+		ServiceName:    "test-service",
+		ServiceVersion: "check",
+		Stdout:         out,
+	})
+	return launcher, out
+}
+
+func TestLaunchCheckDefaults(t *testing.T) {
+	launcher, out := newTestCheckLauncher(t, `
+configType: python
+configVersion: 1
+launchMode: command
+executable: /bin/sh
+args: ["-c", "test -z \"$PROD_ONLY\" && test -z \"$PYTHONMALLOC\" && test \"$CHECK_VAR\" = yes"]
+env:
+  CHECK_VAR: "yes"
+`)
+
+	result, err := launcher.Launch()
+	if err != nil {
+		t.Fatalf("unexpected error: %v\n%s", err, out)
+	}
+	if result.ExitCode != 0 {
+		t.Errorf("expected check env without production vars, got exit %d\n%s", result.ExitCode, out)
+	}
+	if strings.Contains(out.String(), "[watchdog]") {
+		t.Errorf("expected no watchdog for a check, got:\n%s", out)
+	}
+	if !strings.Contains(out.String(), "PID file disabled") {
+		t.Errorf("expected the check not to write a PID file, got:\n%s", out)
+	}
+	if _, err := os.Stat(filepath.Join(launcher.params.DistRoot, "var/run/test-service.pid")); !os.IsNotExist(err) {
+		t.Errorf("expected no service PID file, got %v", err)
+	}
+}
+
+func TestLaunchWritesLimitsDescriptor(t *testing.T) {
+	launcher, out := newTestLauncher(t, `
+configType: python
+configVersion: 1
+launchMode: command
+executable: /bin/sh
+args: ["-c", "cp \"$LAUNCHER_LIMITS_FILE\" var/log/seen.json"]
+memory:
+  mode: fixed
+  fixedLimitBytes: 1073741824
+  descriptorFile: var/run/limits.json
+watchdog:
+  enabled: false
+`)
+
+	result, err := launcher.Launch()
+	if err != nil || result.ExitCode != 0 {
+		t.Fatalf("unexpected result %+v, err %v\n%s", result, err, out)
+	}
+	data, err := os.ReadFile(filepath.Join(launcher.params.DistRoot, "var/log/seen.json"))
+	if err != nil {
+		t.Fatalf("expected LAUNCHER_LIMITS_FILE to reference the descriptor: %v", err)
+	}
+	var descriptor map[string]interface{}
+	if err := json.Unmarshal(data, &descriptor); err != nil {
+		t.Fatalf("expected a JSON object, got %q: %v", data, err)
+	}
+	for _, key := range []string{"cpuCount", "memoryEffectiveBytes", "memoryCgroupBytes",
+		"memorySoftWarnBytes", "memoryHardKillBytes", "isContainer", "cgroupVersion"} {
+		if _, ok := descriptor[key]; !ok {
+			t.Errorf("expected key %q in descriptor, got %s", key, data)
+		}
+	}
+	if descriptor["memoryCgroupBytes"] != float64(1073741824) {
+		t.Errorf("expected memoryCgroupBytes 1073741824, got %v", descriptor["memoryCgroupBytes"])
+	}
+	if cpus, _ := descriptor["cpuCount"].(float64); cpus < 1 {
+		t.Errorf("expected a positive cpuCount, got %v", descriptor["cpuCount"])
+	}
+}
+
+func TestLaunchOutputPrefix(t *testing.T) {
+	launcher, out := newTestLauncher(t, `
+configType: python
+configVersion: 1
+launchMode: command
+executable: /bin/sh
+args: ["-c", "l=line; echo $l-1; echo $l-2; echo $l-3 >&2; printf $l-4; sleep 1"]
+memory:
+  mode: unmanaged
+outputPrefix:
+  enabled: true
+subProcesses:
+  - name: metrics-exporter
+    executable: /bin/sh
+    args: ["-c", "s=sub; echo $s-1; echo $s-2"]
+`)
+	if _, err := launcher.Launch(); err != nil {
+		t.Fatalf("unexpected error: %v\n%s", err, out)
+	}
+	lines := strings.Split(out.String(), "\n")
+	for _, want := range []string{
+		"[primary] line-1",
+		"[primary] line-2",
+		"[primary] line-3",
+		"[primary] line-4",
+		"[metrics-exporter] sub-1",
+		"[metrics-exporter] sub-2",
+	} {
+		if !slices.Contains(lines, want) {
+			t.Errorf("expected output line %q, got:\n%s", want, out)
+		}
+	}
+}
+
+func TestLaunchOutputBufferDoesNotBlockOnStalledStdout(t *testing.T) {
+	launcher, out := newTestLauncher(t, `
+configType: python
+configVersion: 1
+launchMode: command
+executable: /bin/sh
+args: ["-c", "l=line; i=0; while [ $i -lt 500 ]; do echo $l-$i; i=$((i+1)); done"]
+memory:
+  mode: unmanaged
+outputBuffer:
+  enabled: true
+  sizeBytes: 256
+`)
+	gate := newGatedWriter(out, "line-")
+	defer gate.release()
+	launcher.params.Stdout = gate
+	defer func(timeout time.Duration) { outputDrainTimeout = timeout }(outputDrainTimeout)
+	outputDrainTimeout = 50 * time.Millisecond
+
+	type launchResult struct {
+		result LaunchResult
+		err    error
+	}
+	done := make(chan launchResult, 1)
+	go func() {
+		result, err := launcher.Launch()
+		done <- launchResult{result, err}
+	}()
+	var got launchResult
+	select {
+	case got = <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("expected Launch to return while stdout is stalled")
+	}
+	if got.err != nil {
+		t.Fatalf("unexpected error: %v\n%s", got.err, out)
+	}
+	if got.result.ExitCode != 0 {
+		t.Errorf("expected exit code 0, got %d", got.result.ExitCode)
+	}
+	if !strings.Contains(out.String(), "did not accept buffered output") {
+		t.Errorf("expected a drain timeout warning, got:\n%s", out)
+	}
+	if !strings.Contains(out.String(), "Output buffer dropped") {
+		t.Errorf("expected dropped lines to be counted, got:\n%s", out)
+	}
+}
+
+func TestLaunchStartupProbe(t *testing.T) {
+	launcher, out := newTestLauncher(t, `
+configType: python
+configVersion: 1
+launchMode: command
+executable: /bin/sh
+args: ["-c", "touch started; sleep 0.5"]
+memory:
+  mode: unmanaged
+startup:
+  command: ["/bin/sh", "-c", "test -f started"]
+  intervalMs: 20
+`)
+	result, err := launcher.Launch()
+	if err != nil {
+		t.Fatalf("unexpected error: %v\n%s", err, out)
+	}
+	if result.ExitCode != 0 {
+		t.Errorf("expected exit code 0, got %d", result.ExitCode)
+	}
+	if !strings.Contains(out.String(), "Startup probe passed") {
+		t.Errorf("expected the probe to pass, got:\n%s", out)
+	}
+}
+
+func TestLaunchStartupProbeTimeout(t *testing.T) {
+	launcher, out := newTestLauncher(t, `
+configType: python
+configVersion: 1
+launchMode: command
+executable: /bin/sh
+args: ["-c", "exec sleep 30"]
+memory:
+  mode: unmanaged
+startup:
+  tcp: 127.0.0.1:1
+  timeoutSeconds: 1
+  intervalMs: 50
+`)
+	start := time.Now()
+	result, err := launcher.Launch()
+	if !errors.Is(err, ErrStartupProbeFailed) {
+		t.Fatalf("expected ErrStartupProbeFailed, got %v\n%s", err, out)
+	}
+	if result.ExitCode == 0 {
+		t.Error("expected a non-zero exit code")
+	}
+	// Launch only returns once the child has been terminated and reaped.
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("expected the child to be terminated, launch took %s", elapsed)
+	}
+	if !strings.Contains(out.String(), "sending SIGTERM") {
+		t.Errorf("expected the child to be sent SIGTERM, got:\n%s", out)
+	}
+}
+
+func TestLaunchCriticalSubprocessExit(t *testing.T) {
+	launcher, out := newTestLauncher(t, `
+configType: python
+configVersion: 1
+launchMode: command
+executable: /bin/sh
+args: ["-c", "exec sleep 30"]
+memory:
+  mode: unmanaged
+subProcesses:
+  - name: auth-proxy
+    executable: /bin/sh
+    args: ["-c", "sleep 0.2; exit 3"]
+    critical: true
+`)
+	start := time.Now()
+	result, err := launcher.Launch()
+	if err != nil {
+		t.Fatalf("unexpected error: %v\n%s", err, out)
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("expected the primary to be terminated, launch took %s", elapsed)
+	}
+	if result.ExitCode != ExitCodeCriticalSubprocess {
+		t.Errorf("expected exit code %d, got %d", ExitCodeCriticalSubprocess, result.ExitCode)
+	}
+	if !strings.Contains(result.CriticalSubprocessFailure, "auth-proxy exited (exit status 3)") {
+		t.Errorf("expected the failure to name auth-proxy, got %q", result.CriticalSubprocessFailure)
+	}
+	if !strings.Contains(out.String(), "sending SIGTERM to pid") {
+		t.Errorf("expected the primary to be sent SIGTERM, got:\n%s", out)
+	}
+}
+
+func TestLaunchNonCriticalSubprocessExit(t *testing.T) {
+	launcher, out := newTestLauncher(t, `
+configType: python
+configVersion: 1
+launchMode: command
+executable: /bin/sh
+args: ["-c", "sleep 0.5"]
+memory:
+  mode: unmanaged
+subProcesses:
+  - name: exporter
+    executable: /bin/sh
+    args: ["-c", "exit 3"]
+`)
+	result, err := launcher.Launch()
+	if err != nil {
+		t.Fatalf("unexpected error: %v\n%s", err, out)
+	}
+	if result.ExitCode != 0 || result.CriticalSubprocessFailure != "" {
+		t.Errorf("expected the primary to run to completion, got %+v", result)
+	}
+}
+
+func TestLaunchSeparateStderr(t *testing.T) {
+	tests := []struct {
+		name        string
+		mergeStderr string
+		wantMerged  bool
+	}{
+		{"merged by default", "", true},
+		{"separate when disabled", "mergeStderr: false", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			launcher, out := newTestLauncher(t, `
+configType: python
+configVersion: 1
+launchMode: command
+executable: /bin/sh
+args: ["-c", "s=stdout; e=stderr; echo to-$s; echo to-$e >&2"]
+memory:
+  mode: unmanaged
+`+tt.mergeStderr+`
+`)
+			stderr := &syncBuffer{}
+			launcher.params.Stderr = stderr
+
+			if _, err := launcher.Launch(); err != nil {
+				t.Fatalf("unexpected error: %v\n%s", err, out)
+			}
+			if !strings.Contains(out.String(), "to-stdout") || strings.Contains(stderr.String(), "to-stdout") {
+				t.Errorf("expected stdout only in the stdout writer, got stdout:\n%s\nstderr:\n%s", out, stderr)
+			}
+			if tt.wantMerged {
+				if !strings.Contains(out.String(), "to-stderr") || stderr.String() != "" {
+					t.Errorf("expected stderr merged into stdout, got stdout:\n%s\nstderr:\n%s", out, stderr)
+				}
+			} else if strings.Contains(out.String(), "to-stderr") || !strings.Contains(stderr.String(), "to-stderr") {
+				t.Errorf("expected stderr in the stderr writer only, got stdout:\n%s\nstderr:\n%s", out, stderr)
+			}
+		})
+	}
+}
+
+func TestLaunchCheckWatchdogOptIn(t *testing.T) {
+	launcher, out := newTestCheckLauncher(t, `
+configType: python
+configVersion: 1
+launchMode: command
+executable: /bin/sh
+args: ["-c", "sleep 0.2"]
+memory:
+  mode: fixed
+  fixedLimitBytes: 1073741824
+watchdog:
+  enabled: true
+  pollIntervalSeconds: 1
+`)
+
+	if _, err := launcher.Launch(); err != nil {
+		t.Fatalf("unexpected error: %v\n%s", err, out)
+	}
+	if !strings.Contains(out.String(), "[watchdog] Started") {
+		t.Errorf("expected an explicitly enabled watchdog to start, got:\n%s", out)
+	}
+}
+
+func TestLaunchWatchdogKillsAfterGrace(t *testing.T) {
+	launcher, out := newTestLauncher(t, `
+configType: python
+configVersion: 1
+launchMode: command
+executable: /bin/sh
+args: ["-c", "trap '' TERM; while :; do sleep 0.1; done"]
+memory:
+  mode: fixed
+  fixedLimitBytes: 65536
+watchdog:
+  enabled: true
+  pollIntervalMillis: 50
+  gracePeriodSeconds: 1
+`)
+
+	start := time.Now()
+	result, err := launcher.Launch()
+	if err != nil {
+		t.Fatalf("unexpected error: %v\n%s", err, out)
+	}
+	if elapsed := time.Since(start); elapsed < time.Second || elapsed > 10*time.Second {
+		t.Errorf("expected the launch to end about 1s after SIGTERM, got %s\n%s", elapsed, out)
+	}
+	if !result.WatchdogTriggered {
+		t.Errorf("expected the watchdog to trigger\n%s", out)
+	}
+	if n := strings.Count(out.String(), "sending SIGKILL"); n != 1 {
+		t.Errorf("expected exactly one SIGKILL, got %d:\n%s", n, out)
+	}
+}
+
+func TestLaunchArgsFileOrdering(t *testing.T) {
+	launcher, out := newTestLauncher(t, `
+configType: python
+configVersion: 1
+launchMode: command
+executable: /bin/sh
+args: ["-c", "printf '%s\n' \"$@\" > args.out", "sh", "static"]
+argsFile: service/bin/args.txt
+memory:
+  mode: unmanaged
+`)
+	root := launcher.params.DistRoot
+	files := map[string]string{
+		defaultCustomConfigPath: "configType: python\nconfigVersion: 1\nargs: [custom]\n",
+		"service/bin/args.txt":  "# one per line\nfile-1\n\nfile 2\n",
+	}
+	for path, content := range files {
+		path = filepath.Join(root, path)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	result, err := launcher.Launch()
+	if err != nil {
+		t.Fatalf("unexpected error: %v\n%s", err, out)
+	}
+	if result.ExitCode != 0 {
+		t.Fatalf("expected exit 0, got %d\n%s", result.ExitCode, out)
+	}
+	data, err := os.ReadFile(filepath.Join(root, "args.out"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(data), "static\ncustom\nfile-1\nfile 2\n"; got != want {
+		t.Errorf("expected args %q, got %q", want, got)
+	}
+}
+
+func TestLaunchArgsFileMissing(t *testing.T) {
+	launcher, out := newTestLauncher(t, `
+configType: python
+configVersion: 1
+launchMode: command
+executable: /bin/true
+argsFile: service/bin/missing-args.txt
+memory:
+  mode: unmanaged
+`)
+
+	if _, err := launcher.Launch(); err == nil || !strings.Contains(err.Error(), "argsFile") {
+		t.Errorf("expected an argsFile error, got %v\n%s", err, out)
+	}
+}
+
+func TestLaunchUmask(t *testing.T) {
+	launcher, out := newTestLauncher(t, `
+configType: python
+configVersion: 1
+launchMode: command
+executable: /bin/sh
+args: ["-c", "touch created"]
+memory:
+  mode: unmanaged
+resources:
+  umask: "0027"
+`)
+	previous := syscall.Umask(0022)
+	defer syscall.Umask(previous)
+
+	result, err := launcher.Launch()
+	if err != nil {
+		t.Fatalf("unexpected error: %v\n%s", err, out)
+	}
+	if result.ExitCode != 0 {
+		t.Fatalf("expected exit 0, got %d\n%s", result.ExitCode, out)
+	}
+	info, err := os.Stat(filepath.Join(launcher.params.DistRoot, "created"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0640 {
+		t.Errorf("expected child-created file mode 0640 under umask 0027, got %04o", perm)
+	}
+	if current := syscall.Umask(0022); current != 0022 {
+		t.Errorf("expected launcher umask restored to 0022, got %04o", current)
+	}
+}
+
+func TestLaunchDetectsOOMKill(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+		want   bool
+	}{
+		{"oom kill", "printf 'oom 1\noom_kill 3\n' > fakeroot/sys/fs/cgroup/memory.events; kill -9 $$", true},
+		{"sigkill without oom", "kill -9 $$", false},
+		{"oom counter but clean exit", "printf 'oom 1\noom_kill 3\n' > fakeroot/sys/fs/cgroup/memory.events", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			launcher, out := newTestLauncher(t, fmt.Sprintf(`
+configType: python
+configVersion: 1
+launchMode: command
+executable: /bin/sh
+args: ["-c", %q]
+memory:
+  mode: unmanaged
+`, tt.script))
+			cgroupDir := filepath.Join(launcher.params.DistRoot, "fakeroot/sys/fs/cgroup")
+			if err := os.MkdirAll(cgroupDir, 0755); err != nil {
+				t.Fatal(err)
+			}
+			events := "low 0\nhigh 0\nmax 4\noom 0\noom_kill 2\noom_group_kill 0\n"
+			if err := os.WriteFile(filepath.Join(cgroupDir, "memory.events"), []byte(events), 0644); err != nil {
+				t.Fatal(err)
+			}
+			launcher.limiter = NewMemoryLimiterWithFS(os.DirFS(filepath.Join(launcher.params.DistRoot, "fakeroot")))
+
+			result, err := launcher.Launch()
+			if err != nil {
+				t.Fatalf("unexpected error: %v\n%s", err, out)
+			}
+			if result.OOMKilled != tt.want {
+				t.Errorf("expected OOMKilled=%t, got %+v\n%s", tt.want, result, out)
+			}
+			if got := strings.Contains(out.String(), "kernel OOM killer"); got != tt.want {
+				t.Errorf("expected OOM message=%t, got:\n%s", tt.want, out)
+			}
+		})
+	}
+}
+
+func TestLaunchExitCodeMap(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+		want   int
+	}{
+		{"mapped", "exit 3", 78},
+		{"unmapped", "exit 4", 4},
+		{"success unmapped", "exit 0", 0},
+		{"signaled", "kill -TERM $$", 143},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			launcher, out := newTestLauncher(t, fmt.Sprintf(`
+configType: python
+configVersion: 1
+launchMode: command
+executable: /bin/sh
+args: ["-c", %q]
+memory:
+  mode: unmanaged
+exitCodeMap:
+  3: 78
+  -1: 143
+`, tt.script))
+
+			result, err := launcher.Launch()
+			if err != nil {
+				t.Fatalf("unexpected error: %v\n%s", err, out)
+			}
+			if result.ExitCode != tt.want {
+				t.Errorf("expected exit code %d, got %d\n%s", tt.want, result.ExitCode, out)
+			}
+		})
+	}
+}
+
+func TestLaunchSearchesStaticConfig(t *testing.T) {
+	searchDir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	if err := os.WriteFile(filepath.Join(searchDir, "launcher-static.yml"), []byte(`
+configType: python
+configVersion: 1
+launchMode: command
+executable: /bin/sh
+args: ["-c", "exit 0"]
+memory:
+  mode: unmanaged
+`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	out := &syncBuffer{}
+	launcher := NewLauncher(LauncherParams{
+		DistRoot:    t.TempDir(),
+		SearchDir:   searchDir,
+		ServiceName: "test-service",
+		Stdout:      out,
+	})
+	result, err := launcher.Launch()
+	if err != nil {
+		t.Fatalf("Launch failed: %v", err)
+	}
+	if result.ExitCode != 0 {
+		t.Errorf("expected exit code 0, got %d", result.ExitCode)
+	}
+	if want := "using " + filepath.Join(searchDir, "launcher-static.yml"); !strings.Contains(out.String(), want) {
+		t.Errorf("expected output to contain %q, got:\n%s", want, out.String())
+	}
+}
+
+func TestPidFileSearchesStaticConfig(t *testing.T) {
+	searchDir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	if err := os.WriteFile(filepath.Join(searchDir, "launcher-static.yml"), []byte(`
+configType: python
+configVersion: 1
+executable: /bin/true
+paths:
+  pidFile: run/custom-%s.pid
+`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	distRoot := t.TempDir()
+	launcher := NewLauncher(LauncherParams{
+		DistRoot:    distRoot,
+		SearchDir:   searchDir,
+		ServiceName: "test-service",
+		Stdout:      &syncBuffer{},
+	})
+	pidPath, err := launcher.PidFile()
+	if err != nil {
+		t.Fatalf("PidFile failed: %v", err)
+	}
+	if want := filepath.Join(distRoot, "run", "custom-test-service.pid"); pidPath != want {
+		t.Errorf("expected PID file %s, got %s", want, pidPath)
+	}
+}
+
+func TestPidFileReturnsConfigErrors(t *testing.T) {
+	launcher, _ := newTestLauncher(t, `
+configType: python
+configVersion: 2
+executable: /bin/true
+`)
+	if _, err := launcher.PidFile(); !errors.Is(err, ErrConfigValidation) {
+		t.Errorf("expected a validation error, got %v", err)
+	}
+
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	missing := NewLauncher(LauncherParams{
+		DistRoot:    t.TempDir(),
+		SearchDir:   t.TempDir(),
+		ServiceName: "test-service",
+		Stdout:      &syncBuffer{},
+	})
+	if _, err := missing.PidFile(); !errors.Is(err, ErrStaticConfigNotFound) {
+		t.Errorf("expected ErrStaticConfigNotFound, got %v", err)
+	}
+}
+
+func TestLaunchTemplates(t *testing.T) {
+	for _, enabled := range []bool{true, false} {
+		t.Run(fmt.Sprintf("templateEnabled=%v", enabled), func(t *testing.T) {
+			launcher, out := newTestLauncher(t, fmt.Sprintf(`
+configType: python
+configVersion: 1
+launchMode: command
+executable: /bin/sh
+templateEnabled: %v
+args: ["-c", "echo arg={{.ServiceName}} env=$APP_VERSION"]
+env:
+  APP_VERSION: "{{.ServiceVersion}}"
+memory:
+  mode: unmanaged
+`, enabled))
+			if _, err := launcher.Launch(); err != nil {
+				t.Fatalf("Launch failed: %v", err)
+			}
+			want := "arg={{.ServiceName}} env={{.ServiceVersion}}"
+			if enabled {
+				want = "arg=test-service env=1.0.0"
+			}
+			if !strings.Contains(out.String(), want) {
+				t.Errorf("expected output to contain %q, got:\n%s", want, out.String())
+			}
+		})
+	}
+}
+
+func TestLaunchExecMode(t *testing.T) {
+	launcher, out := newTestLauncher(t, `
+configType: python
+configVersion: 1
+launchMode: command
+executable: /bin/sh
+args: ["-c", "exit 0"]
+execMode: true
+workingDir: var/data
+dirs: [var/data, var/log]
+env:
+  APP_MODE: exec
+resources:
+  umask: "0027"
+readiness:
+  enabled: true
+memory:
+  mode: unmanaged
+`)
+	var spec execSpec
+	calls := 0
+	launcher.exec = func(s execSpec) error {
+		calls++
+		spec = s
+		return nil
+	}
+
+	if _, err := launcher.Launch(); err != nil {
+		t.Fatalf("Launch failed: %v", err)
+	}
+	if calls != 1 {
+		t.Fatalf("expected exec to be called once, got %d", calls)
+	}
+
+	distRoot := launcher.params.DistRoot
+	if want := []string{"/bin/sh", "-c", "exit 0"}; !reflect.DeepEqual(spec.argv, want) {
+		t.Errorf("expected argv %v, got %v", want, spec.argv)
+	}
+	if want := filepath.Join(distRoot, "var/data"); spec.dir != want {
+		t.Errorf("expected dir %s, got %s", want, spec.dir)
+	}
+	if spec.umask != 0027 {
+		t.Errorf("expected umask 0027, got %04o", spec.umask)
+	}
+	env := envToMap(spec.env)
+	if env["APP_MODE"] != "exec" || env["SERVICE_NAME"] != "test-service" {
+		t.Errorf("expected config and service env, got %v", spec.env)
+	}
+	if _, err := os.Stat(filepath.Join(distRoot, "var/log")); err != nil {
+		t.Errorf("expected dirs to be created before exec: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(distRoot, "var/run/test-service.pid")); !os.IsNotExist(err) {
+		t.Errorf("expected no PID file in exec mode, got %v", err)
+	}
+	for _, want := range []string{"watchdog, PID file and signal forwarding are disabled", "ignoring readiness"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("expected output to contain %q, got:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestLaunchExecModeFailure(t *testing.T) {
+	launcher, _ := newTestLauncher(t, `
+configType: python
+configVersion: 1
+launchMode: command
+executable: /bin/sh
+memory:
+  mode: unmanaged
+`)
+	launcher.params.Exec = true
+	launcher.exec = func(execSpec) error { return syscall.ENOENT }
+
+	result, err := launcher.Launch()
+	if err == nil || !strings.Contains(err.Error(), "failed to exec process") {
+		t.Errorf("expected an exec error, got %v", err)
+	}
+	if result.ExitCode != 1 {
+		t.Errorf("expected exit code 1, got %d", result.ExitCode)
+	}
+}
+
+func TestLaunchReapsOrphanedGrandchildren(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("orphan reaping needs /proc and PR_SET_CHILD_SUBREAPER")
+	}
+	// The intermediate shell exits immediately, orphaning the backgrounded
+	// sleeps; they are reparented to the launcher and exit while the primary
+	// is still running.
+	launcher, out := newTestLauncher(t, `
+configType: python
+configVersion: 1
+launchMode: command
+executable: /bin/sh
+args: ["-c", "sh -c 'sleep 0.1 & sleep 0.1 &' ; sleep 1; exit 7"]
+reapChildren: true
+memory:
+  mode: unmanaged
+`)
+
+	result, err := launcher.Launch()
+	if err != nil {
+		t.Fatalf("Launch failed: %v", err)
+	}
+	if result.ExitCode != 7 {
+		t.Errorf("expected the primary's exit code 7 to reach cmd.Wait, got %d\n%s", result.ExitCode, out)
+	}
+	if n := strings.Count(out.String(), "Reaped orphaned process"); n != 2 {
+		t.Errorf("expected 2 orphans reaped, got %d\n%s", n, out)
+	}
+	if zombies := zombieChildren(os.Getpid()); len(zombies) != 0 {
+		t.Errorf("expected no zombie children, got %v", zombies)
+	}
+}
+
+func TestLaunchAbortsOnReadinessBindFailure(t *testing.T) {
+	occupied, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer occupied.Close()
+
+	launcher, _ := newTestLauncher(t, fmt.Sprintf(`
+configType: python
+configVersion: 1
+launchMode: command
+executable: /bin/sh
+args: ["-c", "touch started"]
+readiness:
+  enabled: true
+  httpPort: %d
+memory:
+  mode: unmanaged
+`, occupied.Addr().(*net.TCPAddr).Port))
+
+	result, err := launcher.Launch()
+	if err == nil || !strings.Contains(err.Error(), "readiness probe") {
+		t.Fatalf("expected a readiness bind error, got %v", err)
+	}
+	if result.ExitCode != 1 {
+		t.Errorf("expected exit code 1, got %d", result.ExitCode)
+	}
+	if _, err := os.Stat(filepath.Join(launcher.params.DistRoot, "started")); !os.IsNotExist(err) {
+		t.Errorf("expected the process not to be started, got %v", err)
+	}
+}
+
+func TestLaunchCancelShutdownSequence(t *testing.T) {
+	launcher, out := newTestLauncher(t, `
+configType: python
+configVersion: 1
+launchMode: command
+executable: /bin/sh
+args: ["-c", "trap '' TERM; touch ready; exec sleep 30"]
+shutdownSequence:
+  - signal: SIGTERM
+    waitSeconds: 1
+  - signal: SIGQUIT
+    waitSeconds: 10
+  - signal: SIGKILL
+memory:
+  mode: unmanaged
+`)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	readyPath := filepath.Join(launcher.params.DistRoot, "ready")
+	go func() {
+		for {
+			if _, err := os.Stat(readyPath); err == nil {
+				cancel()
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}()
+
+	start := time.Now()
+	result, err := launcher.LaunchWithContext(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !result.Cancelled || result.ExitCode != -1 {
+		t.Errorf("expected a cancelled, signaled exit, got %+v", result)
+	}
+	if elapsed := time.Since(start); elapsed > 8*time.Second {
+		t.Errorf("expected SIGQUIT to end the process before SIGKILL, took %s", elapsed)
+	}
+	if !strings.Contains(out.String(), "after SIGTERM, sending SIGQUIT") {
+		t.Errorf("expected escalation to SIGQUIT, got:\n%s", out.String())
+	}
+	if strings.Contains(out.String(), "sending SIGKILL") {
+		t.Errorf("expected SIGKILL not to be needed, got:\n%s", out.String())
+	}
+}
+
+// sigtermLauncher sends SIGTERM to the test process once readyPath exists,
+// repeating until stop is closed so a signal sent before the launcher's
+// handler is installed is not lost. The test's own handler keeps the default
+// action from killing the test binary.
+func sigtermLauncher(t *testing.T, readyPath string, stop <-chan struct{}) {
+	t.Helper()
+	guard := make(chan os.Signal, 1)
+	signal.Notify(guard, syscall.SIGTERM)
+	t.Cleanup(func() { signal.Stop(guard) })
+	go func() {
+		for {
+			select {
+			case <-stop:
+				return
+			case <-time.After(50 * time.Millisecond):
+			}
+			if _, err := os.Stat(readyPath); err == nil {
+				_ = syscall.Kill(os.Getpid(), syscall.SIGTERM)
+			}
+		}
+	}()
+}
+
+func TestLaunchSIGTERMRunsShutdownSequence(t *testing.T) {
+	launcher, out := newTestLauncher(t, `
+configType: python
+configVersion: 1
+launchMode: command
+executable: /bin/sh
+args: ["-c", "trap '' TERM; touch ready; exec sleep 30"]
+memory:
+  mode: unmanaged
+watchdog:
+  gracePeriodSeconds: 1
+`)
+
+	stop := make(chan struct{})
+	defer close(stop)
+	sigtermLauncher(t, filepath.Join(launcher.params.DistRoot, "ready"), stop)
+
+	start := time.Now()
+	result, err := launcher.Launch()
+	if err != nil {
+		t.Fatalf("unexpected error: %v\n%s", err, out)
+	}
+	if result.ExitCode != -1 {
+		t.Errorf("expected a signaled exit, got %+v\n%s", result, out)
+	}
+	if elapsed := time.Since(start); elapsed > 8*time.Second {
+		t.Errorf("expected SIGKILL after the grace period, took %s", elapsed)
+	}
+	if !strings.Contains(out.String(), "Received SIGTERM, sending SIGTERM") ||
+		!strings.Contains(out.String(), "sending SIGKILL") {
+		t.Errorf("expected SIGTERM to escalate to SIGKILL, got:\n%s", out)
+	}
+}
+
+func TestLaunchSIGTERMDuringStartupProbe(t *testing.T) {
+	launcher, out := newTestLauncher(t, `
+configType: python
+configVersion: 1
+launchMode: command
+executable: /bin/sh
+args: ["-c", "touch ready; exec sleep 30"]
+memory:
+  mode: unmanaged
+startup:
+  tcp: 127.0.0.1:1
+  timeoutSeconds: 30
+  intervalMs: 50
+`)
+
+	stop := make(chan struct{})
+	defer close(stop)
+	sigtermLauncher(t, filepath.Join(launcher.params.DistRoot, "ready"), stop)
+
+	start := time.Now()
+	result, err := launcher.Launch()
+	if err != nil {
+		t.Fatalf("expected the shutdown to preempt the probe, got %v\n%s", err, out)
+	}
+	if result.ExitCode != -1 {
+		t.Errorf("expected the child to be terminated, got %+v\n%s", result, out)
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("expected the launch to end before the probe timeout, took %s", elapsed)
+	}
+	if !strings.Contains(out.String(), "Received SIGTERM, sending SIGTERM") {
+		t.Errorf("expected the shutdown sequence to run, got:\n%s", out)
+	}
+}
+
+// writeFakeCgroup writes files into a cgroup v2 hierarchy under a temp dir and
+// returns its root, for the cgroupRoot config field.
+func writeFakeCgroup(t *testing.T, files map[string]string) string {
+	t.Helper()
+	root := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(root, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func TestValidateQuotaLimitedNoOversubscriptionWarning(t *testing.T) {
+	root := writeFakeCgroup(t, map[string]string{
+		"cgroup.controllers": "cpu memory\n",
+		"memory.max":         "1073741824\n",
+		"cpu.max":            "100000 100000\n",
+	})
+	launcher, out := newTestLauncher(t, `
+configType: python
+configVersion: 1
+launchMode: command
+executable: /bin/true
+cgroupRoot: `+root+`
+memory:
+  mode: cgroup-aware
+`)
+	warnings, err := launcher.Validate()
+	if err != nil {
+		t.Fatalf("unexpected error: %v\n%s", err, out)
+	}
+	if !strings.Contains(out.String(), "CPU: detected 1 effective CPUs") {
+		t.Errorf("expected the quota to be detected, got:\n%s", out)
+	}
+	for _, warning := range warnings {
+		if strings.Contains(warning, "_THREADS") {
+			t.Errorf("expected no thread oversubscription warning, got %q", warning)
+		}
+	}
+}
+
+func TestResolveEnvSizesThreadsFromCpuset(t *testing.T) {
+	root := writeFakeCgroup(t, map[string]string{
+		"cgroup.controllers":    "cpu cpuset memory\n",
+		"memory.max":            "1073741824\n",
+		"cpu.max":               "max 100000\n",
+		"cpuset.cpus.effective": "3\n",
+	})
+	launcher, out := newTestLauncher(t, `
+configType: python
+configVersion: 1
+launchMode: command
+executable: /bin/true
+cgroupRoot: `+root+`
+memory:
+  mode: cgroup-aware
+`)
+	env, err := launcher.ResolveEnv(true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v\n%s", err, out)
+	}
+	got := envToMap(env)
+	for _, name := range append(slices.Clone(threadEnvVars), "SERVICE_CPU_COUNT") {
+		if got[name] != "1" {
+			t.Errorf("expected %s=1 from the cpuset, got %q", name, got[name])
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package launchlib
+
+import "time"
+
+// Leak detection windows. With the default 5s poll interval a window spans
+// one minute, and a warning needs three minutes of steady growth.
+const (
+	leakWindowSamples      = 12
+	leakConsecutiveWindows = 3
+)
+
+// rssSample is one RSS reading.
+type rssSample struct {
+	at  time.Time
+	rss uint64
+}
+
+// leakDetector keeps a ring buffer of RSS samples and, each time it fills,
+// judges the window: leaking if RSS never decreased and its least-squares
+// slope is at least the threshold rate.
+type leakDetector struct {
+	samples [leakWindowSamples]rssSample
+	count   int
+
+	// growingWindows counts consecutive windows judged as leaking, starting
+	// at streakStart.
+	growingWindows int
+	streakStart    time.Time
+	warned         bool
+}
+
+// leakReport describes a detected leak.
+type leakReport struct {
+	bytesPerMinute float64
+	window         time.Duration
+}
+
+// observe records a sample. It returns a report when the leak streak first
+// reaches leakConsecutiveWindows; the streak must break before it reports
+// again.
+func (d *leakDetector) observe(sample rssSample, thresholdBytesPerMinute uint64) (leakReport, bool) {
+	d.samples[d.count] = sample
+	d.count++
+	if d.count < leakWindowSamples {
+		return leakReport{}, false
+	}
+	d.count = 0
+
+	slope, monotonic := windowSlope(d.samples[:])
+	if !monotonic || slope < float64(thresholdBytesPerMinute) {
+		d.growingWindows = 0
+		d.warned = false
+		return leakReport{}, false
+	}
+	d.growingWindows++
+	if d.growingWindows == 1 {
+		d.streakStart = d.samples[0].at
+	}
+	if d.growingWindows < leakConsecutiveWindows || d.warned {
+		return leakReport{}, false
+	}
+	d.warned = true
+	return leakReport{
+		bytesPerMinute: slope,
+		window:         sample.at.Sub(d.streakStart),
+	}, true
+}
+
+// windowSlope returns the least-squares slope of samples in bytes per minute,
+// and whether RSS never decreased across them.
+func windowSlope(samples []rssSample) (float64, bool) {
+	n := float64(len(samples))
+	origin := samples[0].at
+	var sumX, sumY, sumXY, sumXX float64
+	monotonic := true
+	for i, s := range samples {
+		if i > 0 && s.rss < samples[i-1].rss {
+			monotonic = false
+		}
+		x := s.at.Sub(origin).Minutes()
+		y := float64(s.rss)
+		sumX += x
+		sumY += y
+		sumXY += x * y
+		sumXX += x * x
+	}
+	denominator := n*sumXX - sumX*sumX
+	if denominator == 0 {
+		return 0, monotonic
+	}
+	return (n*sumXY - sumX*sumY) / denominator, monotonic
+}
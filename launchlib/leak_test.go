@@ -0,0 +1,107 @@
+package launchlib
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// feedLeakDetector observes n samples taken every 5s, starting at rss and
+// adding step bytes each time, and returns how many reports were made.
+func feedLeakDetector(d *leakDetector, start time.Time, n int, rss uint64, step int64, threshold uint64) int {
+	reports := 0
+	for i := 0; i < n; i++ {
+		sample := rssSample{at: start.Add(time.Duration(i) * 5 * time.Second), rss: uint64(int64(rss) + step*int64(i))}
+		if _, ok := d.observe(sample, threshold); ok {
+			reports++
+		}
+	}
+	return reports
+}
+
+func TestLeakDetectorSteadyGrowth(t *testing.T) {
+	var d leakDetector
+	start := time.Unix(0, 0)
+	// 1 MiB per 5s sample is 12 MiB/min.
+	for i := 0; i < leakWindowSamples*leakConsecutiveWindows-1; i++ {
+		sample := rssSample{at: start.Add(time.Duration(i) * 5 * time.Second), rss: uint64(100+i) << 20}
+		if _, ok := d.observe(sample, 10<<20); ok {
+			t.Fatalf("reported a leak after only %d samples", i+1)
+		}
+	}
+	last := leakWindowSamples*leakConsecutiveWindows - 1
+	report, ok := d.observe(rssSample{at: start.Add(time.Duration(last) * 5 * time.Second), rss: uint64(100+last) << 20}, 10<<20)
+	if !ok {
+		t.Fatal("expected a leak report after consecutive growing windows")
+	}
+	if report.bytesPerMinute < 11.9*(1<<20) || report.bytesPerMinute > 12.1*(1<<20) {
+		t.Errorf("expected ~12 MiB/min, got %.0f bytes/min", report.bytesPerMinute)
+	}
+	if report.window != time.Duration(last)*5*time.Second {
+		t.Errorf("expected the streak to span %s, got %s", time.Duration(last)*5*time.Second, report.window)
+	}
+}
+
+func TestLeakDetectorIgnoresSlowOrNonMonotonicGrowth(t *testing.T) {
+	start := time.Unix(0, 0)
+	var slow leakDetector
+	if n := feedLeakDetector(&slow, start, 10*leakWindowSamples, 100<<20, 1<<10, 10<<20); n != 0 {
+		t.Errorf("expected growth below the threshold not to be reported, got %d reports", n)
+	}
+
+	var sawtooth leakDetector
+	for i := 0; i < 10*leakWindowSamples; i++ {
+		rss := uint64(100+i) << 20
+		if i%leakWindowSamples == 5 {
+			rss -= 50 << 20 // a GC-like drop in every window
+		}
+		if _, ok := sawtooth.observe(rssSample{at: start.Add(time.Duration(i) * 5 * time.Second), rss: rss}, 1<<20); ok {
+			t.Fatal("expected non-monotonic growth not to be reported")
+		}
+	}
+}
+
+func TestLeakDetectorReportsOncePerStreak(t *testing.T) {
+	var d leakDetector
+	start := time.Unix(0, 0)
+	if n := feedLeakDetector(&d, start, 10*leakWindowSamples, 100<<20, 1<<20, 1<<20); n != 1 {
+		t.Errorf("expected one report for a single long streak, got %d", n)
+	}
+	// A flat window breaks the streak; renewed growth is reported again.
+	start = start.Add(10 * leakWindowSamples * 5 * time.Second)
+	feedLeakDetector(&d, start, leakWindowSamples, 500<<20, 0, 1<<20)
+	start = start.Add(leakWindowSamples * 5 * time.Second)
+	if n := feedLeakDetector(&d, start, leakConsecutiveWindows*leakWindowSamples, 500<<20, 1<<20, 1<<20); n != 1 {
+		t.Errorf("expected a new report after the streak broke, got %d", n)
+	}
+}
+
+func TestWatchdogLeakWarning(t *testing.T) {
+	rss := uint64(100)
+	rate := uint64(10)
+	w, buf := newTestWatchdog(WatchdogConfig{LeakWarnBytesPerMinute: &rate}, func(int) (uint64, error) {
+		return rss, nil
+	})
+	now := time.Unix(0, 0)
+	w.now = func() time.Time { return now }
+
+	// 1 byte per 5s poll is 12 bytes/min, well below the 850-byte soft limit.
+	for i := 0; i < leakWindowSamples*leakConsecutiveWindows; i++ {
+		if w.check() {
+			t.Fatal("leak detection must never trigger termination")
+		}
+		rss++
+		now = now.Add(5 * time.Second)
+	}
+
+	out := buf.String()
+	if !strings.Contains(out, "POSSIBLE MEMORY LEAK") {
+		t.Fatalf("expected a leak warning, got:\n%s", out)
+	}
+	if !strings.Contains(out, "hard limit 950 B reached in") {
+		t.Errorf("expected a projected time to the hard limit, got:\n%s", out)
+	}
+	if w.State() != WatchdogStateHealthy {
+		t.Errorf("expected the watchdog to stay healthy, got %s", w.State())
+	}
+}
@@ -6,6 +6,8 @@ import (
 	"io"
 	"log"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -13,8 +15,9 @@ import (
 type LogFormat string
 
 const (
-	LogFormatText LogFormat = "text"
-	LogFormatJSON LogFormat = "json"
+	LogFormatText   LogFormat = "text"
+	LogFormatJSON   LogFormat = "json"
+	LogFormatLogfmt LogFormat = "logfmt"
 )
 
 // LoggingConfig controls launcher log output.
@@ -25,10 +28,22 @@ type LoggingConfig struct {
 	// Level is the minimum log level. Default: "info".
 	Level string `yaml:"level,omitempty"`
 
-	// Fields are extra key-value pairs included in every JSON log line.
+	// Fields are extra key-value pairs included in every log line: as JSON
+	// properties, or appended as key=value pairs (sorted by key) in text and
+	// logfmt mode.
 	Fields map[string]string `yaml:"fields,omitempty"`
+
+	// Color forces ANSI colorization of WARNING/ERROR prefixes in text mode on
+	// or off. Default: enabled only when writing to a terminal and NO_COLOR is unset.
+	Color *bool `yaml:"color,omitempty"`
 }
 
+const (
+	ansiRed    = "\x1b[31m"
+	ansiYellow = "\x1b[33m"
+	ansiReset  = "\x1b[0m"
+)
+
 // DefaultLoggingConfig returns sensible logging defaults.
 func DefaultLoggingConfig() LoggingConfig {
 	return LoggingConfig{
@@ -41,6 +56,10 @@ func DefaultLoggingConfig() LoggingConfig {
 type Logger struct {
 	inner  *log.Logger
 	config LoggingConfig
+	color  bool
+
+	// fieldSuffix is config.Fields rendered for text lines.
+	fieldSuffix string
 }
 
 // NewLogger creates a Logger based on the configuration.
@@ -55,48 +74,120 @@ func NewLogger(w io.Writer, config LoggingConfig) *Logger {
 		config.Level = "info"
 	}
 	var inner *log.Logger
-	if config.Format == LogFormatJSON {
-		inner = log.New(w, "", 0) // no prefix for JSON
+	if config.Format == LogFormatJSON || config.Format == LogFormatLogfmt {
+		inner = log.New(w, "", 0) // the line carries its own timestamp
 	} else {
 		inner = log.New(w, "", log.LstdFlags|log.Lmicroseconds)
 	}
-	return &Logger{inner: inner, config: config}
+	return &Logger{
+		inner:       inner,
+		config:      config,
+		color:       useColor(w, config),
+		fieldSuffix: textFields(config.Fields),
+	}
+}
+
+// textFields renders fields as " key=value" pairs sorted by key, quoting
+// values as logfmtValue does.
+func textFields(fields map[string]string) string {
+	var b strings.Builder
+	for _, k := range sortedKeys(fields) {
+		b.WriteString(" " + k + "=" + logfmtValue(fields[k]))
+	}
+	return b.String()
+}
+
+// logfmtValue quotes v if it is empty or contains whitespace, quotes, '='
+// or control characters, so it reads back as a single logfmt value.
+func logfmtValue(v string) string {
+	if v == "" || strings.ContainsFunc(v, func(r rune) bool {
+		return r <= ' ' || r == '"' || r == '=' || r == 0x7f
+	}) {
+		return strconv.Quote(v)
+	}
+	return v
+}
+
+// useColor decides whether text output to w should be colorized.
+func useColor(w io.Writer, config LoggingConfig) bool {
+	if config.Format != LogFormatText {
+		return false
+	}
+	if config.Color != nil {
+		return *config.Color
+	}
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	return isTerminal(w)
+}
+
+// isTerminal reports whether w is an *os.File backed by a character device.
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// prefix returns the level prefix for text output, colorized if enabled.
+func (l *Logger) prefix(label, color string) string {
+	if l.color {
+		return color + label + ansiReset + " "
+	}
+	return label + " "
 }
 
 // Printf logs a formatted message.
 func (l *Logger) Printf(format string, args ...interface{}) {
-	if l.config.Format == LogFormatJSON {
-		l.jsonLog("info", fmt.Sprintf(format, args...))
-		return
-	}
-	l.inner.Printf(format, args...)
+	l.log("info", "", fmt.Sprintf(format, args...))
 }
 
 // Println logs a message.
 func (l *Logger) Println(msg string) {
-	if l.config.Format == LogFormatJSON {
-		l.jsonLog("info", msg)
-		return
-	}
-	l.inner.Println(msg)
+	l.log("info", "", msg)
 }
 
 // Warnf logs a warning-level formatted message.
 func (l *Logger) Warnf(format string, args ...interface{}) {
-	if l.config.Format == LogFormatJSON {
-		l.jsonLog("warn", fmt.Sprintf(format, args...))
-		return
-	}
-	l.inner.Printf("WARNING: "+format, args...)
+	l.log("warn", l.prefix("WARNING:", ansiYellow), fmt.Sprintf(format, args...))
 }
 
 // Errorf logs an error-level formatted message.
 func (l *Logger) Errorf(format string, args ...interface{}) {
-	if l.config.Format == LogFormatJSON {
-		l.jsonLog("error", fmt.Sprintf(format, args...))
-		return
+	l.log("error", l.prefix("ERROR:", ansiRed), fmt.Sprintf(format, args...))
+}
+
+// log writes message in the configured format. textPrefix is only used for
+// text output, where the level is shown as a prefix.
+func (l *Logger) log(level, textPrefix, message string) {
+	switch l.config.Format {
+	case LogFormatJSON:
+		l.jsonLog(level, message)
+	case LogFormatLogfmt:
+		l.logfmtLog(level, message)
+	default:
+		l.textLog(textPrefix, message)
 	}
-	l.inner.Printf("ERROR: "+format, args...)
+}
+
+// textLog writes a text line: prefix, message, then the configured fields.
+func (l *Logger) textLog(prefix, message string) {
+	l.inner.Output(0, prefix+message+l.fieldSuffix)
+}
+
+// logfmtLog writes a logfmt line: time, level, msg and logger, then the
+// configured fields sorted by key.
+func (l *Logger) logfmtLog(level, message string) {
+	l.inner.Output(0, "time="+time.Now().UTC().Format(time.RFC3339Nano)+
+		" level="+level+
+		" msg="+logfmtValue(message)+
+		" logger=python-service-launcher"+l.fieldSuffix)
 }
 
 func (l *Logger) jsonLog(level, message string) {
@@ -42,6 +42,53 @@ func TestLoggerJSONWithFields(t *testing.T) {
 	}
 }
 
+func TestLoggerTextWithFields(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewLogger(&buf, LoggingConfig{
+		Format: LogFormatText,
+		Fields: map[string]string{"dc": "us-east", "app": "test svc"},
+	})
+	logger.Printf("hello %s", "world")
+	logger.Warnf("careful")
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %q", buf.String())
+	}
+	if !strings.HasSuffix(lines[0], `hello world app="test svc" dc=us-east`) {
+		t.Errorf("expected sorted fields appended to the line, got %q", lines[0])
+	}
+	if !strings.HasSuffix(lines[1], `WARNING: careful app="test svc" dc=us-east`) {
+		t.Errorf("expected fields on warnings too, got %q", lines[1])
+	}
+}
+
+func TestLoggerLogfmtFormat(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewLogger(&buf, LoggingConfig{
+		Format: LogFormatLogfmt,
+		Fields: map[string]string{"service": "foo", "env": "prod east"},
+	})
+	logger.Printf("hello %s", "world")
+	logger.Println("ready")
+	logger.Warnf("disk at %d%%", 90)
+	logger.Errorf(`bad value "x=1"`)
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("expected 4 lines, got %q", buf.String())
+	}
+	want := []string{
+		` level=info msg="hello world" logger=python-service-launcher env="prod east" service=foo`,
+		` level=info msg=ready logger=python-service-launcher env="prod east" service=foo`,
+		` level=warn msg="disk at 90%" logger=python-service-launcher env="prod east" service=foo`,
+		` level=error msg="bad value \"x=1\"" logger=python-service-launcher env="prod east" service=foo`,
+	}
+	for i, line := range lines {
+		if !strings.HasPrefix(line, "time=") || !strings.HasSuffix(line, want[i]) {
+			t.Errorf("expected time=... followed by %q, got %q", want[i], line)
+		}
+	}
+}
+
 func TestLoggerWarnf(t *testing.T) {
 	var buf bytes.Buffer
 	logger := NewLogger(&buf, LoggingConfig{Format: LogFormatText})
@@ -61,3 +108,38 @@ func TestLoggerJSONWarn(t *testing.T) {
 		t.Errorf("expected level:warn in JSON output, got %q", output)
 	}
 }
+
+func TestLoggerNoColorForBuffer(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewLogger(&buf, LoggingConfig{Format: LogFormatText})
+	logger.Warnf("careful")
+	logger.Errorf("broken")
+	if strings.Contains(buf.String(), "\x1b[") {
+		t.Errorf("expected no ANSI codes for a non-terminal writer, got %q", buf.String())
+	}
+}
+
+func TestLoggerColorForced(t *testing.T) {
+	var buf bytes.Buffer
+	color := true
+	logger := NewLogger(&buf, LoggingConfig{Format: LogFormatText, Color: &color})
+	logger.Warnf("careful")
+	logger.Errorf("broken")
+	output := buf.String()
+	if !strings.Contains(output, ansiYellow+"WARNING:"+ansiReset) {
+		t.Errorf("expected yellow WARNING prefix, got %q", output)
+	}
+	if !strings.Contains(output, ansiRed+"ERROR:"+ansiReset) {
+		t.Errorf("expected red ERROR prefix, got %q", output)
+	}
+}
+
+func TestLoggerColorIgnoredForJSON(t *testing.T) {
+	var buf bytes.Buffer
+	color := true
+	logger := NewLogger(&buf, LoggingConfig{Format: LogFormatJSON, Color: &color})
+	logger.Warnf("careful")
+	if strings.Contains(buf.String(), "\x1b[") {
+		t.Errorf("expected no ANSI codes in JSON output, got %q", buf.String())
+	}
+}
@@ -0,0 +1,79 @@
+package launchlib
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// launchManifestPath is where the launch manifest is written, relative to the
+// dist root.
+const launchManifestPath = "var/log/launch-manifest.json"
+
+// LaunchManifest records exactly what was launched, for audit and
+// reproducibility.
+type LaunchManifest struct {
+	Time            time.Time `json:"time"`
+	LauncherVersion string    `json:"launcherVersion"`
+	LauncherCommit  string    `json:"launcherCommit"`
+	ServiceName     string    `json:"serviceName"`
+	ServiceVersion  string    `json:"serviceVersion"`
+
+	// ConfigHash is the SHA-256 of the static and custom configs as read,
+	// after any remote overlay.
+	ConfigHash string `json:"configHash"`
+
+	Argv     []string  `json:"argv"`
+	Env      []string  `json:"env"`
+	CPUCount int       `json:"cpuCount"`
+	Limits   DebugInfo `json:"limits"`
+}
+
+// configHash returns a hex SHA-256 identifying the given configs.
+func configHash(static StaticLauncherConfig, custom CustomLauncherConfig) (string, error) {
+	data, err := json.Marshal(struct {
+		Static StaticLauncherConfig
+		Custom CustomLauncherConfig
+	}{static, custom})
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// newLaunchManifest builds the manifest for plan. Env values are redacted the
+// same way as --print-env output.
+func (l *Launcher) newLaunchManifest(plan launchPlan) LaunchManifest {
+	env := RedactEnv(plan.env, plan.secretEnv)
+	sort.Strings(env)
+	return LaunchManifest{
+		Time:            time.Now(),
+		LauncherVersion: l.params.LauncherVersion,
+		LauncherCommit:  l.params.LauncherCommit,
+		ServiceName:     l.params.ServiceName,
+		ServiceVersion:  l.params.ServiceVersion,
+		ConfigHash:      plan.configHash,
+		Argv:            plan.cmdArgs,
+		Env:             env,
+		CPUCount:        plan.config.EffectiveCPUCount,
+		Limits:          NewDebugInfo(plan.limits),
+	}
+}
+
+// WriteLaunchManifest writes manifest as JSON to path, replacing any
+// previous manifest.
+func WriteLaunchManifest(path string, manifest LaunchManifest) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
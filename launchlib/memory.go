@@ -15,24 +15,35 @@
 package launchlib
 
 import (
+	"errors"
 	"fmt"
 	"io/fs"
 	"os"
 	"path/filepath"
 	"runtime"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 )
 
+// DefaultCgroupRoot is where the cgroup hierarchy is mounted unless
+// StaticLauncherConfig.CgroupRoot says otherwise. The cgroup file paths
+// below are relative to it.
+const DefaultCgroupRoot = "/sys/fs/cgroup"
+
 const (
 	// cgroupV2MemoryMaxPath is the cgroup v2 memory limit file.
-	cgroupV2MemoryMaxPath = "/sys/fs/cgroup/memory.max"
+	cgroupV2MemoryMaxPath = "memory.max"
 
 	// cgroupV1MemoryLimitPath is the cgroup v1 memory limit file.
-	cgroupV1MemoryLimitPath = "/sys/fs/cgroup/memory/memory.limit_in_bytes"
+	cgroupV1MemoryLimitPath = "memory/memory.limit_in_bytes"
+
+	// cgroupV2MemoryEventsPath holds cgroup v2 memory event counters, including oom_kill.
+	cgroupV2MemoryEventsPath = "memory.events"
 
 	// cgroupV2IndicatorPath is used to detect cgroup v2.
-	cgroupV2IndicatorPath = "/sys/fs/cgroup/cgroup.controllers"
+	cgroupV2IndicatorPath = "cgroup.controllers"
 
 	// procMemInfoPath is used as a fallback to get total system memory.
 	procMemInfoPath = "/proc/meminfo"
@@ -42,10 +53,26 @@ const (
 	minimumEffectiveLimitBytes = 64 * 1024 * 1024 // 64 MiB
 )
 
+// Memory limit sources reported in MemoryLimits.LimitSource.
+const (
+	LimitSourceCgroupV2      = "cgroup-v2"
+	LimitSourceCgroupV1      = "cgroup-v1"
+	LimitSourceFixed         = "fixed"
+	LimitSourceSystemMeminfo = "system-meminfo"
+)
+
 // MemoryLimiter detects cgroup memory limits and computes effective limits
 // for the Python process based on the launcher configuration.
 type MemoryLimiter struct {
 	filesystem fs.FS
+	logger     *Logger
+
+	// cgroupRoot is where cgroup files are read from; empty means
+	// DefaultCgroupRoot.
+	cgroupRoot string
+
+	// For testing: override the backoff sleep
+	sleep func(time.Duration)
 }
 
 // MemoryLimits holds the computed memory limits and associated metadata.
@@ -66,18 +93,75 @@ type MemoryLimits struct {
 	// CgroupVersion is 1 or 2, or 0 if cgroups are not available.
 	CgroupVersion int
 
-	// IsContainer is true if the CONTAINER env var is set.
+	// LimitSource records where CgroupLimitBytes came from: one of the
+	// LimitSource constants, or empty when memory is unmanaged.
+	LimitSource string
+
+	// IsContainer is true if the launcher detected a container.
 	IsContainer bool
+
+	// ClampedToFloor is true when the computed effective limit was below
+	// the 64 MiB floor and was raised to it, usually a sign that the
+	// container's memory limit is too small for the service.
+	ClampedToFloor bool
+}
+
+// String summarizes the limits for logs and tooling output.
+func (l MemoryLimits) String() string {
+	return fmt.Sprintf("source=%s cgroupVersion=%d cgroup=%s effective=%s softWarn=%s hardKill=%s container=%t",
+		l.LimitSource,
+		l.CgroupVersion,
+		formatBytes(l.CgroupLimitBytes),
+		formatBytes(l.EffectiveLimitBytes),
+		formatBytes(l.SoftWarnBytes),
+		formatBytes(l.HardKillBytes),
+		l.IsContainer,
+	)
+}
+
+// ComputeMemoryLimits predicts the limits the launcher would choose without
+// building a full MergedConfig. Watchdog thresholds use the default
+// percentages. maxRssPercent is normalized and validated as in a config file,
+// so 0.75 means 75%; fragBuffer must be in [0, 1). For fixed mode, use
+// ComputeLimits with Memory.FixedLimitBytes.
+func ComputeMemoryLimits(mode MemoryMode, maxRssPercent, fragBuffer float64, filesystem fs.FS) (MemoryLimits, error) {
+	maxRssPercent = normalizePercent(maxRssPercent)
+	if err := validatePercent("memory.maxRssPercent", maxRssPercent); err != nil {
+		return MemoryLimits{}, err
+	}
+	if fragBuffer < 0 || fragBuffer >= 1 {
+		return MemoryLimits{}, invalidField("memory.heapFragmentationBuffer", fragBuffer, "must be in [0, 1)")
+	}
+	memory := DefaultMemoryConfig()
+	memory.Mode = mode
+	memory.MaxRSSPercent = maxRssPercent
+	memory.HeapFragmentationBuffer = &fragBuffer
+	config := MergedConfig{
+		Memory:   memory,
+		Watchdog: DefaultWatchdogConfig(),
+	}
+	return NewMemoryLimiterWithFS(filesystem).ComputeLimits(config)
 }
 
 // NewMemoryLimiter creates a new MemoryLimiter using the real filesystem.
 func NewMemoryLimiter() *MemoryLimiter {
-	return &MemoryLimiter{filesystem: os.DirFS("/")}
+	return NewMemoryLimiterWithFS(os.DirFS("/"))
 }
 
 // NewMemoryLimiterWithFS creates a MemoryLimiter with an injected filesystem for testing.
 func NewMemoryLimiterWithFS(filesystem fs.FS) *MemoryLimiter {
-	return &MemoryLimiter{filesystem: filesystem}
+	return &MemoryLimiter{filesystem: filesystem, sleep: time.Sleep}
+}
+
+// SetLogger sets the logger used to report cgroup read retries.
+func (m *MemoryLimiter) SetLogger(logger *Logger) {
+	m.logger = logger
+}
+
+// SetCgroupRoot sets where the cgroup hierarchy is mounted. An empty root
+// means DefaultCgroupRoot.
+func (m *MemoryLimiter) SetCgroupRoot(root string) {
+	m.cgroupRoot = root
 }
 
 // ComputeLimits determines the effective memory limits based on the merged config.
@@ -95,6 +179,7 @@ func (m *MemoryLimiter) ComputeLimits(config MergedConfig) (MemoryLimits, error)
 			return limits, fmt.Errorf("memory mode is 'fixed' but fixedLimitBytes is 0")
 		}
 		limits.CgroupLimitBytes = config.Memory.FixedLimitBytes
+		limits.LimitSource = LimitSourceFixed
 
 	case MemoryModeCgroupAware:
 		cgroupVersion, err := m.detectCgroupVersion()
@@ -103,11 +188,12 @@ func (m *MemoryLimiter) ComputeLimits(config MergedConfig) (MemoryLimits, error)
 		}
 		limits.CgroupVersion = cgroupVersion
 
-		cgroupLimit, err := m.readCgroupMemoryLimit(cgroupVersion)
+		cgroupLimit, source, err := m.readCgroupMemoryLimitWithRetry(cgroupVersion, config.Memory.CgroupReadAttempts)
 		if err != nil {
 			return limits, fmt.Errorf("failed to read cgroup memory limit: %w", err)
 		}
 		limits.CgroupLimitBytes = cgroupLimit
+		limits.LimitSource = source
 
 	default:
 		return limits, fmt.Errorf("unknown memory mode: %q", config.Memory.Mode)
@@ -116,11 +202,20 @@ func (m *MemoryLimiter) ComputeLimits(config MergedConfig) (MemoryLimits, error)
 	// Compute effective limit:
 	//   base = cgroupLimit * maxRssPercent / 100
 	//   effective = base * (1 - heapFragmentationBuffer)
+	//   effective = min(effective, cgroupLimit - minHeadroomBytes), if set
 	base := uint64(float64(limits.CgroupLimitBytes) * config.Memory.MaxRSSPercent / 100.0)
-	effective := uint64(float64(base) * (1.0 - config.Memory.HeapFragmentationBuffer))
+	effective := uint64(float64(base) * (1.0 - valueOr(config.Memory.HeapFragmentationBuffer, 0)))
+	if headroom := config.Memory.MinHeadroomBytes; headroom > 0 {
+		var capped uint64
+		if limits.CgroupLimitBytes > headroom {
+			capped = limits.CgroupLimitBytes - headroom
+		}
+		effective = min(effective, capped)
+	}
 
 	if effective < minimumEffectiveLimitBytes {
 		effective = minimumEffectiveLimitBytes
+		limits.ClampedToFloor = true
 	}
 	limits.EffectiveLimitBytes = effective
 
@@ -151,14 +246,21 @@ func BuildMemoryEnv(config MergedConfig, limits MemoryLimits) map[string]string
 	env["SLS_CGROUP_LIMIT_BYTES"] = strconv.FormatUint(limits.CgroupLimitBytes, 10)
 	env["SLS_MEMORY_MODE"] = string(config.Memory.Mode)
 
+	// Library-specific limit variables. Entries are validated with the config.
+	for _, entry := range config.Memory.ExtraLimitEnvVars {
+		if name, percent, err := parseLimitEnvVar(entry); err == nil {
+			env[name] = strconv.FormatUint(uint64(float64(limits.EffectiveLimitBytes)*percent/100), 10)
+		}
+	}
+
 	// glibc malloc tuning to reduce memory fragmentation.
 	// Python's default allocator (pymalloc) handles small objects, but anything
 	// that goes through C extensions (numpy, pandas, etc.) uses glibc malloc.
-	if config.Memory.MallocArenaMax > 0 {
-		env["MALLOC_ARENA_MAX"] = strconv.Itoa(config.Memory.MallocArenaMax)
+	if arenaMax := valueOr(config.Memory.MallocArenaMax, 0); arenaMax > 0 {
+		env["MALLOC_ARENA_MAX"] = strconv.Itoa(arenaMax)
 	}
-	if config.Memory.MallocTrimThreshold >= 0 {
-		env["MALLOC_TRIM_THRESHOLD_"] = strconv.FormatInt(config.Memory.MallocTrimThreshold, 10)
+	if trim := config.Memory.MallocTrimThreshold; trim != nil && *trim >= 0 {
+		env["MALLOC_TRIM_THRESHOLD_"] = strconv.FormatInt(*trim, 10)
 	}
 
 	// Use system malloc instead of pymalloc so that RSS more accurately reflects
@@ -179,16 +281,34 @@ func BuildMemoryEnv(config MergedConfig, limits MemoryLimits) map[string]string
 	return env
 }
 
+// parseLimitEnvVar parses a memory.extraLimitEnvVars entry, NAME or
+// NAME:PERCENT, returning the variable name and the percentage of the
+// effective limit it receives (100 for a bare NAME).
+func parseLimitEnvVar(entry string) (string, float64, error) {
+	name, percentText, hasPercent := strings.Cut(entry, ":")
+	if name == "" || strings.ContainsAny(name, "= \t") {
+		return "", 0, fmt.Errorf("invalid variable name %q", name)
+	}
+	if !hasPercent {
+		return name, 100, nil
+	}
+	percent, err := strconv.ParseFloat(strings.TrimSuffix(percentText, "%"), 64)
+	if err != nil || percent <= 0 || percent > 100 {
+		return "", 0, fmt.Errorf("percentage must be a number in (0, 100], got %q", percentText)
+	}
+	return name, percent, nil
+}
+
 // detectCgroupVersion determines whether the system uses cgroup v1 or v2.
 func (m *MemoryLimiter) detectCgroupVersion() (int, error) {
 	// cgroup v2 is indicated by the presence of cgroup.controllers at the root
-	_, err := fs.Stat(m.filesystem, relPath(cgroupV2IndicatorPath))
+	_, err := fs.Stat(m.filesystem, cgroupPath(m.cgroupRoot, cgroupV2IndicatorPath))
 	if err == nil {
 		return 2, nil
 	}
 
 	// Check for cgroup v1 memory controller
-	_, err = fs.Stat(m.filesystem, relPath(cgroupV1MemoryLimitPath))
+	_, err = fs.Stat(m.filesystem, cgroupPath(m.cgroupRoot, cgroupV1MemoryLimitPath))
 	if err == nil {
 		return 1, nil
 	}
@@ -197,41 +317,109 @@ func (m *MemoryLimiter) detectCgroupVersion() (int, error) {
 }
 
 // readCgroupMemoryLimit reads the memory limit from the appropriate cgroup path.
-func (m *MemoryLimiter) readCgroupMemoryLimit(cgroupVersion int) (uint64, error) {
-	var path string
+// It also returns the source of the limit, which is system-meminfo when the
+// cgroup reports no limit.
+func (m *MemoryLimiter) readCgroupMemoryLimit(cgroupVersion int) (uint64, string, error) {
+	var path, source string
 	switch cgroupVersion {
 	case 2:
-		path = relPath(cgroupV2MemoryMaxPath)
+		path = cgroupPath(m.cgroupRoot, cgroupV2MemoryMaxPath)
+		source = LimitSourceCgroupV2
 	case 1:
-		path = relPath(cgroupV1MemoryLimitPath)
+		path = cgroupPath(m.cgroupRoot, cgroupV1MemoryLimitPath)
+		source = LimitSourceCgroupV1
 	default:
-		return 0, fmt.Errorf("unsupported cgroup version: %d", cgroupVersion)
+		return 0, "", fmt.Errorf("unsupported cgroup version: %d", cgroupVersion)
 	}
 
 	data, err := fs.ReadFile(m.filesystem, path)
 	if err != nil {
-		return 0, fmt.Errorf("failed to read %s: %w", path, err)
+		return 0, "", fmt.Errorf("failed to read %s: %w", path, err)
 	}
 
 	content := strings.TrimSpace(string(data))
 
 	// cgroup v2 uses "max" to indicate no limit
 	if content == "max" {
-		return m.readSystemMemory()
+		return m.systemMemoryLimit()
 	}
 
 	limit, err := strconv.ParseUint(content, 10, 64)
 	if err != nil {
-		return 0, fmt.Errorf("failed to parse memory limit %q: %w", content, err)
+		return 0, "", fmt.Errorf("failed to parse memory limit %q: %w", content, err)
 	}
 
 	// cgroup v1 uses a very large number to indicate no limit
 	// (typically 2^63 - 4096 or similar). Treat anything over 1 EiB as unlimited.
 	if cgroupVersion == 1 && limit > 1<<60 {
-		return m.readSystemMemory()
+		return m.systemMemoryLimit()
 	}
 
-	return limit, nil
+	return limit, source, nil
+}
+
+// cgroupReadBackoff is the delay before the first cgroup read retry; it doubles
+// on each subsequent retry (250ms, 500ms, ...).
+const cgroupReadBackoff = 250 * time.Millisecond
+
+// readCgroupMemoryLimitWithRetry calls readCgroupMemoryLimit up to attempts
+// times, backing off between transient failures. A missing file, an
+// unsupported cgroup version or an unparseable limit fails at once.
+func (m *MemoryLimiter) readCgroupMemoryLimitWithRetry(cgroupVersion, attempts int) (uint64, string, error) {
+	if attempts <= 0 {
+		attempts = DefaultMemoryConfig().CgroupReadAttempts
+	}
+	backoff := cgroupReadBackoff
+	for attempt := 1; ; attempt++ {
+		limit, source, err := m.readCgroupMemoryLimit(cgroupVersion)
+		if err == nil || attempt >= attempts || !isTransientReadError(err) {
+			return limit, source, err
+		}
+		if m.logger != nil {
+			m.logger.Printf("Cgroup memory limit read failed (attempt %d/%d): %v; retrying in %s",
+				attempt, attempts, err, backoff)
+		}
+		m.sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// isTransientReadError reports whether err is a file read failure that a
+// retry may get past. A missing file is permanent, as are errors that are not
+// read failures, such as parse errors.
+func isTransientReadError(err error) bool {
+	var pathErr *fs.PathError
+	return errors.As(err, &pathErr) && !errors.Is(err, fs.ErrNotExist)
+}
+
+// OOMKillCount returns the cgroup v2 oom_kill counter: how many processes in
+// the cgroup the kernel OOM killer has killed.
+func (m *MemoryLimiter) OOMKillCount() (uint64, error) {
+	path := cgroupPath(m.cgroupRoot, cgroupV2MemoryEventsPath)
+	data, err := fs.ReadFile(m.filesystem, path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 2 && fields[0] == "oom_kill" {
+			count, err := strconv.ParseUint(fields[1], 10, 64)
+			if err != nil {
+				return 0, fmt.Errorf("failed to parse oom_kill %q: %w", fields[1], err)
+			}
+			return count, nil
+		}
+	}
+	return 0, fmt.Errorf("no oom_kill counter in %s", path)
+}
+
+// systemMemoryLimit returns total system memory tagged with its source.
+func (m *MemoryLimiter) systemMemoryLimit() (uint64, string, error) {
+	total, err := m.readSystemMemory()
+	if err != nil {
+		return 0, "", err
+	}
+	return total, LimitSourceSystemMeminfo, nil
 }
 
 // readSystemMemory reads total system memory from /proc/meminfo as a fallback.
@@ -258,6 +446,27 @@ func (m *MemoryLimiter) readSystemMemory() (uint64, error) {
 	return 0, fmt.Errorf("MemTotal not found in %s", procMemInfoPath)
 }
 
+// MemoryEnvOverrides describes each variable from BuildMemoryEnv that config
+// env replaces with a different value, sorted by name. The launcher logs these
+// so superseded memory tuning (e.g. PYTHONMALLOC=pymalloc) is visible.
+func MemoryEnvOverrides(config MergedConfig, limits MemoryLimits) []string {
+	memEnv := BuildMemoryEnv(config, limits)
+	var overrides []string
+	for name, launcherValue := range memEnv {
+		value, ok := config.Env[name]
+		if !ok || value == launcherValue {
+			continue
+		}
+		if _, fromFile := config.EnvFromFile[name]; fromFile || isSensitiveEnvName(name) {
+			value = redactedValue
+		}
+		overrides = append(overrides, fmt.Sprintf("%s=%s from config env overrides the launcher's %s=%s",
+			name, value, name, launcherValue))
+	}
+	sort.Strings(overrides)
+	return overrides
+}
+
 // setDefaultMap sets a key in a map only if it's not already present.
 func setDefaultMap(m map[string]string, key, value string) {
 	if _, exists := m[key]; !exists {
@@ -269,3 +478,12 @@ func setDefaultMap(m map[string]string, key, value string) {
 func relPath(absPath string) string {
 	return filepath.Clean(strings.TrimPrefix(absPath, "/"))
 }
+
+// cgroupPath returns the fs.FS path of the cgroup file name under root, or
+// under DefaultCgroupRoot when root is empty.
+func cgroupPath(root, name string) string {
+	if root == "" {
+		root = DefaultCgroupRoot
+	}
+	return relPath(filepath.Join(root, name))
+}
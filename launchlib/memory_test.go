@@ -15,10 +15,14 @@
 package launchlib
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
 	"io/fs"
+	"strings"
 	"testing"
 	"testing/fstest"
+	"time"
 )
 
 // testFS creates a fake filesystem for testing cgroup scenarios.
@@ -88,7 +92,7 @@ func TestReadCgroupV2MemoryLimit(t *testing.T) {
 			})
 
 			limiter := NewMemoryLimiterWithFS(filesystem)
-			limit, err := limiter.readCgroupMemoryLimit(2)
+			limit, _, err := limiter.readCgroupMemoryLimit(2)
 			if (err != nil) != tt.wantErr {
 				t.Fatalf("unexpected error: %v", err)
 			}
@@ -108,10 +112,13 @@ func TestReadCgroupV2Unlimited(t *testing.T) {
 	})
 
 	limiter := NewMemoryLimiterWithFS(filesystem)
-	limit, err := limiter.readCgroupMemoryLimit(2)
+	limit, source, err := limiter.readCgroupMemoryLimit(2)
 	if err != nil {
 		t.Fatal(err)
 	}
+	if source != LimitSourceSystemMeminfo {
+		t.Errorf("expected source %s, got %s", LimitSourceSystemMeminfo, source)
+	}
 	// 16384000 kB = 16777216000 bytes
 	expected := uint64(16384000 * 1024)
 	if limit != expected {
@@ -127,10 +134,13 @@ func TestReadCgroupV1Unlimited(t *testing.T) {
 	})
 
 	limiter := NewMemoryLimiterWithFS(filesystem)
-	limit, err := limiter.readCgroupMemoryLimit(1)
+	limit, source, err := limiter.readCgroupMemoryLimit(1)
 	if err != nil {
 		t.Fatal(err)
 	}
+	if source != LimitSourceSystemMeminfo {
+		t.Errorf("expected source %s, got %s", LimitSourceSystemMeminfo, source)
+	}
 	expected := uint64(8192000 * 1024)
 	if limit != expected {
 		t.Errorf("expected %d (system memory), got %d", expected, limit)
@@ -150,7 +160,7 @@ func TestComputeLimitsCgroupAware(t *testing.T) {
 		Memory: MemoryConfig{
 			Mode:                    MemoryModeCgroupAware,
 			MaxRSSPercent:           75,
-			HeapFragmentationBuffer: 0.10,
+			HeapFragmentationBuffer: DefaultMemoryConfig().HeapFragmentationBuffer,
 		},
 		Watchdog: WatchdogConfig{
 			SoftLimitPercent: 85,
@@ -189,6 +199,9 @@ func TestComputeLimitsCgroupAware(t *testing.T) {
 	if limits.CgroupVersion != 2 {
 		t.Errorf("expected cgroup v2, got v%d", limits.CgroupVersion)
 	}
+	if limits.ClampedToFloor {
+		t.Error("expected ClampedToFloor to be false for a 1 GiB limit")
+	}
 }
 
 func TestComputeLimitsFixed(t *testing.T) {
@@ -199,7 +212,7 @@ func TestComputeLimitsFixed(t *testing.T) {
 			Mode:                    MemoryModeFixed,
 			FixedLimitBytes:         512 * 1024 * 1024, // 512 MiB
 			MaxRSSPercent:           75,
-			HeapFragmentationBuffer: 0.10,
+			HeapFragmentationBuffer: DefaultMemoryConfig().HeapFragmentationBuffer,
 		},
 		Watchdog: WatchdogConfig{
 			SoftLimitPercent: 85,
@@ -250,7 +263,7 @@ func TestComputeLimitsMinimumFloor(t *testing.T) {
 		Memory: MemoryConfig{
 			Mode:                    MemoryModeCgroupAware,
 			MaxRSSPercent:           75,
-			HeapFragmentationBuffer: 0.10,
+			HeapFragmentationBuffer: DefaultMemoryConfig().HeapFragmentationBuffer,
 		},
 		Watchdog: WatchdogConfig{
 			SoftLimitPercent: 85,
@@ -268,14 +281,55 @@ func TestComputeLimitsMinimumFloor(t *testing.T) {
 		t.Errorf("effective limit %d should not be below minimum %d",
 			limits.EffectiveLimitBytes, minimumEffectiveLimitBytes)
 	}
+	if !limits.ClampedToFloor {
+		t.Error("expected ClampedToFloor for a 32 MiB cgroup limit")
+	}
+}
+
+func TestComputeLimitsMinHeadroom(t *testing.T) {
+	const gib = 1 << 30
+	noBuffer := 0.0
+	tests := []struct {
+		name          string
+		limit         uint64
+		headroom      uint64
+		wantEffective uint64
+	}{
+		{"absolute headroom binds", 1 * gib, gib / 2, gib / 2},                     // 75% = 768MiB > 1GiB - 512MiB
+		{"percentage binds", 32 * gib, 2 * gib, 24 * gib},                          // 75% = 24GiB < 32GiB - 2GiB
+		{"unset", 32 * gib, 0, 24 * gib},                                           // percentage only
+		{"headroom above the limit", 1 * gib, 2 * gib, minimumEffectiveLimitBytes}, // floor applies afterwards
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limiter := NewMemoryLimiterWithFS(testFS(map[string]string{}))
+			limits, err := limiter.ComputeLimits(MergedConfig{
+				Memory: MemoryConfig{
+					Mode:                    MemoryModeFixed,
+					FixedLimitBytes:         tt.limit,
+					MaxRSSPercent:           75,
+					HeapFragmentationBuffer: &noBuffer,
+					MinHeadroomBytes:        tt.headroom,
+				},
+				Watchdog: WatchdogConfig{SoftLimitPercent: 85, HardLimitPercent: 95},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if limits.EffectiveLimitBytes != tt.wantEffective {
+				t.Errorf("expected effective limit %d, got %d", tt.wantEffective, limits.EffectiveLimitBytes)
+			}
+		})
+	}
 }
 
 func TestBuildMemoryEnv(t *testing.T) {
+	arenaMax, trimThreshold := 2, int64(131072)
 	config := MergedConfig{
 		Memory: MemoryConfig{
 			Mode:                MemoryModeCgroupAware,
-			MallocArenaMax:      2,
-			MallocTrimThreshold: 131072,
+			MallocArenaMax:      &arenaMax,
+			MallocTrimThreshold: &trimThreshold,
 		},
 	}
 	limits := MemoryLimits{
@@ -299,6 +353,60 @@ func TestBuildMemoryEnv(t *testing.T) {
 	}
 }
 
+func TestBuildMemoryEnvExtraLimitEnvVars(t *testing.T) {
+	config := MergedConfig{
+		Memory: MemoryConfig{
+			Mode:              MemoryModeCgroupAware,
+			ExtraLimitEnvVars: []string{"POLARS_MAX_MEMORY", "RAY_memory:50", "ARROW_LIMIT:25%"},
+		},
+	}
+	limits := MemoryLimits{EffectiveLimitBytes: 1000000000}
+
+	env := BuildMemoryEnv(config, limits)
+
+	want := map[string]string{
+		"POLARS_MAX_MEMORY":  "1000000000",
+		"RAY_memory":         "500000000",
+		"ARROW_LIMIT":        "250000000",
+		"MEMORY_LIMIT_BYTES": "1000000000",
+	}
+	for k, v := range want {
+		if env[k] != v {
+			t.Errorf("expected %s=%s, got %q", k, v, env[k])
+		}
+	}
+}
+
+func TestParseLimitEnvVar(t *testing.T) {
+	tests := []struct {
+		entry       string
+		wantName    string
+		wantPercent float64
+		wantErr     bool
+	}{
+		{"MY_LIMIT", "MY_LIMIT", 100, false},
+		{"MY_LIMIT:50", "MY_LIMIT", 50, false},
+		{"MY_LIMIT:12.5%", "MY_LIMIT", 12.5, false},
+		{"", "", 0, true},
+		{":50", "", 0, true},
+		{"MY_LIMIT:0", "", 0, true},
+		{"MY_LIMIT:150", "", 0, true},
+		{"MY_LIMIT:half", "", 0, true},
+		{"A=B", "", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.entry, func(t *testing.T) {
+			name, percent, err := parseLimitEnvVar(tt.entry)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if name != tt.wantName || percent != tt.wantPercent {
+				t.Errorf("expected (%s, %g), got (%s, %g)", tt.wantName, tt.wantPercent, name, percent)
+			}
+		})
+	}
+}
+
 func TestBuildMemoryEnvUnmanaged(t *testing.T) {
 	config := MergedConfig{
 		Memory: MemoryConfig{
@@ -336,3 +444,262 @@ func TestFormatBytes(t *testing.T) {
 		})
 	}
 }
+
+func TestComputeMemoryLimitsMatchesFullConfig(t *testing.T) {
+	filesystem := testFS(map[string]string{
+		"sys/fs/cgroup/cgroup.controllers": "cpu memory io",
+		"sys/fs/cgroup/memory.max":         "2147483648", // 2 GiB
+	})
+
+	fragBuffer := 0.2
+	merged := MergeConfigs(StaticLauncherConfig{
+		ConfigVersion: 1,
+		Executable:    "service/bin/app.pex",
+		Memory: MemoryConfig{
+			Mode:                    MemoryModeCgroupAware,
+			MaxRSSPercent:           60,
+			HeapFragmentationBuffer: &fragBuffer,
+		},
+	}, CustomLauncherConfig{})
+	merged.IsContainer = false
+	want, err := NewMemoryLimiterWithFS(filesystem).ComputeLimits(merged)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := ComputeMemoryLimits(MemoryModeCgroupAware, 60, 0.2, filesystem)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != want {
+		t.Errorf("standalone limits differ from full config path:\n got: %s\nwant: %s", got, want)
+	}
+}
+
+func TestComputeMemoryLimitsNormalizesPercent(t *testing.T) {
+	filesystem := testFS(map[string]string{
+		"sys/fs/cgroup/cgroup.controllers": "cpu memory io",
+		"sys/fs/cgroup/memory.max":         "2147483648", // 2 GiB
+	})
+
+	fraction, err := ComputeMemoryLimits(MemoryModeCgroupAware, 0.75, 0.2, filesystem)
+	if err != nil {
+		t.Fatal(err)
+	}
+	percent, err := ComputeMemoryLimits(MemoryModeCgroupAware, 75, 0.2, filesystem)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fraction != percent {
+		t.Errorf("expected 0.75 to mean 75%%:\n got: %s\nwant: %s", fraction, percent)
+	}
+
+	var validationErr *ConfigValidationError
+	if _, err := ComputeMemoryLimits(MemoryModeCgroupAware, 750, 0.2, filesystem); !errors.As(err, &validationErr) {
+		t.Errorf("expected a validation error for 750, got %v", err)
+	}
+}
+
+func TestComputeMemoryLimitsRejectsFragBufferOutOfRange(t *testing.T) {
+	filesystem := testFS(map[string]string{
+		"sys/fs/cgroup/cgroup.controllers": "cpu memory io",
+		"sys/fs/cgroup/memory.max":         "2147483648", // 2 GiB
+	})
+
+	for _, fragBuffer := range []float64{1.5, -0.1} {
+		var validationErr *ConfigValidationError
+		_, err := ComputeMemoryLimits(MemoryModeCgroupAware, 75, fragBuffer, filesystem)
+		if !errors.As(err, &validationErr) {
+			t.Errorf("fragBuffer %v: expected a validation error, got %v", fragBuffer, err)
+			continue
+		}
+		if validationErr.Field != "memory.heapFragmentationBuffer" {
+			t.Errorf("fragBuffer %v: expected field memory.heapFragmentationBuffer, got %s", fragBuffer, validationErr.Field)
+		}
+	}
+}
+
+func TestMemoryLimitsString(t *testing.T) {
+	limits := MemoryLimits{
+		CgroupLimitBytes:    2 * 1024 * 1024 * 1024,
+		EffectiveLimitBytes: 1024 * 1024 * 1024,
+		CgroupVersion:       2,
+		LimitSource:         LimitSourceCgroupV2,
+	}
+	got := limits.String()
+	for _, want := range []string{"source=cgroup-v2", "cgroupVersion=2", "cgroup=2.00 GiB", "effective=1.00 GiB"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("expected %q in %q", want, got)
+		}
+	}
+}
+
+// flakyFS fails the first failures opens of path with a transient error.
+type flakyFS struct {
+	fs.FS
+	path     string
+	failures int
+	opens    int
+}
+
+func (f *flakyFS) Open(name string) (fs.File, error) {
+	if name == f.path {
+		f.opens++
+		if f.opens <= f.failures {
+			return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrPermission}
+		}
+	}
+	return f.FS.Open(name)
+}
+
+func TestComputeLimitsRetriesCgroupRead(t *testing.T) {
+	filesystem := &flakyFS{
+		FS: testFS(map[string]string{
+			"sys/fs/cgroup/cgroup.controllers": "cpu memory io",
+			"sys/fs/cgroup/memory.max":         "1073741824",
+		}),
+		path:     "sys/fs/cgroup/memory.max",
+		failures: 2,
+	}
+	var buf bytes.Buffer
+	limiter := NewMemoryLimiterWithFS(filesystem)
+	limiter.SetLogger(NewLogger(&buf, LoggingConfig{}))
+	var sleeps []time.Duration
+	limiter.sleep = func(d time.Duration) { sleeps = append(sleeps, d) }
+
+	config := MergedConfig{Memory: DefaultMemoryConfig(), Watchdog: DefaultWatchdogConfig()}
+	limits, err := limiter.ComputeLimits(config)
+	if err != nil {
+		t.Fatalf("expected success on the third attempt, got %v", err)
+	}
+	if limits.CgroupLimitBytes != 1073741824 {
+		t.Errorf("expected cgroup limit 1073741824, got %d", limits.CgroupLimitBytes)
+	}
+	if filesystem.opens != 3 {
+		t.Errorf("expected 3 read attempts, got %d", filesystem.opens)
+	}
+	if len(sleeps) != 2 || sleeps[0] != 250*time.Millisecond || sleeps[1] != 500*time.Millisecond {
+		t.Errorf("expected backoff [250ms 500ms], got %v", sleeps)
+	}
+	if got := strings.Count(buf.String(), "retrying"); got != 2 {
+		t.Errorf("expected 2 retry log lines, got %d:\n%s", got, buf.String())
+	}
+}
+
+func TestComputeLimitsGivesUpAfterAttempts(t *testing.T) {
+	filesystem := &flakyFS{
+		FS: testFS(map[string]string{
+			"sys/fs/cgroup/cgroup.controllers": "cpu memory io",
+			"sys/fs/cgroup/memory.max":         "1073741824",
+		}),
+		path:     "sys/fs/cgroup/memory.max",
+		failures: 5,
+	}
+	limiter := NewMemoryLimiterWithFS(filesystem)
+	limiter.sleep = func(time.Duration) {}
+
+	config := MergedConfig{Memory: DefaultMemoryConfig(), Watchdog: DefaultWatchdogConfig()}
+	config.Memory.CgroupReadAttempts = 2
+	if _, err := limiter.ComputeLimits(config); err == nil {
+		t.Fatal("expected an error once attempts are exhausted")
+	}
+	if filesystem.opens != 2 {
+		t.Errorf("expected 2 read attempts, got %d", filesystem.opens)
+	}
+}
+
+func TestComputeLimitsDoesNotRetryMissingCgroupFile(t *testing.T) {
+	filesystem := &flakyFS{
+		FS: testFS(map[string]string{
+			"sys/fs/cgroup/cgroup.controllers": "cpu memory io",
+		}),
+		path: "sys/fs/cgroup/memory.max",
+	}
+	limiter := NewMemoryLimiterWithFS(filesystem)
+	limiter.sleep = func(time.Duration) { t.Error("expected no backoff for a missing memory.max") }
+
+	config := MergedConfig{Memory: DefaultMemoryConfig(), Watchdog: DefaultWatchdogConfig()}
+	if _, err := limiter.ComputeLimits(config); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("expected a not-exist error, got %v", err)
+	}
+	if filesystem.opens != 1 {
+		t.Errorf("expected 1 read attempt, got %d", filesystem.opens)
+	}
+}
+
+func TestOOMKillCount(t *testing.T) {
+	limiter := NewMemoryLimiterWithFS(testFS(map[string]string{
+		"sys/fs/cgroup/memory.events": "low 0\nhigh 12\nmax 40\noom 2\noom_kill 1\noom_group_kill 0\n",
+	}))
+	count, err := limiter.OOMKillCount()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 {
+		t.Errorf("expected oom_kill 1, got %d", count)
+	}
+
+	// cgroup v1 has no memory.events
+	if _, err := NewMemoryLimiterWithFS(testFS(nil)).OOMKillCount(); err == nil {
+		t.Error("expected an error when memory.events is missing")
+	}
+}
+
+func TestCgroupRoot(t *testing.T) {
+	filesystem := testFS(map[string]string{
+		// The default location is ignored when a root is configured.
+		"sys/fs/cgroup/cgroup.controllers":  "cpu memory io",
+		"sys/fs/cgroup/memory.max":          "1073741824",
+		"sandbox/cgroup/cgroup.controllers": "cpu memory io",
+		"sandbox/cgroup/memory.max":         "536870912",
+		"sandbox/cgroup/memory.events":      "oom 1\noom_kill 4\n",
+	})
+
+	limiter := NewMemoryLimiterWithFS(filesystem)
+	limiter.SetCgroupRoot("/sandbox/cgroup")
+	version, err := limiter.detectCgroupVersion()
+	if err != nil {
+		t.Fatal(err)
+	}
+	limit, _, err := limiter.readCgroupMemoryLimit(version)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if limit != 536870912 {
+		t.Errorf("expected 536870912 from /sandbox/cgroup, got %d", limit)
+	}
+	count, err := limiter.OOMKillCount()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 4 {
+		t.Errorf("expected oom_kill 4, got %d", count)
+	}
+
+	limiter.SetCgroupRoot("/missing")
+	if _, err := limiter.detectCgroupVersion(); err == nil {
+		t.Error("expected an error when the cgroup root has no cgroup files")
+	}
+}
+
+func TestMemoryEnvOverrides(t *testing.T) {
+	config := MergedConfig{
+		Memory: DefaultMemoryConfig(),
+		Env: map[string]string{
+			"PYTHONMALLOC":     "pymalloc",
+			"MALLOC_ARENA_MAX": "2", // same as the launcher's value
+			"APP_MODE":         "production",
+		},
+	}
+	limits := MemoryLimits{EffectiveLimitBytes: 1 << 30}
+
+	overrides := MemoryEnvOverrides(config, limits)
+	if len(overrides) != 1 || !strings.HasPrefix(overrides[0], "PYTHONMALLOC=pymalloc ") {
+		t.Errorf("expected only the PYTHONMALLOC override, got %v", overrides)
+	}
+
+	config.Memory.Mode = MemoryModeUnmanaged
+	if overrides := MemoryEnvOverrides(config, limits); len(overrides) != 0 {
+		t.Errorf("expected no overrides in unmanaged mode, got %v", overrides)
+	}
+}
@@ -0,0 +1,42 @@
+package launchlib
+
+import (
+	"errors"
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultManifestPath is where the SLS manifest lives, relative to the dist
+// root.
+const DefaultManifestPath = "deployment/manifest.yml"
+
+// ManifestMetadata is the product identity from the top level of an SLS
+// manifest. Other manifest keys are ignored.
+type ManifestMetadata struct {
+	ProductName    string `yaml:"product-name"`
+	ProductVersion string `yaml:"product-version"`
+	ProductType    string `yaml:"product-type"`
+}
+
+// ReadManifestMetadata reads the SLS manifest at path. Only top-level keys
+// are used, so a product-name nested under another key is not mistaken for
+// the service's own. It is an error for product-name to be missing.
+func ReadManifestMetadata(path string) (ManifestMetadata, error) {
+	data, err := readConfigFile(path)
+	if err != nil {
+		return ManifestMetadata{}, err
+	}
+	return parseManifestMetadata(data)
+}
+
+func parseManifestMetadata(data []byte) (ManifestMetadata, error) {
+	var metadata ManifestMetadata
+	if err := yaml.Unmarshal(data, &metadata); err != nil {
+		return ManifestMetadata{}, fmt.Errorf("invalid manifest: %w", err)
+	}
+	if metadata.ProductName == "" {
+		return ManifestMetadata{}, errors.New("product-name not found in manifest")
+	}
+	return metadata, nil
+}
@@ -0,0 +1,102 @@
+package launchlib
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseManifestMetadata(t *testing.T) {
+	tests := []struct {
+		name     string
+		manifest string
+		want     ManifestMetadata
+	}{
+		{
+			name: "block style",
+			manifest: `manifest-version: "1.0"
+product-type: service.v1
+product-group: com.example
+product-name: my-service
+product-version: 1.2.3
+`,
+			want: ManifestMetadata{ProductName: "my-service", ProductVersion: "1.2.3", ProductType: "service.v1"},
+		},
+		{
+			name: "nested product-name ignored",
+			manifest: `extensions:
+  product-dependencies:
+    - product-name: other-service
+      product-version: 9.9.9
+product-name: my-service
+product-version: 1.2.3
+`,
+			want: ManifestMetadata{ProductName: "my-service", ProductVersion: "1.2.3"},
+		},
+		{
+			name:     "quoted with comments",
+			manifest: "product-name: \"my-service\" # the service\nproduct-version: '1.2.3'\n",
+			want:     ManifestMetadata{ProductName: "my-service", ProductVersion: "1.2.3"},
+		},
+		{
+			name:     "flow style",
+			manifest: "{product-name: my-service, product-version: 1.2.3, product-type: service.v1}\n",
+			want:     ManifestMetadata{ProductName: "my-service", ProductVersion: "1.2.3", ProductType: "service.v1"},
+		},
+		{
+			name:     "multi-line value",
+			manifest: "product-name: >-\n  my-service\nproduct-version: 1.2.3\n",
+			want:     ManifestMetadata{ProductName: "my-service", ProductVersion: "1.2.3"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseManifestMetadata([]byte(tt.manifest))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestParseManifestMetadataErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		manifest string
+		wantErr  string
+	}{
+		{"missing product-name", "product-version: 1.2.3\n", "product-name not found"},
+		{"only nested product-name", "extensions:\n  product-name: other\n", "product-name not found"},
+		{"invalid yaml", "product-name: [unterminated\n", "invalid manifest"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseManifestMetadata([]byte(tt.manifest))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected an error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestReadManifestMetadata(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "manifest.yml")
+	if err := os.WriteFile(path, []byte("product-name: my-service\nproduct-version: 1.2.3\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ReadManifestMetadata(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.ProductName != "my-service" || got.ProductVersion != "1.2.3" {
+		t.Errorf("expected my-service 1.2.3, got %+v", got)
+	}
+
+	if _, err := ReadManifestMetadata(filepath.Join(t.TempDir(), "missing.yml")); !os.IsNotExist(err) {
+		t.Errorf("expected a not-exist error, got %v", err)
+	}
+}
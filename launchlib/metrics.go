@@ -0,0 +1,86 @@
+package launchlib
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// defaultPushJob is the Prometheus job label used when Metrics.Job is unset.
+const defaultPushJob = "python-service-launcher"
+
+// pushTimeout bounds how long a push may delay launcher exit.
+const pushTimeout = 5 * time.Second
+
+// MetricsConfig controls metrics export for the launched process.
+type MetricsConfig struct {
+	// PushGatewayURL, if set, is a Prometheus Pushgateway base URL (e.g.
+	// "http://pushgateway:9091") to which exit metrics are pushed when the
+	// process exits. Useful for check and batch runs that exit before a scrape.
+	PushGatewayURL string `yaml:"pushGatewayUrl,omitempty"`
+
+	// Job is the job label for pushed metrics. Default: "python-service-launcher".
+	Job string `yaml:"job,omitempty"`
+}
+
+// JobMetrics are the values pushed for a finished process.
+type JobMetrics struct {
+	ExitCode     int
+	Duration     time.Duration
+	PeakRSSBytes uint64
+}
+
+// PushJobMetrics PUTs metrics to the Pushgateway, grouped by job, service and
+// instance (the hostname). A PUT replaces any previous push for the group.
+func PushJobMetrics(ctx context.Context, config MetricsConfig, serviceName string, metrics JobMetrics) error {
+	job := config.Job
+	if job == "" {
+		job = defaultPushJob
+	}
+	instance, err := os.Hostname()
+	if err != nil {
+		instance = "unknown"
+	}
+	endpoint := strings.TrimSuffix(config.PushGatewayURL, "/") +
+		"/metrics/job/" + url.PathEscape(job) +
+		"/service/" + url.PathEscape(serviceName) +
+		"/instance/" + url.PathEscape(instance)
+
+	ctx, cancel := context.WithTimeout(ctx, pushTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, endpoint, bytes.NewReader(formatJobMetrics(metrics)))
+	if err != nil {
+		return fmt.Errorf("invalid pushgateway URL: %w", err)
+	}
+	req.Header.Set("Content-Type", "text/plain; version=0.0.4")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("push to %s failed: %w", endpoint, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("push to %s failed: %s", endpoint, resp.Status)
+	}
+	return nil
+}
+
+// formatJobMetrics renders metrics in the Prometheus text exposition format.
+func formatJobMetrics(metrics JobMetrics) []byte {
+	var buf bytes.Buffer
+	writeGauge := func(name, help, value string) {
+		fmt.Fprintf(&buf, "# HELP %s %s\n# TYPE %s gauge\n%s %s\n", name, help, name, name, value)
+	}
+	writeGauge("launcher_job_exit_code", "Exit code of the launched process.",
+		fmt.Sprintf("%d", metrics.ExitCode))
+	writeGauge("launcher_job_duration_seconds", "Wall time the launched process ran.",
+		fmt.Sprintf("%g", metrics.Duration.Seconds()))
+	writeGauge("launcher_job_peak_rss_bytes", "Peak resident set size of the launched process.",
+		fmt.Sprintf("%d", metrics.PeakRSSBytes))
+	return buf.Bytes()
+}
@@ -0,0 +1,132 @@
+package launchlib
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// pushRecorder is a fake Pushgateway that records the last push.
+type pushRecorder struct {
+	mu     sync.Mutex
+	method string
+	path   string
+	body   string
+	status int
+}
+
+func (p *pushRecorder) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.method, p.path, p.body = r.Method, r.URL.EscapedPath(), string(body)
+	if p.status != 0 {
+		w.WriteHeader(p.status)
+	}
+}
+
+func TestPushJobMetrics(t *testing.T) {
+	recorder := &pushRecorder{}
+	server := httptest.NewServer(recorder)
+	defer server.Close()
+
+	err := PushJobMetrics(context.Background(), MetricsConfig{PushGatewayURL: server.URL + "/"}, "my service", JobMetrics{
+		ExitCode:     3,
+		Duration:     1500 * time.Millisecond,
+		PeakRSSBytes: 104857600,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hostname, _ := os.Hostname()
+	wantPath := "/metrics/job/python-service-launcher/service/my%20service/instance/" + url.PathEscape(hostname)
+	if recorder.method != http.MethodPut {
+		t.Errorf("expected PUT, got %s", recorder.method)
+	}
+	if recorder.path != wantPath {
+		t.Errorf("expected path %s, got %s", wantPath, recorder.path)
+	}
+	for _, line := range []string{
+		"# TYPE launcher_job_exit_code gauge",
+		"launcher_job_exit_code 3\n",
+		"launcher_job_duration_seconds 1.5\n",
+		"launcher_job_peak_rss_bytes 104857600\n",
+	} {
+		if !strings.Contains(recorder.body, line) {
+			t.Errorf("expected %q in pushed body:\n%s", line, recorder.body)
+		}
+	}
+}
+
+func TestPushJobMetricsErrorStatus(t *testing.T) {
+	server := httptest.NewServer(&pushRecorder{status: http.StatusBadRequest})
+	defer server.Close()
+
+	err := PushJobMetrics(context.Background(), MetricsConfig{PushGatewayURL: server.URL, Job: "checks"}, "svc", JobMetrics{})
+	if err == nil || !strings.Contains(err.Error(), "400") {
+		t.Errorf("expected a 400 error, got %v", err)
+	}
+}
+
+func TestLaunchPushesMetricsOnExit(t *testing.T) {
+	recorder := &pushRecorder{}
+	server := httptest.NewServer(recorder)
+	defer server.Close()
+
+	launcher, out := newTestLauncher(t, `
+configType: python
+configVersion: 1
+launchMode: command
+executable: /bin/sh
+args: ["-c", "exit 7"]
+memory:
+  mode: unmanaged
+metrics:
+  pushGatewayUrl: `+server.URL+`
+  job: checks
+`)
+
+	if _, err := launcher.Launch(); err != nil {
+		t.Fatalf("unexpected error: %v\n%s", err, out)
+	}
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	if !strings.HasPrefix(recorder.path, "/metrics/job/checks/service/test-service/instance/") {
+		t.Errorf("unexpected push path %q", recorder.path)
+	}
+	if !strings.Contains(recorder.body, "launcher_job_exit_code 7\n") {
+		t.Errorf("expected exit code 7 in pushed body:\n%s", recorder.body)
+	}
+}
+
+func TestLaunchMetricsPushFailureIsWarning(t *testing.T) {
+	server := httptest.NewServer(&pushRecorder{status: http.StatusInternalServerError})
+	defer server.Close()
+
+	launcher, out := newTestLauncher(t, `
+configType: python
+configVersion: 1
+launchMode: command
+executable: /bin/true
+memory:
+  mode: unmanaged
+metrics:
+  pushGatewayUrl: `+server.URL+`
+`)
+
+	result, err := launcher.Launch()
+	if err != nil || result.ExitCode != 0 {
+		t.Fatalf("push failure must not fail the launch: %v (exit %d)\n%s", err, result.ExitCode, out)
+	}
+	if !strings.Contains(out.String(), "Failed to push metrics") {
+		t.Errorf("expected push warning in output:\n%s", out)
+	}
+}
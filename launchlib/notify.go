@@ -0,0 +1,43 @@
+package launchlib
+
+import (
+	"net"
+	"os"
+)
+
+// notifySocketEnv names the systemd notification socket (sd_notify(3)).
+const notifySocketEnv = "NOTIFY_SOCKET"
+
+// systemd notification states.
+const (
+	sdNotifyReady    = "READY=1"
+	sdNotifyStopping = "STOPPING=1"
+)
+
+// SdNotify sends state to the systemd notification socket at socketPath. A
+// leading "@" names an abstract socket. An empty socketPath is a no-op, as
+// when the launcher is not run by systemd with Type=notify.
+func SdNotify(socketPath, state string) error {
+	if socketPath == "" {
+		return nil
+	}
+	addr := &net.UnixAddr{Name: socketPath, Net: "unixgram"}
+	if socketPath[0] == '@' {
+		addr.Name = "\x00" + socketPath[1:]
+	}
+	conn, err := net.DialUnix("unixgram", nil, addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	_, err = conn.Write([]byte(state))
+	return err
+}
+
+// systemdNotifySocket returns $NOTIFY_SOCKET when enabled, "" otherwise.
+func systemdNotifySocket(enabled bool) string {
+	if !enabled {
+		return ""
+	}
+	return os.Getenv(notifySocketEnv)
+}
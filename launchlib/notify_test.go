@@ -0,0 +1,77 @@
+package launchlib
+
+import (
+	"bytes"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// listenNotifySocket starts a fake systemd notification socket and points
+// NOTIFY_SOCKET at it.
+func listenNotifySocket(t *testing.T) *net.UnixConn {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "notify.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	t.Setenv(notifySocketEnv, path)
+	return conn
+}
+
+// readNotification returns the next datagram received on conn.
+func readNotification(t *testing.T, conn *net.UnixConn) string {
+	t.Helper()
+	if err := conn.SetReadDeadline(time.Now().Add(5 * time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 256)
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatalf("no notification received: %v", err)
+	}
+	return string(buf[:n])
+}
+
+func TestReadinessSystemdNotify(t *testing.T) {
+	conn := listenNotifySocket(t)
+	var out bytes.Buffer
+	probe := NewReadinessProbe(ReadinessConfig{SystemdNotify: true}, NewLogger(&out, LoggingConfig{}))
+
+	probe.SetReady()
+	if got := readNotification(t, conn); got != "READY=1" {
+		t.Errorf("expected READY=1, got %q", got)
+	}
+	probe.Drain()
+	if got := readNotification(t, conn); got != "STOPPING=1" {
+		t.Errorf("expected STOPPING=1, got %q", got)
+	}
+	if bytes.Contains(out.Bytes(), []byte("Failed to notify")) {
+		t.Errorf("unexpected notify failure:\n%s", out.String())
+	}
+}
+
+func TestReadinessSystemdNotifyDisabled(t *testing.T) {
+	conn := listenNotifySocket(t)
+	probe := NewReadinessProbe(ReadinessConfig{}, NewLogger(&bytes.Buffer{}, LoggingConfig{}))
+	probe.SetReady()
+
+	if err := conn.SetReadDeadline(time.Now().Add(100 * time.Millisecond)); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := conn.Read(make([]byte, 256)); err == nil {
+		t.Errorf("expected no notification without systemdNotify, got %d bytes", n)
+	}
+}
+
+func TestSdNotifyWithoutSocket(t *testing.T) {
+	if err := SdNotify("", sdNotifyReady); err != nil {
+		t.Errorf("expected a no-op without NOTIFY_SOCKET, got %v", err)
+	}
+	if err := SdNotify(filepath.Join(t.TempDir(), "missing.sock"), sdNotifyReady); err == nil {
+		t.Error("expected an error for a socket that does not exist")
+	}
+}
@@ -0,0 +1,185 @@
+package launchlib
+
+import (
+	"bytes"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// OutputBufferConfig decouples child output from a slow launcher stdout, such
+// as a pipe to a log collector that stops reading.
+type OutputBufferConfig struct {
+	// Enabled buffers the output of the primary, subprocesses and the
+	// launcher's own logs in memory, so a blocked stdout never stalls the
+	// process. Lines that do not fit are dropped and counted. Default: false.
+	Enabled bool `yaml:"enabled,omitempty"`
+
+	// SizeBytes bounds the buffered output. Default: 1 MiB.
+	SizeBytes int `yaml:"sizeBytes,omitempty"`
+}
+
+// defaultOutputBufferBytes is the buffer size when SizeBytes is unset.
+const defaultOutputBufferBytes = 1 << 20
+
+// bufferBytes returns SizeBytes, or the default when it is unset.
+func (c OutputBufferConfig) bufferBytes() int {
+	if c.SizeBytes > 0 {
+		return c.SizeBytes
+	}
+	return defaultOutputBufferBytes
+}
+
+// dropReportInterval is how often dropped output lines are reported.
+var dropReportInterval = 30 * time.Second
+
+// outputDrainTimeout bounds how long the launcher waits for buffered output to
+// be written out before it returns.
+var outputDrainTimeout = 5 * time.Second
+
+// bufferedOutput queues whole lines for a background goroutine to write to
+// out, so Write never blocks on out. A line that would take the queue past
+// its limit is dropped. A trailing partial line is held until its newline
+// arrives, or passed on as is once it reaches the limit.
+type bufferedOutput struct {
+	out   io.Writer
+	limit int
+
+	mu      sync.Mutex
+	lines   [][]byte
+	size    int
+	pending []byte
+	closed  bool
+
+	dropped atomic.Uint64
+
+	// ready wakes the drain goroutine; done is closed when it exits.
+	ready chan struct{}
+	done  chan struct{}
+}
+
+// newBufferedOutput starts writing lines to out in the background, holding at
+// most limit bytes.
+func newBufferedOutput(out io.Writer, limit int) *bufferedOutput {
+	b := &bufferedOutput{
+		out:   out,
+		limit: limit,
+		ready: make(chan struct{}, 1),
+		done:  make(chan struct{}),
+	}
+	go b.drain()
+	return b
+}
+
+// Write implements io.Writer. It never blocks on the underlying writer and
+// always reports success; dropped lines are counted instead.
+func (b *bufferedOutput) Write(data []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.pending = append(b.pending, data...)
+	for {
+		end := bytes.IndexByte(b.pending, '\n')
+		if end < 0 {
+			break
+		}
+		b.enqueue(b.pending[:end+1])
+		b.pending = b.pending[end+1:]
+	}
+	if len(b.pending) >= b.limit {
+		b.enqueue(b.pending)
+		b.pending = nil
+	}
+	return len(data), nil
+}
+
+// enqueue queues a copy of line, or drops it when it does not fit or the
+// output is closed. b.mu must be held.
+func (b *bufferedOutput) enqueue(line []byte) {
+	if b.closed || b.size+len(line) > b.limit {
+		b.dropped.Add(1)
+		return
+	}
+	b.lines = append(b.lines, append([]byte(nil), line...))
+	b.size += len(line)
+	b.wake()
+}
+
+func (b *bufferedOutput) wake() {
+	select {
+	case b.ready <- struct{}{}:
+	default:
+	}
+}
+
+// drain writes queued lines to out until the output is closed and empty.
+func (b *bufferedOutput) drain() {
+	defer close(b.done)
+	for {
+		b.mu.Lock()
+		if len(b.lines) == 0 {
+			closed := b.closed
+			b.mu.Unlock()
+			if closed {
+				return
+			}
+			<-b.ready
+			continue
+		}
+		line := b.lines[0]
+		b.lines = b.lines[1:]
+		b.size -= len(line)
+		b.mu.Unlock()
+		_, _ = b.out.Write(line)
+	}
+}
+
+// Dropped returns how many lines have been dropped so far.
+func (b *bufferedOutput) Dropped() uint64 {
+	return b.dropped.Load()
+}
+
+// Close queues any partial line, stops accepting output and waits up to
+// timeout for the queue to be written out. It reports whether it was.
+func (b *bufferedOutput) Close(timeout time.Duration) bool {
+	b.mu.Lock()
+	if len(b.pending) > 0 {
+		b.enqueue(b.pending)
+		b.pending = nil
+	}
+	b.closed = true
+	b.mu.Unlock()
+	b.wake()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-b.done:
+		return true
+	case <-timer.C:
+		return false
+	}
+}
+
+// reportDrops logs a warning every interval in which lines were dropped, until
+// the returned stop is called.
+func (b *bufferedOutput) reportDrops(logger *Logger, interval time.Duration) (stop func()) {
+	stopped := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		var reported uint64
+		for {
+			select {
+			case <-stopped:
+				return
+			case <-ticker.C:
+			}
+			if dropped := b.Dropped(); dropped > reported {
+				logger.Warnf("Output buffer full: dropped %d log lines in the last %s", dropped-reported, interval)
+				reported = dropped
+			}
+		}
+	}()
+	return func() { close(stopped) }
+}
@@ -0,0 +1,97 @@
+package launchlib
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// gatedWriter blocks writes containing block until release is called.
+type gatedWriter struct {
+	out      *syncBuffer
+	block    string
+	released chan struct{}
+	once     sync.Once
+}
+
+func newGatedWriter(out *syncBuffer, block string) *gatedWriter {
+	return &gatedWriter{out: out, block: block, released: make(chan struct{})}
+}
+
+func (w *gatedWriter) Write(p []byte) (int, error) {
+	if bytes.Contains(p, []byte(w.block)) {
+		<-w.released
+	}
+	return w.out.Write(p)
+}
+
+func (w *gatedWriter) release() {
+	w.once.Do(func() { close(w.released) })
+}
+
+func TestBufferedOutputPreservesLines(t *testing.T) {
+	out := &syncBuffer{}
+	buffered := newBufferedOutput(out, 1024)
+	for _, chunk := range []string{"one\ntw", "o\nthr", "ee"} {
+		if n, err := buffered.Write([]byte(chunk)); n != len(chunk) || err != nil {
+			t.Errorf("expected %d, nil, got %d, %v", len(chunk), n, err)
+		}
+	}
+	if !buffered.Close(time.Second) {
+		t.Fatal("expected the buffer to drain")
+	}
+	if out.String() != "one\ntwo\nthree" {
+		t.Errorf("expected %q, got %q", "one\ntwo\nthree", out.String())
+	}
+	if buffered.Dropped() != 0 {
+		t.Errorf("expected no drops, got %d", buffered.Dropped())
+	}
+}
+
+func TestBufferedOutputDropsWhenFull(t *testing.T) {
+	gate := newGatedWriter(&syncBuffer{}, "line")
+	defer gate.release()
+	buffered := newBufferedOutput(gate, 32)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			buffered.Write([]byte("line-0123456789\n"))
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected writes not to block on a stalled writer")
+	}
+	// One line is held by the stalled write and at most two fit in the buffer.
+	if dropped := buffered.Dropped(); dropped < 97 {
+		t.Errorf("expected at least 97 dropped lines, got %d", dropped)
+	}
+	if buffered.Close(50 * time.Millisecond) {
+		t.Error("expected Close to time out while the writer is stalled")
+	}
+}
+
+func TestBufferedOutputReportsDrops(t *testing.T) {
+	out := &syncBuffer{}
+	gate := newGatedWriter(&syncBuffer{}, "line")
+	defer gate.release()
+	buffered := newBufferedOutput(gate, 16)
+	for i := 0; i < 10; i++ {
+		buffered.Write([]byte("line-0123456789\n"))
+	}
+	stop := buffered.reportDrops(NewLogger(out, LoggingConfig{}), 20*time.Millisecond)
+	defer stop()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !strings.Contains(out.String(), "dropped") && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if !strings.Contains(out.String(), "Output buffer full: dropped ") {
+		t.Errorf("expected a drop report, got:\n%s", out)
+	}
+}
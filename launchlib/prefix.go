@@ -0,0 +1,77 @@
+package launchlib
+
+import (
+	"bytes"
+	"io"
+	"sync"
+)
+
+// OutputPrefixConfig controls tagging of child output, so the primary's and
+// subprocesses' lines can be told apart when they share the launcher's stdout.
+type OutputPrefixConfig struct {
+	// Enabled prefixes every line the primary and subprocesses write to
+	// stdout and stderr with "[tag] ". Default: false (raw output).
+	Enabled bool `yaml:"enabled,omitempty"`
+
+	// PrimaryTag is the primary process's tag. Subprocesses are tagged with
+	// their name. Default: "primary".
+	PrimaryTag string `yaml:"primaryTag,omitempty"`
+}
+
+// defaultPrimaryOutputTag is the primary's tag when PrimaryTag is unset.
+const defaultPrimaryOutputTag = "primary"
+
+// prefixWriter prefixes each line written to it before passing it on to w.
+// Complete lines are written to w in a single Write, so lines from several
+// prefixWriters sharing w do not interleave. A trailing partial line is held
+// until its newline arrives or Flush is called.
+type prefixWriter struct {
+	mu      sync.Mutex
+	w       io.Writer
+	prefix  []byte
+	pending []byte
+}
+
+// newPrefixWriter returns a writer that prefixes each line with "[tag] ".
+func newPrefixWriter(w io.Writer, tag string) *prefixWriter {
+	return &prefixWriter{w: w, prefix: []byte("[" + tag + "] ")}
+}
+
+// Write implements io.Writer.
+func (p *prefixWriter) Write(data []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.pending = append(p.pending, data...)
+	for {
+		end := bytes.IndexByte(p.pending, '\n')
+		if end < 0 {
+			break
+		}
+		if err := p.writeLine(p.pending[:end+1]); err != nil {
+			return len(data), err
+		}
+		p.pending = p.pending[end+1:]
+	}
+	return len(data), nil
+}
+
+// Flush writes out a trailing line that has no newline yet, terminating it so
+// that later output starts on a line of its own.
+func (p *prefixWriter) Flush() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.pending) == 0 {
+		return nil
+	}
+	err := p.writeLine(append(p.pending, '\n'))
+	p.pending = nil
+	return err
+}
+
+func (p *prefixWriter) writeLine(line []byte) error {
+	out := make([]byte, 0, len(p.prefix)+len(line))
+	out = append(out, p.prefix...)
+	out = append(out, line...)
+	_, err := p.w.Write(out)
+	return err
+}
@@ -0,0 +1,25 @@
+package launchlib
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestPrefixWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w := newPrefixWriter(&buf, "primary")
+	for _, chunk := range []string{"one\ntwo\n", "thr", "ee\nfour"} {
+		if _, err := w.Write([]byte(chunk)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if want := "[primary] one\n[primary] two\n[primary] three\n"; buf.String() != want {
+		t.Errorf("expected %q before flush, got %q", want, buf.String())
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if want := "[primary] one\n[primary] two\n[primary] three\n[primary] four\n"; buf.String() != want {
+		t.Errorf("expected %q after flush, got %q", want, buf.String())
+	}
+}
@@ -17,24 +17,196 @@ package launchlib
 import (
 	"fmt"
 	"os"
+	"os/exec"
 	"os/signal"
+	"path"
 	"path/filepath"
 	"strconv"
 	"strings"
 	"syscall"
+	"time"
 )
 
 // CreateDirectories ensures all directories specified in the config exist.
 // Directories are created relative to the working directory (distribution root).
+// A path may contain one brace range such as "var/data/shard-{0..3}", which
+// creates one directory per number (see ExpandDirRange).
 func CreateDirectories(dirs []string) error {
-	for _, dir := range dirs {
-		if err := os.MkdirAll(dir, 0755); err != nil {
-			return fmt.Errorf("failed to create directory %s: %w", dir, err)
+	for _, pattern := range dirs {
+		expanded, err := ExpandDirRange(pattern)
+		if err != nil {
+			return err
 		}
+		for _, dir := range expanded {
+			if err := os.MkdirAll(dir, 0755); err != nil {
+				return fmt.Errorf("failed to create directory %s: %w", dir, err)
+			}
+		}
+	}
+	return nil
+}
+
+// maxDirRangeCount caps how many directories one brace range may expand to.
+const maxDirRangeCount = 256
+
+// ExpandDirRange expands a "{N..M}" brace range in dir into one path per
+// number from N to M inclusive. A zero-padded start such as "{00..15}" pads
+// every number to the same width. Paths without a range, including ones with
+// other braces, are returned unchanged. Only one range per path is supported.
+func ExpandDirRange(dir string) ([]string, error) {
+	open, close, ok := findDirRange(dir)
+	if !ok {
+		return []string{dir}, nil
+	}
+	if _, _, again := findDirRange(dir[close+1:]); again {
+		return nil, fmt.Errorf("invalid directory %s: only one {N..M} range is supported", dir)
+	}
+
+	startText, endText, _ := strings.Cut(dir[open+1:close], "..")
+	start, startErr := parseRangeBound(startText)
+	end, endErr := parseRangeBound(endText)
+	if startErr != nil || endErr != nil {
+		return nil, fmt.Errorf("invalid directory %s: range bounds must be non-negative integers", dir)
+	}
+	if end < start {
+		return nil, fmt.Errorf("invalid directory %s: range end %d is before start %d", dir, end, start)
+	}
+	if count := end - start + 1; count > maxDirRangeCount {
+		return nil, fmt.Errorf("invalid directory %s: range expands to %d directories (max %d)",
+			dir, count, maxDirRangeCount)
+	}
+
+	width := 0
+	if len(startText) > 1 && startText[0] == '0' {
+		width = max(len(startText), len(endText))
+	}
+	prefix, suffix := dir[:open], dir[close+1:]
+	paths := make([]string, 0, end-start+1)
+	for i := start; i <= end; i++ {
+		paths = append(paths, fmt.Sprintf("%s%0*d%s", prefix, width, i, suffix))
+	}
+	return paths, nil
+}
+
+// findDirRange locates the first "{...}" group in s whose contents contain "..".
+func findDirRange(s string) (open, close int, ok bool) {
+	offset := 0
+	for {
+		open = strings.IndexByte(s[offset:], '{')
+		if open < 0 {
+			return 0, 0, false
+		}
+		open += offset
+		close = strings.IndexByte(s[open:], '}')
+		if close < 0 {
+			return 0, 0, false
+		}
+		close += open
+		if strings.Contains(s[open+1:close], "..") {
+			return open, close, true
+		}
+		offset = close + 1
+	}
+}
+
+// parseRangeBound parses one side of a brace range, which must be digits only.
+func parseRangeBound(text string) (int, error) {
+	if text == "" || strings.TrimLeft(text, "0123456789") != "" {
+		return 0, fmt.Errorf("not a non-negative integer: %q", text)
+	}
+	return strconv.Atoi(text)
+}
+
+// DirectoryChownError reports that a directory's ownership could not be set,
+// typically because the launcher is not root. Callers may warn and continue.
+type DirectoryChownError struct {
+	Path  string
+	Owner string
+	Group string
+	Err   error
+}
+
+func (e *DirectoryChownError) Error() string {
+	return fmt.Sprintf("failed to chown %s to %s:%s: %v", e.Path, e.Owner, e.Group, e.Err)
+}
+
+func (e *DirectoryChownError) Unwrap() error {
+	return e.Err
+}
+
+// ApplyDirectoryPermissions sets the configured mode and ownership on path,
+// which must already exist. A chown failure is returned as a
+// *DirectoryChownError; any other error means the config is invalid or the
+// chmod failed.
+func ApplyDirectoryPermissions(path string, dir DirConfig) error {
+	if dir.Mode != "" {
+		mode, err := dir.FileMode()
+		if err != nil {
+			return err
+		}
+		// Chmod explicitly: MkdirAll is subject to the umask and skips existing dirs.
+		if err := os.Chmod(path, mode); err != nil {
+			return fmt.Errorf("failed to chmod %s: %w", path, err)
+		}
+	}
+	if dir.Owner == "" && dir.Group == "" {
+		return nil
+	}
+	uid, gid := -1, -1
+	var err error
+	if dir.Owner != "" {
+		if uid, err = lookupUID(dir.Owner); err != nil {
+			return err
+		}
+	}
+	if dir.Group != "" {
+		if gid, err = lookupGID(dir.Group); err != nil {
+			return err
+		}
+	}
+	if err := os.Chown(path, uid, gid); err != nil {
+		return &DirectoryChownError{Path: path, Owner: dir.Owner, Group: dir.Group, Err: err}
 	}
 	return nil
 }
 
+// defaultPidFilePattern is the PID file location used when Paths.PidFile is unset.
+const defaultPidFilePattern = "var/run/%s.pid"
+
+// PidFilePath returns the PID file location for serviceName. Paths.PidFile may be
+// absolute or relative to the distribution root, and any "%s" in it is replaced
+// by the service name. It returns "" when the PID file is disabled.
+func PidFilePath(paths PathsConfig, serviceName string) string {
+	if paths.PidFileEnabled != nil && !*paths.PidFileEnabled {
+		return ""
+	}
+	pattern := paths.PidFile
+	if pattern == "" {
+		pattern = defaultPidFilePattern
+	}
+	return strings.ReplaceAll(pattern, "%s", serviceName)
+}
+
+// StopProcess sends SIGTERM to pid and waits up to timeout for it to exit,
+// sending SIGKILL if it is still alive afterwards. It reports whether SIGKILL
+// was needed.
+func StopProcess(pid int, timeout time.Duration) (bool, error) {
+	if err := syscall.Kill(pid, syscall.SIGTERM); err != nil {
+		return false, fmt.Errorf("failed to send SIGTERM to pid %d: %w", pid, err)
+	}
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if !IsProcessAlive(pid) {
+			return false, nil
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	if err := syscall.Kill(pid, syscall.SIGKILL); err != nil && err != syscall.ESRCH {
+		return true, fmt.Errorf("failed to send SIGKILL to pid %d: %w", pid, err)
+	}
+	return true, nil
+}
+
 // WritePidFile writes the process ID to the specified file.
 func WritePidFile(pid int, path string) error {
 	dir := filepath.Dir(path)
@@ -64,9 +236,9 @@ func RemovePidFile(path string) {
 
 // SetResourceLimits applies OS-level resource limits before exec.
 func SetResourceLimits(config ResourceConfig) error {
-	if config.MaxOpenFiles > 0 {
-		if err := setRlimit(syscall.RLIMIT_NOFILE, config.MaxOpenFiles); err != nil {
-			return fmt.Errorf("failed to set RLIMIT_NOFILE to %d: %w", config.MaxOpenFiles, err)
+	if soft, hard := config.OpenFilesLimits(); soft > 0 || hard > 0 {
+		if err := setRlimitPair(syscall.RLIMIT_NOFILE, soft, hard); err != nil {
+			return fmt.Errorf("failed to set RLIMIT_NOFILE to soft=%d hard=%d: %w", soft, hard, err)
 		}
 	}
 	if config.MaxProcesses > 0 {
@@ -82,32 +254,138 @@ func SetResourceLimits(config ResourceConfig) error {
 	return nil
 }
 
+const (
+	minNice = -20
+	maxNice = 19
+)
+
+// setPriority sets the scheduling priority of pid, or of the calling thread
+// when pid is 0. It is a variable so tests can observe the call.
+var setPriority = func(pid, nice int) error {
+	return syscall.Setpriority(syscall.PRIO_PROCESS, pid, nice)
+}
+
+// ApplyNice sets the nice value of the started process pid. Setting it on the
+// launcher would only change the calling thread, which need not be the one
+// that forks, and would slow the launcher itself. A value of 0 leaves the
+// inherited priority unchanged.
+func ApplyNice(pid, nice int) error {
+	if nice == 0 {
+		return nil
+	}
+	if nice < minNice || nice > maxNice {
+		return fmt.Errorf("nice value %d out of range [%d, %d]", nice, minNice, maxNice)
+	}
+	if err := setPriority(pid, nice); err != nil {
+		return fmt.Errorf("failed to set nice to %d for pid %d: %w", nice, pid, err)
+	}
+	return nil
+}
+
+const (
+	minOOMScoreAdj = -1000
+	maxOOMScoreAdj = 1000
+)
+
+// writeOOMScoreAdj writes the OOM score adjustment of pid. It is a variable
+// so tests can observe the call.
+var writeOOMScoreAdj = func(pid, adj int) error {
+	return os.WriteFile(fmt.Sprintf("/proc/%d/oom_score_adj", pid), []byte(strconv.Itoa(adj)), 0644)
+}
+
+// ApplyOOMScoreAdj sets the OOM score adjustment of the started process pid.
+// A value of 0 leaves its inherited score unchanged.
+func ApplyOOMScoreAdj(pid, adj int) error {
+	if adj == 0 {
+		return nil
+	}
+	if adj < minOOMScoreAdj || adj > maxOOMScoreAdj {
+		return fmt.Errorf("oom_score_adj %d out of range [%d, %d]", adj, minOOMScoreAdj, maxOOMScoreAdj)
+	}
+	if err := writeOOMScoreAdj(pid, adj); err != nil {
+		return fmt.Errorf("failed to set oom_score_adj to %d for pid %d: %w", adj, pid, err)
+	}
+	return nil
+}
+
+// ParseUmask parses an octal umask string such as "0027".
+func ParseUmask(umask string) (int, error) {
+	mask, err := strconv.ParseUint(umask, 8, 32)
+	if err != nil || mask > 0777 {
+		return 0, fmt.Errorf("invalid umask %q: expected octal like \"0027\"", umask)
+	}
+	return int(mask), nil
+}
+
+// StartWithUmask starts cmd with the umask set to mask so the child inherits
+// it, then restores the launcher's umask. The umask is process-global, so it
+// is only changed around the fork. A negative mask starts cmd unchanged.
+func StartWithUmask(cmd *exec.Cmd, mask int) error {
+	if mask < 0 {
+		return cmd.Start()
+	}
+	previous := syscall.Umask(mask)
+	defer syscall.Umask(previous)
+	return cmd.Start()
+}
+
+// getrlimit and setrlimit are variables so tests can observe the limits set.
+var (
+	getrlimit = syscall.Getrlimit
+	setrlimit = syscall.Setrlimit
+)
+
 func setRlimit(resource int, value uint64) error {
 	limit := syscall.Rlimit{Cur: value, Max: value}
-	return syscall.Setrlimit(resource, &limit)
+	return setrlimit(resource, &limit)
+}
+
+// setRlimitPair sets the soft and hard limits of resource. A zero value keeps
+// that limit's current setting.
+func setRlimitPair(resource int, soft, hard uint64) error {
+	var limit syscall.Rlimit
+	if soft == 0 || hard == 0 {
+		if err := getrlimit(resource, &limit); err != nil {
+			return err
+		}
+	}
+	if soft > 0 {
+		limit.Cur = soft
+	}
+	if hard > 0 {
+		limit.Max = hard
+	}
+	return setrlimit(resource, &limit)
 }
 
 // ResolveEnvVarPath resolves a path that may contain environment variable references.
-// Supports both $VAR and ${VAR} syntax. If the referenced variable is not set,
-// returns the path with the variable reference intact.
-func ResolveEnvVarPath(path string) string {
-	return os.ExpandEnv(path)
+// Supports both $VAR and ${VAR} syntax. Variables are looked up in env (the
+// config's merged env), then in the launcher's own environment; a variable
+// set in neither expands to the empty string, as with os.ExpandEnv.
+func ResolveEnvVarPath(path string, env map[string]string) string {
+	return os.Expand(path, func(name string) string {
+		if value, ok := env[name]; ok {
+			return value
+		}
+		return os.Getenv(name)
+	})
 }
 
 // BuildProcessEnv constructs the full environment for the Python process.
 // Order of precedence (last wins):
-//  1. Current process environment (inherited)
+//  1. Current process environment, filtered by config.EnvInherit
 //  2. Memory management variables (from ComputeMemoryEnv)
 //  3. Static config env
 //  4. Custom config env (via MergedConfig)
 //  5. SLS metadata variables (SLS_SERVICE_NAME, etc.)
+//  6. OpenTelemetry resource attributes, merged with any existing value (if enabled)
 func BuildProcessEnv(config MergedConfig, limits MemoryLimits, serviceName, serviceVersion string) []string {
 	env := make(map[string]string)
 
-	// Start with current environment
+	// Start with the inherited portion of the current environment
 	for _, e := range os.Environ() {
 		parts := strings.SplitN(e, "=", 2)
-		if len(parts) == 2 {
+		if len(parts) == 2 && inheritsEnvVar(config.EnvInherit, parts[0]) {
 			env[parts[0]] = parts[1]
 		}
 	}
@@ -123,6 +401,14 @@ func BuildProcessEnv(config MergedConfig, limits MemoryLimits, serviceName, serv
 		env[k] = v
 	}
 
+	// Path-list additions extend the inherited or configured value
+	if len(config.PythonPathEntries) > 0 {
+		env["PYTHONPATH"] = joinPathList(append(append([]string{}, config.PythonPathEntries...), env["PYTHONPATH"]))
+	}
+	for k, entries := range config.EnvAppend {
+		env[k] = joinPathList(append([]string{env[k]}, entries...))
+	}
+
 	// Generic service metadata (always set)
 	env["SERVICE_NAME"] = serviceName
 	env["SERVICE_VERSION"] = serviceVersion
@@ -131,12 +417,41 @@ func BuildProcessEnv(config MergedConfig, limits MemoryLimits, serviceName, serv
 	env["SLS_SERVICE_NAME"] = serviceName
 	env["SLS_SERVICE_VERSION"] = serviceVersion
 
+	// Launcher build, for correlating incidents to a launcher release
+	if config.LauncherVersion != "" {
+		env["LAUNCHER_VERSION"] = config.LauncherVersion
+	}
+	if config.LauncherCommit != "" {
+		env["LAUNCHER_COMMIT"] = config.LauncherCommit
+	}
+
+	if config.Telemetry.Enabled {
+		buildOTelEnv(env, serviceName, serviceVersion)
+	}
+
 	// Always set these Python best-practice variables unless explicitly overridden
-	setDefault(env, "PYTHONDONTWRITEBYTECODE", "1")
+	if valueOr(config.BytecodeCaching, false) {
+		cacheDir := config.BytecodeCacheDir
+		if cacheDir == "" {
+			cacheDir = defaultBytecodeCacheDir
+		}
+		setDefault(env, "PYTHONPYCACHEPREFIX", cacheDir)
+		// Opting in overrides an inherited PYTHONDONTWRITEBYTECODE, but not
+		// one set in config env.
+		if _, ok := config.Env["PYTHONDONTWRITEBYTECODE"]; !ok {
+			delete(env, "PYTHONDONTWRITEBYTECODE")
+		}
+	} else {
+		setDefault(env, "PYTHONDONTWRITEBYTECODE", "1")
+	}
 	setDefault(env, "PYTHONUNBUFFERED", "1")
 
 	// Set tmpdir
-	setDefault(env, "TMPDIR", "var/data/tmp")
+	tmpDir := config.Paths.TmpDir
+	if tmpDir == "" {
+		tmpDir = defaultTmpDir
+	}
+	setDefault(env, "TMPDIR", tmpDir)
 
 	// Convert back to []string
 	result := make([]string, 0, len(env))
@@ -146,12 +461,115 @@ func BuildProcessEnv(config MergedConfig, limits MemoryLimits, serviceName, serv
 	return result
 }
 
+// defaultTmpDir is the TMPDIR given to the process when Paths.TmpDir is unset.
+const defaultTmpDir = "var/data/tmp"
+
+// defaultBytecodeCacheDir is the PYTHONPYCACHEPREFIX when BytecodeCaching is
+// enabled without a BytecodeCacheDir.
+const defaultBytecodeCacheDir = "var/data/pycache"
+
+// ReadArgsFile reads one argument per line from path. Surrounding whitespace
+// is trimmed, and blank lines and lines starting with '#' are skipped.
+func ReadArgsFile(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("argsFile: %w", err)
+	}
+	var args []string
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		args = append(args, line)
+	}
+	return args, nil
+}
+
+// ReadEnvFromFiles reads each file in files (env var name -> path) and returns
+// the trimmed contents keyed by name. Relative paths are resolved with resolve.
+// A missing or unreadable file is an error.
+func ReadEnvFromFiles(files map[string]string, resolve func(string) string) (map[string]string, error) {
+	values := make(map[string]string, len(files))
+	for name, file := range files {
+		data, err := os.ReadFile(resolve(file))
+		if err != nil {
+			return nil, fmt.Errorf("envFromFile %s: %w", name, err)
+		}
+		values[name] = strings.TrimSpace(string(data))
+	}
+	return values, nil
+}
+
+// redactedValue replaces secret values in printed environments.
+const redactedValue = "<redacted>"
+
+// sensitiveEnvNameFragments mark a variable as secret when its name contains
+// one of them, so inherited credentials are redacted too.
+var sensitiveEnvNameFragments = []string{"SECRET", "PASSWORD", "PASSWD", "TOKEN", "CREDENTIAL", "PRIVATE_KEY", "API_KEY"}
+
+// RedactEnv returns a copy of env ("KEY=value" entries) with the values of the
+// named secrets, and of any variable with a secret-looking name, replaced.
+func RedactEnv(env []string, secrets []string) []string {
+	secretSet := make(map[string]bool, len(secrets))
+	for _, name := range secrets {
+		secretSet[name] = true
+	}
+	result := make([]string, len(env))
+	for i, entry := range env {
+		name, _, _ := strings.Cut(entry, "=")
+		if secretSet[name] || isSensitiveEnvName(name) {
+			entry = name + "=" + redactedValue
+		}
+		result[i] = entry
+	}
+	return result
+}
+
+func isSensitiveEnvName(name string) bool {
+	upper := strings.ToUpper(name)
+	for _, fragment := range sensitiveEnvNameFragments {
+		if strings.Contains(upper, fragment) {
+			return true
+		}
+	}
+	return false
+}
+
+// inheritsEnvVar reports whether the launcher variable name passes the policy.
+func inheritsEnvVar(config EnvInheritConfig, name string) bool {
+	switch config.Policy {
+	case EnvInheritNone:
+		return false
+	case EnvInheritAllowlist:
+		for _, pattern := range config.AllowlistPatterns {
+			if ok, _ := path.Match(pattern, name); ok {
+				return true
+			}
+		}
+		return false
+	default:
+		return true
+	}
+}
+
 func setDefault(env map[string]string, key, value string) {
 	if _, exists := env[key]; !exists {
 		env[key] = value
 	}
 }
 
+// joinPathList joins the non-empty entries with the OS path list separator.
+func joinPathList(entries []string) string {
+	nonEmpty := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if entry != "" {
+			nonEmpty = append(nonEmpty, entry)
+		}
+	}
+	return strings.Join(nonEmpty, string(os.PathListSeparator))
+}
+
 // BuildCommandArgs constructs the full command line based on LaunchMode.
 //
 // Supported modes:
@@ -189,7 +607,7 @@ func BuildCommandArgs(config MergedConfig) []string {
 	default: // LaunchModePEX or empty
 		var args []string
 		if config.PythonPath != "" {
-			resolvedPython := ResolveEnvVarPath(config.PythonPath)
+			resolvedPython := ResolveEnvVarPath(config.PythonPath, config.Env)
 			args = append(args, resolvedPython)
 			args = append(args, config.PythonOpts...)
 			args = append(args, config.Executable)
@@ -208,7 +626,7 @@ func buildPythonArgs(config MergedConfig, extraArgs ...string) []string {
 	if pythonPath == "" {
 		pythonPath = "python3"
 	}
-	args = append(args, ResolveEnvVarPath(pythonPath))
+	args = append(args, ResolveEnvVarPath(pythonPath, config.Env))
 	args = append(args, config.PythonOpts...)
 	for _, a := range extraArgs {
 		if a != "" {
@@ -220,14 +638,25 @@ func buildPythonArgs(config MergedConfig, extraArgs ...string) []string {
 }
 
 // ForwardSignals sets up signal forwarding from the launcher to the child process.
-// SIGTERM and SIGINT are forwarded. SIGKILL cannot be caught or forwarded.
-func ForwardSignals(pid int) chan os.Signal {
+// SIGTERM, SIGINT and SIGHUP are forwarded, each as the signal remap maps it
+// to, or unchanged when it is not in remap. SIGKILL cannot be caught or
+// forwarded.
+func ForwardSignals(pid int, remap map[syscall.Signal]syscall.Signal) chan os.Signal {
+	return forwardSignalsTo(pid, remap, syscall.SIGTERM, syscall.SIGINT, syscall.SIGHUP)
+}
+
+// forwardSignalsTo forwards the given signals received by the launcher to pid,
+// translated through remap.
+func forwardSignalsTo(pid int, remap map[syscall.Signal]syscall.Signal, signals ...os.Signal) chan os.Signal {
 	sigs := make(chan os.Signal, 1)
-	signal.Notify(sigs, syscall.SIGTERM, syscall.SIGINT, syscall.SIGHUP)
+	signal.Notify(sigs, signals...)
 
 	go func() {
 		for sig := range sigs {
 			if sysSig, ok := sig.(syscall.Signal); ok {
+				if out, ok := remap[sysSig]; ok {
+					sysSig = out
+				}
 				_ = syscall.Kill(pid, sysSig)
 			}
 		}
@@ -235,3 +664,27 @@ func ForwardSignals(pid int) chan os.Signal {
 
 	return sigs
 }
+
+// parseSignalRemap parses a signalRemap config into signals, rejecting
+// unknown names and inbound signals the launcher does not forward.
+func parseSignalRemap(remap map[string]string) (map[syscall.Signal]syscall.Signal, error) {
+	if len(remap) == 0 {
+		return nil, nil
+	}
+	parsed := make(map[syscall.Signal]syscall.Signal, len(remap))
+	for _, in := range sortedKeys(remap) {
+		inSig, err := ParseSignal(in)
+		if err != nil {
+			return nil, invalidField("signalRemap", in, "%v", err)
+		}
+		if inSig != syscall.SIGTERM && inSig != syscall.SIGINT && inSig != syscall.SIGHUP {
+			return nil, invalidField("signalRemap", in, "only SIGTERM, SIGINT and SIGHUP are forwarded")
+		}
+		outSig, err := ParseSignal(remap[in])
+		if err != nil {
+			return nil, invalidField("signalRemap."+in, remap[in], "%v", err)
+		}
+		parsed[inSig] = outSig
+	}
+	return parsed, nil
+}
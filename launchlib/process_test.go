@@ -1,7 +1,17 @@
 package launchlib
 
 import (
+	"errors"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"syscall"
 	"testing"
+	"time"
 )
 
 func TestBuildCommandArgsPEXMode(t *testing.T) {
@@ -28,6 +38,26 @@ func TestBuildCommandArgsPEXWithPython(t *testing.T) {
 	assertArgs(t, expected, args)
 }
 
+func TestBuildCommandArgsPythonPathFromConfigEnv(t *testing.T) {
+	t.Setenv("PYTHON_HOME", "/from/launcher/env")
+	t.Setenv("PYTHON_BIN", "bin")
+	config := MergedConfig{
+		LaunchMode: LaunchModeModule,
+		Executable: "myapp.server",
+		PythonPath: "${PYTHON_HOME}/$PYTHON_BIN/python3",
+		Env:        map[string]string{"PYTHON_HOME": "/opt/python"},
+	}
+	args := BuildCommandArgs(config)
+	// PYTHON_HOME comes from the config env, PYTHON_BIN from the launcher's.
+	expected := []string{"/opt/python/bin/python3", "-m", "myapp.server"}
+	assertArgs(t, expected, args)
+
+	config.LaunchMode = LaunchModePEX
+	config.Executable = "service/bin/app.pex"
+	args = BuildCommandArgs(config)
+	assertArgs(t, []string{"/opt/python/bin/python3", "service/bin/app.pex"}, args)
+}
+
 func TestBuildCommandArgsModuleMode(t *testing.T) {
 	config := MergedConfig{
 		LaunchMode: LaunchModeModule,
@@ -125,3 +155,539 @@ func assertArgs(t *testing.T, expected, actual []string) {
 		}
 	}
 }
+
+func TestPidFilePath(t *testing.T) {
+	disabled := false
+	enabled := true
+	tests := []struct {
+		name     string
+		paths    PathsConfig
+		expected string
+	}{
+		{"default", PathsConfig{}, "var/run/my-svc.pid"},
+		{"explicitly enabled", PathsConfig{PidFileEnabled: &enabled}, "var/run/my-svc.pid"},
+		{"custom relative", PathsConfig{PidFile: "var/run/%s-1.pid"}, "var/run/my-svc-1.pid"},
+		{"custom absolute", PathsConfig{PidFile: "/tmp/app.pid"}, "/tmp/app.pid"},
+		{"disabled", PathsConfig{PidFile: "/tmp/app.pid", PidFileEnabled: &disabled}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := PidFilePath(tt.paths, "my-svc"); got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestStopProcess(t *testing.T) {
+	cmd := exec.Command("/bin/sh", "-c", "trap '' TERM; exec sleep 30")
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan struct{})
+	go func() {
+		_ = cmd.Wait()
+		close(done)
+	}()
+
+	// Give the shell time to install the trap before signaling it.
+	time.Sleep(200 * time.Millisecond)
+	killed, err := StopProcess(cmd.Process.Pid, 200*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !killed {
+		t.Error("expected SIGKILL for a process ignoring SIGTERM")
+	}
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("process still running after StopProcess")
+	}
+}
+
+func TestForwardSignalsRemap(t *testing.T) {
+	cmd := exec.Command("/bin/sh", "-c", "trap 'exit 2' INT; trap 'exit 3' TERM; sleep 30 & wait")
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+
+	sigs := ForwardSignals(cmd.Process.Pid, map[syscall.Signal]syscall.Signal{syscall.SIGINT: syscall.SIGTERM})
+	defer func() {
+		signal.Stop(sigs)
+		close(sigs)
+	}()
+
+	// Give the shell time to install its traps before signaling it.
+	time.Sleep(200 * time.Millisecond)
+	if err := syscall.Kill(os.Getpid(), syscall.SIGINT); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		_ = cmd.Process.Kill()
+		t.Fatal("process still running after the forwarded signal")
+	}
+	if code := cmd.ProcessState.ExitCode(); code != 3 {
+		t.Errorf("expected exit code 3 (SIGTERM trap), got %d", code)
+	}
+}
+
+func TestSetResourceLimitsOpenFiles(t *testing.T) {
+	originalGet, originalSet := getrlimit, setrlimit
+	defer func() { getrlimit, setrlimit = originalGet, originalSet }()
+
+	current := syscall.Rlimit{Cur: 1024, Max: 524288}
+	var nofile *syscall.Rlimit
+	getrlimit = func(resource int, limit *syscall.Rlimit) error {
+		*limit = current
+		return nil
+	}
+	setrlimit = func(resource int, limit *syscall.Rlimit) error {
+		if resource == syscall.RLIMIT_NOFILE {
+			copied := *limit
+			nofile = &copied
+		}
+		return nil
+	}
+
+	tests := []struct {
+		name   string
+		config ResourceConfig
+		want   *syscall.Rlimit
+	}{
+		{"unset", ResourceConfig{}, nil},
+		{"maxOpenFiles sets both", ResourceConfig{MaxOpenFiles: 4096}, &syscall.Rlimit{Cur: 4096, Max: 4096}},
+		{"distinct soft and hard", ResourceConfig{MaxOpenFilesSoft: 1024, MaxOpenFilesHard: 65536},
+			&syscall.Rlimit{Cur: 1024, Max: 65536}},
+		{"soft with maxOpenFiles", ResourceConfig{MaxOpenFiles: 65536, MaxOpenFilesSoft: 2048},
+			&syscall.Rlimit{Cur: 2048, Max: 65536}},
+		{"hard only keeps the current soft", ResourceConfig{MaxOpenFilesHard: 8192},
+			&syscall.Rlimit{Cur: 1024, Max: 8192}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nofile = nil
+			if err := SetResourceLimits(tt.config); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(nofile, tt.want) {
+				t.Errorf("expected RLIMIT_NOFILE %+v, got %+v", tt.want, nofile)
+			}
+		})
+	}
+}
+
+func TestApplyNice(t *testing.T) {
+	original := setPriority
+	defer func() { setPriority = original }()
+
+	var got [][2]int
+	setPriority = func(pid, nice int) error {
+		got = append(got, [2]int{pid, nice})
+		return nil
+	}
+
+	if err := ApplyNice(1234, 10); err != nil {
+		t.Fatal(err)
+	}
+	if err := ApplyNice(1234, 0); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0] != [2]int{1234, 10} {
+		t.Errorf("expected a single setpriority call with 10 for pid 1234, got %v", got)
+	}
+
+	if err := ApplyNice(1234, 20); err == nil {
+		t.Error("expected an error for nice 20")
+	}
+	if err := ApplyNice(1234, -21); err == nil {
+		t.Error("expected an error for nice -21")
+	}
+}
+
+func TestApplyNicePermissionError(t *testing.T) {
+	original := setPriority
+	defer func() { setPriority = original }()
+	setPriority = func(int, int) error { return syscall.EACCES }
+
+	err := ApplyNice(1234, -5)
+	if !errors.Is(err, syscall.EACCES) {
+		t.Errorf("expected wrapped EACCES, got %v", err)
+	}
+}
+
+func TestApplyOOMScoreAdj(t *testing.T) {
+	original := writeOOMScoreAdj
+	defer func() { writeOOMScoreAdj = original }()
+
+	var got [][2]int
+	writeOOMScoreAdj = func(pid, adj int) error {
+		got = append(got, [2]int{pid, adj})
+		return nil
+	}
+
+	if err := ApplyOOMScoreAdj(1234, 500); err != nil {
+		t.Fatal(err)
+	}
+	if err := ApplyOOMScoreAdj(1234, 0); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0] != [2]int{1234, 500} {
+		t.Errorf("expected a single write of 500 for pid 1234, got %v", got)
+	}
+
+	if err := ApplyOOMScoreAdj(1234, -1001); err == nil {
+		t.Error("expected an error for oom_score_adj -1001")
+	}
+
+	writeOOMScoreAdj = func(int, int) error { return syscall.EACCES }
+	if err := ApplyOOMScoreAdj(1234, -500); !errors.Is(err, syscall.EACCES) {
+		t.Errorf("expected wrapped EACCES, got %v", err)
+	}
+}
+
+func TestBuildProcessEnvBytecodeCaching(t *testing.T) {
+	enabled, disabled := true, false
+	tests := []struct {
+		name            string
+		caching         *bool
+		cacheDir        string
+		env             map[string]string
+		wantDontWrite   string
+		wantCachePrefix string
+	}{
+		{"default", nil, "", nil, "1", ""},
+		{"disabled", &disabled, "/cache", nil, "1", ""},
+		{"enabled", &enabled, "", nil, "", "var/data/pycache"},
+		{"enabled with dir", &enabled, "/dist/var/pyc", nil, "", "/dist/var/pyc"},
+		{"config env wins", &enabled, "/dist/var/pyc", map[string]string{"PYTHONPYCACHEPREFIX": "/tmp/pyc"}, "", "/tmp/pyc"},
+		{"config env keeps dont-write", &enabled, "", map[string]string{"PYTHONDONTWRITEBYTECODE": "1"}, "1", "var/data/pycache"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := MergedConfig{
+				EnvInherit:       EnvInheritConfig{Policy: EnvInheritNone},
+				Env:              tt.env,
+				BytecodeCaching:  tt.caching,
+				BytecodeCacheDir: tt.cacheDir,
+			}
+			env := envToMap(BuildProcessEnv(config, MemoryLimits{}, "my-service", "1.0.0"))
+			if got := env["PYTHONDONTWRITEBYTECODE"]; got != tt.wantDontWrite {
+				t.Errorf("expected PYTHONDONTWRITEBYTECODE=%q, got %q", tt.wantDontWrite, got)
+			}
+			if got := env["PYTHONPYCACHEPREFIX"]; got != tt.wantCachePrefix {
+				t.Errorf("expected PYTHONPYCACHEPREFIX=%q, got %q", tt.wantCachePrefix, got)
+			}
+		})
+	}
+}
+
+func TestBuildProcessEnvPathEntries(t *testing.T) {
+	t.Setenv("PYTHONPATH", "/inherited/site")
+	t.Setenv("PSL_TEST_LIBS", "/usr/lib")
+	tests := []struct {
+		name       string
+		env        map[string]string
+		entries    []string
+		envAppend  map[string][]string
+		wantPath   string
+		wantAppend string
+	}{
+		{"inherited value kept", nil, []string{"/dist/lib", "/dist/vendor"}, nil, "/dist/lib:/dist/vendor:/inherited/site", "/usr/lib"},
+		{"config env value kept", map[string]string{"PYTHONPATH": "/configured"}, []string{"/dist/lib"}, nil, "/dist/lib:/configured", "/usr/lib"},
+		{"no entries", nil, nil, nil, "/inherited/site", "/usr/lib"},
+		{"append", nil, nil, map[string][]string{"PSL_TEST_LIBS": {"/opt/a", "/opt/b"}}, "/inherited/site", "/usr/lib:/opt/a:/opt/b"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := MergedConfig{
+				Env:               tt.env,
+				PythonPathEntries: tt.entries,
+				EnvAppend:         tt.envAppend,
+			}
+			env := envToMap(BuildProcessEnv(config, MemoryLimits{}, "my-service", "1.0.0"))
+			if got := env["PYTHONPATH"]; got != tt.wantPath {
+				t.Errorf("expected PYTHONPATH=%q, got %q", tt.wantPath, got)
+			}
+			if got := env["PSL_TEST_LIBS"]; got != tt.wantAppend {
+				t.Errorf("expected PSL_TEST_LIBS=%q, got %q", tt.wantAppend, got)
+			}
+		})
+	}
+
+	config := MergedConfig{
+		EnvInherit:        EnvInheritConfig{Policy: EnvInheritNone},
+		PythonPathEntries: []string{"/dist/lib"},
+		EnvAppend:         map[string][]string{"PSL_TEST_LIBS": {"/opt/a"}},
+	}
+	env := envToMap(BuildProcessEnv(config, MemoryLimits{}, "my-service", "1.0.0"))
+	if env["PYTHONPATH"] != "/dist/lib" || env["PSL_TEST_LIBS"] != "/opt/a" {
+		t.Errorf("expected no empty entries without an inherited value, got PYTHONPATH=%q PSL_TEST_LIBS=%q",
+			env["PYTHONPATH"], env["PSL_TEST_LIBS"])
+	}
+}
+
+func TestBuildProcessEnvInheritPolicy(t *testing.T) {
+	t.Setenv("PSL_TEST_HOST_VAR", "host")
+	t.Setenv("PSL_TEST_SECRET", "s3cret")
+	t.Setenv("LC_PSL_TEST", "en_US.UTF-8")
+
+	tests := []struct {
+		name     string
+		inherit  EnvInheritConfig
+		expected map[string]bool // host var name -> should be inherited
+	}{
+		{"default", EnvInheritConfig{}, map[string]bool{
+			"PSL_TEST_HOST_VAR": true, "PSL_TEST_SECRET": true, "LC_PSL_TEST": true,
+		}},
+		{"all", EnvInheritConfig{Policy: EnvInheritAll}, map[string]bool{
+			"PSL_TEST_HOST_VAR": true, "PSL_TEST_SECRET": true, "LC_PSL_TEST": true,
+		}},
+		{"none", EnvInheritConfig{Policy: EnvInheritNone}, map[string]bool{
+			"PSL_TEST_HOST_VAR": false, "PSL_TEST_SECRET": false, "LC_PSL_TEST": false,
+		}},
+		{"allowlist", EnvInheritConfig{
+			Policy:            EnvInheritAllowlist,
+			AllowlistPatterns: []string{"LC_*", "PSL_TEST_HOST_VAR"},
+		}, map[string]bool{
+			"PSL_TEST_HOST_VAR": true, "PSL_TEST_SECRET": false, "LC_PSL_TEST": true,
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := MergedConfig{
+				Memory:     MemoryConfig{Mode: MemoryModeFixed, MaxRSSPercent: 75},
+				Env:        map[string]string{"APP_SETTING": "from-config"},
+				EnvInherit: tt.inherit,
+			}
+			limits := MemoryLimits{EffectiveLimitBytes: 512 * 1024 * 1024}
+			env := envToMap(BuildProcessEnv(config, limits, "my-service", "1.0.0"))
+
+			for name, want := range tt.expected {
+				if _, got := env[name]; got != want {
+					t.Errorf("%s inherited = %t, expected %t", name, got, want)
+				}
+			}
+			if env["APP_SETTING"] != "from-config" {
+				t.Errorf("expected config env always set, got %q", env["APP_SETTING"])
+			}
+			if env["MEMORY_LIMIT_BYTES"] != "536870912" {
+				t.Errorf("expected memory vars always set, got %q", env["MEMORY_LIMIT_BYTES"])
+			}
+			if env["SERVICE_NAME"] != "my-service" {
+				t.Errorf("expected service metadata always set, got %q", env["SERVICE_NAME"])
+			}
+			if env["PYTHONUNBUFFERED"] != "1" {
+				t.Errorf("expected Python defaults always set, got %q", env["PYTHONUNBUFFERED"])
+			}
+		})
+	}
+}
+
+func TestReadEnvFromFiles(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "db-password"), []byte("hunter2\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	absToken := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(absToken, []byte("  abc123 \r\n\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	resolve := func(path string) string {
+		if filepath.IsAbs(path) {
+			return path
+		}
+		return filepath.Join(dir, path)
+	}
+
+	values, err := ReadEnvFromFiles(map[string]string{
+		"DB_PASSWORD": "db-password",
+		"API_TOKEN":   absToken,
+	}, resolve)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if values["DB_PASSWORD"] != "hunter2" {
+		t.Errorf("expected trailing newline trimmed, got %q", values["DB_PASSWORD"])
+	}
+	if values["API_TOKEN"] != "abc123" {
+		t.Errorf("expected surrounding whitespace trimmed, got %q", values["API_TOKEN"])
+	}
+}
+
+func TestReadEnvFromFilesMissing(t *testing.T) {
+	dir := t.TempDir()
+	_, err := ReadEnvFromFiles(map[string]string{"DB_PASSWORD": "missing"}, func(path string) string {
+		return filepath.Join(dir, path)
+	})
+	if err == nil {
+		t.Fatal("expected an error for a missing secret file")
+	}
+	if !errors.Is(err, os.ErrNotExist) || !strings.Contains(err.Error(), "DB_PASSWORD") {
+		t.Errorf("expected a not-exist error naming DB_PASSWORD, got %v", err)
+	}
+}
+
+func TestReadArgsFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "args")
+	content := "# generated by deploy tooling\n--workers\n  4  \n\n   # indented comment\n--bind=0.0.0.0:8080\r\n--name=a b\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	args, err := ReadArgsFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"--workers", "4", "--bind=0.0.0.0:8080", "--name=a b"}
+	if !reflect.DeepEqual(args, expected) {
+		t.Errorf("expected %v, got %v", expected, args)
+	}
+
+	if _, err := ReadArgsFile(filepath.Join(t.TempDir(), "missing")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected a not-exist error for a missing args file, got %v", err)
+	}
+}
+
+func TestParseUmask(t *testing.T) {
+	tests := []struct {
+		umask   string
+		want    int
+		wantErr bool
+	}{
+		{"0027", 0027, false},
+		{"077", 0077, false},
+		{"0", 0, false},
+		{"0o27", 0, true},
+		{"0888", 0, true},
+		{"01777", 0, true},
+		{"", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseUmask(tt.umask)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseUmask(%q): expected error=%t, got %v", tt.umask, tt.wantErr, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseUmask(%q): expected %04o, got %04o", tt.umask, tt.want, got)
+		}
+	}
+}
+
+func TestRedactEnv(t *testing.T) {
+	env := []string{"DB_URL=postgres://x", "MY_SECRET=abc", "github_token=ghp", "PATH=/bin", "EMPTY="}
+	got := RedactEnv(env, []string{"DB_URL"})
+	want := []string{"DB_URL=<redacted>", "MY_SECRET=<redacted>", "github_token=<redacted>", "PATH=/bin", "EMPTY="}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("entry %d: expected %q, got %q", i, want[i], got[i])
+		}
+	}
+	if env[0] != "DB_URL=postgres://x" {
+		t.Error("RedactEnv must not modify its input")
+	}
+}
+
+func TestExpandDirRange(t *testing.T) {
+	tests := []struct {
+		dir  string
+		want []string
+	}{
+		{"var/data/shard-{0..3}", []string{"var/data/shard-0", "var/data/shard-1", "var/data/shard-2", "var/data/shard-3"}},
+		{"var/{08..10}/cache", []string{"var/08/cache", "var/09/cache", "var/10/cache"}},
+		{"var/data/shard-{2..2}", []string{"var/data/shard-2"}},
+		{"var/log", []string{"var/log"}},
+		{"var/{literal}", []string{"var/{literal}"}},
+	}
+	for _, tt := range tests {
+		got, err := ExpandDirRange(tt.dir)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.dir, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: expected %v, got %v", tt.dir, tt.want, got)
+		}
+	}
+}
+
+func TestExpandDirRangeInvalid(t *testing.T) {
+	for _, dir := range []string{
+		"var/shard-{3..0}",
+		"var/shard-{a..c}",
+		"var/shard-{-1..2}",
+		"var/shard-{0..}",
+		"var/shard-{0..1000}",
+		"var/{0..1}/{0..1}",
+	} {
+		if paths, err := ExpandDirRange(dir); err == nil {
+			t.Errorf("%s: expected an error, got %v", dir, paths)
+		}
+	}
+}
+
+func TestCreateDirectoriesRange(t *testing.T) {
+	root := t.TempDir()
+	if err := CreateDirectories([]string{filepath.Join(root, "shard-{0..3}"), filepath.Join(root, "log")}); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"shard-0", "shard-1", "shard-2", "shard-3", "log"} {
+		if info, err := os.Stat(filepath.Join(root, name)); err != nil || !info.IsDir() {
+			t.Errorf("expected directory %s, got %v", name, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(root, "shard-{0..3}")); !os.IsNotExist(err) {
+		t.Errorf("expected the pattern itself not to be created, got %v", err)
+	}
+
+	if err := CreateDirectories([]string{filepath.Join(root, "bad-{3..1}")}); err == nil {
+		t.Error("expected an invalid range to fail")
+	}
+}
+
+func TestApplyDirectoryPermissionsMode(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "secrets")
+	if err := CreateDirectories([]string{dir}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ApplyDirectoryPermissions(dir, DirConfig{Path: "secrets", Mode: "0700"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	info, err := os.Stat(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0700 {
+		t.Errorf("expected mode 0700, got %o", info.Mode().Perm())
+	}
+}
+
+func TestApplyDirectoryPermissionsOwner(t *testing.T) {
+	dir := t.TempDir()
+
+	// Chowning to our own uid/gid succeeds without privileges.
+	own := DirConfig{Path: dir, Owner: strconv.Itoa(os.Getuid()), Group: strconv.Itoa(os.Getgid())}
+	if err := ApplyDirectoryPermissions(dir, own); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	var chownErr *DirectoryChownError
+	err := ApplyDirectoryPermissions(dir, DirConfig{Path: dir, Owner: "no-such-user-psl"})
+	if err == nil || errors.As(err, &chownErr) {
+		t.Errorf("expected a fatal error for an unknown user, got %v", err)
+	}
+
+	if os.Getuid() != 0 {
+		err := ApplyDirectoryPermissions(dir, DirConfig{Path: dir, Owner: "0"})
+		if !errors.As(err, &chownErr) {
+			t.Errorf("expected a *DirectoryChownError when not root, got %v", err)
+		}
+	}
+}
@@ -0,0 +1,203 @@
+package launchlib
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ConfigSource says where a merged config value came from.
+type ConfigSource string
+
+const (
+	// ConfigSourceDefault means neither config file set the field.
+	ConfigSourceDefault ConfigSource = "default"
+
+	// ConfigSourceStatic means the value came from launcher-static.yml.
+	ConfigSourceStatic ConfigSource = "static"
+
+	// ConfigSourceCustom means launcher-custom.yml set or overrode the field.
+	ConfigSourceCustom ConfigSource = "custom"
+
+	// ConfigSourceEnvOverride means a launcher environment variable, such as
+	// LAUNCHER_MEMORY_MODE, replaced the configured value.
+	ConfigSourceEnvOverride ConfigSource = "env-override"
+)
+
+// FieldProvenance is a merged config field, its value, and where it came from.
+type FieldProvenance struct {
+	Field  string
+	Value  string
+	Source ConfigSource
+}
+
+// String formats the field as "field = value (source)".
+func (f FieldProvenance) String() string {
+	return fmt.Sprintf("%s = %s (%s)", f.Field, f.Value, f.Source)
+}
+
+// envProvenancePrefix prefixes env keys in MergedConfig.Provenance.
+const envProvenancePrefix = "env."
+
+// provenanceField describes how to attribute and print one merged field.
+type provenanceField struct {
+	name   string
+	source func(static StaticLauncherConfig, custom CustomLauncherConfig) ConfigSource
+	value  func(config MergedConfig) any
+}
+
+// provenanceFields are the fields whose source is tracked, in report order:
+// the ones that most often explain a surprising memory or watchdog setting.
+var provenanceFields = []provenanceField{
+	{"pythonPath",
+		func(s StaticLauncherConfig, c CustomLauncherConfig) ConfigSource {
+			_, selected := s.PythonVersions[c.PythonVersion]
+			return sourceOf(s.PythonPath != "", selected && c.PythonVersion != "")
+		},
+		func(m MergedConfig) any { return m.PythonPath }},
+	{"memory.mode",
+		func(s StaticLauncherConfig, c CustomLauncherConfig) ConfigSource {
+			return sourceOf(s.Memory.Mode != "", customMemory(c).Mode != "")
+		},
+		func(m MergedConfig) any { return m.Memory.Mode }},
+	{"memory.maxRssPercent",
+		func(s StaticLauncherConfig, c CustomLauncherConfig) ConfigSource {
+			return sourceOf(s.Memory.MaxRSSPercent > 0, customMemory(c).MaxRSSPercent > 0)
+		},
+		func(m MergedConfig) any { return m.Memory.MaxRSSPercent }},
+	{"memory.fixedLimitBytes",
+		func(s StaticLauncherConfig, c CustomLauncherConfig) ConfigSource {
+			return sourceOf(s.Memory.FixedLimitBytes > 0, customMemory(c).FixedLimitBytes > 0)
+		},
+		func(m MergedConfig) any { return m.Memory.FixedLimitBytes }},
+	{"memory.heapFragmentationBuffer",
+		func(s StaticLauncherConfig, c CustomLauncherConfig) ConfigSource {
+			return sourceOf(s.Memory.HeapFragmentationBuffer != nil, customMemory(c).HeapFragmentationBuffer != nil)
+		},
+		func(m MergedConfig) any { return valueOr(m.Memory.HeapFragmentationBuffer, 0) }},
+	{"memory.mallocTrimThreshold",
+		func(s StaticLauncherConfig, c CustomLauncherConfig) ConfigSource {
+			return sourceOf(s.Memory.MallocTrimThreshold != nil, customMemory(c).MallocTrimThreshold != nil)
+		},
+		func(m MergedConfig) any { return valueOr(m.Memory.MallocTrimThreshold, 0) }},
+	{"memory.mallocArenaMax",
+		func(s StaticLauncherConfig, c CustomLauncherConfig) ConfigSource {
+			return sourceOf(s.Memory.MallocArenaMax != nil, customMemory(c).MallocArenaMax != nil)
+		},
+		func(m MergedConfig) any { return valueOr(m.Memory.MallocArenaMax, 0) }},
+	{"watchdog.enabled",
+		func(s StaticLauncherConfig, c CustomLauncherConfig) ConfigSource {
+			return sourceOf(s.Watchdog.Enabled != nil, customWatchdog(c).Enabled != nil)
+		},
+		func(m MergedConfig) any { return valueOr(m.Watchdog.Enabled, false) }},
+	{"watchdog.pollIntervalSeconds",
+		func(s StaticLauncherConfig, c CustomLauncherConfig) ConfigSource {
+			return sourceOf(s.Watchdog.PollIntervalSeconds > 0, customWatchdog(c).PollIntervalSeconds > 0)
+		},
+		func(m MergedConfig) any { return m.Watchdog.PollIntervalSeconds }},
+	{"watchdog.pollIntervalMillis",
+		func(s StaticLauncherConfig, c CustomLauncherConfig) ConfigSource {
+			// A custom pollIntervalSeconds clears a static pollIntervalMillis.
+			custom := customWatchdog(c)
+			return sourceOf(s.Watchdog.PollIntervalMillis > 0, custom.PollIntervalMillis > 0 || custom.PollIntervalSeconds > 0)
+		},
+		func(m MergedConfig) any { return m.Watchdog.PollIntervalMillis }},
+	{"watchdog.softLimitPercent",
+		func(s StaticLauncherConfig, c CustomLauncherConfig) ConfigSource {
+			return sourceOf(s.Watchdog.SoftLimitPercent > 0, customWatchdog(c).SoftLimitPercent > 0)
+		},
+		func(m MergedConfig) any { return m.Watchdog.SoftLimitPercent }},
+	{"watchdog.hardLimitPercent",
+		func(s StaticLauncherConfig, c CustomLauncherConfig) ConfigSource {
+			return sourceOf(s.Watchdog.HardLimitPercent > 0, customWatchdog(c).HardLimitPercent > 0)
+		},
+		func(m MergedConfig) any { return m.Watchdog.HardLimitPercent }},
+	{"watchdog.gracePeriodSeconds",
+		func(s StaticLauncherConfig, c CustomLauncherConfig) ConfigSource {
+			return sourceOf(s.Watchdog.GracePeriodSeconds > 0, customWatchdog(c).GracePeriodSeconds > 0)
+		},
+		func(m MergedConfig) any { return m.Watchdog.GracePeriodSeconds }},
+	{"watchdog.startupGraceSeconds",
+		func(s StaticLauncherConfig, c CustomLauncherConfig) ConfigSource {
+			return sourceOf(s.Watchdog.StartupGraceSeconds != nil, customWatchdog(c).StartupGraceSeconds != nil)
+		},
+		func(m MergedConfig) any { return valueOr(m.Watchdog.StartupGraceSeconds, 0) }},
+}
+
+// sourceOf attributes a field set in the static and/or custom config. The
+// custom config wins, as it does when merging.
+func sourceOf(staticSet, customSet bool) ConfigSource {
+	switch {
+	case customSet:
+		return ConfigSourceCustom
+	case staticSet:
+		return ConfigSourceStatic
+	default:
+		return ConfigSourceDefault
+	}
+}
+
+func customMemory(custom CustomLauncherConfig) MemoryConfig {
+	return valueOr(custom.Memory, MemoryConfig{})
+}
+
+func customWatchdog(custom CustomLauncherConfig) WatchdogConfig {
+	return valueOr(custom.Watchdog, WatchdogConfig{})
+}
+
+// configProvenance attributes the tracked fields and every env key of a merge
+// of static and custom. Environment overrides are recorded by the caller.
+func configProvenance(static StaticLauncherConfig, custom CustomLauncherConfig) map[string]ConfigSource {
+	provenance := make(map[string]ConfigSource, len(provenanceFields)+len(static.Env)+len(custom.Env))
+	for _, field := range provenanceFields {
+		provenance[field.name] = field.source(static, custom)
+	}
+	for key := range static.Env {
+		provenance[envProvenancePrefix+key] = ConfigSourceStatic
+	}
+	for key := range custom.Env {
+		provenance[envProvenancePrefix+key] = ConfigSourceCustom
+	}
+	return provenance
+}
+
+// ExplainConfig lists the tracked fields of config with their values and
+// sources, followed by the config env sorted by name. Values of env keys with
+// secret-looking names are redacted.
+func ExplainConfig(config MergedConfig) []FieldProvenance {
+	fields := make([]FieldProvenance, 0, len(provenanceFields)+len(config.Env))
+	for _, field := range provenanceFields {
+		fields = append(fields, FieldProvenance{
+			Field:  field.name,
+			Value:  fmt.Sprint(field.value(config)),
+			Source: provenanceSource(config, field.name),
+		})
+	}
+	for _, key := range sortedKeys(config.Env) {
+		value := config.Env[key]
+		if isSensitiveEnvName(key) {
+			value = redactedValue
+		}
+		fields = append(fields, FieldProvenance{
+			Field:  envProvenancePrefix + key,
+			Value:  quoteIfEmpty(value),
+			Source: provenanceSource(config, envProvenancePrefix+key),
+		})
+	}
+	return fields
+}
+
+// provenanceSource returns the recorded source of field, or default.
+func provenanceSource(config MergedConfig, field string) ConfigSource {
+	if source, ok := config.Provenance[field]; ok {
+		return source
+	}
+	return ConfigSourceDefault
+}
+
+// quoteIfEmpty makes an empty value visible in the report.
+func quoteIfEmpty(value string) string {
+	if strings.TrimSpace(value) == "" {
+		return fmt.Sprintf("%q", value)
+	}
+	return value
+}
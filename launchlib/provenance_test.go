@@ -0,0 +1,106 @@
+package launchlib
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMergeConfigsProvenance(t *testing.T) {
+	static := StaticLauncherConfig{
+		Memory:   MemoryConfig{Mode: MemoryModeCgroupAware, MaxRSSPercent: 70},
+		Watchdog: WatchdogConfig{SoftLimitPercent: 80, PollIntervalMillis: 500},
+		Env:      map[string]string{"STATIC_ONLY": "1", "SHARED": "static"},
+	}
+	custom := CustomLauncherConfig{
+		Memory:   &MemoryConfig{MaxRSSPercent: 60},
+		Watchdog: &WatchdogConfig{PollIntervalSeconds: 2},
+		Env:      map[string]string{"SHARED": "custom"},
+	}
+
+	merged := MergeConfigs(static, custom)
+	want := map[string]ConfigSource{
+		"memory.mode":                 ConfigSourceStatic,
+		"memory.maxRssPercent":        ConfigSourceCustom,
+		"memory.fixedLimitBytes":      ConfigSourceDefault,
+		"watchdog.softLimitPercent":   ConfigSourceStatic,
+		"watchdog.hardLimitPercent":   ConfigSourceDefault,
+		"watchdog.pollIntervalMillis": ConfigSourceCustom, // cleared by the custom seconds
+		"env.STATIC_ONLY":             ConfigSourceStatic,
+		"env.SHARED":                  ConfigSourceCustom,
+	}
+	for field, source := range want {
+		if got := merged.Provenance[field]; got != source {
+			t.Errorf("expected %s from %s, got %q", field, source, got)
+		}
+	}
+
+	t.Setenv("LAUNCHER_MEMORY_MODE", "unmanaged")
+	merged = MergeConfigs(static, custom)
+	if got := merged.Provenance["memory.mode"]; got != ConfigSourceEnvOverride {
+		t.Errorf("expected memory.mode from %s, got %q", ConfigSourceEnvOverride, got)
+	}
+
+	custom.DangerousDisableContainerSupport = true
+	merged = MergeConfigs(static, custom)
+	if got := merged.Provenance["memory.mode"]; got != ConfigSourceStatic {
+		t.Errorf("expected an ignored override to leave memory.mode from static, got %q", got)
+	}
+}
+
+func TestExplainConfig(t *testing.T) {
+	config := MergeConfigs(StaticLauncherConfig{
+		Memory: MemoryConfig{MaxRSSPercent: 70},
+		Env:    map[string]string{"API_TOKEN": "hunter2", "EMPTY": ""},
+	}, CustomLauncherConfig{Memory: &MemoryConfig{MaxRSSPercent: 60}})
+
+	fields := make(map[string]FieldProvenance)
+	for _, field := range ExplainConfig(config) {
+		fields[field.Field] = field
+	}
+	if got := fields["memory.maxRssPercent"].String(); got != "memory.maxRssPercent = 60 (custom)" {
+		t.Errorf("unexpected maxRssPercent line %q", got)
+	}
+	if got := fields["memory.mode"].String(); got != "memory.mode = cgroup-aware (default)" {
+		t.Errorf("unexpected memory.mode line %q", got)
+	}
+	if got := fields["env.API_TOKEN"].String(); got != "env.API_TOKEN = <redacted> (static)" {
+		t.Errorf("expected a redacted secret, got %q", got)
+	}
+	if got := fields["env.EMPTY"].Value; got != `""` {
+		t.Errorf("expected an empty value to be quoted, got %q", got)
+	}
+}
+
+func TestLauncherExplainConfig(t *testing.T) {
+	launcher, _ := newTestLauncher(t, `
+configType: python
+configVersion: 1
+launchMode: command
+executable: /bin/true
+memory:
+  mode: unmanaged
+  maxRssPercent: 70
+`)
+	customPath := filepath.Join(launcher.params.DistRoot, defaultCustomConfigPath)
+	if err := os.MkdirAll(filepath.Dir(customPath), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(customPath, []byte("configType: python\nconfigVersion: 1\nmemory:\n  maxRssPercent: 0.6\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	fields, err := launcher.ExplainConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, field := range fields {
+		if field.Field == "memory.maxRssPercent" {
+			if field.Source != ConfigSourceCustom || field.Value != "60" {
+				t.Errorf("expected maxRssPercent 60 from custom, got %s", field)
+			}
+			return
+		}
+	}
+	t.Error("expected memory.maxRssPercent in the report")
+}
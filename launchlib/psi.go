@@ -0,0 +1,87 @@
+package launchlib
+
+import (
+	"fmt"
+	"io/fs"
+	"strconv"
+	"strings"
+)
+
+// cgroupV2MemoryPressurePath is the cgroup v2 memory pressure stall information file.
+const cgroupV2MemoryPressurePath = "memory.pressure"
+
+// PSILine holds one line of a pressure stall information file. Averages are
+// the percentage of wall time stalled over 10s, 60s and 300s windows; Total is
+// the cumulative stall time in microseconds.
+type PSILine struct {
+	Avg10  float64 `json:"avg10"`
+	Avg60  float64 `json:"avg60"`
+	Avg300 float64 `json:"avg300"`
+	Total  uint64  `json:"total"`
+}
+
+// PSIStats is the parsed content of a memory.pressure file. "Some" is time at
+// least one task stalled on memory; "Full" is time all tasks stalled at once.
+type PSIStats struct {
+	Some PSILine `json:"some"`
+	Full PSILine `json:"full"`
+}
+
+// ReadMemoryPressure reads and parses the cgroup v2 memory.pressure file
+// under cgroupRoot (DefaultCgroupRoot if empty).
+func ReadMemoryPressure(filesystem fs.FS, cgroupRoot string) (PSIStats, error) {
+	path := cgroupPath(cgroupRoot, cgroupV2MemoryPressurePath)
+	data, err := fs.ReadFile(filesystem, path)
+	if err != nil {
+		return PSIStats{}, fmt.Errorf("failed to read /%s: %w", path, err)
+	}
+	return parsePSI(string(data))
+}
+
+// parsePSI parses lines of the form
+//
+//	some avg10=0.12 avg60=0.05 avg300=0.01 total=12345
+func parsePSI(content string) (PSIStats, error) {
+	var stats PSIStats
+	var sawSome bool
+	for _, line := range strings.Split(strings.TrimSpace(content), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		var target *PSILine
+		switch fields[0] {
+		case "some":
+			target = &stats.Some
+			sawSome = true
+		case "full":
+			target = &stats.Full
+		default:
+			continue
+		}
+		for _, field := range fields[1:] {
+			key, value, ok := strings.Cut(field, "=")
+			if !ok {
+				return PSIStats{}, fmt.Errorf("malformed PSI field %q", field)
+			}
+			var err error
+			switch key {
+			case "avg10":
+				target.Avg10, err = strconv.ParseFloat(value, 64)
+			case "avg60":
+				target.Avg60, err = strconv.ParseFloat(value, 64)
+			case "avg300":
+				target.Avg300, err = strconv.ParseFloat(value, 64)
+			case "total":
+				target.Total, err = strconv.ParseUint(value, 10, 64)
+			}
+			if err != nil {
+				return PSIStats{}, fmt.Errorf("malformed PSI field %q: %w", field, err)
+			}
+		}
+	}
+	if !sawSome {
+		return PSIStats{}, fmt.Errorf("PSI data has no \"some\" line")
+	}
+	return stats, nil
+}
@@ -0,0 +1,100 @@
+package launchlib
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+const testMemoryPressure = `some avg10=12.50 avg60=4.25 avg300=1.10 total=987654
+full avg10=3.00 avg60=1.00 avg300=0.25 total=123456
+`
+
+func TestReadMemoryPressure(t *testing.T) {
+	stats, err := ReadMemoryPressure(testFS(map[string]string{
+		"sys/fs/cgroup/memory.pressure": testMemoryPressure,
+	}), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := PSIStats{
+		Some: PSILine{Avg10: 12.50, Avg60: 4.25, Avg300: 1.10, Total: 987654},
+		Full: PSILine{Avg10: 3.00, Avg60: 1.00, Avg300: 0.25, Total: 123456},
+	}
+	if stats != want {
+		t.Errorf("expected %+v, got %+v", want, stats)
+	}
+}
+
+func TestReadMemoryPressureMissing(t *testing.T) {
+	if _, err := ReadMemoryPressure(testFS(map[string]string{}), ""); err == nil {
+		t.Error("expected an error when memory.pressure is absent (cgroup v1)")
+	}
+}
+
+func TestParsePSIMalformed(t *testing.T) {
+	for _, content := range []string{
+		"",
+		"full avg10=1.00 avg60=0.00 avg300=0.00 total=1",
+		"some avg10=abc avg60=0.00 avg300=0.00 total=1",
+		"some avg10",
+	} {
+		if _, err := parsePSI(content); err == nil {
+			t.Errorf("expected an error parsing %q", content)
+		}
+	}
+}
+
+func TestWatchdogPressureWarning(t *testing.T) {
+	threshold := 10.0
+	w, buf := newTestWatchdog(WatchdogConfig{PressureWarnPercent: &threshold}, func(int) (uint64, error) {
+		return 100, nil
+	})
+	avg10 := 12.5
+	w.readPSI = func() (PSIStats, error) {
+		return PSIStats{Some: PSILine{Avg10: avg10}}, nil
+	}
+	var reported []PSIStats
+	w.OnMemoryPressure(func(stats PSIStats) { reported = append(reported, stats) })
+
+	w.check()
+	if !bytes.Contains(buf.Bytes(), []byte("MEMORY PRESSURE")) {
+		t.Errorf("expected pressure warning, got %q", buf.String())
+	}
+	if got := w.MemoryPressure(); got == nil || got.Some.Avg10 != 12.5 {
+		t.Errorf("expected latest PSI avg10 12.5, got %+v", got)
+	}
+
+	avg10 = 2
+	w.check()
+	if !bytes.Contains(buf.Bytes(), []byte("Memory pressure recovered")) {
+		t.Errorf("expected recovery log, got %q", buf.String())
+	}
+	if len(reported) != 2 {
+		t.Errorf("expected 2 samples reported, got %d", len(reported))
+	}
+}
+
+func TestWatchdogPressureDisabledWhenUnavailable(t *testing.T) {
+	threshold := 10.0
+	w, buf := newTestWatchdog(WatchdogConfig{PressureWarnPercent: &threshold}, func(int) (uint64, error) {
+		return 100, nil
+	})
+	calls := 0
+	w.readPSI = func() (PSIStats, error) {
+		calls++
+		return PSIStats{}, errors.New("no memory.pressure")
+	}
+
+	w.check()
+	w.check()
+	if calls != 1 {
+		t.Errorf("expected PSI reads to stop after the first failure, got %d reads", calls)
+	}
+	if !bytes.Contains(buf.Bytes(), []byte("disabling PSI monitoring")) {
+		t.Errorf("expected disable log, got %q", buf.String())
+	}
+	if w.MemoryPressure() != nil {
+		t.Error("expected no PSI sample when unavailable")
+	}
+}
@@ -0,0 +1,198 @@
+package launchlib
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// pythonVersion is a parsed major.minor.patch interpreter version.
+type pythonVersion [3]int
+
+func (v pythonVersion) String() string {
+	return fmt.Sprintf("%d.%d.%d", v[0], v[1], v[2])
+}
+
+// compare returns -1, 0 or 1 depending on whether v is lower than, equal to,
+// or higher than other.
+func (v pythonVersion) compare(other pythonVersion) int {
+	for i := range v {
+		switch {
+		case v[i] < other[i]:
+			return -1
+		case v[i] > other[i]:
+			return 1
+		}
+	}
+	return 0
+}
+
+// versionClause is a single comparison such as ">=3.11".
+type versionClause struct {
+	op      string
+	version pythonVersion
+}
+
+// versionOperators are checked longest-first so ">=" is not mistaken for ">".
+var versionOperators = []string{">=", "<=", "==", "!=", ">", "<"}
+
+// parseVersionConstraint parses a comma-separated constraint like ">=3.11,<3.13".
+func parseVersionConstraint(constraint string) ([]versionClause, error) {
+	var clauses []versionClause
+	for _, part := range strings.Split(constraint, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		op := ""
+		for _, candidate := range versionOperators {
+			if strings.HasPrefix(part, candidate) {
+				op = candidate
+				break
+			}
+		}
+		if op == "" {
+			return nil, fmt.Errorf("invalid version constraint %q: missing comparison operator", part)
+		}
+		version, err := parsePythonVersion(strings.TrimSpace(part[len(op):]))
+		if err != nil {
+			return nil, fmt.Errorf("invalid version constraint %q: %w", part, err)
+		}
+		clauses = append(clauses, versionClause{op: op, version: version})
+	}
+	if len(clauses) == 0 {
+		return nil, fmt.Errorf("empty version constraint")
+	}
+	return clauses, nil
+}
+
+// parsePythonVersion parses "3.11", "3.11.4" or "3.12.0rc1". Missing components
+// are treated as zero and pre-release suffixes are ignored.
+func parsePythonVersion(s string) (pythonVersion, error) {
+	var version pythonVersion
+	parts := strings.Split(s, ".")
+	if len(parts) == 0 || len(parts) > 3 {
+		return version, fmt.Errorf("unparseable version %q", s)
+	}
+	for i, part := range parts {
+		digits := part
+		for j, r := range part {
+			if r < '0' || r > '9' {
+				digits = part[:j]
+				break
+			}
+		}
+		n, err := strconv.Atoi(digits)
+		if err != nil {
+			return version, fmt.Errorf("unparseable version %q", s)
+		}
+		version[i] = n
+	}
+	return version, nil
+}
+
+// CheckPythonVersion reports whether version satisfies every clause in constraint.
+func CheckPythonVersion(version, constraint string) error {
+	clauses, err := parseVersionConstraint(constraint)
+	if err != nil {
+		return err
+	}
+	parsed, err := parsePythonVersion(version)
+	if err != nil {
+		return err
+	}
+	for _, clause := range clauses {
+		cmp := parsed.compare(clause.version)
+		var ok bool
+		switch clause.op {
+		case ">=":
+			ok = cmp >= 0
+		case "<=":
+			ok = cmp <= 0
+		case ">":
+			ok = cmp > 0
+		case "<":
+			ok = cmp < 0
+		case "==":
+			ok = cmp == 0
+		case "!=":
+			ok = cmp != 0
+		}
+		if !ok {
+			return fmt.Errorf("python version %s does not satisfy %s%s (required: %s)",
+				version, clause.op, clause.version, constraint)
+		}
+	}
+	return nil
+}
+
+// ReadPythonVersion runs "<pythonPath> --version" and returns the reported
+// version, e.g. "3.11.4".
+func ReadPythonVersion(pythonPath string) (string, error) {
+	// Python 2 and some builds print the version to stderr.
+	out, err := exec.Command(pythonPath, "--version").CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("failed to run %s --version: %w", pythonPath, err)
+	}
+	fields := strings.Fields(string(out))
+	if len(fields) < 2 || fields[0] != "Python" {
+		return "", fmt.Errorf("unexpected output from %s --version: %q", pythonPath, strings.TrimSpace(string(out)))
+	}
+	return fields[1], nil
+}
+
+// verifyPythonVersion checks the interpreter at pythonPath against constraint.
+func verifyPythonVersion(pythonPath, constraint string) error {
+	version, err := ReadPythonVersion(pythonPath)
+	if err != nil {
+		return err
+	}
+	return CheckPythonVersion(version, constraint)
+}
+
+// maxShebangLength is how much of a file the kernel reads for its "#!" line.
+const maxShebangLength = 256
+
+// ReadShebangInterpreter returns the interpreter named on the "#!" line of
+// the file at path, and any arguments that follow it. For "/usr/bin/env
+// python3" (optionally "env -S ...") the program is looked up on PATH. It
+// fails, naming the interpreter, when that is missing or not executable.
+func ReadShebangInterpreter(path string) (string, []string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", nil, err
+	}
+	defer f.Close()
+
+	line, err := bufio.NewReaderSize(f, maxShebangLength).ReadSlice('\n')
+	if err != nil && len(line) == 0 {
+		return "", nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if !strings.HasPrefix(string(line), "#!") {
+		return "", nil, fmt.Errorf("%s has no #! line", path)
+	}
+	fields := strings.Fields(string(line[2:]))
+	if len(fields) == 0 {
+		return "", nil, fmt.Errorf("%s has an empty #! line", path)
+	}
+
+	interpreter, args := fields[0], fields[1:]
+	if filepath.Base(interpreter) == "env" {
+		if len(args) > 0 && args[0] == "-S" {
+			args = args[1:]
+		}
+		if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+			return "", nil, fmt.Errorf("unsupported #! line in %s: %q", path, strings.TrimSpace(string(line)))
+		}
+		interpreter, args = args[0], args[1:]
+	}
+	resolved, err := exec.LookPath(interpreter)
+	if err != nil {
+		return "", nil, fmt.Errorf("interpreter %s from the #! line of %s not found: %w", interpreter, path, err)
+	}
+	return resolved, args, nil
+}
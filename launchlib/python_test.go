@@ -0,0 +1,129 @@
+package launchlib
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeFakePython creates a stub interpreter that prints the given --version output.
+func writeFakePython(t *testing.T, output string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "python3")
+	script := "#!/bin/sh\necho '" + output + "'\n"
+	if err := os.WriteFile(path, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestReadPythonVersion(t *testing.T) {
+	python := writeFakePython(t, "Python 3.11.4")
+	version, err := ReadPythonVersion(python)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version != "3.11.4" {
+		t.Errorf("expected 3.11.4, got %s", version)
+	}
+}
+
+func TestReadPythonVersionUnexpectedOutput(t *testing.T) {
+	python := writeFakePython(t, "not a python")
+	if _, err := ReadPythonVersion(python); err == nil {
+		t.Error("expected an error for unexpected --version output")
+	}
+}
+
+func TestVerifyPythonVersion(t *testing.T) {
+	python := writeFakePython(t, "Python 3.12.1")
+
+	tests := []struct {
+		constraint string
+		wantErr    bool
+	}{
+		{">=3.11,<3.13", false},
+		{">=3.11", false},
+		{"==3.12.1", false},
+		{"<3.12", true},
+		{">=3.11,<3.12", true},
+		{"!=3.12.1", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.constraint, func(t *testing.T) {
+			err := verifyPythonVersion(python, tt.constraint)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("verifyPythonVersion(%q) error = %v, wantErr %v", tt.constraint, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCheckPythonVersionPreRelease(t *testing.T) {
+	if err := CheckPythonVersion("3.13.0rc1", ">=3.13"); err != nil {
+		t.Errorf("expected pre-release to satisfy >=3.13, got %v", err)
+	}
+}
+
+func TestParseVersionConstraintInvalid(t *testing.T) {
+	for _, constraint := range []string{"3.11", "", ">=three", "~=3.11"} {
+		if _, err := parseVersionConstraint(constraint); err == nil {
+			t.Errorf("expected error for constraint %q", constraint)
+		}
+	}
+}
+
+// writeFakePex creates a non-executable PEX stub carrying the given shebang.
+func writeFakePex(t *testing.T, shebang string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "service.pex")
+	if err := os.WriteFile(path, []byte(shebang+"\nPK\x03\x04"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestReadShebangInterpreter(t *testing.T) {
+	python := writeFakePython(t, "Python 3.11.4")
+	t.Setenv("PATH", filepath.Dir(python))
+
+	tests := []struct {
+		shebang string
+		want    []string
+	}{
+		{"#!" + python, []string{python}},
+		{"#! " + python + " -sE", []string{python, "-sE"}},
+		{"#!/usr/bin/env python3", []string{python}},
+		{"#!/usr/bin/env -S python3 -I", []string{python, "-I"}},
+	}
+	for _, tt := range tests {
+		interpreter, args, err := ReadShebangInterpreter(writeFakePex(t, tt.shebang))
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", tt.shebang, err)
+			continue
+		}
+		if got := append([]string{interpreter}, args...); strings.Join(got, " ") != strings.Join(tt.want, " ") {
+			t.Errorf("%q: expected %v, got %v", tt.shebang, tt.want, got)
+		}
+	}
+}
+
+func TestReadShebangInterpreterErrors(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+	tests := []struct {
+		shebang string
+		wantErr string
+	}{
+		{"#!/opt/missing/python3.11", "interpreter /opt/missing/python3.11 from the #! line"},
+		{"#!/usr/bin/env python3.11", "interpreter python3.11 from the #! line"},
+		{"#!/usr/bin/env -i python3", "unsupported #! line"},
+		{"PK", "has no #! line"},
+	}
+	for _, tt := range tests {
+		_, _, err := ReadShebangInterpreter(writeFakePex(t, tt.shebang))
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%q: expected error containing %q, got %v", tt.shebang, tt.wantErr, err)
+		}
+	}
+}
@@ -2,9 +2,14 @@ package launchlib
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"net"
 	"net/http"
 	"os"
+	"strconv"
+	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
 )
@@ -17,6 +22,17 @@ type ReadinessConfig struct {
 	// HTTPPort is the port for the readiness HTTP endpoint. Default: 8081.
 	HTTPPort int `yaml:"httpPort,omitempty"`
 
+	// BindAddress is the address the readiness endpoint listens on.
+	// Default: "127.0.0.1". Use "0.0.0.0" for probes from outside the host,
+	// such as Kubernetes httpGet probes, or "::" for all IPv4 and IPv6
+	// addresses in a dual-stack cluster. IPv6 literals may be bracketed.
+	BindAddress string `yaml:"bindAddress,omitempty"`
+
+	// BindInterface, if set, binds to an address of the named network
+	// interface (e.g. "eth0") instead of BindAddress: its first IPv4
+	// address, else its first global IPv6 address, else its link-local one.
+	BindInterface string `yaml:"bindInterface,omitempty"`
+
 	// HTTPPath is the path for the readiness endpoint. Default: "/ready".
 	HTTPPath string `yaml:"httpPath,omitempty"`
 
@@ -27,12 +43,72 @@ type ReadinessConfig struct {
 
 	// FilePath, if set, creates a file when ready and removes it during drain.
 	FilePath string `yaml:"filePath,omitempty"`
+
+	// DebugEnabled additionally serves the detected memory limits as JSON on
+	// /debug. Default: false.
+	DebugEnabled bool `yaml:"debugEnabled,omitempty"`
+
+	// SystemdNotify sends READY=1 to $NOTIFY_SOCKET when the service becomes
+	// ready and STOPPING=1 when it drains, for systemd Type=notify units.
+	// Independent of Enabled; a no-op when NOTIFY_SOCKET is unset.
+	SystemdNotify bool `yaml:"systemdNotify,omitempty"`
+
+	// ControlEnabled additionally accepts POST /drain, which marks the service
+	// not ready and starts the drain period immediately, e.g. from a
+	// Kubernetes preStop hook. The drain after the process exits then only
+	// waits out what is left of that period. Default: false.
+	ControlEnabled bool `yaml:"controlEnabled,omitempty"`
+
+	// Warmup, if its URL is set, sends requests to the service once it
+	// accepts connections and only then marks it ready.
+	Warmup WarmupConfig `yaml:"warmup,omitempty"`
+}
+
+// debugPath is where the readiness server exposes DebugInfo.
+const debugPath = "/debug"
+
+// drainPath is where the readiness server accepts drain requests when
+// ControlEnabled is set.
+const drainPath = "/drain"
+
+// livenessPath is where the readiness server reports whether the process is
+// still alive, independent of readiness and drain state.
+const livenessPath = "/healthz"
+
+// DebugInfo describes how the launcher derived its memory limits.
+type DebugInfo struct {
+	CgroupVersion       int    `json:"cgroupVersion"`
+	LimitSource         string `json:"limitSource"`
+	CgroupLimitBytes    uint64 `json:"cgroupLimitBytes"`
+	EffectiveLimitBytes uint64 `json:"effectiveLimitBytes"`
+	SoftWarnBytes       uint64 `json:"softWarnBytes"`
+	HardKillBytes       uint64 `json:"hardKillBytes"`
+
+	// ContainerRuntime is the detected runtime label, e.g. "kubernetes" or
+	// "none".
+	ContainerRuntime string `json:"containerRuntime,omitempty"`
+
+	// MemoryPressure is the latest PSI sample, when PSI monitoring is enabled.
+	MemoryPressure *PSIStats `json:"memoryPressure,omitempty"`
+}
+
+// NewDebugInfo builds the debug report for the given limits.
+func NewDebugInfo(limits MemoryLimits) DebugInfo {
+	return DebugInfo{
+		CgroupVersion:       limits.CgroupVersion,
+		LimitSource:         limits.LimitSource,
+		CgroupLimitBytes:    limits.CgroupLimitBytes,
+		EffectiveLimitBytes: limits.EffectiveLimitBytes,
+		SoftWarnBytes:       limits.SoftWarnBytes,
+		HardKillBytes:       limits.HardKillBytes,
+	}
 }
 
 // DefaultReadinessConfig returns sensible readiness defaults.
 func DefaultReadinessConfig() ReadinessConfig {
 	return ReadinessConfig{
 		HTTPPort:     8081,
+		BindAddress:  "127.0.0.1",
 		HTTPPath:     "/ready",
 		DrainSeconds: 10,
 	}
@@ -43,7 +119,21 @@ type ReadinessProbe struct {
 	config ReadinessConfig
 	logger *Logger
 	ready  atomic.Bool
+	debug  atomic.Pointer[DebugInfo]
+	psi    atomic.Pointer[PSIStats]
 	server *http.Server
+	addr   net.Addr
+
+	// childPid is the process checked by the liveness endpoint; 0 until it
+	// has been started.
+	childPid atomic.Int64
+
+	// drainMu guards drainDeadline, which is set once draining has begun.
+	drainMu       sync.Mutex
+	drainDeadline time.Time
+
+	// notifySocket is $NOTIFY_SOCKET when SystemdNotify is set.
+	notifySocket string
 }
 
 // NewReadinessProbe creates a new readiness probe.
@@ -51,6 +141,9 @@ func NewReadinessProbe(config ReadinessConfig, logger *Logger) *ReadinessProbe {
 	if config.HTTPPort == 0 {
 		config.HTTPPort = 8081
 	}
+	if config.BindAddress == "" {
+		config.BindAddress = "127.0.0.1"
+	}
 	if config.HTTPPath == "" {
 		config.HTTPPath = "/ready"
 	}
@@ -58,36 +151,38 @@ func NewReadinessProbe(config ReadinessConfig, logger *Logger) *ReadinessProbe {
 		config.DrainSeconds = 10
 	}
 	return &ReadinessProbe{
-		config: config,
-		logger: logger,
+		config:       config,
+		logger:       logger,
+		notifySocket: systemdNotifySocket(config.SystemdNotify),
 	}
 }
 
-// Start begins serving the readiness endpoint and marks the service as ready.
-func (p *ReadinessProbe) Start(ctx context.Context) {
+// Start binds the readiness endpoint and serves it until ctx is cancelled.
+// Binding happens before Start returns, so an address already in use is
+// reported as an error rather than logged from the serving goroutine.
+func (p *ReadinessProbe) Start(ctx context.Context) error {
 	if !p.config.Enabled {
-		return
+		return nil
 	}
 
-	mux := http.NewServeMux()
-	mux.HandleFunc(p.config.HTTPPath, func(w http.ResponseWriter, r *http.Request) {
-		if p.ready.Load() {
-			w.WriteHeader(http.StatusOK)
-			fmt.Fprint(w, "OK")
-		} else {
-			w.WriteHeader(http.StatusServiceUnavailable)
-			fmt.Fprint(w, "NOT READY")
+	host := p.config.BindAddress
+	if p.config.BindInterface != "" {
+		var err error
+		host, err = interfaceAddress(p.config.BindInterface, interfaceAddrs)
+		if err != nil {
+			return fmt.Errorf("readiness probe: %w", err)
 		}
-	})
-
-	p.server = &http.Server{
-		Addr:    fmt.Sprintf(":%d", p.config.HTTPPort),
-		Handler: mux,
 	}
+	listener, err := net.Listen("tcp", readinessAddress(host, p.config.HTTPPort))
+	if err != nil {
+		return fmt.Errorf("readiness probe: %w", err)
+	}
+	p.addr = listener.Addr()
+	p.server = &http.Server{Handler: p.handler()}
 
+	p.logger.Printf("Readiness probe listening on %s%s", p.addr, p.config.HTTPPath)
 	go func() {
-		p.logger.Printf("Readiness probe listening on :%d%s", p.config.HTTPPort, p.config.HTTPPath)
-		if err := p.server.ListenAndServe(); err != http.ErrServerClosed {
+		if err := p.server.Serve(listener); err != http.ErrServerClosed {
 			p.logger.Errorf("Readiness probe failed: %v", err)
 		}
 	}()
@@ -99,29 +194,196 @@ func (p *ReadinessProbe) Start(ctx context.Context) {
 		defer cancel()
 		_ = p.server.Shutdown(shutdownCtx)
 	}()
+	return nil
 }
 
-// SetReady marks the service as ready.
+// readinessAddress joins host and port, accepting IPv6 literals with or
+// without brackets.
+func readinessAddress(host string, port int) string {
+	host = strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+	return net.JoinHostPort(host, strconv.Itoa(port))
+}
+
+// interfaceAddrs returns the addresses of the named network interface.
+// It is a variable so tests can fake the host's interfaces.
+var interfaceAddrs = func(name string) ([]net.Addr, error) {
+	iface, err := net.InterfaceByName(name)
+	if err != nil {
+		return nil, err
+	}
+	return iface.Addrs()
+}
+
+// interfaceAddress picks the address of interface name to bind to: the first
+// IPv4 address, else the first global IPv6 address, else the first IPv6
+// link-local address, which is scoped to the interface.
+func interfaceAddress(name string, addrs func(string) ([]net.Addr, error)) (string, error) {
+	list, err := addrs(name)
+	if err != nil {
+		return "", fmt.Errorf("interface %s: %w", name, err)
+	}
+	var global, linkLocal net.IP
+	for _, addr := range list {
+		ipNet, ok := addr.(*net.IPNet)
+		if !ok {
+			continue
+		}
+		ip := ipNet.IP
+		switch {
+		case ip.To4() != nil:
+			return ip.String(), nil
+		case ip.IsLinkLocalUnicast():
+			if linkLocal == nil {
+				linkLocal = ip
+			}
+		case global == nil:
+			global = ip
+		}
+	}
+	switch {
+	case global != nil:
+		return global.String(), nil
+	case linkLocal != nil:
+		return linkLocal.String() + "%" + name, nil
+	}
+	return "", fmt.Errorf("interface %s has no IP address", name)
+}
+
+// Addr returns the address the readiness endpoint is bound to, or nil if it
+// has not been started.
+func (p *ReadinessProbe) Addr() net.Addr {
+	return p.addr
+}
+
+// handler returns the readiness server's routes.
+func (p *ReadinessProbe) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(p.config.HTTPPath, func(w http.ResponseWriter, r *http.Request) {
+		if p.ready.Load() {
+			w.WriteHeader(http.StatusOK)
+			fmt.Fprint(w, "OK")
+		} else {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			fmt.Fprint(w, "NOT READY")
+		}
+	})
+	// A readiness httpPath of /healthz predates the liveness endpoint and
+	// keeps its meaning.
+	if p.config.HTTPPath != livenessPath {
+		mux.HandleFunc(livenessPath, func(w http.ResponseWriter, r *http.Request) {
+			if pid := int(p.childPid.Load()); pid != 0 && !IsProcessAlive(pid) {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				fmt.Fprint(w, "PROCESS EXITED")
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+			fmt.Fprint(w, "OK")
+		})
+	}
+	if p.config.DebugEnabled {
+		mux.HandleFunc(debugPath, func(w http.ResponseWriter, r *http.Request) {
+			var info DebugInfo
+			if current := p.debug.Load(); current != nil {
+				info = *current
+			}
+			info.MemoryPressure = p.psi.Load()
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(info)
+		})
+	}
+	if p.config.ControlEnabled {
+		mux.HandleFunc(drainPath, func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodPost {
+				w.Header().Set("Allow", http.MethodPost)
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			if _, started := p.beginDrain(); started {
+				p.logger.Printf("Drain requested over HTTP, reporting not ready for %ds",
+					p.config.DrainSeconds)
+			}
+			w.WriteHeader(http.StatusAccepted)
+			fmt.Fprint(w, "DRAINING")
+		})
+	}
+	return mux
+}
+
+// SetChildPid sets the process the liveness endpoint checks. Until it is
+// called, the endpoint reports alive as long as the launcher is serving it.
+func (p *ReadinessProbe) SetChildPid(pid int) {
+	p.childPid.Store(int64(pid))
+}
+
+// SetDebugInfo records the memory limit details served on /debug.
+func (p *ReadinessProbe) SetDebugInfo(info DebugInfo) {
+	p.debug.Store(&info)
+}
+
+// ReportMemoryPressure records the latest PSI sample served on /debug.
+func (p *ReadinessProbe) ReportMemoryPressure(stats PSIStats) {
+	p.psi.Store(&stats)
+}
+
+// SetReady marks the service as ready. It does nothing once draining has
+// begun, so a late warmup cannot undo a drain.
 func (p *ReadinessProbe) SetReady() {
+	p.drainMu.Lock()
+	defer p.drainMu.Unlock()
+	if !p.drainDeadline.IsZero() {
+		return
+	}
 	p.ready.Store(true)
 	if p.config.FilePath != "" {
 		if err := os.WriteFile(p.config.FilePath, []byte("ready\n"), 0644); err != nil {
 			p.logger.Warnf("Failed to write readiness file %s: %v", p.config.FilePath, err)
 		}
 	}
+	p.notify(sdNotifyReady)
 	p.logger.Printf("Service marked as ready")
 }
 
-// Drain marks the service as not ready and waits for the drain period.
-func (p *ReadinessProbe) Drain() {
-	if !p.config.Enabled {
-		return
+// notify sends state to systemd when SystemdNotify is enabled.
+func (p *ReadinessProbe) notify(state string) {
+	if err := SdNotify(p.notifySocket, state); err != nil {
+		p.logger.Warnf("Failed to notify systemd (%s) on %s: %v", state, p.notifySocket, err)
 	}
+}
+
+// beginDrain tells systemd the service is stopping, marks it not ready and
+// starts the drain period. It returns the end of the drain period and whether
+// this call started it; later calls return the existing deadline.
+func (p *ReadinessProbe) beginDrain() (time.Time, bool) {
+	p.drainMu.Lock()
+	defer p.drainMu.Unlock()
+	if !p.drainDeadline.IsZero() {
+		return p.drainDeadline, false
+	}
+	p.notify(sdNotifyStopping)
 	p.ready.Store(false)
 	if p.config.FilePath != "" {
 		_ = os.Remove(p.config.FilePath)
 	}
-	drainDuration := time.Duration(p.config.DrainSeconds) * time.Second
-	p.logger.Printf("Draining for %s before shutdown", drainDuration)
-	time.Sleep(drainDuration)
+	p.drainDeadline = time.Now().Add(time.Duration(p.config.DrainSeconds) * time.Second)
+	return p.drainDeadline, true
+}
+
+// Drain tells systemd the service is stopping, then marks it not ready and
+// waits for the drain period. If a drain was already requested over HTTP, it
+// only waits for the rest of that period.
+func (p *ReadinessProbe) Drain() {
+	if !p.config.Enabled {
+		p.notify(sdNotifyStopping)
+		return
+	}
+	deadline, started := p.beginDrain()
+	remaining := time.Until(deadline)
+	if started {
+		p.logger.Printf("Draining for %s before shutdown", remaining.Round(time.Second))
+	} else if remaining > 0 {
+		p.logger.Printf("Drain already in progress, waiting %s before shutdown", remaining.Round(time.Second))
+	}
+	if remaining > 0 {
+		time.Sleep(remaining)
+	}
 }
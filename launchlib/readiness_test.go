@@ -0,0 +1,353 @@
+package launchlib
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReadinessDebugEndpoint(t *testing.T) {
+	limiter := NewMemoryLimiterWithFS(testFS(map[string]string{
+		"sys/fs/cgroup/cgroup.controllers": "cpu memory io",
+		"sys/fs/cgroup/memory.max":         "2147483648",
+	}))
+	config := MergedConfig{
+		Memory:   DefaultMemoryConfig(),
+		Watchdog: DefaultWatchdogConfig(),
+	}
+	limits, err := limiter.ComputeLimits(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	probe := NewReadinessProbe(ReadinessConfig{Enabled: true, DebugEnabled: true}, NewLogger(&bytes.Buffer{}, LoggingConfig{}))
+	probe.SetDebugInfo(NewDebugInfo(limits))
+
+	rec := httptest.NewRecorder()
+	probe.handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+
+	var info DebugInfo
+	if err := json.Unmarshal(rec.Body.Bytes(), &info); err != nil {
+		t.Fatalf("invalid JSON %q: %v", rec.Body.String(), err)
+	}
+	if info.CgroupVersion != 2 {
+		t.Errorf("expected cgroup version 2, got %d", info.CgroupVersion)
+	}
+	if info.LimitSource != LimitSourceCgroupV2 {
+		t.Errorf("expected source %s, got %s", LimitSourceCgroupV2, info.LimitSource)
+	}
+	if info.CgroupLimitBytes != 2147483648 {
+		t.Errorf("expected raw limit 2147483648, got %d", info.CgroupLimitBytes)
+	}
+	if info.EffectiveLimitBytes != limits.EffectiveLimitBytes ||
+		info.SoftWarnBytes != limits.SoftWarnBytes ||
+		info.HardKillBytes != limits.HardKillBytes {
+		t.Errorf("thresholds do not match computed limits: %+v vs %+v", info, limits)
+	}
+}
+
+func TestReadinessDebugEndpointDisabled(t *testing.T) {
+	probe := NewReadinessProbe(ReadinessConfig{Enabled: true}, NewLogger(&bytes.Buffer{}, LoggingConfig{}))
+
+	rec := httptest.NewRecorder()
+	probe.handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 when debug endpoint is disabled, got %d", rec.Code)
+	}
+}
+
+func TestReadinessDebugEndpointMemoryPressure(t *testing.T) {
+	probe := NewReadinessProbe(ReadinessConfig{Enabled: true, DebugEnabled: true}, NewLogger(&bytes.Buffer{}, LoggingConfig{}))
+	probe.ReportMemoryPressure(PSIStats{Some: PSILine{Avg10: 7.5}})
+
+	rec := httptest.NewRecorder()
+	probe.handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug", nil))
+
+	var info DebugInfo
+	if err := json.Unmarshal(rec.Body.Bytes(), &info); err != nil {
+		t.Fatalf("invalid JSON %q: %v", rec.Body.String(), err)
+	}
+	if info.MemoryPressure == nil || info.MemoryPressure.Some.Avg10 != 7.5 {
+		t.Errorf("expected latest PSI in debug output, got %+v", info.MemoryPressure)
+	}
+}
+
+func TestReadinessDrainEndpoint(t *testing.T) {
+	probe := NewReadinessProbe(ReadinessConfig{Enabled: true, ControlEnabled: true}, NewLogger(&bytes.Buffer{}, LoggingConfig{}))
+	probe.SetReady()
+	handler := probe.handler()
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/drain", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected 405 for GET /drain, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/drain", nil))
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("expected 202 for POST /drain, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/ready", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected 503 from /ready after POST /drain, got %d", rec.Code)
+	}
+}
+
+func TestReadinessDrainEndpointDisabled(t *testing.T) {
+	probe := NewReadinessProbe(ReadinessConfig{Enabled: true}, NewLogger(&bytes.Buffer{}, LoggingConfig{}))
+
+	rec := httptest.NewRecorder()
+	probe.handler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/drain", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 when the control endpoint is disabled, got %d", rec.Code)
+	}
+}
+
+func TestReadinessDrainAfterControlDrain(t *testing.T) {
+	var buf bytes.Buffer
+	probe := NewReadinessProbe(ReadinessConfig{Enabled: true, ControlEnabled: true, DrainSeconds: 60}, NewLogger(&buf, LoggingConfig{}))
+	rec := httptest.NewRecorder()
+	probe.handler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/drain", nil))
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("expected 202 for POST /drain, got %d", rec.Code)
+	}
+
+	// Pretend the drain period requested over HTTP has already elapsed.
+	probe.drainMu.Lock()
+	probe.drainDeadline = time.Now()
+	probe.drainMu.Unlock()
+
+	start := time.Now()
+	probe.Drain()
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected Drain to skip the elapsed drain period, took %s", elapsed)
+	}
+	if strings.Contains(buf.String(), "Draining for") {
+		t.Errorf("expected no second drain, got log:\n%s", buf.String())
+	}
+}
+
+// freePort returns a port that was free on 127.0.0.1 a moment ago.
+func freePort(t *testing.T) int {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	return listener.Addr().(*net.TCPAddr).Port
+}
+
+func TestReadinessLivenessEndpoint(t *testing.T) {
+	probe := NewReadinessProbe(ReadinessConfig{Enabled: true}, NewLogger(&bytes.Buffer{}, LoggingConfig{}))
+	handler := probe.handler()
+	get := func() int {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+		return rec.Code
+	}
+
+	if code := get(); code != http.StatusOK {
+		t.Errorf("expected 200 before the process is started, got %d", code)
+	}
+
+	child := exec.Command("sleep", "30")
+	if err := child.Start(); err != nil {
+		t.Fatal(err)
+	}
+	probe.SetChildPid(child.Process.Pid)
+	if code := get(); code != http.StatusOK {
+		t.Errorf("expected 200 while the process is alive, got %d", code)
+	}
+
+	// Liveness ignores drain state: a draining service is still alive.
+	probe.beginDrain()
+	if code := get(); code != http.StatusOK {
+		t.Errorf("expected 200 while draining, got %d", code)
+	}
+
+	_ = child.Process.Kill()
+	_ = child.Wait()
+	if code := get(); code != http.StatusServiceUnavailable {
+		t.Errorf("expected 503 after the process exited, got %d", code)
+	}
+}
+
+func TestReadinessLivenessPathTakenByReadiness(t *testing.T) {
+	probe := NewReadinessProbe(ReadinessConfig{Enabled: true, HTTPPath: "/healthz"}, NewLogger(&bytes.Buffer{}, LoggingConfig{}))
+
+	rec := httptest.NewRecorder()
+	probe.handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if rec.Code != http.StatusServiceUnavailable || rec.Body.String() != "NOT READY" {
+		t.Errorf("expected /healthz to keep serving readiness, got %d %q", rec.Code, rec.Body.String())
+	}
+}
+
+func TestReadinessStartBindsEphemeralPort(t *testing.T) {
+	probe := NewReadinessProbe(ReadinessConfig{Enabled: true, HTTPPort: freePort(t)}, NewLogger(&bytes.Buffer{}, LoggingConfig{}))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := probe.Start(ctx); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+
+	addr := probe.Addr().(*net.TCPAddr)
+	if !addr.IP.IsLoopback() {
+		t.Errorf("expected the default bind address to be loopback, got %s", addr)
+	}
+
+	// Bound synchronously: the endpoint answers as soon as Start returns.
+	resp, err := http.Get("http://" + addr.String() + "/ready")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("expected 503 before SetReady, got %d", resp.StatusCode)
+	}
+	probe.SetReady()
+	resp, err = http.Get("http://" + addr.String() + "/ready")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected 200 after SetReady, got %d", resp.StatusCode)
+	}
+}
+
+func TestReadinessAddress(t *testing.T) {
+	tests := []struct {
+		name string
+		host string
+		want string
+	}{
+		{"ipv4", "10.0.0.5", "10.0.0.5:8081"},
+		{"ipv4 wildcard", "0.0.0.0", "0.0.0.0:8081"},
+		{"ipv6", "::1", "[::1]:8081"},
+		{"bracketed ipv6", "[2001:db8::1]", "[2001:db8::1]:8081"},
+		{"ipv6 wildcard", "::", "[::]:8081"},
+		{"ipv6 with zone", "fe80::1%eth0", "[fe80::1%eth0]:8081"},
+		{"all interfaces", "", ":8081"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := readinessAddress(tt.host, 8081); got != tt.want {
+				t.Errorf("expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestInterfaceAddress(t *testing.T) {
+	ipNet := func(s string) net.Addr { return &net.IPNet{IP: net.ParseIP(s)} }
+	tests := []struct {
+		name    string
+		addrs   []net.Addr
+		want    string
+		wantErr bool
+	}{
+		{"ipv4 first", []net.Addr{ipNet("fe80::1"), ipNet("2001:db8::5"), ipNet("10.0.0.5")}, "10.0.0.5", false},
+		{"global ipv6 over link-local", []net.Addr{ipNet("fe80::1"), ipNet("2001:db8::5")}, "2001:db8::5", false},
+		{"link-local gets a zone", []net.Addr{ipNet("fe80::1")}, "fe80::1%eth0", false},
+		{"no addresses", nil, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := interfaceAddress("eth0", func(string) ([]net.Addr, error) { return tt.addrs, nil })
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %t, got %v", tt.wantErr, err)
+			}
+			if got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestReadinessStartIPv6(t *testing.T) {
+	probe := NewReadinessProbe(ReadinessConfig{Enabled: true, BindAddress: "[::1]"}, NewLogger(&bytes.Buffer{}, LoggingConfig{}))
+	listener, err := net.Listen("tcp", "[::1]:0")
+	if err != nil {
+		t.Skipf("IPv6 loopback unavailable: %v", err)
+	}
+	probe.config.HTTPPort = listener.Addr().(*net.TCPAddr).Port
+	listener.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := probe.Start(ctx); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	if addr := probe.Addr().(*net.TCPAddr); !addr.IP.Equal(net.IPv6loopback) {
+		t.Errorf("expected to bind ::1, got %s", addr)
+	}
+}
+
+func TestReadinessStartBindInterface(t *testing.T) {
+	original := interfaceAddrs
+	defer func() { interfaceAddrs = original }()
+	interfaceAddrs = func(name string) ([]net.Addr, error) {
+		if name != "lo-test" {
+			return nil, fmt.Errorf("no such interface")
+		}
+		return []net.Addr{&net.IPNet{IP: net.ParseIP("127.0.0.1")}}, nil
+	}
+
+	probe := NewReadinessProbe(ReadinessConfig{Enabled: true, BindInterface: "lo-test", HTTPPort: freePort(t)},
+		NewLogger(&bytes.Buffer{}, LoggingConfig{}))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := probe.Start(ctx); err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+	if addr := probe.Addr().(*net.TCPAddr); !addr.IP.Equal(net.ParseIP("127.0.0.1")) {
+		t.Errorf("expected to bind the interface address 127.0.0.1, got %s", addr)
+	}
+
+	probe = NewReadinessProbe(ReadinessConfig{Enabled: true, BindInterface: "missing0"}, NewLogger(&bytes.Buffer{}, LoggingConfig{}))
+	if err := probe.Start(ctx); err == nil {
+		t.Error("expected an error for an unknown interface")
+	}
+}
+
+func TestReadinessStartConflictingBind(t *testing.T) {
+	occupied, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer occupied.Close()
+	port := occupied.Addr().(*net.TCPAddr).Port
+
+	probe := NewReadinessProbe(ReadinessConfig{Enabled: true, HTTPPort: port}, NewLogger(&bytes.Buffer{}, LoggingConfig{}))
+	if err := probe.Start(context.Background()); err == nil {
+		t.Fatal("expected an error binding an occupied port")
+	}
+	if probe.Addr() != nil {
+		t.Errorf("expected no address after a failed bind, got %s", probe.Addr())
+	}
+}
+
+func TestReadinessStartDisabled(t *testing.T) {
+	probe := NewReadinessProbe(ReadinessConfig{}, NewLogger(&bytes.Buffer{}, LoggingConfig{}))
+	if err := probe.Start(context.Background()); err != nil {
+		t.Errorf("expected a disabled probe to start without binding, got %v", err)
+	}
+	if probe.Addr() != nil {
+		t.Errorf("expected no address for a disabled probe, got %s", probe.Addr())
+	}
+}
@@ -0,0 +1,137 @@
+package launchlib
+
+import (
+	"os"
+	"os/exec"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+// ChildReaper reaps orphaned descendants that are reparented to the launcher,
+// as an init process must when the launcher is PID 1 in a container.
+//
+// It does not wait4(-1): that would also collect the exit status of the
+// primary process and subprocesses from under their exec.Cmd.Wait. Instead,
+// on each SIGCHLD it looks for zombie children of the launcher and reaps only
+// those it did not start through Start.
+type ChildReaper struct {
+	logger *Logger
+	sigs   chan os.Signal
+	done   chan struct{}
+
+	// mu is held while a managed process starts, so its pid is recorded
+	// before a sweep can see it exit.
+	mu      sync.Mutex
+	managed map[int]bool
+
+	// subreaper is set when the reaper made the launcher a child subreaper
+	// and must undo it on Stop.
+	subreaper bool
+}
+
+// reapChildrenEnabled reports whether orphan reaping is on: the configured
+// value, or true when the launcher is PID 1.
+func reapChildrenEnabled(configured *bool) bool {
+	if configured != nil {
+		return *configured
+	}
+	return os.Getpid() == 1
+}
+
+// StartChildReaper installs a SIGCHLD handler that reaps orphans. When the
+// launcher is not PID 1 it is made a child subreaper where supported, so
+// orphaned descendants are reparented to it rather than to init.
+func StartChildReaper(logger *Logger) *ChildReaper {
+	r := &ChildReaper{
+		logger:  logger,
+		sigs:    make(chan os.Signal, 1),
+		done:    make(chan struct{}),
+		managed: make(map[int]bool),
+	}
+	if os.Getpid() != 1 {
+		if err := setChildSubreaper(true); err != nil {
+			logger.Warnf("Cannot become a child subreaper: %v (only orphans of PID 1 are reaped)", err)
+		} else {
+			r.subreaper = true
+		}
+	}
+
+	signal.Notify(r.sigs, syscall.SIGCHLD)
+	go func() {
+		defer close(r.done)
+		for range r.sigs {
+			r.sweep()
+		}
+	}()
+	return r
+}
+
+// Start starts cmd with the given umask and records it as managed, so the
+// reaper leaves its exit status to cmd.Wait. On a nil reaper it only starts
+// cmd.
+func (r *ChildReaper) Start(cmd *exec.Cmd, umask int) error {
+	if r == nil {
+		return StartWithUmask(cmd, umask)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := StartWithUmask(cmd, umask); err != nil {
+		return err
+	}
+	r.managed[cmd.Process.Pid] = true
+	return nil
+}
+
+// Stop removes the SIGCHLD handler, reaps any remaining orphans and restores
+// the launcher's subreaper setting. Stop on a nil reaper does nothing.
+func (r *ChildReaper) Stop() {
+	if r == nil {
+		return
+	}
+	signal.Stop(r.sigs)
+	close(r.sigs)
+	<-r.done
+	r.sweep()
+	if r.subreaper {
+		if err := setChildSubreaper(false); err != nil {
+			r.logger.Warnf("Failed to clear child subreaper: %v", err)
+		}
+	}
+}
+
+// sweep reaps every zombie child of the launcher that it did not start.
+func (r *ChildReaper) sweep() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, pid := range zombieChildren(os.Getpid()) {
+		if r.managed[pid] {
+			continue
+		}
+		var status syscall.WaitStatus
+		if reaped, err := syscall.Wait4(pid, &status, syscall.WNOHANG, nil); err == nil && reaped == pid {
+			r.logger.Printf("Reaped orphaned process: pid=%d status=%d", pid, status.ExitStatus())
+		}
+	}
+}
+
+// parseProcStat returns the state and parent pid from /proc/[pid]/stat. The
+// command name is parenthesized and may itself contain spaces or ")", so
+// fields are read after the last ")".
+func parseProcStat(data string) (state string, ppid int, ok bool) {
+	end := strings.LastIndexByte(data, ')')
+	if end < 0 {
+		return "", 0, false
+	}
+	fields := strings.Fields(data[end+1:])
+	if len(fields) < 2 {
+		return "", 0, false
+	}
+	ppid, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return "", 0, false
+	}
+	return fields[0], ppid, true
+}
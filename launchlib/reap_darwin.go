@@ -0,0 +1,13 @@
+package launchlib
+
+import "errors"
+
+// setChildSubreaper is Linux-only.
+func setChildSubreaper(bool) error {
+	return errors.New("child subreaper is not supported on darwin")
+}
+
+// zombieChildren needs /proc, so no orphans are found on darwin.
+func zombieChildren(int) []int {
+	return nil
+}
@@ -0,0 +1,49 @@
+package launchlib
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"syscall"
+)
+
+// prSetChildSubreaper is PR_SET_CHILD_SUBREAPER from <linux/prctl.h>.
+const prSetChildSubreaper = 36
+
+// setChildSubreaper marks the launcher as a child subreaper, so orphaned
+// descendants are reparented to it instead of to PID 1.
+func setChildSubreaper(enabled bool) error {
+	var arg uintptr
+	if enabled {
+		arg = 1
+	}
+	if _, _, errno := syscall.RawSyscall(syscall.SYS_PRCTL, prSetChildSubreaper, arg, 0); errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// zombieChildren returns the pids of parent's children that have exited but
+// not been reaped, read from /proc/[pid]/stat.
+func zombieChildren(parent int) []int {
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return nil
+	}
+	var pids []int
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+		data, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+		if err != nil {
+			continue
+		}
+		state, ppid, ok := parseProcStat(string(data))
+		if ok && state == "Z" && ppid == parent {
+			pids = append(pids, pid)
+		}
+	}
+	return pids
+}
@@ -0,0 +1,38 @@
+package launchlib
+
+import "testing"
+
+func TestParseProcStat(t *testing.T) {
+	tests := []struct {
+		name      string
+		data      string
+		wantState string
+		wantPpid  int
+		wantOK    bool
+	}{
+		{"zombie", "1234 (sleep) Z 1 1234 1234 0 -1 4227076", "Z", 1, true},
+		{"running", "42 (python3) S 7 42 42 0 -1", "S", 7, true},
+		{"name with spaces and parens", "99 (my (odd) proc) Z 12 99 99", "Z", 12, true},
+		{"truncated", "99 (sleep) Z", "", 0, false},
+		{"garbage", "not a stat line", "", 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state, ppid, ok := parseProcStat(tt.data)
+			if state != tt.wantState || ppid != tt.wantPpid || ok != tt.wantOK {
+				t.Errorf("expected (%q, %d, %v), got (%q, %d, %v)", tt.wantState, tt.wantPpid, tt.wantOK, state, ppid, ok)
+			}
+		})
+	}
+}
+
+func TestReapChildrenEnabled(t *testing.T) {
+	yes, no := true, false
+	if !reapChildrenEnabled(&yes) || reapChildrenEnabled(&no) {
+		t.Error("expected an explicit setting to win")
+	}
+	// The test binary is never PID 1.
+	if reapChildrenEnabled(nil) {
+		t.Error("expected reaping to default off when not PID 1")
+	}
+}
@@ -0,0 +1,13 @@
+package launchlib
+
+// readProcessRSS is unsupported on macOS: there is no /proc, and task_info
+// requires cgo, which release builds disable. The watchdog detects this at
+// startup and disables memory protection with a warning.
+func readProcessRSS(pid int) (uint64, error) {
+	return 0, ErrRSSUnsupported
+}
+
+// readProcessAnonRSS is unsupported on macOS for the same reason.
+func readProcessAnonRSS(pid int) (uint64, error) {
+	return 0, ErrRSSUnsupported
+}
@@ -0,0 +1,42 @@
+package launchlib
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// readProcessRSS reads the RSS of a process from /proc/[pid]/statm.
+// The second field of statm is RSS in pages.
+func readProcessRSS(pid int) (uint64, error) {
+	path := fmt.Sprintf("/proc/%d/statm", pid)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	fields := strings.Fields(string(data))
+	if len(fields) < 2 {
+		return 0, fmt.Errorf("unexpected statm format: %q", string(data))
+	}
+
+	rssPages, err := strconv.ParseUint(fields[1], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse RSS pages: %w", err)
+	}
+
+	pageSize := uint64(os.Getpagesize())
+	return rssPages * pageSize, nil
+}
+
+// readProcessAnonRSS reads the anonymous (non file-backed) RSS of a process
+// from /proc/[pid]/smaps_rollup, available since Linux 4.14.
+func readProcessAnonRSS(pid int) (uint64, error) {
+	path := fmt.Sprintf("/proc/%d/smaps_rollup", pid)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return parseSmapsRollupAnonymous(string(data))
+}
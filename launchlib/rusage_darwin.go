@@ -0,0 +1,4 @@
+package launchlib
+
+// maxrssUnitBytes converts rusage.Maxrss to bytes. macOS reports it in bytes.
+const maxrssUnitBytes = 1
@@ -0,0 +1,4 @@
+package launchlib
+
+// maxrssUnitBytes converts rusage.Maxrss to bytes. Linux reports it in kilobytes.
+const maxrssUnitBytes = 1024
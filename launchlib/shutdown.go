@@ -0,0 +1,141 @@
+package launchlib
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// SignalStep is one step of a shutdown sequence: send Signal, then wait up to
+// WaitSeconds for the process to exit before moving to the next step.
+type SignalStep struct {
+	Signal      string `yaml:"signal"`
+	WaitSeconds int    `yaml:"waitSeconds,omitempty"`
+}
+
+// shutdownStep is a SignalStep with its signal and wait resolved.
+type shutdownStep struct {
+	signal syscall.Signal
+	wait   time.Duration
+}
+
+// resolveShutdownSequence returns the configured steps, or SIGTERM, grace,
+// SIGKILL when none are configured. Steps are validated with the static
+// config, so unparseable signals are skipped.
+func resolveShutdownSequence(steps []SignalStep, grace time.Duration) []shutdownStep {
+	if len(steps) == 0 {
+		return []shutdownStep{{signal: syscall.SIGTERM, wait: grace}, {signal: syscall.SIGKILL}}
+	}
+	resolved := make([]shutdownStep, 0, len(steps))
+	for _, step := range steps {
+		sig, err := ParseSignal(step.Signal)
+		if err != nil {
+			continue
+		}
+		resolved = append(resolved, shutdownStep{signal: sig, wait: time.Duration(step.WaitSeconds) * time.Second})
+	}
+	return resolved
+}
+
+// validateShutdownSequence checks each step's signal and wait.
+func validateShutdownSequence(steps []SignalStep) error {
+	for i, step := range steps {
+		if _, err := ParseSignal(step.Signal); err != nil {
+			return invalidField(fmt.Sprintf("shutdownSequence[%d].signal", i), step.Signal, "%v", err)
+		}
+		if step.WaitSeconds < 0 {
+			return invalidField(fmt.Sprintf("shutdownSequence[%d].waitSeconds", i), step.WaitSeconds, "must not be negative")
+		}
+	}
+	return nil
+}
+
+// ShutdownSequenceWarnings flags a shutdown sequence that does not end in
+// SIGKILL, which can leave the launcher waiting on a process that ignores
+// every signal it is sent.
+func ShutdownSequenceWarnings(steps []SignalStep) []string {
+	if len(steps) == 0 {
+		return nil
+	}
+	last := steps[len(steps)-1]
+	if sig, err := ParseSignal(last.Signal); err == nil && sig == syscall.SIGKILL {
+		return nil
+	}
+	return []string{fmt.Sprintf(
+		"shutdownSequence ends with %s, not SIGKILL; a process that survives it is waited on indefinitely", last.Signal)}
+}
+
+// terminateAndWait runs the shutdown sequence against the process until it
+// exits, returning its wait result. After the last step it waits for the
+// process without a timeout.
+func terminateAndWait(process *os.Process, waitDone <-chan error, steps []shutdownStep, logger *Logger) error {
+	for i, step := range steps {
+		if err := process.Signal(step.signal); err != nil {
+			logger.Printf("WARNING: failed to send %s to pid %d: %v", signalName(step.signal), process.Pid, err)
+		}
+		if i == len(steps)-1 {
+			break
+		}
+		timer := time.NewTimer(step.wait)
+		select {
+		case err := <-waitDone:
+			timer.Stop()
+			return err
+		case <-timer.C:
+			logger.Printf("Process still running %s after %s, sending %s to pid %d",
+				step.wait, signalName(step.signal), signalName(steps[i+1].signal), process.Pid)
+		}
+	}
+	return <-waitDone
+}
+
+// shutdownNotifyFileEnv points the process at the shutdown notice file.
+const shutdownNotifyFileEnv = "LAUNCHER_SHUTDOWN_NOTIFY_FILE"
+
+// shutdownDeadline is when the last step of steps will be sent if the process
+// has not exited by then.
+func shutdownDeadline(steps []shutdownStep, now time.Time) time.Time {
+	deadline := now
+	for i := 0; i < len(steps)-1; i++ {
+		deadline = deadline.Add(steps[i].wait)
+	}
+	return deadline
+}
+
+// WriteShutdownNotice writes the shutdown notice to path as KEY=VALUE lines:
+//
+//	SHUTDOWN_DEADLINE=2025-01-02T15:04:35Z
+//	SHUTDOWN_DEADLINE_UNIX=1735830275
+//	SHUTDOWN_GRACE_SECONDS=30
+//
+// It is written to a temporary file and renamed into place, so a process
+// polling for it never reads a partial notice.
+func WriteShutdownNotice(path string, now, deadline time.Time) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "SHUTDOWN_DEADLINE=%s\n", deadline.UTC().Format(time.RFC3339))
+	fmt.Fprintf(&b, "SHUTDOWN_DEADLINE_UNIX=%d\n", deadline.Unix())
+	fmt.Fprintf(&b, "SHUTDOWN_GRACE_SECONDS=%d\n", int(deadline.Sub(now).Seconds()))
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(b.String()), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// appendShutdownNotifyEnv points shutdownNotifyFileEnv at path unless env
+// already sets it.
+func appendShutdownNotifyEnv(env []string, path string) []string {
+	for _, e := range env {
+		if strings.SplitN(e, "=", 2)[0] == shutdownNotifyFileEnv {
+			return env
+		}
+	}
+	return append(env, shutdownNotifyFileEnv+"="+path)
+}
@@ -0,0 +1,106 @@
+package launchlib
+
+import (
+	"bytes"
+	"errors"
+	"os/exec"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// startTermIgnoringChild starts a process that ignores SIGTERM but dies on
+// SIGQUIT. An ignored signal stays ignored across exec.
+func startTermIgnoringChild(t *testing.T) (*exec.Cmd, <-chan error) {
+	t.Helper()
+	child := exec.Command("/bin/sh", "-c", "trap '' TERM; exec sleep 30")
+	if err := child.Start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = child.Process.Kill() })
+	// Give the shell time to install the trap before it is signalled.
+	time.Sleep(100 * time.Millisecond)
+	waitDone := make(chan error, 1)
+	go func() { waitDone <- child.Wait() }()
+	return child, waitDone
+}
+
+// signaledBy returns the signal that terminated the process, or 0.
+func signaledBy(err error) syscall.Signal {
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		return 0
+	}
+	status, ok := exitErr.Sys().(syscall.WaitStatus)
+	if !ok || !status.Signaled() {
+		return 0
+	}
+	return status.Signal()
+}
+
+func TestTerminateAndWaitShutdownSequence(t *testing.T) {
+	child, waitDone := startTermIgnoringChild(t)
+	var buf bytes.Buffer
+	steps := resolveShutdownSequence([]SignalStep{
+		{Signal: "SIGTERM", WaitSeconds: 1},
+		{Signal: "QUIT", WaitSeconds: 10},
+		{Signal: "SIGKILL"},
+	}, 0)
+
+	start := time.Now()
+	err := terminateAndWait(child.Process, waitDone, steps, NewLogger(&buf, LoggingConfig{}))
+	if sig := signaledBy(err); sig != syscall.SIGQUIT {
+		t.Fatalf("expected the process to die on SIGQUIT, got %v (%v)\n%s", sig, err, buf.String())
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("expected SIGKILL not to be reached, took %s", elapsed)
+	}
+	if !strings.Contains(buf.String(), "after SIGTERM, sending SIGQUIT") {
+		t.Errorf("expected the escalation to SIGQUIT to be logged, got:\n%s", buf.String())
+	}
+}
+
+func TestResolveShutdownSequenceDefault(t *testing.T) {
+	steps := resolveShutdownSequence(nil, 30*time.Second)
+	if len(steps) != 2 || steps[0] != (shutdownStep{syscall.SIGTERM, 30 * time.Second}) || steps[1].signal != syscall.SIGKILL {
+		t.Errorf("expected SIGTERM, 30s, SIGKILL, got %+v", steps)
+	}
+}
+
+func TestValidateShutdownSequence(t *testing.T) {
+	var validationErr *ConfigValidationError
+	err := validateShutdownSequence([]SignalStep{{Signal: "SIGTERM"}, {Signal: "SIGBOGUS"}})
+	if !errors.As(err, &validationErr) || validationErr.Field != "shutdownSequence[1].signal" {
+		t.Errorf("expected an invalid shutdownSequence[1].signal, got %v", err)
+	}
+	err = validateShutdownSequence([]SignalStep{{Signal: "SIGTERM", WaitSeconds: -1}})
+	if !errors.As(err, &validationErr) || validationErr.Field != "shutdownSequence[0].waitSeconds" {
+		t.Errorf("expected an invalid shutdownSequence[0].waitSeconds, got %v", err)
+	}
+
+	if warnings := ShutdownSequenceWarnings([]SignalStep{{Signal: "SIGTERM"}, {Signal: "KILL"}}); len(warnings) != 0 {
+		t.Errorf("expected no warning for a sequence ending in SIGKILL, got %v", warnings)
+	}
+	if warnings := ShutdownSequenceWarnings([]SignalStep{{Signal: "SIGTERM"}, {Signal: "SIGQUIT"}}); len(warnings) != 1 {
+		t.Errorf("expected a warning for a sequence not ending in SIGKILL, got %v", warnings)
+	}
+	if warnings := ShutdownSequenceWarnings(nil); len(warnings) != 0 {
+		t.Errorf("expected no warning for the default sequence, got %v", warnings)
+	}
+}
+
+func TestShutdownDeadline(t *testing.T) {
+	now := time.Unix(1000, 0)
+	steps := []shutdownStep{
+		{signal: syscall.SIGINT, wait: 5 * time.Second},
+		{signal: syscall.SIGTERM, wait: 10 * time.Second},
+		{signal: syscall.SIGKILL, wait: time.Hour},
+	}
+	if got := shutdownDeadline(steps, now); !got.Equal(now.Add(15 * time.Second)) {
+		t.Errorf("expected the deadline 15s out, got %s", got.Sub(now))
+	}
+	if got := shutdownDeadline(resolveShutdownSequence(nil, 30*time.Second), now); !got.Equal(now.Add(30 * time.Second)) {
+		t.Errorf("expected the default deadline after the grace period, got %s", got.Sub(now))
+	}
+}
@@ -0,0 +1,70 @@
+package launchlib
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// listenFDsStart is the first file descriptor passed by the systemd socket
+// activation protocol. It is a variable so tests can pass their own sockets.
+var listenFDsStart = 3
+
+// socketActivationShell sets LISTEN_PID to the shell's own pid and then execs
+// the real command, which keeps that pid. The command and its arguments are
+// passed as positional parameters, never interpolated into the script.
+const socketActivationShell = `LISTEN_PID=$$; export LISTEN_PID; exec "$@"`
+
+// InheritedListenFDs returns the sockets passed to the launcher under the
+// LISTEN_FDS/LISTEN_PID convention, or nil if none were passed to this process.
+func InheritedListenFDs() ([]*os.File, error) {
+	fdsValue := os.Getenv("LISTEN_FDS")
+	if fdsValue == "" {
+		return nil, nil
+	}
+	if pid := os.Getenv("LISTEN_PID"); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		// The sockets were meant for another process.
+		return nil, nil
+	}
+	count, err := strconv.Atoi(fdsValue)
+	if err != nil || count < 0 {
+		return nil, fmt.Errorf("invalid LISTEN_FDS %q", fdsValue)
+	}
+
+	names := strings.Split(os.Getenv("LISTEN_FDNAMES"), ":")
+	files := make([]*os.File, count)
+	for i := range files {
+		name := "listen-fd-" + strconv.Itoa(i)
+		if i < len(names) && names[i] != "" {
+			name = names[i]
+		}
+		files[i] = os.NewFile(uintptr(listenFDsStart+i), name)
+	}
+	return files, nil
+}
+
+// socketActivationArgs wraps cmdArgs so the child sees LISTEN_PID equal to its own pid.
+func socketActivationArgs(cmdArgs []string) []string {
+	return append([]string{"/bin/sh", "-c", socketActivationShell, cmdArgs[0]}, cmdArgs...)
+}
+
+// socketActivationEnv returns env with LISTEN_FDS and LISTEN_FDNAMES passed
+// through and LISTEN_PID removed (the wrapper sets it once the pid is known).
+// They are set explicitly so an envInherit policy cannot drop them.
+func socketActivationEnv(env []string, count int) []string {
+	result := make([]string, 0, len(env)+2)
+	for _, entry := range env {
+		name, _, _ := strings.Cut(entry, "=")
+		switch name {
+		case "LISTEN_PID", "LISTEN_FDS", "LISTEN_FDNAMES":
+			continue
+		}
+		result = append(result, entry)
+	}
+	result = append(result, "LISTEN_FDS="+strconv.Itoa(count))
+	if names := os.Getenv("LISTEN_FDNAMES"); names != "" {
+		result = append(result, "LISTEN_FDNAMES="+names)
+	}
+	return result
+}
@@ -0,0 +1,153 @@
+package launchlib
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os/exec"
+	"time"
+)
+
+// StartupProbeConfig checks that the process started at all. Unlike the
+// readiness probe, a startup probe that never passes fails the launch: the
+// process is terminated and Launch returns an error. Exactly one of Command,
+// TCP and HTTP may be set; the probe is disabled when none is.
+type StartupProbeConfig struct {
+	// Command is run from the distribution root; exit status 0 passes.
+	Command []string `yaml:"command,omitempty"`
+
+	// TCP is a host:port that passes once it accepts a connection.
+	TCP string `yaml:"tcp,omitempty"`
+
+	// HTTP is a URL that passes once a GET returns a 2xx or 3xx status.
+	HTTP string `yaml:"http,omitempty"`
+
+	// TimeoutSeconds is how long the probe may keep failing before the
+	// launch is failed. Default: 60.
+	TimeoutSeconds int `yaml:"timeoutSeconds,omitempty"`
+
+	// IntervalMs is the delay between attempts. Default: 500.
+	IntervalMs int `yaml:"intervalMs,omitempty"`
+}
+
+// Enabled reports whether a probe target is configured.
+func (c StartupProbeConfig) Enabled() bool {
+	return len(c.Command) > 0 || c.TCP != "" || c.HTTP != ""
+}
+
+// ErrStartupProbeFailed is returned when the startup probe does not pass
+// within its timeout.
+var ErrStartupProbeFailed = errors.New("startup probe failed")
+
+// validateStartupProbe checks that at most one probe target is set.
+func validateStartupProbe(config StartupProbeConfig) error {
+	targets := 0
+	for _, set := range []bool{len(config.Command) > 0, config.TCP != "", config.HTTP != ""} {
+		if set {
+			targets++
+		}
+	}
+	if targets > 1 {
+		return invalidField("startup", "", "set only one of command, tcp and http")
+	}
+	if config.TCP != "" {
+		if _, _, err := net.SplitHostPort(config.TCP); err != nil {
+			return invalidField("startup.tcp", config.TCP, "expected host:port")
+		}
+	}
+	if config.HTTP != "" {
+		if u, err := url.Parse(config.HTTP); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return invalidField("startup.http", config.HTTP, "expected an http or https URL")
+		}
+	}
+	if config.TimeoutSeconds < 0 {
+		return invalidField("startup.timeoutSeconds", config.TimeoutSeconds, "must not be negative")
+	}
+	if config.IntervalMs < 0 {
+		return invalidField("startup.intervalMs", config.IntervalMs, "must not be negative")
+	}
+	return nil
+}
+
+// RunStartupProbe retries the configured check until it passes, returning
+// nil, or until the timeout, returning ErrStartupProbeFailed with the last
+// failure. It also returns nil, without waiting further, once exited is
+// closed or ctx is cancelled, leaving the caller to handle the exit. Probe
+// commands run in dir and are started through reaper (which may be nil) so
+// it does not collect their exit status.
+func RunStartupProbe(ctx context.Context, config StartupProbeConfig, dir string, reaper *ChildReaper,
+	exited <-chan struct{}, logger *Logger) error {
+	timeout := time.Duration(config.TimeoutSeconds) * time.Second
+	if timeout == 0 {
+		timeout = 60 * time.Second
+	}
+	interval := time.Duration(config.IntervalMs) * time.Millisecond
+	if interval == 0 {
+		interval = 500 * time.Millisecond
+	}
+
+	deadline := time.Now().Add(timeout)
+	probeCtx, cancel := context.WithDeadline(ctx, deadline)
+	defer cancel()
+
+	attempts := 0
+	for {
+		attempts++
+		err := checkStartup(probeCtx, config, dir, reaper)
+		if err == nil {
+			logger.Printf("Startup probe passed after %d attempt(s)", attempts)
+			return nil
+		}
+		select {
+		case <-exited:
+			logger.Warnf("Process exited before the startup probe passed")
+			return nil
+		case <-ctx.Done():
+			return nil
+		case <-probeCtx.Done():
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("%w: no success within %s (%d attempts, last error: %v)",
+				ErrStartupProbeFailed, timeout, attempts, err)
+		case <-time.After(interval):
+		}
+	}
+}
+
+// checkStartup makes a single probe attempt.
+func checkStartup(ctx context.Context, config StartupProbeConfig, dir string, reaper *ChildReaper) error {
+	switch {
+	case len(config.Command) > 0:
+		cmd := exec.CommandContext(ctx, config.Command[0], config.Command[1:]...)
+		cmd.Dir = dir
+		if err := reaper.Start(cmd, -1); err != nil {
+			return err
+		}
+		return cmd.Wait()
+	case config.TCP != "":
+		var dialer net.Dialer
+		conn, err := dialer.DialContext(ctx, "tcp", config.TCP)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	default:
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, config.HTTP, nil)
+		if err != nil {
+			return err
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= 400 {
+			return fmt.Errorf("status %s", resp.Status)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,76 @@
+package launchlib
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRunStartupProbePasses(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	tests := []struct {
+		name   string
+		config StartupProbeConfig
+	}{
+		{"command", StartupProbeConfig{Command: []string{"/bin/sh", "-c", "exit 0"}}},
+		{"tcp", StartupProbeConfig{TCP: listener.Addr().String()}},
+		{"http", StartupProbeConfig{HTTP: server.URL}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := RunStartupProbe(context.Background(), tt.config, t.TempDir(), nil, nil, NewLogger(&bytes.Buffer{}, LoggingConfig{}))
+			if err != nil {
+				t.Errorf("expected the probe to pass, got %v", err)
+			}
+		})
+	}
+}
+
+func TestRunStartupProbeRetriesUntilPass(t *testing.T) {
+	dir := t.TempDir()
+	config := StartupProbeConfig{
+		// Passes on the third attempt.
+		Command:    []string{"/bin/sh", "-c", "echo x >> attempts; [ $(wc -l < attempts) -ge 3 ]"},
+		IntervalMs: 10,
+	}
+	var buf bytes.Buffer
+	if err := RunStartupProbe(context.Background(), config, dir, nil, nil, NewLogger(&buf, LoggingConfig{})); err != nil {
+		t.Fatalf("expected the probe to pass, got %v", err)
+	}
+	if !bytes.Contains(buf.Bytes(), []byte("after 3 attempt(s)")) {
+		t.Errorf("expected 3 attempts, got %q", buf.String())
+	}
+}
+
+func TestRunStartupProbeTimeout(t *testing.T) {
+	config := StartupProbeConfig{Command: []string{"/bin/sh", "-c", "exit 1"}, TimeoutSeconds: 1, IntervalMs: 50}
+	start := time.Now()
+	err := RunStartupProbe(context.Background(), config, t.TempDir(), nil, nil, NewLogger(&bytes.Buffer{}, LoggingConfig{}))
+	if !errors.Is(err, ErrStartupProbeFailed) {
+		t.Fatalf("expected ErrStartupProbeFailed, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < time.Second || elapsed > 5*time.Second {
+		t.Errorf("expected to give up after about 1s, took %s", elapsed)
+	}
+}
+
+func TestRunStartupProbeProcessExited(t *testing.T) {
+	exited := make(chan struct{})
+	close(exited)
+	config := StartupProbeConfig{TCP: "127.0.0.1:1", TimeoutSeconds: 30}
+	if err := RunStartupProbe(context.Background(), config, t.TempDir(), nil, exited, NewLogger(&bytes.Buffer{}, LoggingConfig{})); err != nil {
+		t.Errorf("expected an exited process to be left to the caller, got %v", err)
+	}
+}
@@ -0,0 +1,109 @@
+package launchlib
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"syscall"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Output formats accepted by FormatStatus.
+const (
+	OutputText = "text"
+	OutputJSON = "json"
+	OutputYAML = "yaml"
+)
+
+// Reasons reported in ServiceStatus.Reason.
+const (
+	StatusReasonPidFileDisabled = "pid file disabled"
+	StatusReasonNoPidFile       = "no pid file"
+	StatusReasonStalePidFile    = "stale pid file"
+	StatusReasonStopped         = "stopped"
+	StatusReasonKilled          = "killed after stop timeout"
+	StatusReasonReloaded        = "reloaded"
+)
+
+// ServiceStatus describes whether the service recorded in a PID file is running.
+type ServiceStatus struct {
+	Running bool   `json:"running" yaml:"running"`
+	Pid     int    `json:"pid,omitempty" yaml:"pid,omitempty"`
+	PidFile string `json:"pidFile" yaml:"pidFile"`
+	Reason  string `json:"reason,omitempty" yaml:"reason,omitempty"`
+}
+
+// CheckServiceStatus reads the PID file at pidPath and reports whether the
+// process it names is alive. An empty pidPath means PID files are disabled.
+func CheckServiceStatus(pidPath string) ServiceStatus {
+	status := ServiceStatus{PidFile: pidPath}
+	if pidPath == "" {
+		status.Reason = StatusReasonPidFileDisabled
+		return status
+	}
+	pid, err := ReadPidFile(pidPath)
+	if err != nil {
+		status.Reason = StatusReasonNoPidFile
+		return status
+	}
+	status.Pid = pid
+	if !IsProcessAlive(pid) {
+		status.Reason = StatusReasonStalePidFile
+		return status
+	}
+	status.Running = true
+	return status
+}
+
+// ReloadService sends SIGHUP to the live process recorded in the PID file at
+// pidPath, asking the service to reload itself. When no live process is
+// recorded it sends nothing and returns the status as CheckServiceStatus
+// reports it. On success the status reason is StatusReasonReloaded.
+func ReloadService(pidPath string) (ServiceStatus, error) {
+	status := CheckServiceStatus(pidPath)
+	if !status.Running {
+		return status, nil
+	}
+	if err := syscall.Kill(status.Pid, syscall.SIGHUP); err != nil {
+		return status, fmt.Errorf("failed to send SIGHUP to pid %d: %w", status.Pid, err)
+	}
+	status.Reason = StatusReasonReloaded
+	return status, nil
+}
+
+// FormatStatus renders status as text, json, or yaml.
+func FormatStatus(status ServiceStatus, format string) (string, error) {
+	switch format {
+	case "", OutputText:
+		if status.Running {
+			if status.Reason == StatusReasonReloaded {
+				return fmt.Sprintf("Sent SIGHUP to service: pid=%d", status.Pid), nil
+			}
+			return fmt.Sprintf("Service running: pid=%d", status.Pid), nil
+		}
+		switch status.Reason {
+		case StatusReasonPidFileDisabled:
+			return "PID file is disabled; cannot determine service status", nil
+		case StatusReasonNoPidFile:
+			return fmt.Sprintf("Service not running (no pid file at %s)", status.PidFile), nil
+		case StatusReasonStalePidFile:
+			return fmt.Sprintf("Service not running (stale pid file, pid=%d)", status.Pid), nil
+		}
+		return fmt.Sprintf("Service not running (%s)", status.Reason), nil
+	case OutputJSON:
+		data, err := json.Marshal(status)
+		if err != nil {
+			return "", err
+		}
+		return string(data), nil
+	case OutputYAML:
+		data, err := yaml.Marshal(status)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimSuffix(string(data), "\n"), nil
+	default:
+		return "", fmt.Errorf("unknown output format %q (expected text, json, or yaml)", format)
+	}
+}
@@ -0,0 +1,160 @@
+package launchlib
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+// decodeStatusJSON formats status as JSON and decodes it into a generic map so
+// tests assert on the wire schema rather than the Go struct.
+func decodeStatusJSON(t *testing.T, status ServiceStatus) map[string]interface{} {
+	t.Helper()
+	out, err := FormatStatus(status, OutputJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal([]byte(out), &fields); err != nil {
+		t.Fatalf("invalid JSON %q: %v", out, err)
+	}
+	return fields
+}
+
+func TestServiceStatusJSONRunning(t *testing.T) {
+	pidPath := filepath.Join(t.TempDir(), "service.pid")
+	if err := WritePidFile(os.Getpid(), pidPath); err != nil {
+		t.Fatal(err)
+	}
+
+	fields := decodeStatusJSON(t, CheckServiceStatus(pidPath))
+	if fields["running"] != true {
+		t.Errorf("expected running=true, got %v", fields["running"])
+	}
+	if fields["pid"] != float64(os.Getpid()) {
+		t.Errorf("expected pid=%d, got %v", os.Getpid(), fields["pid"])
+	}
+	if fields["pidFile"] != pidPath {
+		t.Errorf("expected pidFile=%s, got %v", pidPath, fields["pidFile"])
+	}
+	if _, ok := fields["reason"]; ok {
+		t.Errorf("expected no reason for a running service, got %v", fields["reason"])
+	}
+}
+
+func TestServiceStatusJSONStale(t *testing.T) {
+	exited := exec.Command("true")
+	if err := exited.Run(); err != nil {
+		t.Fatal(err)
+	}
+	pidPath := filepath.Join(t.TempDir(), "service.pid")
+	if err := WritePidFile(exited.Process.Pid, pidPath); err != nil {
+		t.Fatal(err)
+	}
+
+	fields := decodeStatusJSON(t, CheckServiceStatus(pidPath))
+	if fields["running"] != false {
+		t.Errorf("expected running=false, got %v", fields["running"])
+	}
+	if fields["pid"] != float64(exited.Process.Pid) {
+		t.Errorf("expected stale pid %d, got %v", exited.Process.Pid, fields["pid"])
+	}
+	if fields["reason"] != StatusReasonStalePidFile {
+		t.Errorf("expected reason %q, got %v", StatusReasonStalePidFile, fields["reason"])
+	}
+}
+
+func TestServiceStatusJSONNotFound(t *testing.T) {
+	pidPath := filepath.Join(t.TempDir(), "missing.pid")
+
+	fields := decodeStatusJSON(t, CheckServiceStatus(pidPath))
+	if fields["running"] != false {
+		t.Errorf("expected running=false, got %v", fields["running"])
+	}
+	if _, ok := fields["pid"]; ok {
+		t.Errorf("expected no pid, got %v", fields["pid"])
+	}
+	if fields["pidFile"] != pidPath {
+		t.Errorf("expected pidFile=%s, got %v", pidPath, fields["pidFile"])
+	}
+	if fields["reason"] != StatusReasonNoPidFile {
+		t.Errorf("expected reason %q, got %v", StatusReasonNoPidFile, fields["reason"])
+	}
+}
+
+func TestFormatStatusTextAndYAML(t *testing.T) {
+	status := ServiceStatus{Running: true, Pid: 42, PidFile: "var/run/svc.pid"}
+
+	text, err := FormatStatus(status, OutputText)
+	if err != nil || text != "Service running: pid=42" {
+		t.Errorf("unexpected text output %q (err %v)", text, err)
+	}
+	yamlOut, err := FormatStatus(status, OutputYAML)
+	if err != nil || !strings.Contains(yamlOut, "running: true") || !strings.Contains(yamlOut, "pid: 42") {
+		t.Errorf("unexpected yaml output %q (err %v)", yamlOut, err)
+	}
+	if _, err := FormatStatus(status, "xml"); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+}
+
+func TestReloadServiceMissingPidFile(t *testing.T) {
+	status, err := ReloadService(filepath.Join(t.TempDir(), "missing.pid"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Running || status.Reason != StatusReasonNoPidFile {
+		t.Errorf("expected not running with reason %q, got %+v", StatusReasonNoPidFile, status)
+	}
+}
+
+func TestReloadServiceStalePid(t *testing.T) {
+	exited := exec.Command("true")
+	if err := exited.Run(); err != nil {
+		t.Fatal(err)
+	}
+	pidPath := filepath.Join(t.TempDir(), "service.pid")
+	if err := WritePidFile(exited.Process.Pid, pidPath); err != nil {
+		t.Fatal(err)
+	}
+
+	status, err := ReloadService(pidPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Running || status.Reason != StatusReasonStalePidFile {
+		t.Errorf("expected not running with reason %q, got %+v", StatusReasonStalePidFile, status)
+	}
+}
+
+func TestReloadServiceSendsSIGHUP(t *testing.T) {
+	child := exec.Command("sleep", "30")
+	if err := child.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = child.Process.Kill() }()
+	pidPath := filepath.Join(t.TempDir(), "service.pid")
+	if err := WritePidFile(child.Process.Pid, pidPath); err != nil {
+		t.Fatal(err)
+	}
+
+	status, err := ReloadService(pidPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !status.Running || status.Reason != StatusReasonReloaded {
+		t.Errorf("expected running with reason %q, got %+v", StatusReasonReloaded, status)
+	}
+	// sleep has no SIGHUP handler, so the signal terminates it.
+	if sig := signaledBy(child.Wait()); sig != syscall.SIGHUP {
+		t.Errorf("expected the process to receive SIGHUP, got %v", sig)
+	}
+	if text, _ := FormatStatus(status, OutputText); text != fmt.Sprintf("Sent SIGHUP to service: pid=%d", child.Process.Pid) {
+		t.Errorf("unexpected text output %q", text)
+	}
+}
@@ -0,0 +1,58 @@
+package launchlib
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// TelemetryConfig controls propagation of service identity to the process
+// using OpenTelemetry environment conventions.
+type TelemetryConfig struct {
+	// Enabled sets OTEL_SERVICE_NAME and OTEL_RESOURCE_ATTRIBUTES. Default: false.
+	Enabled bool `yaml:"enabled,omitempty"`
+}
+
+// buildOTelEnv sets OTEL_SERVICE_NAME and merges the launcher's resource
+// attributes into OTEL_RESOURCE_ATTRIBUTES. Values already present in env
+// (inherited or from config) take precedence over the launcher's.
+func buildOTelEnv(env map[string]string, serviceName, serviceVersion string) {
+	setDefault(env, "OTEL_SERVICE_NAME", serviceName)
+
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		hostname = "unknown"
+	}
+	instanceID := fmt.Sprintf("%s-%d", hostname, os.Getpid())
+
+	env["OTEL_RESOURCE_ATTRIBUTES"] = mergeOTelResourceAttributes(
+		env["OTEL_RESOURCE_ATTRIBUTES"],
+		[][2]string{
+			{"service.version", serviceVersion},
+			{"service.instance.id", instanceID},
+		},
+	)
+}
+
+// mergeOTelResourceAttributes appends each attribute to the comma-separated
+// key=value list in existing unless that key is already present.
+func mergeOTelResourceAttributes(existing string, attrs [][2]string) string {
+	var pairs []string
+	present := make(map[string]bool)
+	for _, pair := range strings.Split(existing, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		key, _, _ := strings.Cut(pair, "=")
+		present[strings.TrimSpace(key)] = true
+		pairs = append(pairs, pair)
+	}
+	for _, attr := range attrs {
+		if present[attr[0]] || attr[1] == "" {
+			continue
+		}
+		pairs = append(pairs, attr[0]+"="+attr[1])
+	}
+	return strings.Join(pairs, ",")
+}
@@ -0,0 +1,72 @@
+package launchlib
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestMergeOTelResourceAttributesPreservesUserValues(t *testing.T) {
+	got := mergeOTelResourceAttributes(
+		"deployment.environment=prod, service.version=9.9.9",
+		[][2]string{
+			{"service.version", "1.2.3"},
+			{"service.instance.id", "host-42"},
+		},
+	)
+	want := "deployment.environment=prod,service.version=9.9.9,service.instance.id=host-42"
+	if got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestBuildProcessEnvTelemetry(t *testing.T) {
+	config := MergedConfig{
+		Memory:    MemoryConfig{Mode: MemoryModeUnmanaged},
+		Telemetry: TelemetryConfig{Enabled: true},
+		Env: map[string]string{
+			"OTEL_RESOURCE_ATTRIBUTES": "team=payments",
+		},
+	}
+	env := envToMap(BuildProcessEnv(config, MemoryLimits{}, "my-service", "1.2.3"))
+
+	if env["OTEL_SERVICE_NAME"] != "my-service" {
+		t.Errorf("expected OTEL_SERVICE_NAME=my-service, got %q", env["OTEL_SERVICE_NAME"])
+	}
+
+	hostname, _ := os.Hostname()
+	attrs := env["OTEL_RESOURCE_ATTRIBUTES"]
+	for _, pair := range strings.Split(attrs, ",") {
+		if key, value, ok := strings.Cut(pair, "="); !ok || key == "" || value == "" {
+			t.Errorf("malformed attribute %q in %q", pair, attrs)
+		}
+	}
+	if !strings.HasPrefix(attrs, "team=payments,") {
+		t.Errorf("expected user attributes preserved first, got %q", attrs)
+	}
+	if !strings.Contains(attrs, "service.version=1.2.3") {
+		t.Errorf("expected service.version in %q", attrs)
+	}
+	if !strings.Contains(attrs, "service.instance.id="+hostname+"-") {
+		t.Errorf("expected service.instance.id derived from hostname in %q", attrs)
+	}
+}
+
+func TestBuildProcessEnvTelemetryDisabled(t *testing.T) {
+	config := MergedConfig{Memory: MemoryConfig{Mode: MemoryModeUnmanaged}}
+	env := envToMap(BuildProcessEnv(config, MemoryLimits{}, "my-service", "1.2.3"))
+	if _, ok := env["OTEL_SERVICE_NAME"]; ok && os.Getenv("OTEL_SERVICE_NAME") == "" {
+		t.Error("expected OTEL_SERVICE_NAME unset when telemetry is disabled")
+	}
+}
+
+// envToMap converts a KEY=VALUE slice into a map.
+func envToMap(env []string) map[string]string {
+	m := make(map[string]string, len(env))
+	for _, e := range env {
+		if k, v, ok := strings.Cut(e, "="); ok {
+			m[k] = v
+		}
+	}
+	return m
+}
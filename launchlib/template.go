@@ -0,0 +1,66 @@
+package launchlib
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// TemplateContext holds the values available to config templates. The child's
+// pid is not included because templates are expanded before the fork.
+type TemplateContext struct {
+	ServiceName    string
+	ServiceVersion string
+	Hostname       string
+}
+
+// ExpandTemplates expands text/template actions in the args, env values and
+// entry point of config. A template that does not parse or references a field
+// TemplateContext does not have is reported as a *ConfigValidationError. The config's Args and Env are copied, not modified.
+func ExpandTemplates(config MergedConfig, ctx TemplateContext) (MergedConfig, error) {
+	entryPoint, err := expandTemplate("entryPoint", config.EntryPoint, ctx)
+	if err != nil {
+		return config, err
+	}
+
+	args := make([]string, len(config.Args))
+	for i, arg := range config.Args {
+		if args[i], err = expandTemplate(fmt.Sprintf("args[%d]", i), arg, ctx); err != nil {
+			return config, err
+		}
+	}
+
+	keys := make([]string, 0, len(config.Env))
+	for k := range config.Env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	env := make(map[string]string, len(config.Env))
+	for _, k := range keys {
+		if env[k], err = expandTemplate("env."+k, config.Env[k], ctx); err != nil {
+			return config, err
+		}
+	}
+
+	config.EntryPoint = entryPoint
+	config.Args = args
+	config.Env = env
+	return config, nil
+}
+
+// expandTemplate executes text as a template named name against ctx.
+func expandTemplate(name, text string, ctx TemplateContext) (string, error) {
+	if !strings.Contains(text, "{{") {
+		return text, nil
+	}
+	tmpl, err := template.New(name).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", invalidField(name, text, "%v", err)
+	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, ctx); err != nil {
+		return "", invalidField(name, text, "%v", err)
+	}
+	return b.String(), nil
+}
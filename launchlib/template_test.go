@@ -0,0 +1,86 @@
+package launchlib
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var testTemplateContext = TemplateContext{
+	ServiceName:    "my-service",
+	ServiceVersion: "1.2.3",
+	Hostname:       "host-1",
+}
+
+func TestExpandTemplatesArgs(t *testing.T) {
+	config := MergedConfig{Args: []string{"--app-name={{.ServiceName}}", "--version={{.ServiceVersion}}", "plain"}}
+	got, err := ExpandTemplates(config, testTemplateContext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"--app-name=my-service", "--version=1.2.3", "plain"}
+	if !reflect.DeepEqual(got.Args, want) {
+		t.Errorf("expected %v, got %v", want, got.Args)
+	}
+	if config.Args[0] != "--app-name={{.ServiceName}}" {
+		t.Errorf("expected the input args to be left unchanged, got %v", config.Args)
+	}
+}
+
+func TestExpandTemplatesEnv(t *testing.T) {
+	config := MergedConfig{Env: map[string]string{
+		"INSTANCE": "{{.ServiceName}}@{{.Hostname}}",
+		"PLAIN":    "value",
+	}}
+	got, err := ExpandTemplates(config, testTemplateContext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"INSTANCE": "my-service@host-1", "PLAIN": "value"}
+	if !reflect.DeepEqual(got.Env, want) {
+		t.Errorf("expected %v, got %v", want, got.Env)
+	}
+	if config.Env["INSTANCE"] != "{{.ServiceName}}@{{.Hostname}}" {
+		t.Errorf("expected the input env to be left unchanged, got %v", config.Env)
+	}
+}
+
+func TestExpandTemplatesEntryPoint(t *testing.T) {
+	got, err := ExpandTemplates(MergedConfig{EntryPoint: "{{.ServiceName}}.app:main"}, testTemplateContext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.EntryPoint != "my-service.app:main" {
+		t.Errorf("expected my-service.app:main, got %s", got.EntryPoint)
+	}
+}
+
+func TestExpandTemplatesErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		config MergedConfig
+		field  string
+	}{
+		{"unknown key in args", MergedConfig{Args: []string{"ok", "{{.Pid}}"}}, "args[1]"},
+		{"unknown key in env", MergedConfig{Env: map[string]string{"PORT": "{{.Port}}"}}, "env.PORT"},
+		{"unparseable entry point", MergedConfig{EntryPoint: "{{.ServiceName"}, "entryPoint"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ExpandTemplates(tt.config, testTemplateContext)
+			var validationErr *ConfigValidationError
+			if !errors.As(err, &validationErr) {
+				t.Fatalf("expected a *ConfigValidationError, got %v", err)
+			}
+			if validationErr.Field != tt.field {
+				t.Errorf("expected field %s, got %s", tt.field, validationErr.Field)
+			}
+		})
+	}
+
+	_, err := ExpandTemplates(MergedConfig{Args: []string{"{{.Pid}}"}}, testTemplateContext)
+	if err == nil || !strings.Contains(err.Error(), "Pid") {
+		t.Errorf("expected the error to name the unknown key, got %v", err)
+	}
+}
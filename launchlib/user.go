@@ -0,0 +1,108 @@
+package launchlib
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/user"
+	"strconv"
+	"syscall"
+)
+
+// lookupUID resolves a user name or numeric uid.
+func lookupUID(name string) (int, error) {
+	if uid, err := strconv.Atoi(name); err == nil {
+		return uid, nil
+	}
+	u, err := user.Lookup(name)
+	if err != nil {
+		return 0, fmt.Errorf("unknown user %q: %w", name, err)
+	}
+	return strconv.Atoi(u.Uid)
+}
+
+// lookupGID resolves a group name or numeric gid.
+func lookupGID(name string) (int, error) {
+	if gid, err := strconv.Atoi(name); err == nil {
+		return gid, nil
+	}
+	g, err := user.LookupGroup(name)
+	if err != nil {
+		return 0, fmt.Errorf("unknown group %q: %w", name, err)
+	}
+	return strconv.Atoi(g.Gid)
+}
+
+// BuildCredential returns the credential for RunAsUser/RunAsGroup, or nil when
+// neither is set. The credential carries the user's supplementary groups. It
+// fails if switching identity requires root and the launcher is not running
+// as root.
+func BuildCredential(config ResourceConfig) (*syscall.Credential, error) {
+	if config.RunAsUser == "" && config.RunAsGroup == "" {
+		return nil, nil
+	}
+
+	uid, gid := os.Getuid(), os.Getgid()
+	if config.RunAsUser != "" {
+		var err error
+		if uid, err = lookupUID(config.RunAsUser); err != nil {
+			return nil, fmt.Errorf("runAsUser: %w", err)
+		}
+		if config.RunAsGroup == "" {
+			if gid, err = primaryGID(uid); err != nil {
+				return nil, fmt.Errorf("runAsUser %s: %w; set runAsGroup explicitly", config.RunAsUser, err)
+			}
+		}
+	}
+	if config.RunAsGroup != "" {
+		var err error
+		if gid, err = lookupGID(config.RunAsGroup); err != nil {
+			return nil, fmt.Errorf("runAsGroup: %w", err)
+		}
+	}
+
+	if os.Geteuid() != 0 && (uid != os.Geteuid() || gid != os.Getegid()) {
+		return nil, fmt.Errorf("cannot run as uid=%d gid=%d: the launcher must run as root to switch users", uid, gid)
+	}
+	groups, err := supplementaryGroups(uid)
+	if err != nil {
+		return nil, err
+	}
+	return &syscall.Credential{Uid: uint32(uid), Gid: uint32(gid), Groups: groups}, nil
+}
+
+// supplementaryGroups returns the groups of the user with the given uid, so
+// the process keeps them instead of having them cleared by setgroups. A uid
+// without a user entry has none.
+func supplementaryGroups(uid int) ([]uint32, error) {
+	u, err := user.LookupId(strconv.Itoa(uid))
+	if err != nil {
+		var unknown user.UnknownUserIdError
+		if errors.As(err, &unknown) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("cannot look up groups of uid %d: %w", uid, err)
+	}
+	ids, err := u.GroupIds()
+	if err != nil {
+		return nil, fmt.Errorf("cannot look up groups of %s: %w", u.Username, err)
+	}
+	groups := make([]uint32, 0, len(ids))
+	for _, id := range ids {
+		gid, err := strconv.Atoi(id)
+		if err != nil {
+			return nil, fmt.Errorf("invalid gid %q for %s", id, u.Username)
+		}
+		groups = append(groups, uint32(gid))
+	}
+	return groups, nil
+}
+
+// primaryGID returns the primary group of the user with the given uid.
+func primaryGID(uid int) (int, error) {
+	u, err := user.LookupId(strconv.Itoa(uid))
+	if err != nil {
+		return 0, fmt.Errorf("cannot determine primary group: %w", err)
+	}
+	return strconv.Atoi(u.Gid)
+}
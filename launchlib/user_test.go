@@ -0,0 +1,110 @@
+package launchlib
+
+import (
+	"os"
+	"os/exec"
+	"os/user"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+func TestBuildCredentialUnset(t *testing.T) {
+	credential, err := BuildCredential(ResourceConfig{})
+	if err != nil || credential != nil {
+		t.Errorf("expected no credential, got %+v (err %v)", credential, err)
+	}
+}
+
+func TestBuildCredentialNumeric(t *testing.T) {
+	credential, err := BuildCredential(ResourceConfig{
+		RunAsUser:  strconv.Itoa(os.Getuid()),
+		RunAsGroup: strconv.Itoa(os.Getgid()),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if credential.Uid != uint32(os.Getuid()) || credential.Gid != uint32(os.Getgid()) {
+		t.Errorf("expected uid=%d gid=%d, got %+v", os.Getuid(), os.Getgid(), credential)
+	}
+}
+
+func TestBuildCredentialNameUsesPrimaryGroup(t *testing.T) {
+	current, err := user.Current()
+	if err != nil {
+		t.Skipf("cannot look up current user: %v", err)
+	}
+	credential, err := BuildCredential(ResourceConfig{RunAsUser: current.Username})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strconv.Itoa(int(credential.Uid)) != current.Uid || strconv.Itoa(int(credential.Gid)) != current.Gid {
+		t.Errorf("expected uid=%s gid=%s, got %+v", current.Uid, current.Gid, credential)
+	}
+}
+
+func TestBuildCredentialSupplementaryGroups(t *testing.T) {
+	current, err := user.Current()
+	if err != nil {
+		t.Skipf("cannot look up current user: %v", err)
+	}
+	ids, err := current.GroupIds()
+	if err != nil {
+		t.Skipf("cannot look up groups: %v", err)
+	}
+	credential, err := BuildCredential(ResourceConfig{RunAsUser: current.Username})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if credential.NoSetGroups {
+		t.Error("expected setgroups to apply the user's groups")
+	}
+	got := make([]string, len(credential.Groups))
+	for i, gid := range credential.Groups {
+		got[i] = strconv.Itoa(int(gid))
+	}
+	if strings.Join(got, ",") != strings.Join(ids, ",") {
+		t.Errorf("expected groups %v, got %v", ids, got)
+	}
+}
+
+func TestBuildCredentialErrors(t *testing.T) {
+	if _, err := BuildCredential(ResourceConfig{RunAsUser: "no-such-user-psl"}); err == nil {
+		t.Error("expected an error for an unknown user")
+	}
+	if _, err := BuildCredential(ResourceConfig{RunAsGroup: "no-such-group-psl"}); err == nil {
+		t.Error("expected an error for an unknown group")
+	}
+	if os.Geteuid() != 0 {
+		_, err := BuildCredential(ResourceConfig{RunAsUser: "0"})
+		if err == nil || !strings.Contains(err.Error(), "must run as root") {
+			t.Errorf("expected a privilege error when not root, got %v", err)
+		}
+	}
+}
+
+func TestBuildCredentialDropsPrivileges(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("requires root")
+	}
+	nobody, err := user.Lookup("nobody")
+	if err != nil {
+		t.Skipf("no nobody user: %v", err)
+	}
+	credential, err := BuildCredential(ResourceConfig{RunAsUser: "nobody"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := exec.Command("id", "-u")
+	cmd.Dir = "/"
+	cmd.SysProcAttr = &syscall.SysProcAttr{Credential: credential}
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.TrimSpace(string(out)); got != nobody.Uid {
+		t.Errorf("expected child uid %s, got %s", nobody.Uid, got)
+	}
+}
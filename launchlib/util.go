@@ -15,6 +15,7 @@
 package launchlib
 
 import (
+	"sort"
 	"syscall"
 )
 
@@ -23,3 +24,13 @@ import (
 func IsProcessAlive(pid int) bool {
 	return syscall.Kill(pid, 0) == nil
 }
+
+// sortedKeys returns the keys of m in lexical order, for deterministic output.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
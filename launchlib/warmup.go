@@ -0,0 +1,108 @@
+package launchlib
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// WarmupConfig sends requests to the service before it is marked ready, so
+// the first real requests do not pay for cold imports and caches.
+type WarmupConfig struct {
+	// URL is requested with GET, e.g. "http://127.0.0.1:8080/health".
+	// Warmup is disabled when empty.
+	URL string `yaml:"url,omitempty"`
+
+	// Count is the number of warmup requests. Default: 1.
+	Count int `yaml:"count,omitempty"`
+
+	// TimeoutSeconds bounds each warmup request. Default: 10.
+	TimeoutSeconds int `yaml:"timeoutSeconds,omitempty"`
+}
+
+// warmupDialInterval is how often the warmup URL's port is polled until the
+// service accepts connections.
+var warmupDialInterval = 100 * time.Millisecond
+
+// RunWarmup waits until the warmup URL's host accepts TCP connections, then
+// sends the configured number of GET requests. Failed requests are logged and
+// do not stop the remaining attempts. It returns ctx.Err() if ctx is cancelled
+// first, for example because the process exited.
+func RunWarmup(ctx context.Context, config WarmupConfig, logger *Logger) error {
+	target, err := url.Parse(config.URL)
+	if err != nil {
+		return fmt.Errorf("invalid warmup url %q: %w", config.URL, err)
+	}
+	count := config.Count
+	if count <= 0 {
+		count = 1
+	}
+	timeout := time.Duration(config.TimeoutSeconds) * time.Second
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+
+	if err := waitForPort(ctx, warmupAddress(target)); err != nil {
+		return err
+	}
+
+	client := &http.Client{Timeout: timeout}
+	failures := 0
+	for i := 1; i <= count; i++ {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, config.URL, nil)
+		if err != nil {
+			return fmt.Errorf("invalid warmup url %q: %w", config.URL, err)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			failures++
+			logger.Warnf("Warmup request %d/%d failed: %v", i, count, err)
+			continue
+		}
+		_, _ = io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode >= 400 {
+			failures++
+			logger.Warnf("Warmup request %d/%d returned %s", i, count, resp.Status)
+		}
+	}
+	logger.Printf("Warmup finished: requests=%d failed=%d", count, failures)
+	return nil
+}
+
+// warmupAddress returns the host:port to dial for target, filling in the
+// scheme's default port.
+func warmupAddress(target *url.URL) string {
+	if target.Port() != "" {
+		return target.Host
+	}
+	port := "80"
+	if target.Scheme == "https" {
+		port = "443"
+	}
+	return net.JoinHostPort(target.Hostname(), port)
+}
+
+// waitForPort polls address until it accepts a TCP connection or ctx is done.
+func waitForPort(ctx context.Context, address string) error {
+	dialer := net.Dialer{Timeout: time.Second}
+	for {
+		conn, err := dialer.DialContext(ctx, "tcp", address)
+		if err == nil {
+			conn.Close()
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(warmupDialInterval):
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package launchlib
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRunWarmup(t *testing.T) {
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		if r.URL.Path != "/warm" {
+			t.Errorf("expected /warm, got %s", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	var buf bytes.Buffer
+	config := WarmupConfig{URL: server.URL + "/warm", Count: 3}
+	if err := RunWarmup(context.Background(), config, NewLogger(&buf, LoggingConfig{})); err != nil {
+		t.Fatalf("RunWarmup failed: %v", err)
+	}
+	if got := hits.Load(); got != 3 {
+		t.Errorf("expected 3 warmup requests, got %d", got)
+	}
+	if !strings.Contains(buf.String(), "Warmup finished: requests=3 failed=0") {
+		t.Errorf("expected a summary line, got:\n%s", buf.String())
+	}
+}
+
+func TestRunWarmupFailuresProceed(t *testing.T) {
+	var hits atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	var buf bytes.Buffer
+	config := WarmupConfig{URL: server.URL, Count: 2}
+	if err := RunWarmup(context.Background(), config, NewLogger(&buf, LoggingConfig{})); err != nil {
+		t.Fatalf("expected failed requests not to fail the warmup, got %v", err)
+	}
+	if got := hits.Load(); got != 2 {
+		t.Errorf("expected every attempt to be made, got %d", got)
+	}
+	if !strings.Contains(buf.String(), "failed=2") {
+		t.Errorf("expected both failures to be counted, got:\n%s", buf.String())
+	}
+}
+
+func TestRunWarmupWaitsForPort(t *testing.T) {
+	port := freePort(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+
+	config := WarmupConfig{URL: fmt.Sprintf("http://127.0.0.1:%d/", port)}
+	if err := RunWarmup(ctx, config, NewLogger(&bytes.Buffer{}, LoggingConfig{})); err != context.DeadlineExceeded {
+		t.Errorf("expected the warmup to wait for the port until cancelled, got %v", err)
+	}
+}
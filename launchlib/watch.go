@@ -0,0 +1,99 @@
+package launchlib
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"os"
+	"time"
+)
+
+// watchPollInterval is how often watch mode checks the watched files.
+var watchPollInterval = time.Second
+
+// watchDebounce is how long the watched files must stay unchanged after a
+// change before the process is restarted, so a build that writes a file in
+// several steps causes a single restart.
+var watchDebounce = 500 * time.Millisecond
+
+// fileStamp identifies a version of a file. It is zero for a missing file.
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// statFiles stamps each of paths.
+func statFiles(paths []string) map[string]fileStamp {
+	stamps := make(map[string]fileStamp, len(paths))
+	for _, path := range paths {
+		var stamp fileStamp
+		if info, err := os.Stat(path); err == nil {
+			stamp = fileStamp{modTime: info.ModTime(), size: info.Size()}
+		}
+		stamps[path] = stamp
+	}
+	return stamps
+}
+
+// waitForChange polls paths until one of them changes and then stays
+// unchanged for watchDebounce, and returns the path that changed last. It
+// returns false when ctx is done first.
+func waitForChange(ctx context.Context, paths []string) (string, bool) {
+	last := statFiles(paths)
+	ticker := time.NewTicker(watchPollInterval)
+	defer ticker.Stop()
+
+	changed := ""
+	var changedAt time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return "", false
+		case <-ticker.C:
+		}
+		current := statFiles(paths)
+		if !maps.Equal(current, last) {
+			for _, path := range paths {
+				if current[path] != last[path] {
+					changed = path
+				}
+			}
+			last, changedAt = current, time.Now()
+			continue
+		}
+		if changed != "" && time.Since(changedAt) >= watchDebounce {
+			return changed, true
+		}
+	}
+}
+
+// errWatchRequiresDev rejects watch mode outside dev mode.
+var errWatchRequiresDev = errors.New("watch mode is only available in dev mode")
+
+// launchWatching launches the process and relaunches it, re-reading the
+// configs, each time a watched file changes. It returns once the process
+// exits without a change, or a launch fails.
+func (l *Launcher) launchWatching() (LaunchResult, error) {
+	if !l.params.Dev {
+		return LaunchResult{ExitCode: 1}, errWatchRequiresDev
+	}
+	for {
+		l.restartPending.Store(false)
+		result, err := l.launch(context.Background(), true)
+		if err != nil || !l.restartPending.Load() {
+			return result, err
+		}
+	}
+}
+
+// watchForRestart stops the launch via stop when a watched file changes,
+// marking the stop as a restart. It returns when ctx is done.
+func (l *Launcher) watchForRestart(ctx context.Context, stop context.CancelFunc, paths []string) {
+	path, ok := waitForChange(ctx, paths)
+	if !ok {
+		return
+	}
+	l.logger.Printf("Watch: %s changed, restarting the process", path)
+	l.restartPending.Store(true)
+	stop()
+}
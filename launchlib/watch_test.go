@@ -0,0 +1,166 @@
+package launchlib
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fastWatch shortens the watch poll interval and debounce for a test.
+func fastWatch(t *testing.T) {
+	t.Helper()
+	poll, debounce := watchPollInterval, watchDebounce
+	watchPollInterval, watchDebounce = 20*time.Millisecond, 100*time.Millisecond
+	t.Cleanup(func() { watchPollInterval, watchDebounce = poll, debounce })
+}
+
+// touch moves the modification time of path forward by offset.
+func touch(t *testing.T, path string, offset time.Duration) {
+	t.Helper()
+	mtime := time.Now().Add(offset)
+	if err := os.Chtimes(path, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWaitForChangeDebounces(t *testing.T) {
+	fastWatch(t)
+	path := filepath.Join(t.TempDir(), "app.pex")
+	if err := os.WriteFile(path, []byte("v1"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	type change struct {
+		path string
+		at   time.Time
+	}
+	done := make(chan change, 1)
+	go func() {
+		changed, _ := waitForChange(context.Background(), []string{path, filepath.Join(t.TempDir(), "missing.yml")})
+		done <- change{changed, time.Now()}
+	}()
+
+	// A burst of writes, each within the debounce of the last.
+	time.Sleep(50 * time.Millisecond)
+	var lastWrite time.Time
+	for i := 1; i <= 4; i++ {
+		touch(t, path, time.Duration(i)*time.Second)
+		lastWrite = time.Now()
+		time.Sleep(40 * time.Millisecond)
+	}
+
+	select {
+	case got := <-done:
+		if got.path != path {
+			t.Errorf("expected %s to be reported, got %q", path, got.path)
+		}
+		if since := got.at.Sub(lastWrite); since < watchDebounce {
+			t.Errorf("expected the change to be reported after the burst settled, got %s after the last write", since)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the change to be reported")
+	}
+}
+
+func TestWaitForChangeStopsOnCancel(t *testing.T) {
+	fastWatch(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan bool, 1)
+	go func() {
+		_, changed := waitForChange(ctx, []string{filepath.Join(t.TempDir(), "app.pex")})
+		done <- changed
+	}()
+	cancel()
+	select {
+	case changed := <-done:
+		if changed {
+			t.Error("expected no change to be reported after cancellation")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected waitForChange to return when cancelled")
+	}
+}
+
+func TestLaunchWatchRestartsOnChange(t *testing.T) {
+	fastWatch(t)
+	launcher, out := newTestLauncher(t, `
+configType: python
+configVersion: 1
+launchMode: command
+executable: service/bin/run.sh
+memory:
+  mode: unmanaged
+`)
+	root := launcher.params.DistRoot
+	script := filepath.Join(root, "service/bin/run.sh")
+	// Each run is recorded; once the stop file exists the next run exits.
+	content := "#!/bin/sh\necho run >> runs.out\n[ -e stop ] && exit 0\nexec sleep 30\n"
+	if err := os.WriteFile(script, []byte(content), 0755); err != nil {
+		t.Fatal(err)
+	}
+	launcher.params.Dev = true
+	launcher.params.Watch = true
+
+	type launched struct {
+		result LaunchResult
+		err    error
+	}
+	done := make(chan launched, 1)
+	go func() {
+		result, err := launcher.Launch()
+		done <- launched{result, err}
+	}()
+
+	runs := func() int {
+		data, _ := os.ReadFile(filepath.Join(root, "runs.out"))
+		return strings.Count(string(data), "run\n")
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for runs() < 1 && time.Now().Before(deadline) {
+		time.Sleep(20 * time.Millisecond)
+	}
+	if runs() != 1 {
+		t.Fatalf("expected the process to start once\n%s", out)
+	}
+
+	if err := os.WriteFile(filepath.Join(root, "stop"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	touch(t, script, time.Second)
+
+	select {
+	case got := <-done:
+		if got.err != nil {
+			t.Fatalf("unexpected error: %v\n%s", got.err, out)
+		}
+		if got.result.ExitCode != 0 {
+			t.Errorf("expected the restarted process to exit 0, got %d\n%s", got.result.ExitCode, out)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatalf("expected the process to be restarted and exit\n%s", out)
+	}
+	if n := runs(); n != 2 {
+		t.Errorf("expected exactly one restart, got %d runs\n%s", n, out)
+	}
+	if !strings.Contains(out.String(), "Watch: "+script+" changed, restarting the process") {
+		t.Errorf("expected the restart to be logged, got:\n%s", out)
+	}
+}
+
+func TestLaunchWatchRequiresDev(t *testing.T) {
+	launcher, _ := newTestLauncher(t, `
+configType: python
+configVersion: 1
+launchMode: command
+executable: /bin/true
+memory:
+  mode: unmanaged
+`)
+	launcher.params.Watch = true
+	if _, err := launcher.Launch(); err != errWatchRequiresDev {
+		t.Errorf("expected %v, got %v", errWatchRequiresDev, err)
+	}
+}
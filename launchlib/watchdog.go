@@ -16,14 +16,29 @@ package launchlib
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
+	"math/rand"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"syscall"
 	"time"
 )
 
+// pollJitterFraction is the maximum random delay added to each poll interval,
+// as a fraction of the interval. Jitter keeps many launchers on the same node
+// from sampling /proc in lockstep.
+const pollJitterFraction = 0.1
+
+// ErrRSSUnsupported is returned by the RSS reader on platforms where process
+// RSS cannot be read.
+var ErrRSSUnsupported = errors.New("reading process RSS is not supported on this platform")
+
 // WatchdogState tracks the current state of the RSS watchdog.
 type WatchdogState int
 
@@ -57,86 +72,426 @@ func (s WatchdogState) String() string {
 // interval. It transitions through states:
 //
 //	healthy -> soft_warning (log) -> hard_limit (SIGTERM) -> terminating (SIGKILL after grace)
+//
+// The watchdog only sends the first signal. The caller waiting on the process
+// runs the rest of the sequence with CompleteShutdown, so a single goroutine
+// owns the deadline.
 type RSSWatchdog struct {
 	pid    int
 	limits MemoryLimits
 	config WatchdogConfig
 	logger *Logger
-	state  WatchdogState
 
-	// For testing: override the RSS reader
-	readRSS func(pid int) (uint64, error)
+	// state is the current WatchdogState. Accessed atomically because
+	// diagnostic snapshots read it outside the watchdog goroutine.
+	state atomic.Int32
+
+	// readFailures counts consecutive failed RSS reads. Accessed atomically
+	// because it is exposed to callers outside the watchdog goroutine.
+	readFailures atomic.Int32
+
+	// peak is the highest RSS observed.
+	peak peakTracker
+
+	// started is when monitoring began, for the startup grace window.
+	started time.Time
+
+	// pressure is the latest memory PSI sample, when PSI monitoring is enabled.
+	pressure         atomic.Pointer[PSIStats]
+	pressureHigh     bool
+	pressureDisabled bool
+	onPressure       func(PSIStats)
+
+	// anonFallbackLogged records that AnonymousOnly fell back to statm.
+	anonFallbackLogged bool
+
+	// leak tracks RSS growth when LeakWarnBytesPerMinute is set.
+	leak leakDetector
+
+	// smoothed is the moving average of RSS when SmoothingAlpha is set; 0
+	// until the first sample.
+	smoothed float64
+
+	// shutdownSequence replaces SIGTERM, grace, SIGKILL when set.
+	shutdownSequence []SignalStep
+
+	// events receives state transitions and peak updates when an event log
+	// is set.
+	events *watchdogEventLog
+
+	// pendingSteps and pendingCgroupDir are the shutdown started by
+	// terminateProcess, for CompleteShutdown.
+	pendingSteps     []shutdownStep
+	pendingCgroupDir string
+
+	// refreshLimits recomputes the limits when RefreshLimits is enabled; ticks
+	// counts polls since the last refresh.
+	refreshLimits func() (MemoryLimits, error)
+	ticks         int
+
+	// For testing: override the RSS readers, PSI reader and clock
+	readRSS     func(pid int) (uint64, error)
+	readAnonRSS func(pid int) (uint64, error)
+	readPSI     func() (PSIStats, error)
+	now         func() time.Time
+
+	// rootDir is where /proc and the cgroup root are found. Overridden in tests.
+	rootDir string
+
+	// cgroupRoot is where the cgroup hierarchy is mounted; empty means
+	// DefaultCgroupRoot.
+	cgroupRoot string
 }
 
 // NewRSSWatchdog creates a new watchdog for the given process.
 func NewRSSWatchdog(pid int, limits MemoryLimits, config WatchdogConfig, logger *Logger) *RSSWatchdog {
-	return &RSSWatchdog{
-		pid:     pid,
-		limits:  limits,
-		config:  config,
-		logger:  logger,
-		state:   WatchdogStateHealthy,
-		readRSS: readProcessRSS,
+	w := &RSSWatchdog{
+		pid:         pid,
+		limits:      limits,
+		config:      config,
+		logger:      logger,
+		readRSS:     readProcessRSS,
+		readAnonRSS: readProcessAnonRSS,
+		now:         time.Now,
+		rootDir:     "/",
 	}
+	w.readPSI = func() (PSIStats, error) { return ReadMemoryPressure(os.DirFS("/"), w.cgroupRoot) }
+	return w
+}
+
+// SetCgroupRoot sets where the cgroup hierarchy is mounted, for memory
+// pressure and cgroup kills. An empty root means DefaultCgroupRoot.
+func (w *RSSWatchdog) SetCgroupRoot(root string) {
+	w.cgroupRoot = root
+}
+
+// State returns the current watchdog state.
+func (w *RSSWatchdog) State() WatchdogState {
+	return WatchdogState(w.state.Load())
+}
+
+func (w *RSSWatchdog) setState(state WatchdogState) {
+	w.state.Store(int32(state))
+}
+
+// enterState sets state and, if it changed, records the transition in the
+// event log with the reading that caused it.
+func (w *RSSWatchdog) enterState(state WatchdogState, rss uint64) {
+	changed := w.State() != state
+	w.setState(state)
+	if changed {
+		w.recordEvent(WatchdogEventState, rss)
+	}
+}
+
+// recordEvent appends an event to the event log, if one is set.
+func (w *RSSWatchdog) recordEvent(kind string, rss uint64) {
+	if w.events == nil {
+		return
+	}
+	err := w.events.write(WatchdogEvent{
+		Time:  w.now(),
+		Event: kind,
+		State: w.State().String(),
+		RSS:   rss,
+		Limit: w.limits.HardKillBytes,
+		PID:   w.pid,
+	})
+	if err != nil {
+		w.logger.Warnf("[watchdog] Failed to write event log: %v", err)
+	}
+}
+
+// OnMemoryPressure registers fn to receive every memory PSI sample.
+func (w *RSSWatchdog) OnMemoryPressure(fn func(PSIStats)) {
+	w.onPressure = fn
+}
+
+// SetShutdownSequence sets the signals sent when the hard limit is exceeded.
+// An empty sequence means SIGTERM, then SIGKILL after the grace period.
+func (w *RSSWatchdog) SetShutdownSequence(steps []SignalStep) {
+	w.shutdownSequence = steps
+}
+
+// SetEventLog appends a JSON line to out for every state transition and new
+// peak RSS. Nil disables the event log.
+func (w *RSSWatchdog) SetEventLog(out io.Writer) {
+	if out == nil {
+		w.events = nil
+		return
+	}
+	w.events = &watchdogEventLog{out: out}
+}
+
+// SetLimitRefresher sets how the limits are recomputed every
+// limitRefreshTicks polls. Nil disables refreshing.
+func (w *RSSWatchdog) SetLimitRefresher(refresh func() (MemoryLimits, error)) {
+	w.refreshLimits = refresh
+}
+
+// MemoryPressure returns the latest memory PSI sample, or nil if PSI
+// monitoring is disabled or unavailable.
+func (w *RSSWatchdog) MemoryPressure() *PSIStats {
+	return w.pressure.Load()
 }
 
 // Run starts the watchdog monitoring loop. It blocks until the context is
 // cancelled or the process is terminated. Returns true if the watchdog
-// triggered a termination.
+// triggered a termination, after sending the first shutdown signal; the
+// caller then finishes the shutdown with CompleteShutdown.
 func (w *RSSWatchdog) Run(ctx context.Context) bool {
 	if w.limits.HardKillBytes == 0 {
 		w.logger.Println("[watchdog] No memory limit configured, watchdog disabled")
 		return false
 	}
 
-	interval := time.Duration(w.config.PollIntervalSeconds) * time.Second
-	ticker := time.NewTicker(interval)
-	defer ticker.Stop()
+	w.started = w.now()
+
+	// Probe once up front: if RSS cannot be read at all, polling would only log
+	// an error every interval while providing no protection.
+	if rss, err := w.sampleRSS(); err != nil {
+		if rssUnavailable(err) {
+			w.logger.Warnf("[watchdog] Cannot read RSS for pid %d (%v); memory protection is DISABLED", w.pid, err)
+			return false
+		}
+	} else if w.peak.observe(rss) {
+		w.recordEvent(WatchdogEventPeak, rss)
+	}
+
+	interval := w.config.PollInterval()
+	timer := time.NewTimer(jitterInterval(interval))
+	defer timer.Stop()
 
-	w.logger.Printf("[watchdog] Started: pid=%d soft_warn=%s hard_kill=%s poll=%s grace=%ds",
+	w.logger.Printf("[watchdog] Started: pid=%d soft_warn=%s hard_kill=%s poll=%s grace=%ds startup_grace=%ds",
 		w.pid,
 		formatBytes(w.limits.SoftWarnBytes),
 		formatBytes(w.limits.HardKillBytes),
 		interval,
 		w.config.GracePeriodSeconds,
+		valueOr(w.config.StartupGraceSeconds, 0),
 	)
+	if alpha := valueOr(w.config.SmoothingAlpha, 0); alpha > 0 {
+		w.logger.Printf("[watchdog] Comparing a moving average of RSS (alpha=%.2f) against the limits", alpha)
+	}
+	if valueOr(w.config.ObserveOnly, false) {
+		w.logger.Printf("[watchdog] Observe-only mode: exceeding the hard limit is logged but the process is not terminated")
+	}
+	if w.refreshLimits != nil {
+		w.logger.Printf("[watchdog] Re-reading the memory limit every %d polls", limitRefreshTicks)
+	}
 
 	for {
 		select {
 		case <-ctx.Done():
 			return false
-		case <-ticker.C:
+		case <-timer.C:
+			w.maybeRefreshLimits()
 			if triggered := w.check(); triggered {
 				return true
 			}
+			if w.readFailuresExhausted() {
+				w.logger.Printf("[watchdog] %d consecutive RSS read failures for pid %d, assuming process exited; stopping",
+					w.ConsecutiveReadFailures(), w.pid)
+				return false
+			}
+			timer.Reset(jitterInterval(interval))
+		}
+	}
+}
+
+// PeakRSS returns the highest RSS observed so far, in bytes.
+func (w *RSSWatchdog) PeakRSS() uint64 {
+	return w.peak.load()
+}
+
+// ConsecutiveReadFailures returns the number of RSS reads in a row that have failed.
+// It resets to zero after any successful read.
+func (w *RSSWatchdog) ConsecutiveReadFailures() int {
+	return int(w.readFailures.Load())
+}
+
+// readFailuresExhausted reports whether the consecutive read failure budget is spent.
+func (w *RSSWatchdog) readFailuresExhausted() bool {
+	limit := w.config.MaxConsecutiveReadFailures
+	if limit <= 0 {
+		limit = DefaultWatchdogConfig().MaxConsecutiveReadFailures
+	}
+	return w.ConsecutiveReadFailures() >= limit
+}
+
+// startupGraceRemaining returns how much of the startup grace window is left,
+// or zero once enforcement has begun.
+func (w *RSSWatchdog) startupGraceRemaining() time.Duration {
+	if w.started.IsZero() {
+		w.started = w.now()
+	}
+	grace := time.Duration(valueOr(w.config.StartupGraceSeconds, 0)) * time.Second
+	if remaining := grace - w.now().Sub(w.started); remaining > 0 {
+		return remaining
+	}
+	return 0
+}
+
+// sampleRSS reads the memory compared against the limits: anonymous RSS when
+// AnonymousOnly is set and smaps_rollup is readable, total RSS otherwise.
+func (w *RSSWatchdog) sampleRSS() (uint64, error) {
+	if valueOr(w.config.AnonymousOnly, false) {
+		rss, err := w.readAnonRSS(w.pid)
+		if err == nil {
+			return rss, nil
+		}
+		if !w.anonFallbackLogged {
+			w.anonFallbackLogged = true
+			w.logger.Printf("[watchdog] Cannot read anonymous RSS (%v); falling back to total RSS from statm", err)
+		}
+	}
+	return w.readRSS(w.pid)
+}
+
+// parseSmapsRollupAnonymous returns the "Anonymous:" total from
+// smaps_rollup content, in bytes.
+func parseSmapsRollupAnonymous(data string) (uint64, error) {
+	for _, line := range strings.Split(data, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) >= 2 && fields[0] == "Anonymous:" {
+			kb, err := strconv.ParseUint(fields[1], 10, 64)
+			if err != nil {
+				return 0, fmt.Errorf("failed to parse Anonymous: %w", err)
+			}
+			return kb * 1024, nil
 		}
 	}
+	return 0, fmt.Errorf("no Anonymous field in smaps_rollup")
+}
+
+// rssUnavailable reports whether err means RSS can never be read for the
+// process: the platform lacks support, or its /proc entry does not exist.
+func rssUnavailable(err error) bool {
+	return errors.Is(err, ErrRSSUnsupported) || errors.Is(err, fs.ErrNotExist)
+}
+
+// jitterInterval adds up to pollJitterFraction of random delay to the interval.
+func jitterInterval(interval time.Duration) time.Duration {
+	maxJitter := int64(float64(interval) * pollJitterFraction)
+	if maxJitter <= 0 {
+		return interval
+	}
+	return interval + time.Duration(rand.Int63n(maxJitter))
+}
+
+// checkPressure samples memory PSI and warns when "some avg10" crosses the
+// configured threshold. PSI is only available on cgroup v2; if it cannot be
+// read, monitoring is disabled after a single log line.
+func (w *RSSWatchdog) checkPressure() {
+	threshold := valueOr(w.config.PressureWarnPercent, 0)
+	if threshold <= 0 || w.pressureDisabled {
+		return
+	}
+	stats, err := w.readPSI()
+	if err != nil {
+		w.pressureDisabled = true
+		w.logger.Printf("[watchdog] Memory pressure (PSI) unavailable, disabling PSI monitoring: %v", err)
+		return
+	}
+	w.pressure.Store(&stats)
+	if w.onPressure != nil {
+		w.onPressure(stats)
+	}
+
+	switch {
+	case stats.Some.Avg10 > threshold && !w.pressureHigh:
+		w.pressureHigh = true
+		w.logger.Warnf("[watchdog] MEMORY PRESSURE: some avg10=%.2f%% avg60=%.2f%% full avg10=%.2f%% (threshold %.2f%%). "+
+			"The process is stalling on memory and may be thrashing.",
+			stats.Some.Avg10, stats.Some.Avg60, stats.Full.Avg10, threshold)
+	case stats.Some.Avg10 <= threshold && w.pressureHigh:
+		w.pressureHigh = false
+		w.logger.Printf("[watchdog] Memory pressure recovered: some avg10=%.2f%%", stats.Some.Avg10)
+	}
+}
+
+// checkLeak feeds rss to the leak detector and warns when it reports steady
+// growth. The warning is advisory; it never changes the watchdog state.
+func (w *RSSWatchdog) checkLeak(rss uint64) {
+	report, leaking := w.leak.observe(rssSample{at: w.now(), rss: rss}, valueOr(w.config.LeakWarnBytesPerMinute, 0))
+	if !leaking {
+		return
+	}
+	projection := "already at or above the hard limit"
+	if rss < w.limits.HardKillBytes {
+		minutes := float64(w.limits.HardKillBytes-rss) / report.bytesPerMinute
+		projection = fmt.Sprintf("hard limit %s reached in ~%s at this rate",
+			formatBytes(w.limits.HardKillBytes),
+			time.Duration(minutes*float64(time.Minute)).Round(time.Second))
+	}
+	w.logger.Warnf("[watchdog] POSSIBLE MEMORY LEAK: rss=%s growing %s/min over the last %s (threshold %s/min); %s",
+		formatBytes(rss),
+		formatBytes(uint64(report.bytesPerMinute)),
+		report.window.Round(time.Second),
+		formatBytes(valueOr(w.config.LeakWarnBytesPerMinute, 0)),
+		projection,
+	)
 }
 
 // check performs a single RSS check and transitions state if needed.
 func (w *RSSWatchdog) check() bool {
-	rss, err := w.readRSS(w.pid)
+	w.checkPressure()
+
+	rss, err := w.sampleRSS()
 	if err != nil {
-		// Process may have already exited
-		w.logger.Printf("[watchdog] Failed to read RSS for pid %d: %v", w.pid, err)
+		// Process may have already exited, or /proc raced with a fork
+		failures := w.readFailures.Add(1)
+		w.logger.Printf("[watchdog] Failed to read RSS for pid %d (attempt %d): %v", w.pid, failures, err)
 		return false
 	}
+	w.readFailures.Store(0)
+	if w.peak.observe(rss) {
+		w.recordEvent(WatchdogEventPeak, rss)
+	}
+	if valueOr(w.config.LeakWarnBytesPerMinute, 0) > 0 {
+		w.checkLeak(rss)
+	}
+
+	// Peak and leak tracking use the raw sample; the limits use the smoothed one.
+	rss = w.smoothRSS(rss)
+	graceRemaining := w.startupGraceRemaining()
 
 	switch {
-	case rss >= w.limits.HardKillBytes && w.state < WatchdogStateHardLimit:
-		w.state = WatchdogStateHardLimit
-		w.logger.Printf("[watchdog] HARD LIMIT EXCEEDED: rss=%s limit=%s (%.1f%% of cgroup limit %s). Sending SIGTERM to pid %d.",
+	case rss >= w.limits.HardKillBytes && w.State() < WatchdogStateHardLimit && graceRemaining > 0:
+		w.logger.Printf("[watchdog] rss=%s exceeds hard limit %s during startup grace (%s remaining); not enforcing",
+			formatBytes(rss),
+			formatBytes(w.limits.HardKillBytes),
+			graceRemaining.Round(time.Second),
+		)
+		w.enterState(WatchdogStateSoftWarning, rss)
+
+	case rss >= w.limits.HardKillBytes && w.State() < WatchdogStateHardLimit && valueOr(w.config.ObserveOnly, false):
+		w.enterState(WatchdogStateHardLimit, rss)
+		w.logger.Warnf("[watchdog] OBSERVE ONLY: rss=%s exceeds hard limit %s (%.1f%% of cgroup limit %s); "+
+			"would send %s to pid %d, not enforcing.",
+			formatBytes(rss),
+			formatBytes(w.limits.HardKillBytes),
+			float64(rss)/float64(w.limits.CgroupLimitBytes)*100,
+			formatBytes(w.limits.CgroupLimitBytes),
+			signalName(w.firstShutdownSignal()),
+			w.pid,
+		)
+
+	case rss >= w.limits.HardKillBytes && w.State() < WatchdogStateHardLimit:
+		w.enterState(WatchdogStateHardLimit, rss)
+		w.logger.Printf("[watchdog] HARD LIMIT EXCEEDED: rss=%s limit=%s (%.1f%% of cgroup limit %s). Sending %s to pid %d.",
 			formatBytes(rss),
 			formatBytes(w.limits.HardKillBytes),
 			float64(rss)/float64(w.limits.CgroupLimitBytes)*100,
 			formatBytes(w.limits.CgroupLimitBytes),
+			signalName(w.firstShutdownSignal()),
 			w.pid,
 		)
-		w.terminateProcess()
+		w.terminateProcess(rss)
 		return true
 
-	case rss >= w.limits.SoftWarnBytes && w.state < WatchdogStateSoftWarning:
-		w.state = WatchdogStateSoftWarning
+	case rss >= w.limits.SoftWarnBytes && w.State() < WatchdogStateSoftWarning:
+		w.enterState(WatchdogStateSoftWarning, rss)
 		w.logger.Printf("[watchdog] SOFT WARNING: rss=%s warn_at=%s (%.1f%% of cgroup limit %s). "+
 			"Process will be terminated at %s.",
 			formatBytes(rss),
@@ -146,9 +501,10 @@ func (w *RSSWatchdog) check() bool {
 			formatBytes(w.limits.HardKillBytes),
 		)
 
-	case rss < w.limits.SoftWarnBytes && w.state == WatchdogStateSoftWarning:
-		// RSS dropped back below soft warning threshold
-		w.state = WatchdogStateHealthy
+	case rss < w.limits.SoftWarnBytes && (w.State() == WatchdogStateSoftWarning || w.State() == WatchdogStateHardLimit):
+		// RSS dropped back below soft warning threshold. The hard limit state
+		// is only left standing in observe-only mode.
+		w.enterState(WatchdogStateHealthy, rss)
 		w.logger.Printf("[watchdog] RSS recovered: rss=%s, back below soft warning threshold",
 			formatBytes(rss))
 	}
@@ -156,51 +512,297 @@ func (w *RSSWatchdog) check() bool {
 	return false
 }
 
-// terminateProcess sends SIGTERM followed by SIGKILL after the grace period.
-func (w *RSSWatchdog) terminateProcess() {
-	w.state = WatchdogStateTerminating
+// minAdaptiveGrace is the shortest grace period AdaptiveGrace scales down to.
+const minAdaptiveGrace = 5 * time.Second
 
-	// Send SIGTERM for graceful shutdown
-	if err := syscall.Kill(w.pid, syscall.SIGTERM); err != nil {
-		w.logger.Printf("[watchdog] Failed to send SIGTERM to pid %d: %v", w.pid, err)
+// limitRefreshTicks is how many polls pass between limit refreshes: about a
+// minute at the default poll interval.
+const limitRefreshTicks = 12
+
+// maybeRefreshLimits recomputes the limits every limitRefreshTicks polls and
+// adopts them when the cgroup limit has changed. A failed read keeps the
+// current limits.
+func (w *RSSWatchdog) maybeRefreshLimits() {
+	if w.refreshLimits == nil {
+		return
+	}
+	w.ticks++
+	if w.ticks < limitRefreshTicks {
 		return
 	}
+	w.ticks = 0
 
-	// Wait for grace period, then force kill
-	go func() {
-		grace := time.Duration(w.config.GracePeriodSeconds) * time.Second
-		time.Sleep(grace)
+	limits, err := w.refreshLimits()
+	if err != nil {
+		w.logger.Warnf("[watchdog] Failed to refresh memory limits: %v (keeping cgroup=%s)", err, formatBytes(w.limits.CgroupLimitBytes))
+		return
+	}
+	if limits.CgroupLimitBytes == w.limits.CgroupLimitBytes || limits.HardKillBytes == 0 {
+		return
+	}
+	w.logger.Printf("[watchdog] Memory limit changed: cgroup=%s -> %s, effective=%s soft_warn=%s hard_kill=%s",
+		formatBytes(w.limits.CgroupLimitBytes),
+		formatBytes(limits.CgroupLimitBytes),
+		formatBytes(limits.EffectiveLimitBytes),
+		formatBytes(limits.SoftWarnBytes),
+		formatBytes(limits.HardKillBytes),
+	)
+	w.limits = limits
+}
+
+// adaptiveGracePeriod scales grace down in proportion to how far rss is over
+// limit: grace at or below the limit, grace/2 at twice the limit, and so on,
+// but never below minAdaptiveGrace (or grace itself, if that is shorter).
+func adaptiveGracePeriod(grace time.Duration, rss, limit uint64) time.Duration {
+	if limit == 0 || rss <= limit {
+		return grace
+	}
+	floor := min(minAdaptiveGrace, grace)
+	scaled := time.Duration(float64(grace) * float64(limit) / float64(rss))
+	return max(scaled, floor)
+}
 
-		// Check if process is still alive
-		if isProcessAlive(w.pid) {
-			w.logger.Printf("[watchdog] Grace period (%s) expired, sending SIGKILL to pid %d",
-				grace, w.pid)
-			_ = syscall.Kill(w.pid, syscall.SIGKILL)
+// smoothRSS folds sample into the moving average when SmoothingAlpha is set
+// and returns the average, or returns sample unchanged otherwise. The first
+// sample seeds the average.
+func (w *RSSWatchdog) smoothRSS(sample uint64) uint64 {
+	alpha := valueOr(w.config.SmoothingAlpha, 0)
+	if alpha <= 0 || alpha >= 1 {
+		return sample
+	}
+	if w.smoothed == 0 {
+		w.smoothed = float64(sample)
+	} else {
+		w.smoothed = alpha*float64(sample) + (1-alpha)*w.smoothed
+	}
+	return uint64(w.smoothed)
+}
+
+// terminateProcess sends the first shutdown step (SIGTERM by default) and
+// records the rest for CompleteShutdown. rss is the reading that crossed the
+// hard limit, used by AdaptiveGrace.
+func (w *RSSWatchdog) terminateProcess(rss uint64) {
+	w.enterState(WatchdogStateTerminating, rss)
+
+	// Resolve the cgroup now: once the process exits its /proc entry is gone.
+	cgroupDir := ""
+	if valueOr(w.config.KillCgroupOnEscalation, false) {
+		dir, err := w.processCgroupDir()
+		if err != nil {
+			w.logger.Printf("[watchdog] Cannot resolve cgroup of pid %d, escalation will SIGKILL the pid only: %v", w.pid, err)
 		}
-	}()
+		cgroupDir = dir
+	}
+
+	// The first step (SIGTERM by default) asks for a graceful shutdown.
+	grace := time.Duration(w.config.GracePeriodSeconds) * time.Second
+	if valueOr(w.config.AdaptiveGrace, false) && len(w.shutdownSequence) == 0 {
+		grace = adaptiveGracePeriod(grace, rss, w.limits.HardKillBytes)
+		w.logger.Printf("[watchdog] Adaptive grace: rss is %.1fx the hard limit, allowing %s before SIGKILL",
+			float64(rss)/float64(w.limits.HardKillBytes), grace.Round(100*time.Millisecond))
+	}
+	steps := resolveShutdownSequence(w.shutdownSequence, grace)
+	if err := syscall.Kill(w.pid, steps[0].signal); err != nil {
+		w.logger.Printf("[watchdog] Failed to send %s to pid %d: %v", signalName(steps[0].signal), w.pid, err)
+		return
+	}
+	w.pendingSteps, w.pendingCgroupDir = steps, cgroupDir
 }
 
-// readProcessRSS reads the RSS of a process from /proc/[pid]/statm.
-// The second field of statm is RSS in pages.
-func readProcessRSS(pid int) (uint64, error) {
-	path := fmt.Sprintf("/proc/%d/statm", pid)
-	data, err := os.ReadFile(path)
+// firstShutdownSignal is the signal terminateProcess sends first.
+func (w *RSSWatchdog) firstShutdownSignal() syscall.Signal {
+	return resolveShutdownSequence(w.shutdownSequence, 0)[0].signal
+}
+
+// CompleteShutdown runs the rest of the shutdown sequence started when Run
+// returned true, until the process exits, and returns the result received
+// from waitDone. Each wait ends as soon as waitDone delivers; a SIGKILL step
+// escalates, killing the cgroup when configured. After the last step it waits
+// for the process without a timeout.
+func (w *RSSWatchdog) CompleteShutdown(waitDone <-chan error) error {
+	steps, cgroupDir := w.pendingSteps, w.pendingCgroupDir
+	var elapsed time.Duration
+	for i := 1; i < len(steps); i++ {
+		timer := time.NewTimer(steps[i-1].wait)
+		select {
+		case err := <-waitDone:
+			timer.Stop()
+			w.killCgroupOverLimit(cgroupDir)
+			return err
+		case <-timer.C:
+		}
+		elapsed += steps[i-1].wait
+		if steps[i].signal == syscall.SIGKILL {
+			w.escalate(elapsed, cgroupDir)
+			break
+		}
+		w.logger.Printf("[watchdog] pid %d still running %s after %s, sending %s",
+			w.pid, steps[i-1].wait, signalName(steps[i-1].signal), signalName(steps[i].signal))
+		if err := syscall.Kill(w.pid, steps[i].signal); err != nil {
+			w.logger.Printf("[watchdog] Failed to send %s to pid %d: %v", signalName(steps[i].signal), w.pid, err)
+		}
+	}
+	return <-waitDone
+}
+
+// killCgroupOverLimit kills the rest of the cgroup when the primary exited
+// during the shutdown but the cgroup's memory is still over the hard limit,
+// e.g. from leaked workers. An empty cgroupDir does nothing.
+func (w *RSSWatchdog) killCgroupOverLimit(cgroupDir string) {
+	if cgroupDir == "" || !w.cgroupOverLimit(cgroupDir) {
+		return
+	}
+	if err := writeCgroupKill(cgroupDir); err != nil {
+		w.logger.Printf("[watchdog] Failed to kill cgroup %s: %v", cgroupDir, err)
+		return
+	}
+	w.logger.Printf("[watchdog] pid %d exited but cgroup %s is still over the hard limit, killed all processes in it",
+		w.pid, cgroupDir)
+}
+
+// escalate force-kills after the grace period. With a cgroup directory, every
+// process in the cgroup is killed if the primary is still alive or the
+// cgroup's memory is still over the hard limit; otherwise only the primary is.
+func (w *RSSWatchdog) escalate(grace time.Duration, cgroupDir string) {
+	alive := isProcessAlive(w.pid)
+	if cgroupDir != "" && (alive || w.cgroupOverLimit(cgroupDir)) {
+		err := writeCgroupKill(cgroupDir)
+		if err == nil {
+			w.logger.Printf("[watchdog] Grace period (%s) expired, killed all processes in cgroup %s",
+				grace, cgroupDir)
+			return
+		}
+		w.logger.Printf("[watchdog] Failed to kill cgroup %s, falling back to SIGKILL: %v", cgroupDir, err)
+	}
+
+	if alive {
+		w.logger.Printf("[watchdog] Grace period (%s) expired, sending SIGKILL to pid %d",
+			grace, w.pid)
+		_ = syscall.Kill(w.pid, syscall.SIGKILL)
+	}
+}
+
+// processCgroupDir returns the cgroup v2 directory of the watched process.
+func (w *RSSWatchdog) processCgroupDir() (string, error) {
+	data, err := os.ReadFile(filepath.Join(w.rootDir, "proc", strconv.Itoa(w.pid), "cgroup"))
 	if err != nil {
-		return 0, fmt.Errorf("failed to read %s: %w", path, err)
+		return "", err
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		// The unified (v2) hierarchy is the "0::<path>" entry.
+		if path, ok := strings.CutPrefix(line, "0::"); ok {
+			return filepath.Join(w.rootDir, cgroupPath(w.cgroupRoot, path)), nil
+		}
 	}
+	return "", fmt.Errorf("pid %d is not in a cgroup v2 hierarchy", w.pid)
+}
 
-	fields := strings.Fields(string(data))
-	if len(fields) < 2 {
-		return 0, fmt.Errorf("unexpected statm format: %q", string(data))
+// cgroupOverLimit reports whether the cgroup's memory.current is at or above
+// the hard limit.
+func (w *RSSWatchdog) cgroupOverLimit(cgroupDir string) bool {
+	data, err := os.ReadFile(filepath.Join(cgroupDir, "memory.current"))
+	if err != nil {
+		return false
 	}
+	current, err := strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+	return err == nil && current >= w.limits.HardKillBytes
+}
 
-	rssPages, err := strconv.ParseUint(fields[1], 10, 64)
+// writeCgroupKill kills every process in the cgroup via cgroup.kill (Linux
+// 5.14+). The file is never created, so a missing file is an error.
+func writeCgroupKill(cgroupDir string) error {
+	f, err := os.OpenFile(filepath.Join(cgroupDir, "cgroup.kill"), os.O_WRONLY, 0)
 	if err != nil {
-		return 0, fmt.Errorf("failed to parse RSS pages: %w", err)
+		return err
+	}
+	if _, err := f.Write([]byte("1")); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// peakTracker records the maximum of a series of RSS samples. It is safe for
+// concurrent use.
+type peakTracker struct {
+	value atomic.Uint64
+}
+
+// observe records rss and reports whether it is a new peak.
+func (p *peakTracker) observe(rss uint64) bool {
+	for {
+		current := p.value.Load()
+		if rss <= current {
+			return false
+		}
+		if p.value.CompareAndSwap(current, rss) {
+			return true
+		}
+	}
+}
+
+func (p *peakTracker) load() uint64 {
+	return p.value.Load()
+}
+
+// RSSSampler periodically records the peak RSS of a process without enforcing
+// any limits. It is used when the watchdog is not running.
+type RSSSampler struct {
+	pid      int
+	interval time.Duration
+	peak     peakTracker
+
+	readRSS func(pid int) (uint64, error)
+}
+
+// NewRSSSampler creates a sampler that reads the RSS of pid every interval.
+func NewRSSSampler(pid int, interval time.Duration) *RSSSampler {
+	return &RSSSampler{pid: pid, interval: interval, readRSS: readProcessRSS}
+}
+
+// Run samples once immediately and then every interval until ctx is cancelled.
+func (s *RSSSampler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		rss, err := s.readRSS(s.pid)
+		if err == nil {
+			s.peak.observe(rss)
+		} else if errors.Is(err, ErrRSSUnsupported) {
+			return
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// PeakRSS returns the highest RSS observed so far, in bytes.
+func (s *RSSSampler) PeakRSS() uint64 {
+	return s.peak.load()
+}
+
+// processMaxRSS returns the peak RSS the kernel recorded for an exited process,
+// or 0 if it is unavailable.
+func processMaxRSS(state *os.ProcessState) uint64 {
+	if state == nil {
+		return 0
 	}
+	usage, ok := state.SysUsage().(*syscall.Rusage)
+	if !ok || usage.Maxrss <= 0 {
+		return 0
+	}
+	return uint64(usage.Maxrss) * maxrssUnitBytes
+}
 
-	pageSize := uint64(os.Getpagesize())
-	return rssPages * pageSize, nil
+// WritePeakRSSFile writes the peak RSS in bytes to path.
+func WritePeakRSSFile(path string, peak uint64) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, []byte(strconv.FormatUint(peak, 10)+"\n"), 0644)
 }
 
 // readProcessRSSWithChildren reads RSS for the process and all its children.
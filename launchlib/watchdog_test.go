@@ -0,0 +1,726 @@
+package launchlib
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+// newTestWatchdog creates a watchdog with a fake RSS reader and a buffered logger.
+func newTestWatchdog(config WatchdogConfig, readRSS func(pid int) (uint64, error)) (*RSSWatchdog, *bytes.Buffer) {
+	var buf bytes.Buffer
+	limits := MemoryLimits{
+		CgroupLimitBytes: 1000,
+		SoftWarnBytes:    850,
+		HardKillBytes:    950,
+	}
+	w := NewRSSWatchdog(-1, limits, config, NewLogger(&buf, LoggingConfig{}))
+	w.readRSS = readRSS
+	return w, &buf
+}
+
+func TestWatchdogReadFailureThreshold(t *testing.T) {
+	w, _ := newTestWatchdog(WatchdogConfig{MaxConsecutiveReadFailures: 3}, func(int) (uint64, error) {
+		return 0, errors.New("no such process")
+	})
+
+	for i := 1; i <= 2; i++ {
+		if w.check() {
+			t.Fatal("a failed read must not trigger termination")
+		}
+		if w.ConsecutiveReadFailures() != i {
+			t.Errorf("expected %d consecutive failures, got %d", i, w.ConsecutiveReadFailures())
+		}
+		if w.readFailuresExhausted() {
+			t.Fatalf("failure budget exhausted after only %d failures", i)
+		}
+	}
+
+	w.check()
+	if !w.readFailuresExhausted() {
+		t.Errorf("expected failure budget exhausted after 3 failures")
+	}
+}
+
+func TestWatchdogReadFailureResetsOnSuccess(t *testing.T) {
+	fail := true
+	w, _ := newTestWatchdog(WatchdogConfig{MaxConsecutiveReadFailures: 3}, func(int) (uint64, error) {
+		if fail {
+			return 0, errors.New("transient")
+		}
+		return 100, nil
+	})
+
+	w.check()
+	w.check()
+	fail = false
+	w.check()
+	if w.ConsecutiveReadFailures() != 0 {
+		t.Errorf("expected failure count reset after a successful read, got %d", w.ConsecutiveReadFailures())
+	}
+}
+
+func TestWatchdogRunStopsAfterReadFailures(t *testing.T) {
+	w, buf := newTestWatchdog(WatchdogConfig{
+		PollIntervalSeconds:        1,
+		MaxConsecutiveReadFailures: 1,
+	}, func(int) (uint64, error) {
+		return 0, errors.New("gone")
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if w.Run(ctx) {
+		t.Fatal("watchdog should not report a trigger when reads fail")
+	}
+	if ctx.Err() != nil {
+		t.Fatal("watchdog did not stop on its own after exhausting read failures")
+	}
+	if !bytes.Contains(buf.Bytes(), []byte("assuming process exited")) {
+		t.Errorf("expected exit log line, got %q", buf.String())
+	}
+}
+
+func TestWatchdogRunPollIntervalMillis(t *testing.T) {
+	reads := 0
+	w, buf := newTestWatchdog(WatchdogConfig{
+		PollIntervalSeconds:        5,
+		PollIntervalMillis:         50,
+		MaxConsecutiveReadFailures: 1,
+	}, func(int) (uint64, error) {
+		reads++
+		if reads == 1 {
+			return 1 << 20, nil
+		}
+		return 0, errors.New("gone")
+	})
+
+	start := time.Now()
+	w.Run(context.Background())
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected the second poll after ~50ms, took %s", elapsed)
+	}
+	if !bytes.Contains(buf.Bytes(), []byte("poll=50ms")) {
+		t.Errorf("expected poll=50ms in the start log, got %q", buf.String())
+	}
+}
+
+func TestJitterInterval(t *testing.T) {
+	interval := time.Second
+	for i := 0; i < 100; i++ {
+		got := jitterInterval(interval)
+		if got < interval || got >= interval+interval/10 {
+			t.Fatalf("jittered interval %s outside [%s, %s)", got, interval, interval+interval/10)
+		}
+	}
+}
+
+func TestWatchdogTracksPeakRSS(t *testing.T) {
+	samples := []uint64{300, 700, 500}
+	i := 0
+	w, _ := newTestWatchdog(WatchdogConfig{}, func(int) (uint64, error) {
+		rss := samples[i]
+		i++
+		return rss, nil
+	})
+	for range samples {
+		w.check()
+	}
+	if w.PeakRSS() != 700 {
+		t.Errorf("expected peak 700, got %d", w.PeakRSS())
+	}
+}
+
+func TestRSSSamplerRecordsPeak(t *testing.T) {
+	sampler := NewRSSSampler(-1, time.Millisecond)
+	var mu sync.Mutex
+	calls := 0
+	sampler.readRSS = func(int) (uint64, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls++
+		if calls == 2 {
+			return 4096, nil
+		}
+		return 1024, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	sampler.Run(ctx)
+
+	if sampler.PeakRSS() != 4096 {
+		t.Errorf("expected peak 4096, got %d", sampler.PeakRSS())
+	}
+}
+
+func TestWatchdogRunDisablesWhenRSSUnavailable(t *testing.T) {
+	for _, readErr := range []error{
+		fmt.Errorf("failed to read /proc/1/statm: %w", fs.ErrNotExist),
+		ErrRSSUnsupported,
+	} {
+		calls := 0
+		w, buf := newTestWatchdog(WatchdogConfig{PollIntervalSeconds: 1}, func(int) (uint64, error) {
+			calls++
+			return 0, readErr
+		})
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		if w.Run(ctx) {
+			t.Error("watchdog should not report a trigger when RSS is unavailable")
+		}
+		if ctx.Err() != nil {
+			t.Error("watchdog did not return immediately after the failed probe")
+		}
+		cancel()
+
+		if calls != 1 {
+			t.Errorf("expected a single probe read, got %d", calls)
+		}
+		if !bytes.Contains(buf.Bytes(), []byte("memory protection is DISABLED")) {
+			t.Errorf("expected disabled warning, got %q", buf.String())
+		}
+	}
+}
+
+func TestWatchdogStartupGrace(t *testing.T) {
+	// The hard limit sends real signals, so point the watchdog at a child we own.
+	child := exec.Command("sleep", "30")
+	if err := child.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = child.Process.Kill()
+		_ = child.Wait()
+	}()
+
+	startupGrace := 60
+	w, buf := newTestWatchdog(WatchdogConfig{StartupGraceSeconds: &startupGrace, GracePeriodSeconds: 30}, func(int) (uint64, error) {
+		return 990, nil // above the hard limit of 950
+	})
+	w.pid = child.Process.Pid
+	now := time.Unix(1_700_000_000, 0)
+	w.now = func() time.Time { return now }
+	w.started = now
+
+	now = now.Add(30 * time.Second)
+	if w.check() {
+		t.Fatal("over-limit reading during startup grace must not trigger termination")
+	}
+	if !bytes.Contains(buf.Bytes(), []byte("during startup grace")) {
+		t.Errorf("expected grace log line, got %q", buf.String())
+	}
+
+	now = now.Add(31 * time.Second)
+	if !w.check() {
+		t.Fatal("over-limit reading after startup grace must trigger termination")
+	}
+	if err := child.Wait(); err == nil {
+		t.Error("expected child to be terminated by the watchdog")
+	}
+}
+
+// fakeCgroupRoot lays out /proc/<pid>/cgroup and the cgroup v2 directory for
+// pid under a temp root, optionally with a cgroup.kill file.
+func fakeCgroupRoot(t *testing.T, pid int, withKillFile bool) (root, cgroupDir string) {
+	t.Helper()
+	root = t.TempDir()
+	procDir := filepath.Join(root, "proc", strconv.Itoa(pid))
+	if err := os.MkdirAll(procDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(procDir, "cgroup"), []byte("0::/kubepods/pod1/app\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cgroupDir = filepath.Join(root, "sys/fs/cgroup/kubepods/pod1/app")
+	if err := os.MkdirAll(cgroupDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if withKillFile {
+		if err := os.WriteFile(filepath.Join(cgroupDir, "cgroup.kill"), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root, cgroupDir
+}
+
+func TestWatchdogEscalationKillsCgroup(t *testing.T) {
+	child := exec.Command("sleep", "30")
+	if err := child.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = child.Process.Kill()
+		_ = child.Wait()
+	}()
+
+	killCgroup := true
+	w, buf := newTestWatchdog(WatchdogConfig{KillCgroupOnEscalation: &killCgroup}, nil)
+	w.pid = child.Process.Pid
+	root, cgroupDir := fakeCgroupRoot(t, w.pid, true)
+	w.rootDir = root
+
+	dir, err := w.processCgroupDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dir != cgroupDir {
+		t.Fatalf("expected cgroup dir %s, got %s", cgroupDir, dir)
+	}
+
+	w.escalate(time.Second, dir)
+
+	data, err := os.ReadFile(filepath.Join(cgroupDir, "cgroup.kill"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "1" {
+		t.Errorf("expected cgroup.kill to contain 1, got %q", data)
+	}
+	if !strings.Contains(buf.String(), "killed all processes in cgroup") {
+		t.Errorf("expected cgroup kill log, got:\n%s", buf.String())
+	}
+	if !isProcessAlive(w.pid) {
+		t.Error("expected the primary not to be signalled when cgroup.kill succeeds")
+	}
+}
+
+func TestWatchdogEscalationCgroupKillFallsBackToSIGKILL(t *testing.T) {
+	child := exec.Command("sleep", "30")
+	if err := child.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = child.Process.Kill()
+		_ = child.Wait()
+	}()
+
+	killCgroup := true
+	w, buf := newTestWatchdog(WatchdogConfig{KillCgroupOnEscalation: &killCgroup}, nil)
+	w.pid = child.Process.Pid
+	root, cgroupDir := fakeCgroupRoot(t, w.pid, false)
+	w.rootDir = root
+
+	w.escalate(time.Second, cgroupDir)
+
+	if _, err := os.Stat(filepath.Join(cgroupDir, "cgroup.kill")); !os.IsNotExist(err) {
+		t.Errorf("expected cgroup.kill not to be created, got %v", err)
+	}
+	if err := child.Wait(); err == nil {
+		t.Error("expected the primary to be killed")
+	}
+	if !strings.Contains(buf.String(), "sending SIGKILL") {
+		t.Errorf("expected SIGKILL fallback log, got:\n%s", buf.String())
+	}
+}
+
+func TestWatchdogEscalationKillsCgroupOverLimitAfterExit(t *testing.T) {
+	child := exec.Command("true")
+	if err := child.Run(); err != nil {
+		t.Fatal(err)
+	}
+
+	killCgroup := true
+	w, _ := newTestWatchdog(WatchdogConfig{KillCgroupOnEscalation: &killCgroup}, nil)
+	w.pid = child.Process.Pid
+	_, cgroupDir := fakeCgroupRoot(t, w.pid, true)
+	if err := os.WriteFile(filepath.Join(cgroupDir, "memory.current"), []byte("990\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	w.escalate(time.Second, cgroupDir)
+
+	data, err := os.ReadFile(filepath.Join(cgroupDir, "cgroup.kill"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "1" {
+		t.Errorf("expected leaked workers over the limit to trigger cgroup.kill, got %q", data)
+	}
+}
+
+func TestWatchdogAnonymousOnly(t *testing.T) {
+	child := exec.Command("sleep", "30")
+	if err := child.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = child.Process.Kill()
+		_ = child.Wait()
+	}()
+
+	totalRSS := func(int) (uint64, error) { return 990, nil } // above the hard limit of 950
+	anonRSS := func(int) (uint64, error) { return 500, nil }  // mostly file-backed mappings
+
+	anonymousOnly := true
+	w, _ := newTestWatchdog(WatchdogConfig{AnonymousOnly: &anonymousOnly, GracePeriodSeconds: 30}, totalRSS)
+	w.pid = child.Process.Pid
+	w.readAnonRSS = anonRSS
+	if w.check() {
+		t.Fatal("anonymous-only accounting must not trigger on file-backed RSS")
+	}
+	if w.State() != WatchdogStateHealthy {
+		t.Errorf("expected healthy, got %s", w.State())
+	}
+
+	w, _ = newTestWatchdog(WatchdogConfig{GracePeriodSeconds: 30}, totalRSS)
+	w.pid = child.Process.Pid
+	w.readAnonRSS = anonRSS
+	if !w.check() {
+		t.Error("expected total RSS accounting to trigger at the hard limit")
+	}
+}
+
+func TestWatchdogAnonymousOnlyFallsBackToStatm(t *testing.T) {
+	anonymousOnly := true
+	w, buf := newTestWatchdog(WatchdogConfig{AnonymousOnly: &anonymousOnly}, func(int) (uint64, error) {
+		return 900, nil // above the soft limit of 850
+	})
+	w.readAnonRSS = func(int) (uint64, error) {
+		return 0, fs.ErrNotExist
+	}
+
+	for i := 0; i < 2; i++ {
+		if w.check() {
+			t.Fatal("soft limit must not trigger termination")
+		}
+	}
+	if w.State() != WatchdogStateSoftWarning {
+		t.Errorf("expected statm fallback to reach soft_warning, got %s", w.State())
+	}
+	if n := strings.Count(buf.String(), "falling back to total RSS"); n != 1 {
+		t.Errorf("expected the fallback to be logged once, got %d times:\n%s", n, buf.String())
+	}
+}
+
+func TestParseSmapsRollupAnonymous(t *testing.T) {
+	data := `55d0c8a4c000-7ffd3a5f2000 ---p 00000000 00:00 0                          [rollup]
+Rss:              204800 kB
+Pss:              180000 kB
+Shared_Clean:     150000 kB
+Private_Dirty:     50000 kB
+Anonymous:         51200 kB
+Swap:                  0 kB
+`
+	anon, err := parseSmapsRollupAnonymous(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if anon != 51200*1024 {
+		t.Errorf("expected %d, got %d", 51200*1024, anon)
+	}
+	if _, err := parseSmapsRollupAnonymous("Rss: 10 kB\n"); err == nil {
+		t.Error("expected an error without an Anonymous field")
+	}
+}
+
+func TestWatchdogShutdownSequence(t *testing.T) {
+	child, waitDone := startTermIgnoringChild(t)
+
+	w, buf := newTestWatchdog(WatchdogConfig{GracePeriodSeconds: 30}, func(int) (uint64, error) {
+		return 990, nil // above the hard limit of 950
+	})
+	w.pid = child.Process.Pid
+	w.SetShutdownSequence([]SignalStep{
+		{Signal: "SIGTERM", WaitSeconds: 1},
+		{Signal: "SIGQUIT", WaitSeconds: 10},
+		{Signal: "SIGKILL"},
+	})
+	if !w.check() {
+		t.Fatal("expected the hard limit to trigger termination")
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- w.CompleteShutdown(waitDone) }()
+	select {
+	case err := <-done:
+		if sig := signaledBy(err); sig != syscall.SIGQUIT {
+			t.Errorf("expected the process to die on SIGQUIT, got %v (%v)\n%s", sig, err, buf.String())
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("process survived the SIGQUIT step\n%s", buf.String())
+	}
+}
+
+func TestWatchdogCompleteShutdownKillsAfterGrace(t *testing.T) {
+	child, waitDone := startTermIgnoringChild(t)
+
+	w, buf := newTestWatchdog(WatchdogConfig{GracePeriodSeconds: 1}, func(int) (uint64, error) {
+		return 990, nil // above the hard limit of 950
+	})
+	w.pid = child.Process.Pid
+	if !w.check() {
+		t.Fatal("expected the hard limit to trigger termination")
+	}
+
+	start := time.Now()
+	err := w.CompleteShutdown(waitDone)
+	if elapsed := time.Since(start); elapsed < time.Second || elapsed > 5*time.Second {
+		t.Errorf("expected SIGKILL about 1s after SIGTERM, got %s", elapsed)
+	}
+	if sig := signaledBy(err); sig != syscall.SIGKILL {
+		t.Errorf("expected the process to die on SIGKILL, got %v (%v)\n%s", sig, err, buf.String())
+	}
+	if n := strings.Count(buf.String(), "sending SIGKILL"); n != 1 {
+		t.Errorf("expected exactly one SIGKILL, got %d:\n%s", n, buf.String())
+	}
+}
+
+func TestWatchdogCompleteShutdownReturnsOnExit(t *testing.T) {
+	child := exec.Command("sleep", "30")
+	if err := child.Start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = child.Process.Kill() })
+	waitDone := make(chan error, 1)
+	go func() { waitDone <- child.Wait() }()
+
+	w, buf := newTestWatchdog(WatchdogConfig{GracePeriodSeconds: 30}, func(int) (uint64, error) {
+		return 990, nil
+	})
+	w.pid = child.Process.Pid
+	if !w.check() {
+		t.Fatal("expected the hard limit to trigger termination")
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- w.CompleteShutdown(waitDone) }()
+	select {
+	case err := <-done:
+		if sig := signaledBy(err); sig != syscall.SIGTERM {
+			t.Errorf("expected the process to die on SIGTERM, got %v (%v)", sig, err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("expected the shutdown to end when the process exited\n%s", buf.String())
+	}
+	if strings.Contains(buf.String(), "SIGKILL") {
+		t.Errorf("expected no SIGKILL for a process that exited on SIGTERM, got:\n%s", buf.String())
+	}
+}
+
+func TestAdaptiveGracePeriod(t *testing.T) {
+	grace := 30 * time.Second
+	tests := []struct {
+		name string
+		rss  uint64
+		want time.Duration
+	}{
+		{"at the limit", 1000, 30 * time.Second},
+		{"slightly over", 1100, 27272727272 * time.Nanosecond},
+		{"twice the limit", 2000, 15 * time.Second},
+		{"three times the limit", 3000, 10 * time.Second},
+		{"far over the limit", 100000, minAdaptiveGrace},
+	}
+	previous := grace
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := adaptiveGracePeriod(grace, tt.rss, 1000)
+			if got.Round(time.Millisecond) != tt.want.Round(time.Millisecond) {
+				t.Errorf("expected %s, got %s", tt.want, got)
+			}
+			if got > previous {
+				t.Errorf("expected grace to shrink as rss grows, got %s after %s", got, previous)
+			}
+			previous = got
+		})
+	}
+
+	if got := adaptiveGracePeriod(2*time.Second, 100000, 1000); got != 2*time.Second {
+		t.Errorf("expected a grace shorter than the floor to be kept, got %s", got)
+	}
+}
+
+func TestWatchdogAdaptiveGrace(t *testing.T) {
+	child := exec.Command("sleep", "30")
+	if err := child.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = child.Process.Kill()
+		_ = child.Wait()
+	}()
+
+	adaptive := true
+	w, buf := newTestWatchdog(WatchdogConfig{GracePeriodSeconds: 30, AdaptiveGrace: &adaptive}, func(int) (uint64, error) {
+		return 2850, nil // three times the hard limit of 950
+	})
+	w.pid = child.Process.Pid
+	if !w.check() {
+		t.Fatal("expected the hard limit to trigger termination")
+	}
+	if !strings.Contains(buf.String(), "rss is 3.0x the hard limit, allowing 10s before SIGKILL") {
+		t.Errorf("expected the adaptive grace to be logged, got:\n%s", buf.String())
+	}
+}
+
+func TestWatchdogObserveOnly(t *testing.T) {
+	child := exec.Command("sleep", "30")
+	if err := child.Start(); err != nil {
+		t.Fatal(err)
+	}
+	waitDone := make(chan error, 1)
+	go func() { waitDone <- child.Wait() }()
+	defer func() { _ = child.Process.Kill() }()
+
+	observeOnly := true
+	rss := uint64(990) // above the hard limit of 950
+	w, buf := newTestWatchdog(WatchdogConfig{GracePeriodSeconds: 1, ObserveOnly: &observeOnly, PollIntervalMillis: 50},
+		func(int) (uint64, error) { return rss, nil })
+	w.pid = child.Process.Pid
+
+	if w.check() {
+		t.Fatal("expected observe-only mode not to trigger termination")
+	}
+	if w.State() != WatchdogStateHardLimit {
+		t.Errorf("expected state %s, got %s", WatchdogStateHardLimit, w.State())
+	}
+	if strings.Count(buf.String(), "would send SIGTERM") != 1 {
+		t.Errorf("expected one observe-only log line, got:\n%s", buf.String())
+	}
+
+	// Dropping below the soft limit and crossing again is reported again.
+	rss = 100
+	w.check()
+	rss = 990
+	w.check()
+	if strings.Count(buf.String(), "would send SIGTERM") != 2 {
+		t.Errorf("expected the second crossing to be logged, got:\n%s", buf.String())
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	if w.Run(ctx) {
+		t.Error("expected Run to return false in observe-only mode")
+	}
+
+	select {
+	case err := <-waitDone:
+		t.Fatalf("expected no signal to be sent, but the process exited: %v", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestWatchdogSmoothingAlpha(t *testing.T) {
+	child := exec.Command("sleep", "30")
+	if err := child.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = child.Process.Kill()
+		_ = child.Wait()
+	}()
+
+	alpha := 0.3
+	var rss uint64
+	w, buf := newTestWatchdog(WatchdogConfig{GracePeriodSeconds: 30, SmoothingAlpha: &alpha}, func(int) (uint64, error) {
+		return rss, nil
+	})
+	w.pid = child.Process.Pid
+
+	// A single spike above the hard limit of 950 amid steady readings.
+	for _, sample := range []uint64{500, 500, 990, 500, 500} {
+		rss = sample
+		if w.check() {
+			t.Fatalf("expected a single spike not to trip the hard limit\n%s", buf.String())
+		}
+	}
+	if w.PeakRSS() != 990 {
+		t.Errorf("expected the peak to track the raw spike, got %d", w.PeakRSS())
+	}
+
+	// A sustained rise trips it once the average catches up.
+	rss = 990
+	checks := 0
+	for !w.check() {
+		checks++
+		if checks > 20 {
+			t.Fatalf("expected sustained RSS above the hard limit to trip\n%s", buf.String())
+		}
+	}
+	if checks == 0 {
+		t.Error("expected the average to lag the first sustained sample")
+	}
+}
+
+func TestWatchdogSmoothingDisabledByDefault(t *testing.T) {
+	w, _ := newTestWatchdog(WatchdogConfig{}, nil)
+	for _, sample := range []uint64{500, 990} {
+		if got := w.smoothRSS(sample); got != sample {
+			t.Errorf("expected raw sample %d without smoothing, got %d", sample, got)
+		}
+	}
+}
+
+func TestWatchdogRefreshLimits(t *testing.T) {
+	child := exec.Command("sleep", "30")
+	if err := child.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = child.Process.Kill()
+		_ = child.Wait()
+	}()
+
+	filesystem := fstest.MapFS{
+		"sys/fs/cgroup/cgroup.controllers": {Data: []byte("cpu memory io")},
+		"sys/fs/cgroup/memory.max":         {Data: []byte("1000\n")},
+	}
+	config := MergedConfig{Memory: DefaultMemoryConfig(), Watchdog: DefaultWatchdogConfig()}
+	config.Watchdog.SoftLimitPercent = 85
+	config.Watchdog.HardLimitPercent = 95
+	config.Memory.CgroupReadAttempts = 1
+	limiter := NewMemoryLimiterWithFS(filesystem)
+
+	rss := uint64(1200)
+	w, buf := newTestWatchdog(config.Watchdog, func(int) (uint64, error) { return rss, nil })
+	w.pid = child.Process.Pid
+	w.SetLimitRefresher(func() (MemoryLimits, error) { return limiter.ComputeLimits(config) })
+
+	// An unchanged limit is kept without logging.
+	for i := 0; i < limitRefreshTicks; i++ {
+		w.maybeRefreshLimits()
+	}
+	if w.limits.HardKillBytes != 950 || strings.Contains(buf.String(), "Memory limit changed") {
+		t.Fatalf("expected the limits unchanged, got %+v\n%s", w.limits, buf.String())
+	}
+
+	// The pod is resized between reads.
+	filesystem["sys/fs/cgroup/memory.max"] = &fstest.MapFile{Data: []byte("2000\n")}
+	for i := 0; i < limitRefreshTicks-1; i++ {
+		w.maybeRefreshLimits()
+	}
+	if w.limits.CgroupLimitBytes != 1000 {
+		t.Fatalf("expected no refresh before %d polls, got %+v", limitRefreshTicks, w.limits)
+	}
+	w.maybeRefreshLimits()
+	if w.limits.CgroupLimitBytes != 2000 || w.limits.SoftWarnBytes != 1700 || w.limits.HardKillBytes != 1900 {
+		t.Errorf("expected limits recomputed for 2000 bytes, got %+v", w.limits)
+	}
+	if !strings.Contains(buf.String(), "Memory limit changed: cgroup=") {
+		t.Errorf("expected the adjustment to be logged, got:\n%s", buf.String())
+	}
+	if w.check() {
+		t.Errorf("expected RSS %d to be under the raised hard limit\n%s", rss, buf.String())
+	}
+
+	// A failed read keeps the current limits.
+	delete(filesystem, "sys/fs/cgroup/memory.max")
+	for i := 0; i < limitRefreshTicks; i++ {
+		w.maybeRefreshLimits()
+	}
+	if w.limits.HardKillBytes != 1900 {
+		t.Errorf("expected the limits kept after a failed refresh, got %+v", w.limits)
+	}
+}